@@ -2,11 +2,18 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"html/template"
+	"maps"
 	"net"
 	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
+	"reflect"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/caarlos0/env/v6"
@@ -14,39 +21,111 @@ import (
 	"github.com/rs/zerolog/log"
 
 	"github.com/OpenSlides/openslides-go/datastore"
+	"github.com/OpenSlides/openslides-go/datastore/dskey"
 	"github.com/OpenSlides/openslides-go/datastore/flow"
 	"github.com/OpenSlides/openslides-go/environment"
 	"github.com/OpenSlides/openslides-go/redis"
 	"github.com/OpenSlides/openslides-projector-service/pkg/database"
 	projectorHttp "github.com/OpenSlides/openslides-projector-service/pkg/http"
+	"github.com/OpenSlides/openslides-projector-service/pkg/projector"
+	"github.com/OpenSlides/openslides-projector-service/pkg/projector/slide"
+	"github.com/OpenSlides/openslides-projector-service/pkg/tracing"
 )
 
+// config is read from the environment and the optional CONFIG_FILE. Fields
+// tagged with reload:"hot" are applied on SIGHUP without a restart.
 type config struct {
-	Bind                 string        `env:"BIND" envDefault:":9051"`
-	Development          bool          `env:"OPENSLIDES_DEVELOPMENT" envDefault:"false"`
-	MetricInterval       time.Duration `env:"METRIC_INTERVAL" envDefault:"5m"`
-	PostgresHost         string        `env:"DATABASE_HOST" envDefault:"localhost"`
-	PostgresPort         string        `env:"DATABASE_PORT" envDefault:"5432"`
-	PostgresDatabase     string        `env:"DATABASE_NAME" envDefault:"openslides"`
-	PostgresUser         string        `env:"DATABASE_USER" envDefault:"openslides"`
-	PostgresPasswordFile string        `env:"DATABASE_PASSWORD_FILE" envDefault:"/run/secrets/postgres_password"`
-	MessageBusHost       string        `env:"MESSAGE_BUS_HOST" envDetault:"localhost"`
-	MessageBusPort       string        `env:"MESSAGE_BUS_PORT" envDetault:"6379"`
-	RestricterUrl        string        `env:"RESTRICTER_URL" envDetault:"http://autoupdate:9012/internal/autoupdate"`
-	PublicAccessOnly     bool          `env:"OPENSLIDES_PUBLIC_ACCESS_ONLY" envDefault:"false"`
+	Bind                  string        `env:"BIND" envDefault:":9051"`
+	Development           bool          `env:"OPENSLIDES_DEVELOPMENT" envDefault:"false"`
+	MetricInterval        time.Duration `env:"METRIC_INTERVAL" envDefault:"5m"`
+	PostgresHost          string        `env:"DATABASE_HOST" envDefault:"localhost"`
+	PostgresPort          string        `env:"DATABASE_PORT" envDefault:"5432"`
+	PostgresDatabase      string        `env:"DATABASE_NAME" envDefault:"openslides"`
+	PostgresUser          string        `env:"DATABASE_USER" envDefault:"openslides"`
+	PostgresReadHost      string        `env:"DATABASE_READ_HOST" envDefault:""`
+	PostgresReadPort      string        `env:"DATABASE_READ_PORT" envDefault:""`
+	ReadPrimarySnapshot   bool          `env:"DATABASE_READ_PRIMARY_SNAPSHOT" envDefault:"true"`
+	PostgresPasswordFile  string        `env:"DATABASE_PASSWORD_FILE" envDefault:"/run/secrets/postgres_password"`
+	MessageBusHost        string        `env:"MESSAGE_BUS_HOST" envDefault:"localhost"`
+	MessageBusPort        string        `env:"MESSAGE_BUS_PORT" envDefault:"6379"`
+	RestricterUrl         string        `env:"RESTRICTER_URL" envDefault:"http://autoupdate:9012/internal/autoupdate"`
+	RestricterUrls        []string      `env:"RESTRICTER_URLS" envSeparator:","`
+	RestricterStaleWindow time.Duration `env:"RESTRICTER_STALE_WINDOW" envDefault:"30s"`
+	RestricterCAFile      string        `env:"RESTRICTER_CA_FILE" envDefault:""`
+	PermissionRecheck     time.Duration `env:"PERMISSION_RECHECK_INTERVAL" envDefault:"1m"`
+	RestricterInsecureTLS bool          `env:"RESTRICTER_INSECURE_SKIP_VERIFY" envDefault:"false"`
+	PublicAccessOnly      bool          `env:"OPENSLIDES_PUBLIC_ACCESS_ONLY" envDefault:"false"`
+	AnonymousUserID       int           `env:"ANONYMOUS_USER_ID" envDefault:"0"`
+	MaxRequestBodySize    int64         `env:"MAX_REQUEST_BODY_SIZE" envDefault:"1048576"`
+	MaxRequestHeaderSize  int           `env:"MAX_REQUEST_HEADER_SIZE" envDefault:"1048576"`
+	DefaultLanguage       string        `env:"DEFAULT_LANGUAGE" envDefault:"en"`
+	SSERetryMs            int           `env:"SSE_RETRY_MS" envDefault:"3000" reload:"hot"`
+	SSERetryJitterMs      int           `env:"SSE_RETRY_JITTER_MS" envDefault:"0" reload:"hot"`
+	SSEFlushPolicy        string        `env:"SSE_FLUSH_POLICY" envDefault:"immediate" reload:"hot"`
+	SSEFlushIntervalMs    int           `env:"SSE_FLUSH_INTERVAL_MS" envDefault:"50" reload:"hot"`
+	SSEFlushThreshold     int           `env:"SSE_FLUSH_THRESHOLD_BYTES" envDefault:"4096" reload:"hot"`
+	SSEReplayBuffer       int           `env:"SSE_REPLAY_BUFFER" envDefault:"64"`
+	MediaProxy            bool          `env:"MEDIA_PROXY" envDefault:"false"`
+	MediaServiceUrl       string        `env:"MEDIA_SERVICE_URL" envDefault:"http://media:9006/system/media/get"`
+	MediaCDNBase          string        `env:"MEDIA_CDN_BASE" envDefault:""`
+	MaxSlideSize          int           `env:"MAX_SLIDE_SIZE" envDefault:"2097152"`
+	StaleContentWindow    time.Duration `env:"STALE_CONTENT_WINDOW" envDefault:"30s"`
+	ProjectorStaleAfter   time.Duration `env:"PROJECTOR_STALE_AFTER" envDefault:"0"`
+	RenderCacheSize       int           `env:"RENDER_CACHE_SIZE" envDefault:"512"`
+	RenderCacheMaxMB      int           `env:"RENDER_CACHE_MAX_MB" envDefault:"256"`
+	FontMappingFile       string        `env:"FONT_MAPPING_FILE" envDefault:""`
+	SanitizerConfigFile   string        `env:"SANITIZER_CONFIG_FILE" envDefault:""`
+	SanitizerOrgField     string        `env:"SANITIZER_ORGANIZATION_FIELD" envDefault:""`
+	SanitizerMeetingField string        `env:"SANITIZER_MEETING_FIELD" envDefault:""`
+	DefaultSlide          bool          `env:"DEFAULT_SLIDE" envDefault:"true"`
+	DefaultSlideFile      string        `env:"DEFAULT_SLIDE_FILE" envDefault:""`
+	ChangeWebhook         string        `env:"PROJECTION_CHANGE_WEBHOOK" envDefault:""`
+	ChangeWebhookIDs      []int         `env:"PROJECTION_CHANGE_WEBHOOK_PROJECTORS" envSeparator:","`
+	OrganizationMessage   string        `env:"ORGANIZATION_MESSAGE_FIELD" envDefault:""`
+	AdminTokenFile        string        `env:"ADMIN_TOKEN_FILE" envDefault:""`
+	ProjectorIDAllowlist  []int         `env:"PROJECTOR_ID_ALLOWLIST" envSeparator:","`
+	WebsocketOrigins      []string      `env:"WEBSOCKET_ALLOWED_ORIGINS" envSeparator:","`
+	HealthPath            string        `env:"HEALTH_PATH" envDefault:"/system/projector/health"`
+	MinifyHTML            bool          `env:"MINIFY_HTML" envDefault:"false" reload:"hot"`
+	PollInterval          time.Duration `env:"POLL_INTERVAL" envDefault:"5s" reload:"hot"`
+	RequestTimeout        time.Duration `env:"REQUEST_TIMEOUT" envDefault:"30s" reload:"hot"`
+	MaxConcurrentRequests int           `env:"MAX_CONCURRENT_REQUESTS" envDefault:"0" reload:"hot"`
+	MaxConcurrentStreams  int           `env:"MAX_CONCURRENT_STREAMS" envDefault:"0" reload:"hot"`
+	RenderMaxConcurrency  int           `env:"RENDER_MAX_CONCURRENCY" envDefault:"0"`
+	RenderQueueSize       int           `env:"RENDER_QUEUE_SIZE" envDefault:"16"`
+	RenderQueueTimeout    time.Duration `env:"RENDER_QUEUE_TIMEOUT" envDefault:"10s"`
+	ConfigFile            string        `env:"CONFIG_FILE" envDefault:""`
+	LogLevel              string        `env:"LOG_LEVEL" envDefault:"info" reload:"hot"`
+	OTLPEndpoint          string        `env:"OTEL_EXPORTER_OTLP_ENDPOINT" envDefault:""`
+	ServerTiming          bool          `env:"SERVER_TIMING" envDefault:"false"`
 }
 
+const (
+	// voteRetryMaxDelay is the longest wait before reconnecting to the vote
+	// service after repeated failures.
+	voteRetryMaxDelay = 30 * time.Second
+
+	// shutdownTimeout bounds the wait for open requests on shutdown.
+	shutdownTimeout = 10 * time.Second
+)
+
 func main() {
-	var cfg config
-	err := env.Parse(&cfg)
+	cfg, err := loadConfig()
 	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stdout})
 	if err != nil {
-		log.Err(err).Msg("parsing config")
+		log.Fatal().Err(err).Msg("parsing config")
+	}
+
+	logConfig(cfg)
+
+	if err := validateConfig(cfg); err != nil {
+		log.Fatal().Err(err).Msg("invalid config")
 	}
 
 	if cfg.Development {
 		log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
 	}
+	setLogLevel(cfg.LogLevel)
 
 	if err := run(cfg); err != nil {
 		log.Fatal().Err(err).Msg("Error during startup")
@@ -55,8 +134,302 @@ func main() {
 	log.Info().Msg("Stopped")
 }
 
+// loadConfig reads the config from the environment. Values in the file set in
+// CONFIG_FILE take precedence over the environment.
+func loadConfig() (config, error) {
+	environ := make(map[string]string)
+	for _, entry := range os.Environ() {
+		if key, value, ok := strings.Cut(entry, "="); ok {
+			environ[key] = value
+		}
+	}
+
+	if file := environ["CONFIG_FILE"]; file != "" {
+		values, err := readConfigFile(file)
+		if err != nil {
+			return config{}, fmt.Errorf("reading config file: %w", err)
+		}
+		maps.Copy(environ, values)
+	}
+
+	var cfg config
+	if err := env.Parse(&cfg, env.Options{Environment: environ}); err != nil {
+		return config{}, err
+	}
+
+	return cfg, nil
+}
+
+// readConfigFile reads KEY=VALUE lines. Empty lines and lines starting with #
+// are skipped.
+func readConfigFile(file string) (map[string]string, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string)
+	for i, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d of %s is not KEY=VALUE", i+1, file)
+		}
+		values[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+
+	return values, nil
+}
+
+// logConfig logs the effective config. Secrets are only shown as the path of
+// the file they are read from, credentials in urls and the database password
+// are masked.
+func logConfig(cfg config) {
+	event := log.Info()
+
+	value := reflect.ValueOf(cfg)
+	for _, field := range reflect.VisibleFields(value.Type()) {
+		name := field.Tag.Get("env")
+		if name == "" {
+			continue
+		}
+
+		v := fmt.Sprint(value.FieldByIndex(field.Index).Interface())
+		if u, err := url.Parse(v); err == nil && u.User != nil {
+			v = u.Redacted()
+		}
+
+		event = event.Str(name, v)
+	}
+
+	event.Str("DATABASE_DSN", postgresDSN(cfg, "*****")).Msg("Effective config")
+}
+
+// validateConfig checks that all required values are set and well-formed.
+func validateConfig(cfg config) error {
+	if cfg.Bind == "" {
+		return fmt.Errorf("BIND must not be empty")
+	}
+
+	if _, _, err := net.SplitHostPort(cfg.Bind); err != nil {
+		return fmt.Errorf("BIND is not a valid address %q: %w", cfg.Bind, err)
+	}
+
+	if len(cleanList(cfg.RestricterUrls)) == 0 {
+		if err := validateRestricterUrl("RESTRICTER_URL", cfg.RestricterUrl); err != nil {
+			return err
+		}
+	}
+
+	for _, u := range cleanList(cfg.RestricterUrls) {
+		if err := validateRestricterUrl("RESTRICTER_URLS", u); err != nil {
+			return err
+		}
+	}
+
+	if cfg.PostgresHost == "" {
+		return fmt.Errorf("DATABASE_HOST must not be empty")
+	}
+
+	if _, err := projectorHttp.ParseDefaultLanguage(cfg.DefaultLanguage); err != nil {
+		return fmt.Errorf("DEFAULT_LANGUAGE is invalid: %w", err)
+	}
+
+	if cfg.MaxRequestBodySize <= 0 {
+		return fmt.Errorf("MAX_REQUEST_BODY_SIZE must be positive, got %d", cfg.MaxRequestBodySize)
+	}
+
+	if cfg.MaxRequestHeaderSize <= 0 {
+		return fmt.Errorf("MAX_REQUEST_HEADER_SIZE must be positive, got %d", cfg.MaxRequestHeaderSize)
+	}
+
+	if cfg.RestricterStaleWindow < 0 {
+		return fmt.Errorf("RESTRICTER_STALE_WINDOW must not be negative, got %s", cfg.RestricterStaleWindow)
+	}
+
+	if cfg.PermissionRecheck < 0 {
+		return fmt.Errorf("PERMISSION_RECHECK_INTERVAL must not be negative, got %s", cfg.PermissionRecheck)
+	}
+
+	if cfg.RestricterInsecureTLS && !cfg.Development {
+		return fmt.Errorf("RESTRICTER_INSECURE_SKIP_VERIFY is only allowed with OPENSLIDES_DEVELOPMENT")
+	}
+
+	if cfg.SSERetryMs <= 0 {
+		return fmt.Errorf("SSE_RETRY_MS must be positive, got %d", cfg.SSERetryMs)
+	}
+
+	if cfg.SSERetryJitterMs < 0 {
+		return fmt.Errorf("SSE_RETRY_JITTER_MS must not be negative, got %d", cfg.SSERetryJitterMs)
+	}
+
+	if cfg.SSEFlushPolicy != projectorHttp.SSEFlushImmediate && cfg.SSEFlushPolicy != projectorHttp.SSEFlushInterval {
+		return fmt.Errorf("SSE_FLUSH_POLICY must be %q or %q, got %q", projectorHttp.SSEFlushImmediate, projectorHttp.SSEFlushInterval, cfg.SSEFlushPolicy)
+	}
+
+	if cfg.SSEFlushPolicy == projectorHttp.SSEFlushInterval && cfg.SSEFlushIntervalMs <= 0 {
+		return fmt.Errorf("SSE_FLUSH_INTERVAL_MS must be positive, got %d", cfg.SSEFlushIntervalMs)
+	}
+
+	if cfg.SSEFlushThreshold < 0 {
+		return fmt.Errorf("SSE_FLUSH_THRESHOLD_BYTES must not be negative, got %d", cfg.SSEFlushThreshold)
+	}
+
+	if cfg.SSEReplayBuffer < 1 {
+		return fmt.Errorf("SSE_REPLAY_BUFFER must be at least 1, got %d", cfg.SSEReplayBuffer)
+	}
+
+	if cfg.MediaProxy {
+		mediaUrl, err := url.Parse(cfg.MediaServiceUrl)
+		if err != nil || (mediaUrl.Scheme != "http" && mediaUrl.Scheme != "https") || mediaUrl.Host == "" {
+			return fmt.Errorf("MEDIA_SERVICE_URL must be an absolute http(s) url, got %q", cfg.MediaServiceUrl)
+		}
+	}
+
+	if cfg.MediaCDNBase != "" {
+		cdnUrl, err := url.Parse(cfg.MediaCDNBase)
+		if err != nil || (cdnUrl.Scheme != "http" && cdnUrl.Scheme != "https") || cdnUrl.Host == "" {
+			return fmt.Errorf("MEDIA_CDN_BASE must be an absolute http(s) url, got %q", cfg.MediaCDNBase)
+		}
+	}
+
+	if cfg.ChangeWebhook != "" {
+		webhookUrl, err := url.Parse(cfg.ChangeWebhook)
+		if err != nil || (webhookUrl.Scheme != "http" && webhookUrl.Scheme != "https") || webhookUrl.Host == "" {
+			return fmt.Errorf("PROJECTION_CHANGE_WEBHOOK must be an absolute http(s) url, got %q", cfg.ChangeWebhook)
+		}
+	}
+
+	if cfg.OrganizationMessage != "" && !dskey.ValidateCollectionField("organization", cfg.OrganizationMessage) {
+		return fmt.Errorf("ORGANIZATION_MESSAGE_FIELD must be a field of the organization, got %q", cfg.OrganizationMessage)
+	}
+
+	if cfg.SanitizerOrgField != "" && !dskey.ValidateCollectionField("organization", cfg.SanitizerOrgField) {
+		return fmt.Errorf("SANITIZER_ORGANIZATION_FIELD must be a field of the organization, got %q", cfg.SanitizerOrgField)
+	}
+
+	if cfg.SanitizerMeetingField != "" && !dskey.ValidateCollectionField("meeting", cfg.SanitizerMeetingField) {
+		return fmt.Errorf("SANITIZER_MEETING_FIELD must be a field of the meeting, got %q", cfg.SanitizerMeetingField)
+	}
+
+	for _, id := range cfg.ProjectorIDAllowlist {
+		if id <= 0 {
+			return fmt.Errorf("PROJECTOR_ID_ALLOWLIST must only contain positive ids, got %d", id)
+		}
+	}
+
+	if cfg.MaxSlideSize < 0 {
+		return fmt.Errorf("MAX_SLIDE_SIZE must not be negative, got %d", cfg.MaxSlideSize)
+	}
+
+	if cfg.RenderCacheSize < 0 {
+		return fmt.Errorf("RENDER_CACHE_SIZE must not be negative, got %d", cfg.RenderCacheSize)
+	}
+
+	if cfg.RenderCacheMaxMB < 0 {
+		return fmt.Errorf("RENDER_CACHE_MAX_MB must not be negative, got %d", cfg.RenderCacheMaxMB)
+	}
+
+	if cfg.StaleContentWindow < 0 {
+		return fmt.Errorf("STALE_CONTENT_WINDOW must not be negative, got %s", cfg.StaleContentWindow)
+	}
+
+	if cfg.ProjectorStaleAfter < 0 {
+		return fmt.Errorf("PROJECTOR_STALE_AFTER must not be negative, got %s", cfg.ProjectorStaleAfter)
+	}
+
+	if cfg.PollInterval < 0 {
+		return fmt.Errorf("POLL_INTERVAL must not be negative, got %s", cfg.PollInterval)
+	}
+
+	if cfg.RequestTimeout < 0 {
+		return fmt.Errorf("REQUEST_TIMEOUT must not be negative, got %s", cfg.RequestTimeout)
+	}
+
+	if cfg.MaxConcurrentRequests < 0 {
+		return fmt.Errorf("MAX_CONCURRENT_REQUESTS must not be negative, got %d", cfg.MaxConcurrentRequests)
+	}
+
+	if cfg.MaxConcurrentStreams < 0 {
+		return fmt.Errorf("MAX_CONCURRENT_STREAMS must not be negative, got %d", cfg.MaxConcurrentStreams)
+	}
+
+	if cfg.RenderMaxConcurrency < 0 {
+		return fmt.Errorf("RENDER_MAX_CONCURRENCY must not be negative, got %d", cfg.RenderMaxConcurrency)
+	}
+
+	if cfg.RenderQueueSize < 0 {
+		return fmt.Errorf("RENDER_QUEUE_SIZE must not be negative, got %d", cfg.RenderQueueSize)
+	}
+
+	if cfg.RenderQueueTimeout < 0 {
+		return fmt.Errorf("RENDER_QUEUE_TIMEOUT must not be negative, got %s", cfg.RenderQueueTimeout)
+	}
+
+	if !strings.HasPrefix(cfg.HealthPath, "/") || strings.HasSuffix(cfg.HealthPath, "/") || strings.ContainsAny(cfg.HealthPath, "{} ") {
+		return fmt.Errorf("HEALTH_PATH must be an absolute path without trailing slash, got %q", cfg.HealthPath)
+	}
+
+	if cfg.MetricInterval <= 0 {
+		return fmt.Errorf("METRIC_INTERVAL must be positive, got %s", cfg.MetricInterval)
+	}
+
+	if _, err := zerolog.ParseLevel(cfg.LogLevel); err != nil || cfg.LogLevel == "" {
+		return fmt.Errorf("LOG_LEVEL must be one of trace, debug, info, warn, error, fatal, panic or disabled, got %q", cfg.LogLevel)
+	}
+
+	return nil
+}
+
+// validateRestricterUrl checks that u is an absolute http(s) url. name is the
+// environment variable used in the error.
+func validateRestricterUrl(name string, u string) error {
+	if u == "" {
+		return fmt.Errorf("%s must not be empty", name)
+	}
+
+	restricterUrl, err := url.Parse(u)
+	if err != nil {
+		return fmt.Errorf("%s is not a valid url %q: %w", name, u, err)
+	}
+
+	if restricterUrl.Scheme != "http" && restricterUrl.Scheme != "https" || restricterUrl.Host == "" {
+		return fmt.Errorf("%s must be an absolute http(s) url, got %q", name, u)
+	}
+
+	return nil
+}
+
+// cleanList removes surrounding whitespace and empty entries from comma
+// separated lists like RESTRICTER_URLS.
+func cleanList(entries []string) []string {
+	var cleaned []string
+	for _, e := range entries {
+		if e = strings.TrimSpace(e); e != "" {
+			cleaned = append(cleaned, e)
+		}
+	}
+
+	return cleaned
+}
+
 func run(cfg config) error {
-	ctx := context.Background()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	shutdownTracing, err := tracing.Setup(ctx, cfg.OTLPEndpoint)
+	if err != nil {
+		return fmt.Errorf("setting up tracing: %w", err)
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			log.Warn().Err(err).Msg("flushing traces")
+		}
+	}()
 
 	env := &environment.ForProduction{}
 	messageBus := redis.New(env)
@@ -68,6 +441,7 @@ func run(cfg config) error {
 
 	vote := datastore.NewFlowVoteCount(env)
 
+	voteDone := make(chan struct{})
 	var dataFlow flow.Flow = dsFlow
 	if !cfg.PublicAccessOnly {
 		dataFlow = flow.Combine(
@@ -75,12 +449,12 @@ func run(cfg config) error {
 			map[string]flow.Flow{"poll/live_votes": vote},
 		)
 
-		eventer := func() (<-chan time.Time, func() bool) {
-			timer := time.NewTimer(time.Second)
-			return timer.C, timer.Stop
-		}
-
-		go vote.Connect(ctx, eventer, func(err error) {})
+		go func() {
+			defer close(voteDone)
+			database.ConnectVoteCount(ctx, vote, time.Second, voteRetryMaxDelay)
+		}()
+	} else {
+		close(voteDone)
 	}
 
 	ds, err := getDatabase(cfg, dataFlow)
@@ -88,39 +462,213 @@ func run(cfg config) error {
 		return fmt.Errorf("connecting to database: %w", err)
 	}
 
+	if cfg.PostgresReadHost != "" {
+		replicaFlow, err := datastore.NewFlowPostgres(replicaEnvironment{Environmenter: env, cfg: cfg}, messageBus)
+		if err != nil {
+			return fmt.Errorf("connecting to read replica: %w", err)
+		}
+
+		var replica flow.Flow = replicaFlow
+		if !cfg.PublicAccessOnly {
+			replica = flow.Combine(
+				replicaFlow,
+				map[string]flow.Flow{"poll/live_votes": vote},
+			)
+		}
+
+		password, err := postgresPassword(cfg)
+		if err != nil {
+			return err
+		}
+
+		replicaCfg := cfg
+		replicaCfg.PostgresHost = cfg.PostgresReadHost
+		if cfg.PostgresReadPort != "" {
+			replicaCfg.PostgresPort = cfg.PostgresReadPort
+		}
+
+		if err := ds.UseReadReplica(replica, postgresDSN(replicaCfg, password), cfg.ReadPrimarySnapshot); err != nil {
+			return fmt.Errorf("connecting to read replica: %w", err)
+		}
+		log.Info().Msgf("Reading from database replica %s", cfg.PostgresReadHost)
+	}
+
+	defaultLanguage, err := projectorHttp.ParseDefaultLanguage(cfg.DefaultLanguage)
+	if err != nil {
+		return fmt.Errorf("parsing default language: %w", err)
+	}
+
+	var fonts projector.FontMapping
+	if cfg.FontMappingFile != "" {
+		fonts, err = projector.LoadFontMapping(cfg.FontMappingFile)
+		if err != nil {
+			return fmt.Errorf("loading font mapping: %w", err)
+		}
+	}
+
+	var sanitizer *slide.SanitizerConfig
+	if cfg.SanitizerConfigFile != "" {
+		sanitizer, err = slide.LoadSanitizerConfig(cfg.SanitizerConfigFile)
+		if err != nil {
+			return fmt.Errorf("loading sanitizer config: %w", err)
+		}
+	}
+
+	// Rules in the datastore default to the rules of the config file
+	if cfg.SanitizerOrgField != "" || cfg.SanitizerMeetingField != "" {
+		if sanitizer == nil {
+			sanitizer = &slide.SanitizerConfig{}
+		}
+		sanitizer.OrganizationField = cfg.SanitizerOrgField
+		sanitizer.MeetingField = cfg.SanitizerMeetingField
+	}
+
+	var defaultSlide string
+	if cfg.DefaultSlide {
+		defaultSlide = projector.DefaultSlideTemplate
+		if cfg.DefaultSlideFile != "" {
+			if _, err := template.ParseFiles(cfg.DefaultSlideFile); err != nil {
+				return fmt.Errorf("loading default slide: %w", err)
+			}
+			defaultSlide = cfg.DefaultSlideFile
+		}
+	}
+
+	outboundTLS, err := projectorHttp.LoadOutboundTLSConfig(cfg.RestricterCAFile, cfg.RestricterInsecureTLS)
+	if err != nil {
+		return fmt.Errorf("loading restricter CA: %w", err)
+	}
+
+	if cfg.RestricterInsecureTLS {
+		log.Warn().Msg("Certificates of outbound HTTPS requests are not verified")
+	}
+
+	var adminToken string
+	if cfg.AdminTokenFile != "" {
+		adminToken, err = parseSecretsFile(cfg.AdminTokenFile)
+		if err != nil {
+			return fmt.Errorf("reading admin token: %w", err)
+		}
+
+		adminToken = strings.TrimSpace(adminToken)
+		if adminToken == "" {
+			return fmt.Errorf("admin token in %s is empty", cfg.AdminTokenFile)
+		}
+	}
+
+	hot := runtimeConfig(cfg)
 	serverMux := http.NewServeMux()
-	projectorHttp.New(ctx, projectorHttp.ProjectorConfig{
-		RestricterUrl:  cfg.RestricterUrl,
-		MetricInterval: cfg.MetricInterval,
+	reloader := projectorHttp.New(ctx, projectorHttp.ProjectorConfig{
+		RestricterUrl:         cfg.RestricterUrl,
+		RestricterUrls:        cleanList(cfg.RestricterUrls),
+		RestricterStaleWindow: cfg.RestricterStaleWindow,
+		PermissionRecheck:     cfg.PermissionRecheck,
+		MetricInterval:        cfg.MetricInterval,
+		AnonymousUserID:       cfg.AnonymousUserID,
+		PublicAccessOnly:      cfg.PublicAccessOnly,
+		MaxBodySize:           cfg.MaxRequestBodySize,
+		DefaultLanguage:       defaultLanguage,
+		SSERetry:              hot.SSERetry,
+		SSERetryJitter:        hot.SSERetryJitter,
+		SSEFlushInterval:      hot.SSEFlushInterval,
+		SSEFlushThreshold:     hot.SSEFlushThreshold,
+		SSEReplayBuffer:       cfg.SSEReplayBuffer,
+		MediaProxy:            cfg.MediaProxy,
+		MediaServiceUrl:       strings.TrimSuffix(cfg.MediaServiceUrl, "/"),
+		MediaCDNBase:          cfg.MediaCDNBase,
+		MaxSlideSize:          cfg.MaxSlideSize,
+		StaleContentWindow:    cfg.StaleContentWindow,
+		ProjectorStaleAfter:   cfg.ProjectorStaleAfter,
+		RenderCacheSize:       cfg.RenderCacheSize,
+		MemoryBudget:          int64(cfg.RenderCacheMaxMB) << 20,
+		Fonts:                 fonts,
+		Sanitizer:             sanitizer,
+		DefaultSlide:          defaultSlide,
+		ChangeWebhook:         cfg.ChangeWebhook,
+		ChangeWebhookIDs:      cfg.ChangeWebhookIDs,
+		OrganizationMessage:   cfg.OrganizationMessage,
+		AdminToken:            adminToken,
+		OutboundTLS:           outboundTLS,
+		ProjectorAllowlist:    cfg.ProjectorIDAllowlist,
+		WebsocketOrigins:      cleanList(cfg.WebsocketOrigins),
+		ServerTiming:          cfg.ServerTiming || cfg.Development,
+		HealthPath:            cfg.HealthPath,
+		MinifyHTML:            hot.MinifyHTML,
+		PollInterval:          hot.PollInterval,
+		RequestTimeout:        hot.RequestTimeout,
+		MaxConcurrentRequests: hot.MaxConcurrentRequests,
+		MaxConcurrentStreams:  hot.MaxConcurrentStreams,
+		RenderMaxConcurrency:  cfg.RenderMaxConcurrency,
+		RenderQueueSize:       cfg.RenderQueueSize,
+		RenderQueueTimeout:    cfg.RenderQueueTimeout,
 	}, serverMux, ds, dsFlow)
-	fileHandler := http.StripPrefix("/system/projector/static/", http.FileServer(http.Dir("static")))
-	serverMux.Handle("/system/projector/static/", fileHandler)
+	go reloadOnHangup(ctx, cfg, reloader)
+	// Without the static files projectors cannot be displayed, in
+	// development the service is still usable for the api.
+	if err := projectorHttp.RegisterStatic(serverMux, "static", !cfg.Development); err != nil {
+		return fmt.Errorf("serving static files: %w", err)
+	}
 
 	log.Info().Msgf("Starting server on %s", cfg.Bind)
 	srv := &http.Server{
-		Addr:        cfg.Bind,
-		Handler:     serverMux,
-		BaseContext: func(net.Listener) context.Context { return ctx },
+		Addr:           cfg.Bind,
+		Handler:        projectorHttp.TracingMiddleware(projectorHttp.RequestIDMiddleware(serverMux)),
+		BaseContext:    func(net.Listener) context.Context { return ctx },
+		MaxHeaderBytes: cfg.MaxRequestHeaderSize,
 	}
 
-	if err := srv.ListenAndServe(); err != nil {
+	go func() {
+		<-ctx.Done()
+		log.Info().Msg("Shutting down")
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			log.Warn().Err(err).Msg("Closing open connections")
+		}
+	}()
+
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		log.Fatal().Err(err).Msg("Could not listen and serve")
 	}
 
+	<-voteDone
 	return nil
 }
 
 func getDatabase(cfg config, dsFlow flow.Flow) (*database.Datastore, error) {
+	password, err := postgresPassword(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	redisAddr := cfg.MessageBusHost + ":" + cfg.MessageBusPort
+
+	ds, err := database.New(postgresDSN(cfg, password), redisAddr, dsFlow)
+	if err != nil {
+		return nil, fmt.Errorf("creating datastore: %w", err)
+	}
+
+	return ds, nil
+}
+
+// postgresPassword reads the database password from the secrets. In
+// development the default password is used if there is none.
+func postgresPassword(cfg config) (string, error) {
 	password, err := parseSecretsFile(cfg.PostgresPasswordFile)
 	if err != nil {
 		if cfg.Development {
-			password = "openslides"
-		} else {
-			return nil, fmt.Errorf("reading password from secrets: %w", err)
+			return "openslides", nil
 		}
+		return "", fmt.Errorf("reading password from secrets: %w", err)
 	}
 
-	pgAddr := fmt.Sprintf(
+	return password, nil
+}
+
+// postgresDSN returns the connection string of the database.
+func postgresDSN(cfg config, password string) string {
+	return fmt.Sprintf(
 		`user='%s' password='%s' host='%s' port='%s' dbname='%s'`,
 		encodePostgresConfig(cfg.PostgresUser),
 		encodePostgresConfig(password),
@@ -128,14 +676,26 @@ func getDatabase(cfg config, dsFlow flow.Flow) (*database.Datastore, error) {
 		encodePostgresConfig(cfg.PostgresPort),
 		encodePostgresConfig(cfg.PostgresDatabase),
 	)
-	redisAddr := cfg.MessageBusHost + ":" + cfg.MessageBusPort
+}
 
-	ds, err := database.New(pgAddr, redisAddr, dsFlow)
-	if err != nil {
-		return nil, fmt.Errorf("creating datastore: %w", err)
+// replicaEnvironment overwrites the database address of the environment with
+// the address of the read replica.
+type replicaEnvironment struct {
+	environment.Environmenter
+	cfg config
+}
+
+func (e replicaEnvironment) Getenv(key string) string {
+	switch key {
+	case "DATABASE_HOST":
+		return e.cfg.PostgresReadHost
+	case "DATABASE_PORT":
+		if e.cfg.PostgresReadPort != "" {
+			return e.cfg.PostgresReadPort
+		}
 	}
 
-	return ds, nil
+	return e.Environmenter.Getenv(key)
 }
 
 // encodePostgresConfig encodes a string to be used in the postgres key value style.
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"reflect"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+
+	projectorHttp "github.com/OpenSlides/openslides-projector-service/pkg/http"
+)
+
+// reloadOnHangup reads the config again whenever the process receives SIGHUP
+// and applies the settings tagged with reload:"hot". Open connections are
+// kept. Changes of other settings are only logged, they need a restart.
+func reloadOnHangup(ctx context.Context, cfg config, reloader projectorHttp.Reloader) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangup:
+		}
+
+		reloaded, err := loadConfig()
+		if err == nil {
+			err = validateConfig(reloaded)
+		}
+		if err != nil {
+			log.Err(err).Msg("Could not reload config, keeping the current one")
+			continue
+		}
+
+		for _, name := range restartRequired(cfg, reloaded) {
+			log.Warn().Msgf("%s changed, it is applied on the next restart", name)
+		}
+
+		setLogLevel(reloaded.LogLevel)
+		reloader.Reload(runtimeConfig(reloaded))
+		log.Info().Msg("Config reloaded")
+	}
+}
+
+// restartRequired returns the environment variables of the settings which
+// differ and cannot be reloaded.
+func restartRequired(current config, reloaded config) []string {
+	var changed []string
+
+	currentValue := reflect.ValueOf(current)
+	reloadedValue := reflect.ValueOf(reloaded)
+	for _, field := range reflect.VisibleFields(currentValue.Type()) {
+		if field.Tag.Get("env") == "" || field.Tag.Get("reload") == "hot" {
+			continue
+		}
+
+		if !reflect.DeepEqual(currentValue.FieldByIndex(field.Index).Interface(), reloadedValue.FieldByIndex(field.Index).Interface()) {
+			changed = append(changed, field.Tag.Get("env"))
+		}
+	}
+
+	return changed
+}
+
+// runtimeConfig returns the settings of cfg which can be changed while the
+// service is running.
+func runtimeConfig(cfg config) projectorHttp.RuntimeConfig {
+	var sseFlushInterval time.Duration
+	if cfg.SSEFlushPolicy == projectorHttp.SSEFlushInterval {
+		sseFlushInterval = time.Duration(cfg.SSEFlushIntervalMs) * time.Millisecond
+	}
+
+	return projectorHttp.RuntimeConfig{
+		SSERetry:              time.Duration(cfg.SSERetryMs) * time.Millisecond,
+		SSERetryJitter:        time.Duration(cfg.SSERetryJitterMs) * time.Millisecond,
+		SSEFlushInterval:      sseFlushInterval,
+		SSEFlushThreshold:     cfg.SSEFlushThreshold,
+		PollInterval:          cfg.PollInterval,
+		MinifyHTML:            cfg.MinifyHTML && !cfg.Development,
+		RequestTimeout:        cfg.RequestTimeout,
+		MaxConcurrentRequests: cfg.MaxConcurrentRequests,
+		MaxConcurrentStreams:  cfg.MaxConcurrentStreams,
+	}
+}
+
+// setLogLevel sets the global log level. The level has to be validated by
+// validateConfig.
+func setLogLevel(level string) {
+	parsed, err := zerolog.ParseLevel(level)
+	if err != nil {
+		return
+	}
+
+	zerolog.SetGlobalLevel(parsed)
+}
@@ -2,29 +2,91 @@ package database
 
 import (
 	"context"
+	"fmt"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/OpenSlides/openslides-go/datastore/dskey"
 	"github.com/OpenSlides/openslides-go/datastore/dsmodels"
 	"github.com/OpenSlides/openslides-go/datastore/flow"
+	"github.com/rs/zerolog/log"
 )
 
 type Datastore struct {
 	mu          sync.RWMutex
 	ctx         context.Context
 	ds          flow.Flow
+	reader      flow.Getter
+	snapshot    flow.Getter
 	dsListeners []*dsChangeListener
+	positions   positionSource
+	history     positionGetter
+	position    atomic.Uint64
+	posUpdate   positionUpdate
+	lastUpdate  atomic.Int64
+	stall       stallCheck
 	Fetch       *dsmodels.Fetch
 }
 
+// positionUpdate coalesces the position reads after change notifications.
+// Notifications arriving while a read is running lead to one further read.
+type positionUpdate struct {
+	mu      sync.Mutex
+	running bool
+	pending bool
+
+	// notified counts the change notifications, read the notifications
+	// covered by the last finished position read.
+	notified uint64
+	read     uint64
+
+	// done is closed and replaced after each position read.
+	done chan struct{}
+}
+
+// stallCheck caches the result of Stalled, so projectors checking at the
+// same time share one read of the position.
+type stallCheck struct {
+	mu        sync.Mutex
+	checkedAt time.Time
+	stalled   bool
+}
+
 func New(addr string, redisAddr string, dsFlow flow.Flow) (*Datastore, error) {
 	ctx := context.Background()
 	ds := Datastore{
-		ctx:   context.Background(),
-		ds:    dsFlow,
-		Fetch: dsmodels.New(dsFlow),
+		ctx:      context.Background(),
+		ds:       dsFlow,
+		reader:   dsFlow,
+		snapshot: dsFlow,
+		Fetch:    dsmodels.New(tracedGetter{dsFlow}),
+	}
+	ds.posUpdate.done = make(chan struct{})
+
+	if source, ok := dsFlow.(positionSource); ok {
+		ds.positions = source
+	}
+	if getter, ok := dsFlow.(positionGetter); ok {
+		ds.history = getter
+	}
+	if addr != "" {
+		history, err := newPostgresHistory(addr)
+		if err != nil {
+			return nil, fmt.Errorf("connecting to datastore history: %w", err)
+		}
+		ds.positions = history
+		ds.history = history
 	}
+
+	ds.lastUpdate.Store(time.Now().UnixNano())
+	go ds.updatePosition()
 	go dsFlow.Update(ctx, func(m map[dskey.Key][]byte, err error) {
+		if err == nil && len(m) > 0 {
+			ds.requestPositionUpdate()
+			ds.lastUpdate.Store(time.Now().UnixNano())
+		}
+
 		hasCanceled := false
 		ds.mu.RLock()
 		for _, listener := range ds.dsListeners {
@@ -60,6 +122,236 @@ func New(addr string, redisAddr string, dsFlow flow.Flow) (*Datastore, error) {
 	return &ds, nil
 }
 
+// UseReadReplica routes all reads to the given replica. Change notifications
+// are still received from the primary flow. If primarySnapshot is set, the
+// first read of a context and SnapshotFetch use the primary so new projectors
+// do not start with data the replica has not caught up with yet.
+//
+// The change position of the replica is read from the database at
+// replicaAddr. Reads go to the primary as long as the replica is behind the
+// position of the last change notification. Without an address the replica
+// is always used.
+//
+// Has to be called before the datastore is used.
+func (ds *Datastore) UseReadReplica(replica flow.Getter, replicaAddr string, primarySnapshot bool) error {
+	reader := &replicaReader{ds: ds, replica: replica}
+	if source, ok := replica.(positionSource); ok {
+		reader.positions = source
+	}
+	if replicaAddr != "" {
+		positions, err := newPostgresHistory(replicaAddr)
+		if err != nil {
+			return fmt.Errorf("connecting to replica: %w", err)
+		}
+		reader.positions = positions
+	}
+
+	ds.reader = reader
+	ds.snapshot = reader
+	if primarySnapshot {
+		ds.snapshot = ds.ds
+	}
+	ds.Fetch = dsmodels.New(tracedGetter{reader})
+	return nil
+}
+
+// replicaReader reads from the replica unless it is behind the newest change
+// position of the primary.
+type replicaReader struct {
+	ds        *Datastore
+	replica   flow.Getter
+	positions positionSource
+
+	// position is the newest position the replica was seen at.
+	position atomic.Uint64
+}
+
+func (r *replicaReader) Get(ctx context.Context, keys ...dskey.Key) (map[dskey.Key][]byte, error) {
+	if !r.caughtUp(ctx) {
+		return r.ds.ds.Get(ctx, keys...)
+	}
+
+	return r.replica.Get(ctx, keys...)
+}
+
+// caughtUp reports whether the replica contains the last notified change.
+// It waits until the position of that change was read from the primary. The
+// position of the replica is only read again while it seems behind.
+func (r *replicaReader) caughtUp(ctx context.Context) bool {
+	if r.positions == nil {
+		return true
+	}
+
+	if err := r.ds.waitPosition(ctx); err != nil {
+		return false
+	}
+
+	required := r.ds.position.Load()
+	if r.position.Load() >= required {
+		return true
+	}
+
+	position, err := r.positions.MaxPosition(ctx)
+	if err != nil {
+		log.Warn().Err(err).Msg("Could not read replica position, reading from primary")
+		return false
+	}
+
+	for {
+		current := r.position.Load()
+		if position <= current || r.position.CompareAndSwap(current, position) {
+			break
+		}
+	}
+
+	return position >= required
+}
+
+// SnapshotFetch returns a fetcher for reading the initial state of a
+// projector.
+func (ds *Datastore) SnapshotFetch() *dsmodels.Fetch {
+	if ds.snapshot == ds.reader {
+		return ds.Fetch
+	}
+
+	return dsmodels.New(tracedGetter{ds.snapshot})
+}
+
 func (ds *Datastore) NumDsListeners() int {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+
 	return len(ds.dsListeners)
 }
+
+// Position returns the newest change position of the datastore. It is read in
+// the background after change notifications, so it can lag shortly behind
+// them. It can be used to check if anything could have changed. It is 0 if
+// the position is unknown.
+func (ds *Datastore) Position() uint64 {
+	return ds.position.Load()
+}
+
+// LastUpdate returns the time of the last change notification received from
+// the flow, or the creation of the datastore if there was none yet.
+func (ds *Datastore) LastUpdate() time.Time {
+	return time.Unix(0, ds.lastUpdate.Load())
+}
+
+// Stalled reports whether the flow stopped delivering changes: no change
+// notification was received for the given duration although the position of
+// the datastore moved on since the last one. A datastore without changes is
+// not stalled. Without a position source it cannot be told and is never
+// stalled. The result is reused for half of the duration.
+func (ds *Datastore) Stalled(ctx context.Context, after time.Duration) bool {
+	if ds.positions == nil || time.Since(ds.LastUpdate()) < after {
+		return false
+	}
+
+	ds.stall.mu.Lock()
+	defer ds.stall.mu.Unlock()
+
+	if time.Since(ds.stall.checkedAt) < after/2 {
+		return ds.stall.stalled
+	}
+
+	position, err := ds.positions.MaxPosition(ctx)
+	if err != nil {
+		log.Warn().Err(err).Msg("Could not read datastore position")
+		return ds.stall.stalled
+	}
+
+	ds.stall.checkedAt = time.Now()
+	ds.stall.stalled = position > ds.Position()
+	return ds.stall.stalled
+}
+
+// requestPositionUpdate reads the position in the background without
+// delaying the change notification.
+func (ds *Datastore) requestPositionUpdate() {
+	if ds.positions == nil {
+		return
+	}
+
+	u := &ds.posUpdate
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.notified++
+	u.pending = true
+	if u.running {
+		return
+	}
+
+	u.running = true
+	go func() {
+		for {
+			u.mu.Lock()
+			if !u.pending {
+				u.running = false
+				u.mu.Unlock()
+				return
+			}
+			u.pending = false
+			notified := u.notified
+			u.mu.Unlock()
+
+			ds.updatePosition()
+
+			u.mu.Lock()
+			u.read = notified
+			close(u.done)
+			u.done = make(chan struct{})
+			u.mu.Unlock()
+		}
+	}()
+}
+
+// waitPosition waits until the position covers all change notifications
+// received before the call.
+func (ds *Datastore) waitPosition(ctx context.Context) error {
+	u := &ds.posUpdate
+	u.mu.Lock()
+	target := u.notified
+	u.mu.Unlock()
+
+	for {
+		u.mu.Lock()
+		read, done := u.read, u.done
+		u.mu.Unlock()
+
+		if read >= target {
+			return nil
+		}
+
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// updatePosition reads the newest change position from the datastore. The
+// position never goes back, even if an older read finishes last.
+func (ds *Datastore) updatePosition() {
+	if ds.positions == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ds.ctx, 5*time.Second)
+	defer cancel()
+
+	position, err := ds.positions.MaxPosition(ctx)
+	if err != nil {
+		log.Warn().Err(err).Msg("Could not read datastore position")
+		return
+	}
+
+	for {
+		current := ds.position.Load()
+		if position <= current || ds.position.CompareAndSwap(current, position) {
+			return
+		}
+	}
+}
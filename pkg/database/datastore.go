@@ -15,8 +15,8 @@ type dsChangeListener struct {
 }
 
 func (db *Datastore) NewContext(ctx context.Context, handler func(*dsmodels.Fetch)) {
-	recorder := dsrecorder.New(db.ds)
-	fetch := dsmodels.New(recorder)
+	recorder := dsrecorder.New(db.snapshot)
+	fetch := dsmodels.New(requiredFieldGetter{tracedGetter{recorder}})
 
 	handler(fetch)
 	listener := dsChangeListener{
@@ -24,8 +24,18 @@ func (db *Datastore) NewContext(ctx context.Context, handler func(*dsmodels.Fetc
 		keys: recorder.Keys(),
 	}
 
+	fromSnapshot := db.snapshot != db.reader
 	listener.handler = func() {
-		recorder.Reset()
+		if fromSnapshot {
+			// Only the first read uses the snapshot source, updates are
+			// read from the regular reader.
+			recorder = dsrecorder.New(db.reader)
+			fetch = dsmodels.New(requiredFieldGetter{tracedGetter{recorder}})
+			fromSnapshot = false
+		} else {
+			recorder.Reset()
+		}
+
 		handler(fetch)
 		listener.keys = recorder.Keys()
 	}
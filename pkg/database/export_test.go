@@ -0,0 +1,5 @@
+package database
+
+type HistoryEvent = historyEvent
+
+var ReplayEvents = replayEvents
@@ -0,0 +1,118 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/OpenSlides/openslides-go/datastore/dsfetch"
+	"github.com/OpenSlides/openslides-go/datastore/dskey"
+	"github.com/OpenSlides/openslides-go/datastore/flow"
+)
+
+// MissingFieldError is returned when a required field of an existing object
+// has no value, e.g. while a migration is running.
+type MissingFieldError struct {
+	Key dskey.Key
+}
+
+func (e MissingFieldError) Error() string {
+	return fmt.Sprintf("required field %s is missing", e.Key)
+}
+
+// requiredFieldGetter returns a MissingFieldError if a required field of an
+// existing object has no value. Without it the generated fetcher fails with
+// an error which cannot be told apart from an unavailable datastore.
+type requiredFieldGetter struct {
+	flow.Getter
+}
+
+func (g requiredFieldGetter) Get(ctx context.Context, keys ...dskey.Key) (map[dskey.Key][]byte, error) {
+	data, err := g.Getter.Get(ctx, keys...)
+	if err != nil {
+		return nil, err
+	}
+
+	required := requiredFields()
+	for _, key := range keys {
+		if data[key] != nil || key == key.IDField() || data[key.IDField()] == nil {
+			continue
+		}
+
+		if _, ok := required[fieldName(key.Collection(), key.Field())]; ok {
+			return nil, MissingFieldError{Key: key}
+		}
+	}
+
+	return data, nil
+}
+
+var (
+	requiredFieldsOnce sync.Once
+	requiredFieldsSet  map[string]struct{}
+)
+
+// requiredFields returns the fieldName of every required field. The fields
+// are found by loading every field of the generated fetcher from an object
+// which exists but has no values. Relations are left out, they are checked
+// when the related object is loaded.
+func requiredFields() map[string]struct{} {
+	requiredFieldsOnce.Do(func() {
+		requiredFieldsSet = make(map[string]struct{})
+
+		ctx := context.Background()
+		fetch := reflect.ValueOf(dsfetch.New(emptyObjectGetter{}))
+		intType := reflect.TypeFor[int]()
+		errorType := reflect.TypeFor[error]()
+		for i := range fetch.NumMethod() {
+			method := fetch.Type().Method(i)
+			collection, field, found := strings.Cut(method.Name, "_")
+			if !found || strings.HasSuffix(field, "ID") || method.Type.NumIn() != 2 || method.Type.In(1) != intType || method.Type.NumOut() != 1 {
+				continue
+			}
+
+			value := fetch.Method(i).Call([]reflect.Value{reflect.ValueOf(1)})[0]
+			valueMethod := value.MethodByName("Value")
+			if !valueMethod.IsValid() || valueMethod.Type().NumOut() != 2 || valueMethod.Type().Out(1) != errorType {
+				continue
+			}
+
+			result := valueMethod.Call([]reflect.Value{reflect.ValueOf(ctx)})
+			if result[1].IsNil() {
+				continue
+			}
+
+			requiredFieldsSet[fieldName(collection, field)] = struct{}{}
+		}
+	})
+
+	return requiredFieldsSet
+}
+
+// fieldName returns a name of a collection field which is the same for the
+// generated method name (MotionBlock_AgendaItemID) and the datastore key
+// (motion_block/1/agenda_item_id).
+func fieldName(collection, field string) string {
+	normalize := func(s string) string {
+		return strings.ToLower(strings.ReplaceAll(s, "_", ""))
+	}
+
+	return normalize(collection) + "/" + normalize(field)
+}
+
+// emptyObjectGetter returns objects which exist but have no fields.
+type emptyObjectGetter struct{}
+
+func (emptyObjectGetter) Get(ctx context.Context, keys ...dskey.Key) (map[dskey.Key][]byte, error) {
+	data := make(map[dskey.Key][]byte, len(keys))
+	for _, key := range keys {
+		data[key] = nil
+		if key == key.IDField() {
+			data[key] = []byte("1")
+		}
+	}
+
+	return data, nil
+}
@@ -0,0 +1,58 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/OpenSlides/openslides-go/datastore/dskey"
+	"github.com/OpenSlides/openslides-go/datastore/dsmodels"
+)
+
+// ErrHistoryUnavailable is returned when data of a past position is
+// requested but neither the database nor the flow provide history access.
+var ErrHistoryUnavailable = errors.New("history is not available")
+
+type positionGetter interface {
+	GetPosition(ctx context.Context, position int, keys ...dskey.Key) (map[dskey.Key][]byte, error)
+}
+
+// historyFlow is a flow serving the data of a fixed position. It never
+// receives updates.
+type historyFlow struct {
+	getter   positionGetter
+	position int
+}
+
+func (f *historyFlow) Get(ctx context.Context, keys ...dskey.Key) (map[dskey.Key][]byte, error) {
+	return f.getter.GetPosition(ctx, f.position, keys...)
+}
+
+func (f *historyFlow) Update(ctx context.Context, updateFn func(map[dskey.Key][]byte, error)) {
+	<-ctx.Done()
+}
+
+// AtPosition returns a datastore serving the data as it was at the given
+// change position.
+func (ds *Datastore) AtPosition(position int) (*Datastore, error) {
+	if ds.history == nil {
+		return nil, ErrHistoryUnavailable
+	}
+
+	if position <= 0 {
+		return nil, fmt.Errorf("invalid position %d", position)
+	}
+
+	flow := &historyFlow{
+		getter:   ds.history,
+		position: position,
+	}
+
+	return &Datastore{
+		ctx:      ds.ctx,
+		ds:       flow,
+		reader:   flow,
+		snapshot: flow,
+		Fetch:    dsmodels.New(flow),
+	}, nil
+}
@@ -0,0 +1,144 @@
+package database_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/OpenSlides/openslides-go/datastore/dskey"
+	"github.com/OpenSlides/openslides-projector-service/pkg/database"
+)
+
+type historyFlow struct {
+	current map[dskey.Key][]byte
+	history map[int]map[dskey.Key][]byte
+}
+
+func (f *historyFlow) Get(ctx context.Context, keys ...dskey.Key) (map[dskey.Key][]byte, error) {
+	return f.GetPosition(ctx, 0, keys...)
+}
+
+func (f *historyFlow) GetPosition(ctx context.Context, position int, keys ...dskey.Key) (map[dskey.Key][]byte, error) {
+	data := f.current
+	if position > 0 {
+		data = f.history[position]
+	}
+
+	result := make(map[dskey.Key][]byte, len(keys))
+	for _, key := range keys {
+		result[key] = data[key]
+	}
+	return result, nil
+}
+
+func (f *historyFlow) Update(ctx context.Context, updateFn func(map[dskey.Key][]byte, error)) {
+	<-ctx.Done()
+}
+
+type currentOnlyFlow struct{}
+
+func (f *currentOnlyFlow) Get(ctx context.Context, keys ...dskey.Key) (map[dskey.Key][]byte, error) {
+	return make(map[dskey.Key][]byte), nil
+}
+
+func (f *currentOnlyFlow) Update(ctx context.Context, updateFn func(map[dskey.Key][]byte, error)) {
+	<-ctx.Done()
+}
+
+func TestDatastoreAtPosition(t *testing.T) {
+	ctx := context.Background()
+	idKey := dskey.MustKey("projector/1/id")
+	key := dskey.MustKey("projector/1/name")
+	flow := &historyFlow{
+		current: map[dskey.Key][]byte{idKey: []byte("1"), key: []byte(`"Current"`)},
+		history: map[int]map[dskey.Key][]byte{
+			1: {idKey: []byte("1"), key: []byte(`"Old"`)},
+		},
+	}
+
+	db, err := database.New("", "", flow)
+	if err != nil {
+		t.Fatalf("create datastore: %v", err)
+	}
+
+	old, err := db.AtPosition(1)
+	if err != nil {
+		t.Fatalf("get datastore at position: %v", err)
+	}
+
+	oldName, err := old.Fetch.Projector_Name(1).Value(ctx)
+	if err != nil {
+		t.Fatalf("fetch old name: %v", err)
+	}
+
+	currentName, err := db.Fetch.Projector_Name(1).Value(ctx)
+	if err != nil {
+		t.Fatalf("fetch current name: %v", err)
+	}
+
+	if oldName != "Old" || currentName != "Current" {
+		t.Errorf("expected old and current name to differ, got %q and %q", oldName, currentName)
+	}
+}
+
+func TestDatastoreAtPositionUnavailable(t *testing.T) {
+	db, err := database.New("", "", &currentOnlyFlow{})
+	if err != nil {
+		t.Fatalf("create datastore: %v", err)
+	}
+
+	if _, err := db.AtPosition(1); !errors.Is(err, database.ErrHistoryUnavailable) {
+		t.Errorf("expected ErrHistoryUnavailable, got %v", err)
+	}
+}
+
+func TestReplayEvents(t *testing.T) {
+	events := []database.HistoryEvent{
+		{FQID: "projector/1", Type: "create", Data: []byte(`{"id":1,"name":"First","color":"#000000","current_projection_ids":[1,2]}`)},
+		{FQID: "projector/2", Type: "create", Data: []byte(`{"id":2,"name":"Side"}`)},
+		{FQID: "projector/1", Type: "update", Data: []byte(`{"name":"Second"}`)},
+		{FQID: "projector/1", Type: "deletefields", Data: []byte(`["color"]`)},
+		{FQID: "projector/1", Type: "listfields", Data: []byte(`{"add":{"current_projection_ids":[3,2],"preview_projection_ids":[5]},"remove":{"current_projection_ids":[1]}}`)},
+		{FQID: "projector/2", Type: "delete"},
+	}
+
+	keys := []dskey.Key{
+		dskey.MustKey("projector/1/id"),
+		dskey.MustKey("projector/1/name"),
+		dskey.MustKey("projector/1/color"),
+		dskey.MustKey("projector/1/current_projection_ids"),
+		dskey.MustKey("projector/1/preview_projection_ids"),
+		dskey.MustKey("projector/2/name"),
+		dskey.MustKey("projector/3/name"),
+	}
+
+	values, err := database.ReplayEvents(events, keys)
+	if err != nil {
+		t.Fatalf("replay events: %v", err)
+	}
+
+	expected := map[string]string{
+		"projector/1/id":                     "1",
+		"projector/1/name":                   `"Second"`,
+		"projector/1/color":                  "",
+		"projector/1/current_projection_ids": "[2,3]",
+		"projector/1/preview_projection_ids": "[5]",
+		"projector/2/name":                   "",
+		"projector/3/name":                   "",
+	}
+	for _, key := range keys {
+		if got := string(values[key]); got != expected[key.String()] {
+			t.Errorf("expected %s to be %q, got %q", key, expected[key.String()], got)
+		}
+	}
+
+	events = append(events, database.HistoryEvent{FQID: "projector/2", Type: "restore"})
+	values, err = database.ReplayEvents(events, keys)
+	if err != nil {
+		t.Fatalf("replay events: %v", err)
+	}
+
+	if got := string(values[dskey.MustKey("projector/2/name")]); got != `"Side"` {
+		t.Errorf("expected the restored name, got %q", got)
+	}
+}
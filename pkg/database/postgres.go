@@ -0,0 +1,229 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/OpenSlides/openslides-go/datastore/dskey"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// positionSource reports the newest change position written to the
+// datastore.
+type positionSource interface {
+	MaxPosition(ctx context.Context) (uint64, error)
+}
+
+// postgresHistory reads the change positions and the past states of models
+// from the positions and events tables of the datastore.
+type postgresHistory struct {
+	pool *pgxpool.Pool
+}
+
+func newPostgresHistory(addr string) (*postgresHistory, error) {
+	config, err := pgxpool.ParseConfig(addr)
+	if err != nil {
+		return nil, fmt.Errorf("parse config: %w", err)
+	}
+
+	config.ConnConfig.DefaultQueryExecMode = pgx.QueryExecModeSimpleProtocol
+
+	pool, err := pgxpool.NewWithConfig(context.Background(), config)
+	if err != nil {
+		return nil, fmt.Errorf("creating connection pool: %w", err)
+	}
+
+	return &postgresHistory{pool: pool}, nil
+}
+
+func (p *postgresHistory) MaxPosition(ctx context.Context) (uint64, error) {
+	var position int64
+	if err := p.pool.QueryRow(ctx, `SELECT COALESCE(MAX(position), 0) FROM positions;`).Scan(&position); err != nil {
+		return 0, fmt.Errorf("query max position: %w", err)
+	}
+
+	return uint64(position), nil
+}
+
+// GetPosition returns the values of the keys as they were after the given
+// position by replaying the events of their models.
+func (p *postgresHistory) GetPosition(ctx context.Context, position int, keys ...dskey.Key) (map[dskey.Key][]byte, error) {
+	fqids := make(map[string]struct{})
+	for _, key := range keys {
+		fqids[key.FQID()] = struct{}{}
+	}
+
+	uniqueFQIDs := make([]string, 0, len(fqids))
+	for fqid := range fqids {
+		uniqueFQIDs = append(uniqueFQIDs, fqid)
+	}
+
+	rows, err := p.pool.Query(
+		ctx,
+		`SELECT fqid, type, data FROM events WHERE fqid = ANY ($1) AND position <= $2 ORDER BY position, weight;`,
+		uniqueFQIDs,
+		position,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []historyEvent
+	for rows.Next() {
+		var event historyEvent
+		if err := rows.Scan(&event.FQID, &event.Type, &event.Data); err != nil {
+			return nil, fmt.Errorf("reading event: %w", err)
+		}
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("reading events: %w", err)
+	}
+
+	return replayEvents(events, keys)
+}
+
+// historyEvent is a row of the events table. Create and update events carry
+// the fields of the model, deletefields events a list of field names and
+// listfields events the values added to and removed from list fields.
+type historyEvent struct {
+	FQID string
+	Type string
+	Data []byte
+}
+
+// replayEvents applies the events in order and returns the values of the
+// keys afterwards. Keys of deleted or unknown models are nil.
+func replayEvents(events []historyEvent, keys []dskey.Key) (map[dskey.Key][]byte, error) {
+	type model struct {
+		fields  map[string]json.RawMessage
+		deleted bool
+	}
+
+	models := make(map[string]*model)
+	for _, event := range events {
+		m := models[event.FQID]
+		if m == nil {
+			m = &model{fields: make(map[string]json.RawMessage)}
+			models[event.FQID] = m
+		}
+
+		switch event.Type {
+		case "create":
+			m.fields = make(map[string]json.RawMessage)
+			m.deleted = false
+			if err := json.Unmarshal(event.Data, &m.fields); err != nil {
+				return nil, fmt.Errorf("decoding create event of %s: %w", event.FQID, err)
+			}
+
+		case "update":
+			var fields map[string]json.RawMessage
+			if err := json.Unmarshal(event.Data, &fields); err != nil {
+				return nil, fmt.Errorf("decoding update event of %s: %w", event.FQID, err)
+			}
+			for field, value := range fields {
+				m.fields[field] = value
+			}
+
+		case "deletefields":
+			var fields []string
+			if err := json.Unmarshal(event.Data, &fields); err != nil {
+				return nil, fmt.Errorf("decoding deletefields event of %s: %w", event.FQID, err)
+			}
+			for _, field := range fields {
+				delete(m.fields, field)
+			}
+
+		case "listfields":
+			var lists struct {
+				Add    map[string][]json.RawMessage `json:"add"`
+				Remove map[string][]json.RawMessage `json:"remove"`
+			}
+			if err := json.Unmarshal(event.Data, &lists); err != nil {
+				return nil, fmt.Errorf("decoding listfields event of %s: %w", event.FQID, err)
+			}
+
+			for field, values := range lists.Add {
+				list, err := updateList(m.fields[field], values, true)
+				if err != nil {
+					return nil, fmt.Errorf("adding to %s/%s: %w", event.FQID, field, err)
+				}
+				m.fields[field] = list
+			}
+
+			for field, values := range lists.Remove {
+				list, err := updateList(m.fields[field], values, false)
+				if err != nil {
+					return nil, fmt.Errorf("removing from %s/%s: %w", event.FQID, field, err)
+				}
+				m.fields[field] = list
+			}
+
+		case "delete":
+			m.deleted = true
+
+		case "restore":
+			m.deleted = false
+
+		default:
+			return nil, fmt.Errorf("unknown event type %q of %s", event.Type, event.FQID)
+		}
+	}
+
+	values := make(map[dskey.Key][]byte, len(keys))
+	for _, key := range keys {
+		var value []byte
+		if m, ok := models[key.FQID()]; ok && !m.deleted {
+			value = m.fields[key.Field()]
+		}
+
+		if string(value) == "null" {
+			value = nil
+		}
+
+		values[key] = value
+	}
+
+	return values, nil
+}
+
+// updateList adds the values missing in the json list or removes the values
+// from it. A missing list is empty.
+func updateList(list json.RawMessage, values []json.RawMessage, add bool) (json.RawMessage, error) {
+	var entries []json.RawMessage
+	if len(list) > 0 && string(list) != "null" {
+		if err := json.Unmarshal(list, &entries); err != nil {
+			return nil, fmt.Errorf("decoding list: %w", err)
+		}
+	}
+
+	contains := func(entries []json.RawMessage, value json.RawMessage) bool {
+		return slices.ContainsFunc(entries, func(entry json.RawMessage) bool {
+			return bytes.Equal(entry, value)
+		})
+	}
+
+	if add {
+		for _, value := range values {
+			if !contains(entries, value) {
+				entries = append(entries, value)
+			}
+		}
+	} else {
+		entries = slices.DeleteFunc(entries, func(entry json.RawMessage) bool {
+			return contains(values, entry)
+		})
+	}
+
+	if entries == nil {
+		entries = []json.RawMessage{}
+	}
+
+	return json.Marshal(entries)
+}
@@ -0,0 +1,225 @@
+package database_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/OpenSlides/openslides-go/datastore/dskey"
+	"github.com/OpenSlides/openslides-go/datastore/dsmodels"
+	"github.com/OpenSlides/openslides-projector-service/pkg/database"
+)
+
+// countingFlow serves a fixed name and counts the reads. Every change
+// increases its position. If positionGate is set, reading the position
+// waits until it is closed.
+type countingFlow struct {
+	mu           sync.Mutex
+	name         string
+	reads        int
+	position     uint64
+	changes      chan map[dskey.Key][]byte
+	positionGate chan struct{}
+}
+
+func (f *countingFlow) Get(ctx context.Context, keys ...dskey.Key) (map[dskey.Key][]byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.reads++
+	result := make(map[dskey.Key][]byte, len(keys))
+	for _, key := range keys {
+		switch key.String() {
+		case "projector/1/id":
+			result[key] = []byte("1")
+		case "projector/1/name":
+			result[key] = []byte(`"` + f.name + `"`)
+		}
+	}
+	return result, nil
+}
+
+func (f *countingFlow) Update(ctx context.Context, updateFn func(map[dskey.Key][]byte, error)) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case changes := <-f.changes:
+			f.mu.Lock()
+			f.position++
+			f.mu.Unlock()
+
+			updateFn(changes, nil)
+		}
+	}
+}
+
+func (f *countingFlow) MaxPosition(ctx context.Context) (uint64, error) {
+	if f.positionGate != nil {
+		select {
+		case <-f.positionGate:
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.position, nil
+}
+
+func (f *countingFlow) setPosition(position uint64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.position = position
+}
+
+func (f *countingFlow) numReads() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.reads
+}
+
+func TestReadsUseReplica(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	primary := &countingFlow{name: "primary", changes: make(chan map[dskey.Key][]byte)}
+	replica := &countingFlow{name: "replica"}
+
+	ds, err := database.New("", "", primary)
+	if err != nil {
+		t.Fatalf("create datastore: %v", err)
+	}
+	if err := ds.UseReadReplica(replica, "", true); err != nil {
+		t.Fatalf("use replica: %v", err)
+	}
+
+	name, err := ds.Fetch.Projector_Name(1).Value(ctx)
+	if err != nil {
+		t.Fatalf("fetch name: %v", err)
+	}
+
+	if name != "replica" || primary.numReads() != 0 {
+		t.Errorf("expected fetch to read from replica, got %q with %d primary reads", name, primary.numReads())
+	}
+
+	names := make(chan string, 2)
+	ds.NewContext(ctx, func(f *dsmodels.Fetch) {
+		name, err := f.Projector_Name(1).Value(ctx)
+		if err != nil {
+			t.Errorf("fetch name in context: %v", err)
+		}
+		names <- name
+	})
+
+	if got := <-names; got != "primary" {
+		t.Errorf("expected initial snapshot to be read from primary, got %q", got)
+	}
+
+	// The replica has caught up with the change
+	replica.setPosition(1)
+	primary.changes <- map[dskey.Key][]byte{dskey.MustKey("projector/1/name"): []byte(`"changed"`)}
+
+	select {
+	case got := <-names:
+		if got != "replica" {
+			t.Errorf("expected update to be read from replica, got %q", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("context was not updated")
+	}
+}
+
+func TestReadsUsePrimaryWhileReplicaIsBehind(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	primary := &countingFlow{name: "primary", changes: make(chan map[dskey.Key][]byte)}
+	replica := &countingFlow{name: "replica"}
+
+	ds, err := database.New("", "", primary)
+	if err != nil {
+		t.Fatalf("create datastore: %v", err)
+	}
+	if err := ds.UseReadReplica(replica, "", false); err != nil {
+		t.Fatalf("use replica: %v", err)
+	}
+
+	names := make(chan string, 2)
+	ds.NewContext(ctx, func(f *dsmodels.Fetch) {
+		name, err := f.Projector_Name(1).Value(ctx)
+		if err != nil {
+			t.Errorf("fetch name in context: %v", err)
+		}
+		names <- name
+	})
+
+	if got := <-names; got != "replica" {
+		t.Errorf("expected initial read from the replica, got %q", got)
+	}
+
+	primary.changes <- map[dskey.Key][]byte{dskey.MustKey("projector/1/name"): []byte(`"changed"`)}
+
+	select {
+	case got := <-names:
+		if got != "primary" {
+			t.Errorf("expected update to be read from primary while the replica is behind, got %q", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("context was not updated")
+	}
+
+	replica.setPosition(1)
+	name, err := ds.Fetch.Projector_Name(1).Value(ctx)
+	if err != nil {
+		t.Fatalf("fetch name: %v", err)
+	}
+
+	if name != "replica" {
+		t.Errorf("expected read from the replica after it caught up, got %q", name)
+	}
+}
+
+func TestChangesDoNotWaitForPosition(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	flow := &countingFlow{
+		name:         "primary",
+		changes:      make(chan map[dskey.Key][]byte),
+		positionGate: make(chan struct{}),
+	}
+
+	ds, err := database.New("", "", flow)
+	if err != nil {
+		t.Fatalf("create datastore: %v", err)
+	}
+
+	updates := make(chan struct{}, 2)
+	ds.NewContext(ctx, func(f *dsmodels.Fetch) {
+		if _, err := f.Projector_Name(1).Value(ctx); err != nil {
+			t.Errorf("fetch name in context: %v", err)
+		}
+		updates <- struct{}{}
+	})
+	<-updates
+
+	flow.changes <- map[dskey.Key][]byte{dskey.MustKey("projector/1/name"): []byte(`"changed"`)}
+
+	select {
+	case <-updates:
+	case <-time.After(time.Second):
+		t.Fatalf("change notification waited for the position read")
+	}
+
+	close(flow.positionGate)
+	deadline := time.Now().Add(time.Second)
+	for ds.Position() != 1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected position 1 after the read, got %d", ds.Position())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
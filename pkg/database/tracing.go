@@ -0,0 +1,28 @@
+package database
+
+import (
+	"context"
+
+	"github.com/OpenSlides/openslides-go/datastore/dskey"
+	"github.com/OpenSlides/openslides-go/datastore/flow"
+	"github.com/OpenSlides/openslides-projector-service/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracedGetter creates a span for every read of a traced request or render
+// step. Reads outside of a trace are passed on unchanged.
+type tracedGetter struct {
+	flow.Getter
+}
+
+func (g tracedGetter) Get(ctx context.Context, keys ...dskey.Key) (_ map[dskey.Key][]byte, err error) {
+	if !trace.SpanFromContext(ctx).IsRecording() {
+		return g.Getter.Get(ctx, keys...)
+	}
+
+	ctx, span := tracing.Start(ctx, "datastore.get", attribute.Int("keys", len(keys)))
+	defer func() { tracing.End(span, err) }()
+
+	return g.Getter.Get(ctx, keys...)
+}
@@ -0,0 +1,60 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// VoteCountConnector keeps a connection to the vote service, e.g.
+// datastore.FlowVoteCount. Connect reconnects until the context is done and
+// waits for an event of eventProvider before each new attempt.
+type VoteCountConnector interface {
+	Connect(ctx context.Context, eventProvider func() (<-chan time.Time, func() bool), errHandler func(error))
+}
+
+// ConnectVoteCount connects vote until ctx is done and returns afterwards.
+// Errors are logged. After a failed connection the next attempt is delayed,
+// starting with minDelay and doubling up to maxDelay. Once a connection was
+// closed without an error the delay starts over.
+func ConnectVoteCount(ctx context.Context, vote VoteCountConnector, minDelay, maxDelay time.Duration) {
+	backoff := voteBackoff{min: minDelay, max: maxDelay}
+
+	errHandler := func(err error) {
+		if ctx.Err() != nil {
+			return
+		}
+
+		backoff.failed = true
+		log.Warn().Err(err).Msg("Vote service connection failed")
+	}
+
+	eventer := func() (<-chan time.Time, func() bool) {
+		timer := time.NewTimer(backoff.next())
+		return timer.C, timer.Stop
+	}
+
+	vote.Connect(ctx, eventer, errHandler)
+}
+
+// voteBackoff calculates the delay before the next connection attempt. It is
+// only used by the goroutine calling Connect.
+type voteBackoff struct {
+	min    time.Duration
+	max    time.Duration
+	delay  time.Duration
+	failed bool
+}
+
+func (b *voteBackoff) next() time.Duration {
+	switch {
+	case !b.failed || b.delay == 0:
+		b.delay = b.min
+	default:
+		b.delay = min(b.delay*2, b.max)
+	}
+
+	b.failed = false
+	return b.delay
+}
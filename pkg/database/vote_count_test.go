@@ -0,0 +1,101 @@
+package database_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/OpenSlides/openslides-projector-service/pkg/database"
+)
+
+// scriptedVoteCount reconnects like datastore.FlowVoteCount. Each attempt
+// fails or succeeds as given by results and the time waited before the next
+// attempt is recorded. The context is canceled after the last attempt.
+type scriptedVoteCount struct {
+	results []error
+	cancel  context.CancelFunc
+	waited  []time.Duration
+}
+
+func (v *scriptedVoteCount) Connect(ctx context.Context, eventProvider func() (<-chan time.Time, func() bool), errHandler func(error)) {
+	for attempt := 0; ctx.Err() == nil; attempt++ {
+		if attempt == len(v.results) {
+			v.cancel()
+			break
+		}
+
+		if err := v.results[attempt]; err != nil {
+			errHandler(err)
+		}
+
+		start := time.Now()
+		event, stop := eventProvider()
+		select {
+		case <-ctx.Done():
+		case <-event:
+		}
+		stop()
+		v.waited = append(v.waited, time.Since(start))
+	}
+}
+
+func TestConnectVoteCountBackoff(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	failed := errors.New("message bus unavailable")
+	vote := &scriptedVoteCount{
+		results: []error{failed, failed, failed, failed, failed, nil},
+		cancel:  cancel,
+	}
+
+	done := make(chan struct{})
+	go func() {
+		database.ConnectVoteCount(ctx, vote, 5*time.Millisecond, 40*time.Millisecond)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("ConnectVoteCount did not return after the context was canceled")
+	}
+
+	minimum := []time.Duration{5, 10, 20, 40, 40, 5}
+	if len(vote.waited) != len(minimum) {
+		t.Fatalf("expected %d attempts, got %d", len(minimum), len(vote.waited))
+	}
+
+	for i, want := range minimum {
+		if vote.waited[i] < want*time.Millisecond {
+			t.Errorf("attempt %d: waited %v, expected at least %v", i+1, vote.waited[i], want*time.Millisecond)
+		}
+	}
+
+	if last := vote.waited[len(vote.waited)-1]; last >= 40*time.Millisecond {
+		t.Errorf("expected the delay to start over after a successful connection, waited %v", last)
+	}
+}
+
+func TestConnectVoteCountStopsOnShutdown(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	vote := &scriptedVoteCount{
+		results: []error{errors.New("message bus unavailable")},
+		cancel:  func() {},
+	}
+
+	done := make(chan struct{})
+	go func() {
+		database.ConnectVoteCount(ctx, vote, time.Hour, time.Hour)
+		close(done)
+	}()
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("ConnectVoteCount did not return after the context was canceled")
+	}
+}
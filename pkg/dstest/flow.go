@@ -0,0 +1,112 @@
+// Package dstest provides an in-memory datastore flow for tests.
+package dstest
+
+import (
+	"context"
+	"sync"
+
+	"github.com/OpenSlides/openslides-go/datastore/dskey"
+)
+
+// Flow serves its data from memory. Changes sent to Changes are applied to
+// the data and passed to the updater, nil values delete the key. Every
+// change increases the position of the flow.
+type Flow struct {
+	Changes chan map[dskey.Key][]byte
+
+	mu       sync.Mutex
+	data     map[dskey.Key][]byte
+	err      error
+	position uint64
+}
+
+// NewFlow creates a flow with the given data, keys are like
+// "projector/1/name" and values are JSON encoded.
+func NewFlow(data map[string]string) *Flow {
+	flow := &Flow{
+		Changes: make(chan map[dskey.Key][]byte),
+		data:    make(map[dskey.Key][]byte),
+	}
+
+	for key, value := range data {
+		flow.data[dskey.MustKey(key)] = []byte(value)
+	}
+
+	return flow
+}
+
+func (f *Flow) Get(ctx context.Context, keys ...dskey.Key) (map[dskey.Key][]byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	result := make(map[dskey.Key][]byte, len(keys))
+	for _, key := range keys {
+		result[key] = f.data[key]
+	}
+	return result, nil
+}
+
+func (f *Flow) Update(ctx context.Context, updateFn func(map[dskey.Key][]byte, error)) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case changes := <-f.Changes:
+			f.mu.Lock()
+			f.position++
+			f.apply(changes)
+			f.mu.Unlock()
+
+			updateFn(changes, nil)
+		}
+	}
+}
+
+// MaxPosition returns the number of changes sent since the initial
+// position.
+func (f *Flow) MaxPosition(ctx context.Context) (uint64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.position, nil
+}
+
+// Set changes the data without notifying the updater. Values are JSON
+// encoded, nil deletes the key.
+func (f *Flow) Set(key string, value []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.apply(map[dskey.Key][]byte{dskey.MustKey(key): value})
+}
+
+// SetPosition sets the position changes are counted from.
+func (f *Flow) SetPosition(position uint64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.position = position
+}
+
+// SetError makes all following reads fail with err until it is reset with
+// nil.
+func (f *Flow) SetError(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.err = err
+}
+
+func (f *Flow) apply(changes map[dskey.Key][]byte) {
+	for key, value := range changes {
+		if value == nil {
+			delete(f.data, key)
+		} else {
+			f.data[key] = value
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package http
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+)
+
+// projectorAllowlistMiddleware answers requests for projectors not in
+// allowlist with 404 before any other work is done. An empty allowlist allows
+// all projectors.
+func projectorAllowlistMiddleware(next http.Handler, allowlist []int) http.Handler {
+	if len(allowlist) == 0 {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil || !slices.Contains(allowlist, id) {
+			writeError(w, http.StatusNotFound, "Projector not found")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// projectorsAllowed returns true if all ids are in allowlist. An empty
+// allowlist allows all projectors.
+func projectorsAllowed(allowlist []int, ids []int) bool {
+	if len(allowlist) == 0 {
+		return true
+	}
+
+	for _, id := range ids {
+		if !slices.Contains(allowlist, id) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,44 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestProjectorAllowlist(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	for _, tt := range []struct {
+		name      string
+		allowlist []int
+		path      string
+		expected  int
+	}{
+		// Without an auth service, requests passing the allowlist are
+		// answered with 503 by the authentication.
+		{"allowed id", []int{1}, "/system/projector/get/1", http.StatusServiceUnavailable},
+		{"disallowed id", []int{1}, "/system/projector/get/2", http.StatusNotFound},
+		{"disallowed id of meeting", []int{1}, "/system/projector/meeting/1/subscribe/2", http.StatusNotFound},
+		{"invalid id", []int{1}, "/system/projector/get/main", http.StatusNotFound},
+		{"bulk preview allowed", []int{1, 2}, "/system/projector/preview?meeting_id=1&ids=1,2", http.StatusServiceUnavailable},
+		{"bulk preview disallowed", []int{1}, "/system/projector/preview?meeting_id=1&ids=1,2", http.StatusNotFound},
+		{"unset", nil, "/system/projector/get/2", http.StatusServiceUnavailable},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := newTestProjectorHttp(t, ctx)
+			s.serverMux = http.NewServeMux()
+			s.cfg.ProjectorAllowlist = tt.allowlist
+			s.registerRoutes(s.cfg)
+
+			rec := httptest.NewRecorder()
+			s.serverMux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if rec.Code != tt.expected {
+				t.Errorf("expected status %d, got %d: %s", tt.expected, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
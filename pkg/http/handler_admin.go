@@ -0,0 +1,40 @@
+package http
+
+import (
+	"crypto/subtle"
+	"net/http"
+)
+
+const adminTokenHeader = "X-Admin-Token"
+
+// adminMiddleware only passes requests carrying the configured admin token.
+func adminMiddleware(next http.Handler, token string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		given := r.Header.Get(adminTokenHeader)
+		if token == "" || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			writeError(w, http.StatusUnauthorized, "admin token invalid")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// AdminSubscriptionsHandler lists all active subscriptions.
+func (s *projectorHttp) AdminSubscriptionsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, subscriptionsResponse{Subscriptions: s.subscriptions.list()})
+	}
+}
+
+// AdminCloseSubscriptionHandler force closes a subscription.
+func (s *projectorHttp) AdminCloseSubscriptionHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !s.subscriptions.close(r.PathValue("id")) {
+			writeError(w, http.StatusNotFound, "Subscription not found")
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,108 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAdminSubscriptions(t *testing.T) {
+	t.Chdir("../..")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s, flow := newTestProjectorHttp(t, ctx)
+	flow.Set("user/5/id", []byte("5"))
+	flow.Set("user/5/meeting_ids", []byte("[1]"))
+	s.serverMux = http.NewServeMux()
+	s.cfg.AdminToken = "secret"
+
+	// Stands in for the auth middleware
+	withUser := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userIDKey{}, 5)))
+		})
+	}
+	s.serverMux.Handle("GET /system/projector/subscribe/{id}", withUser(s.ProjectorSubscribeHandler()))
+	s.serverMux.Handle("GET /system/projector/admin/subscriptions", adminMiddleware(s.AdminSubscriptionsHandler(), s.cfg.AdminToken))
+	s.serverMux.Handle("DELETE /system/projector/admin/subscriptions/{id}", adminMiddleware(s.AdminCloseSubscriptionHandler(), s.cfg.AdminToken))
+	srv := httptest.NewServer(s.serverMux)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/system/projector/subscribe/1")
+	if err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+	defer resp.Body.Close()
+
+	reader := bufio.NewReader(resp.Body)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read subscription: %v", err)
+		}
+
+		if line == "event: connected\n" {
+			break
+		}
+	}
+
+	adminRequest := func(method string, path string, token string) *http.Response {
+		t.Helper()
+
+		req, err := http.NewRequest(method, srv.URL+path, nil)
+		if err != nil {
+			t.Fatalf("create request: %v", err)
+		}
+		if token != "" {
+			req.Header.Set(adminTokenHeader, token)
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+
+	if resp := adminRequest(http.MethodGet, "/system/projector/admin/subscriptions", "wrong"); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected status 401 with wrong token, got %d", resp.StatusCode)
+	}
+
+	listResp := adminRequest(http.MethodGet, "/system/projector/admin/subscriptions", "secret")
+	var list subscriptionsResponse
+	if err := json.NewDecoder(listResp.Body).Decode(&list); err != nil {
+		t.Fatalf("decode subscriptions: %v", err)
+	}
+
+	if len(list.Subscriptions) != 1 {
+		t.Fatalf("expected one subscription, got %v", list.Subscriptions)
+	}
+
+	sub := list.Subscriptions[0]
+	if sub.UserID != 5 || sub.ProjectorID != 1 || sub.Transport != "sse" || sub.ConnectedAt.IsZero() {
+		t.Errorf("unexpected subscription %v", sub)
+	}
+
+	if resp := adminRequest(http.MethodDelete, "/system/projector/admin/subscriptions/"+sub.ID, "secret"); resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("expected status 204, got %d", resp.StatusCode)
+	}
+
+	if _, err := io.ReadAll(reader); err != nil {
+		t.Errorf("expected subscription to be closed, got %v", err)
+	}
+
+	if len(s.subscriptions.list()) != 0 {
+		t.Errorf("expected no subscriptions after closing")
+	}
+
+	if resp := adminRequest(http.MethodDelete, "/system/projector/admin/subscriptions/"+sub.ID, "secret"); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected status 404 for closed subscription, got %d", resp.StatusCode)
+	}
+}
@@ -1,12 +1,97 @@
 package http
 
 import (
+	"context"
 	"net/http"
+	"time"
+
+	"github.com/rs/zerolog/log"
 )
 
+// DefaultHealthPath is used for the health endpoints if no other path is
+// configured.
+const DefaultHealthPath = "/system/projector/health"
+
+// readyTimeout limits the time the readiness check waits for dependencies.
+const readyTimeout = 2 * time.Second
+
+// processStartedAt is used to report the uptime of the service.
+var processStartedAt = time.Now()
+
+// HealthHandler reports that the service is running. It never touches any
+// dependency so it can be used as liveness check.
 func (s *projectorHttp) HealthHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		writeResponse(w, `{"healthy": true, "service":"projector"}`)
+		writeJSON(w, http.StatusOK, healthResponse{
+			Healthy: true,
+			Service: "projector",
+			Stats:   s.healthStats(r),
+		})
+	}
+}
+
+// ReadyHandler reports whether the dependencies needed to render projectors
+// are reachable.
+func (s *projectorHttp) ReadyHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), readyTimeout)
+		defer cancel()
+
+		checks := map[string]bool{
+			"datastore": s.datastoreReady(ctx),
+		}
+
+		if s.auth != nil && s.auth.messageBus != nil {
+			checks["message_bus"] = s.auth.messageBus.Healthy()
+		}
+
+		status := http.StatusOK
+		healthy := true
+		for _, ok := range checks {
+			if !ok {
+				status = http.StatusServiceUnavailable
+				healthy = false
+			}
+		}
+
+		writeJSON(w, status, healthResponse{
+			Healthy: healthy,
+			Service: "projector",
+			Checks:  checks,
+			Stats:   s.healthStats(r),
+		})
 	}
 }
+
+// healthStats returns the stats of the service if they are requested with
+// ?verbose=1.
+func (s *projectorHttp) healthStats(r *http.Request) *healthStats {
+	if r.URL.Query().Get("verbose") != "1" {
+		return nil
+	}
+
+	cacheBytes := s.projector.RenderCache.Stats().Bytes
+	budget := s.projector.MemoryBudget.Stats()
+	usedBytes := budget.UsedBytes
+	if s.projector.MemoryBudget == nil {
+		usedBytes = cacheBytes
+	}
+
+	return &healthStats{
+		UptimeSeconds:     int64(time.Since(processStartedAt).Seconds()),
+		Subscriptions:     s.subscriptions.count(),
+		DatastorePosition: s.db.Position(),
+		RenderCacheBytes:  cacheBytes,
+		MemoryBudgetBytes: budget.MaxBytes,
+		MemoryUsedBytes:   usedBytes,
+	}
+}
+
+func (s *projectorHttp) datastoreReady(ctx context.Context) bool {
+	if _, err := s.db.Fetch.Organization_ID(1).Value(ctx); err != nil {
+		log.Warn().Err(err).Msg("readiness check: datastore not reachable")
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,106 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/OpenSlides/openslides-go/datastore/dskey"
+)
+
+func TestHealthPathAndReadiness(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s, flow := newTestProjectorHttp(t, ctx)
+	s.serverMux = http.NewServeMux()
+	s.registerRoutes(ProjectorConfig{HealthPath: "/status"})
+
+	get := func(path string) (int, healthResponse) {
+		rec := httptest.NewRecorder()
+		s.serverMux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+		var resp healthResponse
+		if rec.Code != http.StatusNotFound {
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("%s: parse response: %v", path, err)
+			}
+		}
+		return rec.Code, resp
+	}
+
+	for _, path := range []string{"/status", "/status/live", "/status/ready"} {
+		if code, resp := get(path); code != http.StatusOK || !resp.Healthy {
+			t.Errorf("%s: expected healthy response, got %d %+v", path, code, resp)
+		}
+	}
+
+	if code, _ := get(DefaultHealthPath); code != http.StatusNotFound {
+		t.Errorf("expected default health path to be unused, got %d", code)
+	}
+
+	flow.Set("organization/1/id", nil)
+
+	code, resp := get("/status/ready")
+	if code != http.StatusServiceUnavailable || resp.Healthy || resp.Checks["datastore"] {
+		t.Errorf("expected unavailable datastore, got %d %+v", code, resp)
+	}
+
+	// Liveness never checks dependencies
+	if code, _ := get("/status/live"); code != http.StatusOK {
+		t.Errorf("expected live check to succeed, got %d", code)
+	}
+}
+
+func TestHealthVerboseStats(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s, flow := newTestProjectorHttp(t, ctx)
+	s.serverMux = http.NewServeMux()
+	s.registerRoutes(ProjectorConfig{})
+
+	get := func(path string) healthResponse {
+		rec := httptest.NewRecorder()
+		s.serverMux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+		var resp healthResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("%s: parse response: %v", path, err)
+		}
+		return resp
+	}
+
+	for _, path := range []string{DefaultHealthPath, DefaultHealthPath + "/ready"} {
+		if resp := get(path); resp.Stats != nil {
+			t.Errorf("%s: expected no stats without verbose, got %+v", path, resp.Stats)
+		}
+	}
+
+	_, unregister := s.subscriptions.add(ctx, 1, 1, "sse", "")
+	defer unregister()
+
+	flow.Changes <- map[dskey.Key][]byte{dskey.MustKey("projector/1/name"): []byte(`"Main"`)}
+	waitFor(t, "datastore position", func() bool { return s.db.Position() > 0 })
+
+	for _, path := range []string{DefaultHealthPath + "?verbose=1", DefaultHealthPath + "/ready?verbose=1"} {
+		resp := get(path)
+		if resp.Stats == nil {
+			t.Fatalf("%s: expected stats", path)
+		}
+
+		if resp.Stats.Subscriptions != 1 {
+			t.Errorf("%s: expected 1 subscription, got %d", path, resp.Stats.Subscriptions)
+		}
+
+		if resp.Stats.DatastorePosition != s.db.Position() {
+			t.Errorf("%s: expected position %d, got %d", path, s.db.Position(), resp.Stats.DatastorePosition)
+		}
+
+		if resp.Stats.UptimeSeconds < 0 {
+			t.Errorf("%s: expected uptime, got %d", path, resp.Stats.UptimeSeconds)
+		}
+	}
+}
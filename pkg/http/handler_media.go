@@ -0,0 +1,70 @@
+package http
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/rs/zerolog/log"
+)
+
+// mediaProxyURL is the prefix of mediafile urls in rendered content if the
+// media proxy is enabled.
+const mediaProxyURL = "/system/projector/media/"
+
+// Request and response headers passed between the client and the media
+// service.
+var (
+	mediaRequestHeaders  = []string{"Authorization", "Cookie", "Range", "If-None-Match", "If-Modified-Since"}
+	mediaResponseHeaders = []string{"Content-Type", "Content-Length", "Content-Range", "Content-Disposition", "Accept-Ranges", "Cache-Control", "ETag", "Last-Modified"}
+)
+
+// MediaProxyHandler streams a mediafile from the media service. The
+// credentials of the client are passed on so the media service applies the
+// same visibility checks as for direct requests.
+func (s *projectorHttp) MediaProxyHandler() http.HandlerFunc {
+	client := newOutboundClient(s.cfg.OutboundTLS)
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil || id <= 0 {
+			writeError(w, http.StatusBadRequest, "Mediafile id invalid")
+			return
+		}
+
+		req, err := http.NewRequestWithContext(r.Context(), http.MethodGet, fmt.Sprintf("%s/%d", s.cfg.MediaServiceUrl, id), nil)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "creating media request failed")
+			return
+		}
+
+		for _, header := range mediaRequestHeaders {
+			if value := r.Header.Get(header); value != "" {
+				req.Header.Set(header, value)
+			}
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			log.Ctx(r.Context()).Err(err).Msgf("fetching mediafile %d", id)
+			writeError(w, http.StatusBadGateway, "media request failed")
+			return
+		}
+		defer func() {
+			if err := resp.Body.Close(); err != nil {
+				log.Err(err).Msg("error closing media response body")
+			}
+		}()
+
+		for _, header := range mediaResponseHeaders {
+			if value := resp.Header.Get(header); value != "" {
+				w.Header().Set(header, value)
+			}
+		}
+		w.WriteHeader(resp.StatusCode)
+
+		if _, err := io.Copy(w, resp.Body); err != nil {
+			log.Ctx(r.Context()).Err(err).Msgf("streaming mediafile %d", id)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMediaProxyHandler(t *testing.T) {
+	media := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		w.Header().Set("Content-Type", "image/png")
+		fmt.Fprintf(w, "file %s", r.URL.Path)
+	}))
+	defer media.Close()
+
+	s := &projectorHttp{
+		serverMux: http.NewServeMux(),
+		cfg:       ProjectorConfig{MediaProxy: true, MediaServiceUrl: media.URL + "/system/media/get"},
+	}
+	s.registerRoutes(s.cfg)
+
+	req := httptest.NewRequest(http.MethodGet, "/system/projector/media/5", nil)
+	req.Header.Set("Authorization", "Bearer token")
+	rec := httptest.NewRecorder()
+	s.serverMux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+
+	if got := rec.Header().Get("Content-Type"); got != "image/png" {
+		t.Errorf("expected content type image/png, got %q", got)
+	}
+
+	if got := rec.Body.String(); got != "file /system/media/get/5" {
+		t.Errorf("unexpected body %q", got)
+	}
+
+	rec = httptest.NewRecorder()
+	s.serverMux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/system/projector/media/5", nil))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("expected media service to deny request without credentials, got %d", rec.Code)
+	}
+}
+
+func TestMediaProxyDisabled(t *testing.T) {
+	s := &projectorHttp{serverMux: http.NewServeMux()}
+	s.registerRoutes(ProjectorConfig{})
+
+	rec := httptest.NewRecorder()
+	s.serverMux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/system/projector/media/5", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 without media proxy, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,68 @@
+package http
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/OpenSlides/openslides-projector-service/pkg/projector"
+	"github.com/OpenSlides/openslides-projector-service/pkg/projector/slide"
+	"github.com/rs/zerolog/log"
+)
+
+// metricsContentType is the content type of the Prometheus text format.
+const metricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// MetricsHandler exposes the update and render metrics of the projectors in
+// the Prometheus text format.
+func (s *projectorHttp) MetricsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", metricsContentType)
+		replayed, resynced := projector.Resumes()
+		resumes := map[string]uint64{"replayed": replayed, "resync": resynced}
+		if err := writeMetrics(w, projector.ProjectorUpdates(), slide.RenderDurations(), resumes); err != nil {
+			log.Err(err).Msg("writing metrics")
+		}
+	}
+}
+
+// writeMetrics writes the metrics sorted by their labels, so the output only
+// changes with the values.
+func writeMetrics(w io.Writer, updates map[string]uint64, durations map[string]slide.DurationHistogram, resumes map[string]uint64) error {
+	var err error
+	printf := func(format string, args ...any) {
+		if err == nil {
+			_, err = fmt.Fprintf(w, format, args...)
+		}
+	}
+
+	printf("# HELP projector_updates_total Number of updates sent to the subscribers of a projector.\n")
+	printf("# TYPE projector_updates_total counter\n")
+	for _, id := range slices.Sorted(maps.Keys(updates)) {
+		printf("projector_updates_total{projector_id=%s} %d\n", strconv.Quote(id), updates[id])
+	}
+
+	printf("# HELP projector_render_duration_seconds Time needed to render a slide.\n")
+	printf("# TYPE projector_render_duration_seconds histogram\n")
+	for _, collection := range slices.Sorted(maps.Keys(durations)) {
+		histogram := durations[collection]
+		label := strconv.Quote(collection)
+		for i, bound := range slide.RenderDurationBuckets {
+			printf("projector_render_duration_seconds_bucket{collection=%s,le=\"%s\"} %d\n", label, strconv.FormatFloat(bound, 'g', -1, 64), histogram.Buckets[i])
+		}
+		printf("projector_render_duration_seconds_bucket{collection=%s,le=\"+Inf\"} %d\n", label, histogram.Count)
+		printf("projector_render_duration_seconds_sum{collection=%s} %s\n", label, strconv.FormatFloat(histogram.Sum, 'g', -1, 64))
+		printf("projector_render_duration_seconds_count{collection=%s} %d\n", label, histogram.Count)
+	}
+
+	printf("# HELP projector_sse_resumes_total Number of subscriptions resumed with Last-Event-ID by result.\n")
+	printf("# TYPE projector_sse_resumes_total counter\n")
+	for _, result := range slices.Sorted(maps.Keys(resumes)) {
+		printf("projector_sse_resumes_total{result=%s} %d\n", strconv.Quote(result), resumes[result])
+	}
+
+	return err
+}
@@ -0,0 +1,41 @@
+package http
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/OpenSlides/openslides-projector-service/pkg/projector/slide"
+)
+
+func TestWriteMetrics(t *testing.T) {
+	buckets := make([]uint64, len(slide.RenderDurationBuckets))
+	for i := range buckets {
+		buckets[i] = 3
+	}
+	buckets[0] = 1
+
+	var out strings.Builder
+	err := writeMetrics(&out, map[string]uint64{"2": 4, "10": 1, "other": 7}, map[string]slide.DurationHistogram{
+		"topic": {Buckets: buckets, Count: 3, Sum: 0.25},
+	}, map[string]uint64{"replayed": 5, "resync": 2})
+	if err != nil {
+		t.Fatalf("write metrics: %v", err)
+	}
+
+	for _, expected := range []string{
+		"# TYPE projector_updates_total counter\n",
+		"projector_updates_total{projector_id=\"10\"} 1\nprojector_updates_total{projector_id=\"2\"} 4\nprojector_updates_total{projector_id=\"other\"} 7\n",
+		"# TYPE projector_render_duration_seconds histogram\n",
+		"projector_render_duration_seconds_bucket{collection=\"topic\",le=\"0.001\"} 1\n",
+		"projector_render_duration_seconds_bucket{collection=\"topic\",le=\"5\"} 3\n",
+		"projector_render_duration_seconds_bucket{collection=\"topic\",le=\"+Inf\"} 3\n",
+		"projector_render_duration_seconds_sum{collection=\"topic\"} 0.25\n",
+		"projector_render_duration_seconds_count{collection=\"topic\"} 3\n",
+		"# TYPE projector_sse_resumes_total counter\n",
+		"projector_sse_resumes_total{result=\"replayed\"} 5\nprojector_sse_resumes_total{result=\"resync\"} 2\n",
+	} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("expected %q in metrics, got:\n%s", expected, out.String())
+		}
+	}
+}
@@ -0,0 +1,13 @@
+package http
+
+import (
+	"net/http"
+)
+
+func (s *projectorHttp) PositionHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, positionResponse{
+			Position: s.db.Position(),
+		})
+	}
+}
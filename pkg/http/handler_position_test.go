@@ -0,0 +1,93 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/OpenSlides/openslides-go/datastore/dskey"
+	"github.com/OpenSlides/openslides-projector-service/pkg/database"
+	"github.com/OpenSlides/openslides-projector-service/pkg/dstest"
+	"github.com/OpenSlides/openslides-projector-service/pkg/projector"
+	"golang.org/x/text/language"
+)
+
+// newTestProjectorHttp creates a handler with a projector pool for the
+// projectors 1 and 2 of meeting 1.
+func newTestProjectorHttp(t *testing.T, ctx context.Context) (*projectorHttp, *dstest.Flow) {
+	t.Helper()
+
+	flow := dstest.NewFlow(map[string]string{
+		"projector/1/id":                "1",
+		"projector/1/meeting_id":        "1",
+		"projector/1/sequential_number": "1",
+		"projector/1/name":              `"Main"`,
+		"projector/2/id":                "2",
+		"projector/2/meeting_id":        "1",
+		"projector/2/sequential_number": "2",
+		"projector/2/name":              `"Side"`,
+		"meeting/1/id":                  "1",
+		"meeting/1/name":                `"Meeting"`,
+		"meeting/1/enable_anonymous":    "true",
+		"organization/1/id":             "1",
+		"organization/1/theme_id":       "1",
+		"theme/1/id":                    "1",
+		"theme/1/name":                  `"Theme"`,
+		"theme/1/organization_id":       "1",
+	})
+
+	db, err := database.New("", "", flow)
+	if err != nil {
+		t.Fatalf("create datastore: %v", err)
+	}
+
+	return &projectorHttp{
+		ctx:           ctx,
+		db:            db,
+		projector:     projector.NewProjectorPool(ctx, db, flow),
+		cfg:           ProjectorConfig{DefaultLanguage: language.English},
+		subscriptions: newSubscriptionRegistry(),
+	}, flow
+}
+
+func requestPosition(t *testing.T, s *projectorHttp) uint64 {
+	rec := httptest.NewRecorder()
+	s.PositionHandler()(rec, httptest.NewRequest(http.MethodGet, "/system/projector/position", nil))
+
+	var resp positionResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("parse response: %v", err)
+	}
+
+	return resp.Position
+}
+
+func TestPositionHandler(t *testing.T) {
+	flow := dstest.NewFlow(nil)
+	flow.SetPosition(41)
+	db, err := database.New("", "", flow)
+	if err != nil {
+		t.Fatalf("create datastore: %v", err)
+	}
+
+	s := &projectorHttp{db: db}
+	waitForPosition := func(expected uint64) {
+		t.Helper()
+
+		deadline := time.Now().Add(time.Second)
+		for requestPosition(t, s) != expected {
+			if time.Now().After(deadline) {
+				t.Fatalf("expected position %d, got %d", expected, requestPosition(t, s))
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	waitForPosition(41)
+
+	flow.Changes <- map[dskey.Key][]byte{dskey.MustKey("projector/1/name"): []byte(`"Main"`)}
+	waitForPosition(42)
+}
@@ -0,0 +1,173 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/OpenSlides/openslides-projector-service/pkg/tracing"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/text/language"
+)
+
+const (
+	// bulkPreviewMaxIDs is the maximum number of projectors rendered by a
+	// single bulk preview request.
+	bulkPreviewMaxIDs = 50
+
+	// bulkPreviewConcurrency is the number of projectors rendered in
+	// parallel by a bulk preview request.
+	bulkPreviewConcurrency = 4
+)
+
+// ProjectorBulkPreviewHandler renders all requested projectors of a meeting
+// at once. Projectors the user cannot see or which do not belong to the
+// meeting are listed as denied, projectors which could not be rendered, e.g.
+// because the render limiter had no free slot, as failed.
+func (s *projectorHttp) ProjectorBulkPreviewHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		meetingID, err := strconv.Atoi(r.URL.Query().Get("meeting_id"))
+		if err != nil || meetingID <= 0 {
+			writeError(w, http.StatusBadRequest, "Meeting id invalid")
+			return
+		}
+
+		ids, err := parseIDList(r.URL.Query().Get("ids"))
+		if err != nil || len(ids) == 0 {
+			writeError(w, http.StatusBadRequest, "Projector ids invalid")
+			return
+		}
+
+		if len(ids) > bulkPreviewMaxIDs {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("At most %d projectors can be requested at once", bulkPreviewMaxIDs))
+			return
+		}
+
+		if !projectorsAllowed(s.cfg.ProjectorAllowlist, ids) {
+			writeError(w, http.StatusNotFound, "Projector not found")
+			return
+		}
+
+		lang, err := getPreviewLanguage(r, s.cfg.DefaultLanguage)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "Language not supported")
+			return
+		}
+
+		ctx, userID, ok := authenticate(w, r, s.auth, s.cfg)
+		if !ok {
+			return
+		}
+
+		resp, err := s.bulkPreview(ctx, userID, meetingID, ids, lang)
+		if err != nil {
+			var statusErr restricterStatusError
+			if errors.As(err, &statusErr) {
+				writeError(w, statusErr.status, "restriction request failed")
+				return
+			}
+
+			log.Ctx(r.Context()).Err(err).Msg("bulk preview failed")
+			writeError(w, http.StatusInternalServerError, "restriction request failed")
+			return
+		}
+
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
+
+func (s *projectorHttp) bulkPreview(ctx context.Context, userID int, meetingID int, ids []int, lang language.Tag) (bulkPreviewResponse, error) {
+	resp := bulkPreviewResponse{
+		Previews: make(map[int]string),
+		Denied:   []int{},
+		Failed:   []int{},
+	}
+
+	visible, err := s.restricter.VisibleProjectors(ctx, userID, ids)
+	if err != nil {
+		return resp, fmt.Errorf("checking projector restrictions: %w", err)
+	}
+
+	allowed := []int{}
+	for _, id := range ids {
+		if !visible[id] {
+			resp.Denied = append(resp.Denied, id)
+			continue
+		}
+
+		projectorMeetingID, err := s.db.Fetch.Projector_MeetingID(id).Value(ctx)
+		if err != nil || projectorMeetingID != meetingID {
+			resp.Denied = append(resp.Denied, id)
+			continue
+		}
+
+		allowed = append(allowed, id)
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, bulkPreviewConcurrency)
+	for _, id := range allowed {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			// Every render takes a slot of the render limiter, like a
+			// single preview
+			release, ok := s.renderLimiter.acquire(ctx)
+			if !ok {
+				mu.Lock()
+				defer mu.Unlock()
+				log.Ctx(ctx).Warn().Msgf("no render slot for the preview of projector %d", id)
+				resp.Failed = append(resp.Failed, id)
+				return
+			}
+			defer release()
+
+			stopRender := tracing.Measure(ctx, "render")
+			content, err := s.projector.RenderProjector(id, lang)
+			stopRender()
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil || content == nil {
+				log.Ctx(ctx).Err(err).Msgf("rendering preview of projector %d", id)
+				resp.Failed = append(resp.Failed, id)
+				return
+			}
+			resp.Previews[id] = s.minify(*content)
+		}()
+	}
+	wg.Wait()
+
+	return resp, nil
+}
+
+// parseIDList parses a comma separated list of ids.
+func parseIDList(value string) ([]int, error) {
+	ids := []int{}
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		id, err := strconv.Atoi(part)
+		if err != nil || id <= 0 {
+			return nil, fmt.Errorf("invalid id %q", part)
+		}
+
+		if !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids, nil
+}
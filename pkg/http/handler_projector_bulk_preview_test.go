@@ -0,0 +1,103 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/text/language"
+)
+
+func TestBulkPreviewPartialResults(t *testing.T) {
+	t.Chdir("../..")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var requests atomic.Int32
+	restricterSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+
+		// Projector 1 is visible, projector 2 is not.
+		fmt.Fprint(w, `{"projector/1/id":1}`)
+	}))
+	defer restricterSrv.Close()
+
+	s, _ := newTestProjectorHttp(t, ctx)
+	s.restricter = newRestricter([]string{restricterSrv.URL}, time.Minute)
+
+	resp, err := s.bulkPreview(ctx, 1, 1, []int{1, 2, 3}, language.English)
+	if err != nil {
+		t.Fatalf("bulk preview: %v", err)
+	}
+
+	if got := requests.Load(); got != 1 {
+		t.Errorf("expected a single restriction request, got %d", got)
+	}
+
+	if len(resp.Previews) != 1 || !strings.Contains(resp.Previews[1], "projector-container") {
+		t.Errorf("expected only projector 1 to be rendered, got %v", resp.Previews)
+	}
+
+	if !slices.Equal(resp.Denied, []int{2, 3}) {
+		t.Errorf("expected projectors 2 and 3 to be denied, got %v", resp.Denied)
+	}
+}
+
+func TestBulkPreviewTakesRenderSlots(t *testing.T) {
+	t.Chdir("../..")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	restricterSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"projector/1/id":1,"projector/2/id":2}`)
+	}))
+	defer restricterSrv.Close()
+
+	s, _ := newTestProjectorHttp(t, ctx)
+	s.restricter = newRestricter([]string{restricterSrv.URL}, time.Minute)
+	// Renders wait a short time for the single slot
+	s.renderLimiter = newConcurrencyLimiter(1, 200*time.Millisecond)
+
+	release, ok := s.renderLimiter.acquire(ctx)
+	if !ok {
+		t.Fatalf("acquire render slot")
+	}
+
+	resp, err := s.bulkPreview(ctx, 1, 1, []int{1, 2}, language.English)
+	if err != nil {
+		t.Fatalf("bulk preview: %v", err)
+	}
+
+	if len(resp.Previews) != 0 || !slices.Equal(slices.Sorted(slices.Values(resp.Failed)), []int{1, 2}) {
+		t.Errorf("expected all projectors to fail without a render slot, got %v, failed %v", resp.Previews, resp.Failed)
+	}
+
+	release()
+	resp, err = s.bulkPreview(ctx, 1, 1, []int{1, 2}, language.English)
+	if err != nil {
+		t.Fatalf("bulk preview: %v", err)
+	}
+
+	if len(resp.Previews) != 2 || len(resp.Failed) != 0 {
+		t.Errorf("expected both projectors to be rendered one after another, got %d previews, failed %v", len(resp.Previews), resp.Failed)
+	}
+}
+
+func TestParseIDList(t *testing.T) {
+	ids, err := parseIDList("3, 1,3,,2")
+	if err != nil || !slices.Equal(ids, []int{3, 1, 2}) {
+		t.Errorf("expected [3 1 2], got %v, %v", ids, err)
+	}
+
+	if _, err := parseIDList("1,x"); err == nil {
+		t.Errorf("expected error for invalid id")
+	}
+}
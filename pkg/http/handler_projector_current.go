@@ -0,0 +1,34 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/OpenSlides/openslides-go/datastore/dsfetch"
+)
+
+// ProjectorCurrentHandler returns what is shown on the projector without its
+// rendered content. Clients can compare the hashes to decide whether the full
+// content needs to be fetched.
+func (s *projectorHttp) ProjectorCurrentHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "Projector id invalid")
+			return
+		}
+
+		projections, err := s.projector.GetCurrentProjections(r.Context(), id, s.projectorLanguage(r, id))
+		var doesNotExist dsfetch.DoesNotExistError
+		if errors.As(err, &doesNotExist) {
+			writeError(w, http.StatusNotFound, "Projector not found")
+			return
+		} else if err != nil {
+			writeError(w, http.StatusInternalServerError, "Error reading projector content")
+			return
+		}
+
+		writeJSON(w, http.StatusOK, currentResponse{Projections: projections})
+	}
+}
@@ -0,0 +1,70 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestProjectorCurrent(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s, flow := newTestProjectorHttp(t, ctx)
+	for key, value := range map[string]string{
+		"projector/1/current_projection_ids": "[5, 6]",
+		"projection/5/id":                    "5",
+		"projection/5/meeting_id":            "1",
+		"projection/5/content_object_id":     `"motion/12"`,
+		"projection/5/weight":                "2",
+		"projection/6/id":                    "6",
+		"projection/6/meeting_id":            "1",
+		"projection/6/content_object_id":     `"meeting/1"`,
+		"projection/6/type":                  `"agenda_item_list"`,
+		"projection/6/stable":                "true",
+	} {
+		flow.Set(key, []byte(value))
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /system/projector/current/{id}", s.ProjectorCurrentHandler())
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/system/projector/current/1", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	type reference struct {
+		ID              int    `json:"id"`
+		Collection      string `json:"collection"`
+		ContentObjectID string `json:"content_object_id"`
+		ObjectID        int    `json:"object_id"`
+		Type            string `json:"type"`
+		Stable          bool   `json:"stable"`
+	}
+
+	var resp struct {
+		Projections []reference `json:"projections"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+
+	expected := []reference{
+		{ID: 5, Collection: "motion", ContentObjectID: "motion/12", ObjectID: 12},
+		{ID: 6, Collection: "meeting", ContentObjectID: "meeting/1", ObjectID: 1, Type: "agenda_item_list", Stable: true},
+	}
+	if !reflect.DeepEqual(resp.Projections, expected) {
+		t.Errorf("expected projections %+v, got %+v", expected, resp.Projections)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/system/projector/current/3", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for an unknown projector, got %d", rec.Code)
+	}
+}
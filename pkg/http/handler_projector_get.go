@@ -2,49 +2,192 @@ package http
 
 import (
 	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
 	"html/template"
+	"math"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
+
+	"github.com/OpenSlides/openslides-projector-service/pkg/database"
+	"github.com/OpenSlides/openslides-projector-service/pkg/tracing"
+	"golang.org/x/text/language"
 )
 
+// maxPollWait is the longest duration a long poll of get is held open.
+const maxPollWait = 60 * time.Second
+
+// errPollWaitInvalid is returned for a wait query parameter which is not a
+// positive number of seconds.
+var errPollWaitInvalid = errors.New("wait invalid")
+
+// getPollWait returns the duration a get request waits for the projector to
+// change, given in seconds by the wait query parameter. It is capped at
+// maxPollWait.
+func getPollWait(r *http.Request) (time.Duration, error) {
+	waitVar := r.URL.Query().Get("wait")
+	if waitVar == "" {
+		return 0, nil
+	}
+
+	wait, err := strconv.Atoi(waitVar)
+	if err != nil || wait < 0 {
+		return 0, errPollWaitInvalid
+	}
+
+	return min(time.Duration(wait)*time.Second, maxPollWait), nil
+}
+
+// projectorETag returns the entity tag of a rendered projector page.
+func projectorETag(page string) string {
+	hash := fnv.New64a()
+	hash.Write([]byte(page))
+	return fmt.Sprintf(`"%016x"`, hash.Sum64())
+}
+
+// etagMatches reports whether the If-None-Match header of the request
+// contains the entity tag.
+func etagMatches(r *http.Request, etag string) bool {
+	for _, value := range strings.Split(r.Header.Get("If-None-Match"), ",") {
+		value = strings.TrimPrefix(strings.TrimSpace(value), "W/")
+		if value == etag || value == "*" {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (s *projectorHttp) ProjectorGetHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		id, err := strconv.Atoi(r.PathValue("id"))
 		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			writeResponse(w, `{"error": true, "msg": "Projector id invalid"}`)
+			writeError(w, http.StatusBadRequest, "Projector id invalid")
 			return
 		}
 
-		projectorContent, err := s.projector.GetProjectorContent(id, getRequestLanguage(r))
+		position, err := getRequestPosition(r)
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			writeResponse(w, `{"error": true, "msg": "Error reading projector content"}`)
+			writeError(w, http.StatusBadRequest, "Position invalid")
 			return
 		}
 
-		if projectorContent == nil {
-			w.WriteHeader(http.StatusNotFound)
-			writeResponse(w, `{"error": true, "msg": "Projector not found"}`)
+		wait, err := getPollWait(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "Wait invalid")
 			return
 		}
 
-		tmpl, err := template.ParseFiles("templates/projector.html")
-		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			writeResponse(w, `{"error": true, "msg": "Error providing projector content"}`)
+		lang := s.projectorLanguage(r, id)
+		stopRender := tracing.Measure(r.Context(), "render")
+		page, stale, ok := s.renderProjectorPage(w, id, lang, position)
+		stopRender()
+		if !ok {
 			return
 		}
 
-		var content bytes.Buffer
-		if err := tmpl.Execute(&content, map[string]any{
-			"ProjectorContent": template.HTML(*projectorContent),
-		}); err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			writeResponse(w, `{"error": true, "msg": "Error providing projector content"}`)
+		etag := projectorETag(page)
+		if wait > 0 && position == 0 && etagMatches(r, etag) {
+			page, stale, ok = s.waitForProjectorChange(w, r.Context(), id, lang, etag, wait)
+			if !ok {
+				return
+			}
+			etag = projectorETag(page)
+		}
+
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Cache-Control", "no-cache")
+		if pollInterval := s.runtimeConfig().PollInterval; pollInterval > 0 {
+			w.Header().Set("X-Poll-Interval", strconv.Itoa(int(math.Ceil(pollInterval.Seconds()))))
+		}
+
+		if stale {
+			w.Header().Set("X-Projector-Stale", "true")
+		}
+
+		if etagMatches(r, etag) {
+			w.WriteHeader(http.StatusNotModified)
 			return
 		}
 
-		writeResponse(w, content.String())
+		writeResponse(w, page)
+	}
+}
+
+// waitForProjectorChange holds a long poll until the rendered projector
+// differs from the entity tag or the wait elapsed. The last rendered page is
+// returned in both cases.
+func (s *projectorHttp) waitForProjectorChange(w http.ResponseWriter, ctx context.Context, id int, lang language.Tag, etag string, wait time.Duration) (string, bool, bool) {
+	ctx, cancel := context.WithTimeout(ctx, wait)
+	defer cancel()
+
+	updates, err := s.projector.SubscribeProjectorContent(ctx, id, lang, nil)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Error reading projector content")
+		return "", false, false
+	}
+
+	for {
+		// The projector may have changed before the subscription was added.
+		page, stale, ok := s.renderProjectorPage(w, id, lang, 0)
+		if !ok || projectorETag(page) != etag {
+			return page, stale, ok
+		}
+
+		select {
+		case _, open := <-updates:
+			if !open {
+				return page, stale, true
+			}
+		case <-ctx.Done():
+			return page, stale, true
+		}
+	}
+}
+
+// renderProjectorPage renders the projector page with the current content of
+// the projector or its content at the datastore position. If it fails, the
+// error is written and false returned.
+func (s *projectorHttp) renderProjectorPage(w http.ResponseWriter, id int, lang language.Tag, position int) (string, bool, bool) {
+	var projectorContent *string
+	var err error
+	stale := false
+	if position > 0 {
+		projectorContent, err = s.projector.GetProjectorContentAtPosition(id, lang, position)
+	} else {
+		projectorContent, stale, err = s.projector.GetProjectorContentState(id, lang)
+	}
+
+	if errors.Is(err, database.ErrHistoryUnavailable) {
+		writeError(w, http.StatusBadRequest, "History not available for position")
+		return "", false, false
+	} else if err != nil {
+		writeError(w, http.StatusInternalServerError, "Error reading projector content")
+		return "", false, false
+	}
+
+	if projectorContent == nil {
+		writeError(w, http.StatusNotFound, "Projector not found")
+		return "", false, false
 	}
+
+	tmpl, err := template.ParseFiles("templates/projector.html")
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Error providing projector content")
+		return "", false, false
+	}
+
+	var content bytes.Buffer
+	if err := tmpl.Execute(&content, map[string]any{
+		"ProjectorContent": template.HTML(*projectorContent),
+	}); err != nil {
+		writeError(w, http.StatusInternalServerError, "Error providing projector content")
+		return "", false, false
+	}
+
+	return s.minify(content.String()), stale, true
 }
@@ -0,0 +1,82 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/OpenSlides/openslides-go/datastore/dskey"
+)
+
+func TestProjectorGetPolling(t *testing.T) {
+	t.Chdir("../..")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s, flow := newTestProjectorHttp(t, ctx)
+	s.cfg.PollInterval = 5 * time.Second
+
+	get := func(query string, etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/system/projector/get/1"+query, nil)
+		req.SetPathValue("id", "1")
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+
+		rec := httptest.NewRecorder()
+		s.ProjectorGetHandler()(rec, req)
+		return rec
+	}
+
+	first := get("", "")
+	etag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || etag == "" {
+		t.Fatalf("expected 200 with etag, got %d with etag %q", first.Code, etag)
+	}
+
+	if got := first.Header().Get("X-Poll-Interval"); got != "5" {
+		t.Errorf("expected poll interval 5, got %q", got)
+	}
+
+	if rec := get("", etag); rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+		t.Errorf("expected 304 without body for unchanged projector, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	if rec := get("?wait=-1", etag); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for negative wait, got %d", rec.Code)
+	}
+
+	start := time.Now()
+	if rec := get("?wait=1", etag); rec.Code != http.StatusNotModified {
+		t.Errorf("expected 304 after long poll without change, got %d", rec.Code)
+	}
+	if waited := time.Since(start); waited < time.Second {
+		t.Errorf("expected long poll to wait a second, returned after %s", waited)
+	}
+
+	changed := make(chan *httptest.ResponseRecorder)
+	go func() {
+		changed <- get("?wait=10", etag)
+	}()
+
+	flow.Changes <- map[dskey.Key][]byte{
+		dskey.MustKey("projector/1/color"): []byte(`"#123456"`),
+	}
+
+	select {
+	case rec := <-changed:
+		if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "#123456") {
+			t.Errorf("expected changed projector, got %d: %s", rec.Code, rec.Body.String())
+		}
+
+		if rec.Header().Get("ETag") == etag {
+			t.Errorf("expected new etag after change")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("long poll did not return after change")
+	}
+}
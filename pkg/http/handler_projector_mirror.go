@@ -0,0 +1,120 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ProjectorMirrorHandler streams the content of a projector to passive
+// satellite displays. Mirrors receive the events of the projector unchanged,
+// always starting with its current content, and take no options which could
+// affect other subscriptions, like a client id. They are served from the
+// same rendered projector, so mirrors never cause additional rendering.
+// Access is checked against the mirrored projector.
+func (s *projectorHttp) ProjectorMirrorHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "Projector id invalid")
+			return
+		}
+
+		if !s.allowProjectorMeeting(w, r, id) {
+			return
+		}
+
+		logger := log.Ctx(r.Context()).With().Int("projector", id).Logger()
+
+		ctx, unregister := s.subscriptions.add(r.Context(), requestUserID(r.Context()), id, "mirror", "")
+		defer unregister()
+
+		lang := s.projectorLanguage(r, id)
+		content, err := s.projector.SubscribeProjectorContent(ctx, id, lang, nil)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "Error reading projector content")
+			return
+		}
+
+		if content == nil {
+			writeError(w, http.StatusNotFound, "Projector not found")
+			return
+		}
+
+		projectorContent, err := s.projector.GetProjectorContent(id, lang)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "Error reading projector content")
+			return
+		}
+
+		if projectorContent != nil {
+			minified := s.minify(*projectorContent)
+			projectorContent = &minified
+		}
+
+		currentContent, err := json.Marshal(projectorContent)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "Error encoding projector content")
+			return
+		}
+
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+
+		runtimeCfg := s.runtimeConfig()
+		sse := newSSEWriter(w, runtimeCfg.SSEFlushInterval, runtimeCfg.SSEFlushThreshold)
+		var flushTick <-chan time.Time
+		if runtimeCfg.SSEFlushInterval > 0 {
+			ticker := time.NewTicker(runtimeCfg.SSEFlushInterval)
+			defer ticker.Stop()
+			flushTick = ticker.C
+		}
+
+		if retry := sseRetryDelay(runtimeCfg); retry > 0 {
+			if _, err := fmt.Fprintf(w, "retry: %d\n\n", retry.Milliseconds()); err != nil {
+				logger.Err(err).Msg("error sending retry delay")
+			}
+		}
+
+		if err := sse.event("", "projector-replace", string(currentContent)); err != nil {
+			logger.Err(err).Msg("error sending event")
+			return
+		}
+		endRequestSpan(r.Context())
+
+		revoked := s.watchAccess(ctx, requestUserID(r.Context()), requestMeetingID(r.Context()), id, s.cfg.PermissionRecheck)
+
+		for {
+			select {
+			case event, ok := <-content:
+				if !ok {
+					return
+				}
+
+				if err := sse.send(event); err != nil {
+					logger.Err(err).Msg("error sending event")
+					return
+				}
+
+				if event.Event == "deleted" {
+					return
+				}
+			case <-flushTick:
+				sse.flushPending()
+			case <-revoked:
+				if err := sse.event("", "permission-revoked", ""); err != nil {
+					logger.Err(err).Msg("error sending event")
+				}
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
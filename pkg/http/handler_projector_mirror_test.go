@@ -0,0 +1,105 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/OpenSlides/openslides-go/datastore/dskey"
+)
+
+func TestProjectorMirror(t *testing.T) {
+	t.Chdir("../..")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s, flow := newTestProjectorHttp(t, ctx)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /system/projector/subscribe/{id}", s.ProjectorSubscribeHandler())
+	mux.HandleFunc("GET /system/projector/mirror/{id}", s.ProjectorMirrorHandler())
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	reqCtx, reqCancel := context.WithCancel(ctx)
+	defer reqCancel()
+
+	open := func(path string) *bufio.Scanner {
+		t.Helper()
+
+		req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, srv.URL+path, nil)
+		if err != nil {
+			t.Fatalf("create request: %v", err)
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("open %s: %v", path, err)
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected 200 for %s, got %d", path, resp.StatusCode)
+		}
+
+		scanner := bufio.NewScanner(resp.Body)
+		scanner.Buffer(nil, 1<<20)
+		return scanner
+	}
+
+	// nextEvent returns the data of the next event with the given name
+	nextEvent := func(scanner *bufio.Scanner, name string) string {
+		t.Helper()
+
+		found := false
+		for scanner.Scan() {
+			line := scanner.Text()
+			if line == "event: "+name {
+				found = true
+			} else if data, ok := strings.CutPrefix(line, "data: "); ok && found {
+				return data
+			}
+		}
+
+		t.Fatalf("no %s event received", name)
+		return ""
+	}
+
+	subscriber := open("/system/projector/subscribe/1?client_id=display-1")
+	nextEvent(subscriber, "connected")
+
+	// The client id of a mirror is ignored, so it does not replace the
+	// subscription of the display
+	mirror := open("/system/projector/mirror/1?client_id=display-1")
+
+	var content string
+	if err := json.Unmarshal([]byte(nextEvent(mirror, "projector-replace")), &content); err != nil {
+		t.Fatalf("expected html string as snapshot: %v", err)
+	}
+
+	if !strings.Contains(content, "projector") {
+		t.Errorf("expected rendered projector in snapshot, got %q", content)
+	}
+
+	nextEvent(mirror, "connected")
+	flow.Changes <- map[dskey.Key][]byte{dskey.MustKey("projector/1/color"): []byte(`"#123456"`)}
+	if settings := nextEvent(mirror, "settings"); !strings.Contains(settings, "#123456") {
+		t.Errorf("expected the changed settings on the mirror, got %q", settings)
+	}
+	if settings := nextEvent(subscriber, "settings"); !strings.Contains(settings, "#123456") {
+		t.Errorf("expected the subscription to stay open, got %q", settings)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for s.subscriptions.count() != 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected 2 subscriptions, got %d", s.subscriptions.count())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -3,60 +3,155 @@ package http
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
 	"net/http"
 	"strconv"
 
+	"github.com/OpenSlides/openslides-projector-service/pkg/database"
 	"github.com/OpenSlides/openslides-projector-service/pkg/projector"
+	"github.com/OpenSlides/openslides-projector-service/pkg/tracing"
 )
 
 func (s *projectorHttp) ProjectorPreviewHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		id, err := strconv.Atoi(r.PathValue("id"))
 		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			writeResponse(w, `{"error": true, "msg": "Projector id invalid"}`)
+			writeError(w, http.StatusBadRequest, "Projector id invalid")
 			return
 		}
 
 		decoder := json.NewDecoder(r.Body)
 		var settings projector.ProjectorPreviewSettings
 		if err := decoder.Decode(&settings); err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			writeResponse(w, fmt.Sprintf(`{"error": true, "msg": "Could not parse json", "error": "%s"}`, err.Error()))
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				writeError(w, http.StatusRequestEntityTooLarge, "Request body too large")
+				return
+			}
+
+			writeJSON(w, http.StatusBadRequest, errorResponse{
+				Error:  true,
+				Msg:    "Could not parse json",
+				Detail: err.Error(),
+			})
+			return
+		}
+
+		if themeVar := r.URL.Query().Get("theme"); themeVar != "" {
+			themeID, err := strconv.Atoi(themeVar)
+			if err != nil || themeID <= 0 {
+				writeError(w, http.StatusBadRequest, "Theme id invalid")
+				return
+			}
+			settings.ThemeID = themeID
+		}
+
+		width, height, err := previewSize(r, settings)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("Preview size has to be between %d and %d pixels", previewMinSize, previewMaxSize))
+			return
+		}
+
+		position, err := getRequestPosition(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "Position invalid")
 			return
 		}
 
-		projectorContent, err := s.projector.GetProjectorPreview(id, getRequestLanguage(r), settings)
+		lang, err := getPreviewLanguage(r, s.cfg.DefaultLanguage)
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			writeResponse(w, `{"error": true, "msg": "Error reading projector content"}`)
+			writeError(w, http.StatusBadRequest, "Language not supported")
+			return
+		}
+
+		stopRender := tracing.Measure(r.Context(), "render")
+		projectorContent, err := s.projector.GetProjectorPreview(id, lang, settings, position)
+		stopRender()
+		if errors.Is(err, database.ErrHistoryUnavailable) {
+			writeError(w, http.StatusBadRequest, "History not available for position")
+			return
+		} else if err != nil {
+			writeError(w, http.StatusInternalServerError, "Error reading projector content")
 			return
 		}
 
 		if projectorContent == nil {
-			w.WriteHeader(http.StatusNotFound)
-			writeResponse(w, `{"error": true, "msg": "Projector not found"}`)
+			writeError(w, http.StatusNotFound, "Projector not found")
 			return
 		}
 
 		tmpl, err := template.ParseFiles("templates/projector-preview.html")
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			writeResponse(w, `{"error": true, "msg": "Error providing projector content"}`)
+			writeError(w, http.StatusInternalServerError, "Error providing projector content")
 			return
 		}
 
 		var content bytes.Buffer
 		if err := tmpl.Execute(&content, map[string]any{
 			"ProjectorContent": template.HTML(*projectorContent),
+			"Width":            width,
+			"Height":           height,
 		}); err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			writeResponse(w, `{"error": true, "msg": "Error providing projector content"}`)
+			writeError(w, http.StatusInternalServerError, "Error providing projector content")
 			return
 		}
 
-		writeResponse(w, content.String())
+		serveBuffered(w, r, "text/html; charset=utf-8", []byte(s.minify(content.String())))
+	}
+}
+
+// Bounds of the size of a preview in pixels.
+const (
+	previewMinSize = 16
+	previewMaxSize = 8192
+)
+
+// previewSize returns the size in pixels the preview is rendered at. It is
+// taken from the width and height query parameters. If only one is given the
+// other follows the aspect ratio of the projector, without both the native
+// size of the projector is used. Returns 0, 0 if the settings have no size.
+func previewSize(r *http.Request, settings projector.ProjectorPreviewSettings) (int, int, error) {
+	parse := func(name string) (int, error) {
+		raw := r.URL.Query().Get(name)
+		if raw == "" {
+			return 0, nil
+		}
+
+		size, err := strconv.Atoi(raw)
+		if err != nil || size < previewMinSize || size > previewMaxSize {
+			return 0, fmt.Errorf("invalid preview %s %q", name, raw)
+		}
+		return size, nil
+	}
+
+	width, err := parse("width")
+	if err != nil {
+		return 0, 0, err
+	}
+
+	height, err := parse("height")
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// Projectors without an aspect ratio are shown in 16:9
+	numerator, denominator := 16, 9
+	if settings.AspectRatioNumerator > 0 && settings.AspectRatioDenominator > 0 {
+		numerator, denominator = settings.AspectRatioNumerator, settings.AspectRatioDenominator
+	}
+
+	switch {
+	case width != 0 && height != 0:
+	case width != 0:
+		height = max(width*denominator/numerator, 1)
+	case height != 0:
+		width = max(height*numerator/denominator, 1)
+	case settings.Width > 0:
+		width = settings.Width
+		height = max(width*denominator/numerator, 1)
 	}
+
+	return width, height, nil
 }
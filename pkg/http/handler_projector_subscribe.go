@@ -1,50 +1,187 @@
 package http
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math/rand/v2"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/OpenSlides/openslides-projector-service/pkg/projector"
 	"github.com/rs/zerolog/log"
+	"golang.org/x/text/language"
 )
 
+// Reasons logged when a subscription is closed.
+const (
+	subscriptionCloseClientCancel = "client-cancel"
+	subscriptionCloseDeleted      = "deleted"
+	subscriptionCloseMaintenance  = "maintenance"
+	subscriptionCloseAdmin        = "admin"
+	subscriptionCloseReplaced     = "replaced"
+	subscriptionCloseRevoked      = "permission-revoked"
+	subscriptionCloseError        = "error"
+)
+
+// Formats of the subscribe stream. The html format sends the rendered
+// projector, the data format the current projections instead.
+const (
+	subscribeFormatHTML = "html"
+	subscribeFormatData = "data"
+)
+
+// subscribeDeltaJSONPatch sends changes of the data format as JSON Patch
+// (RFC 6902) against the data sent before.
+const subscribeDeltaJSONPatch = "json-patch"
+
+// maxClientIDLength is the longest client_id accepted to replace previous
+// subscriptions of a client.
+const maxClientIDLength = 128
+
+// htmlEvents carry rendered html. In the data format they are replaced by a
+// projector-data event.
+var htmlEvents = []string{"projector-replace", "projection-updated", "projection-deleted", "projection-order"}
+
+// projectorData returns the current projections of the projector encoded
+// like the response of current. If collections are given only projections of
+// these collections are included.
+func (s *projectorHttp) projectorData(ctx context.Context, id int, lang language.Tag, collections []string) (string, error) {
+	projections, err := s.projector.GetCurrentProjections(ctx, id, lang)
+	if err != nil {
+		return "", fmt.Errorf("reading current projections: %w", err)
+	}
+
+	if len(collections) > 0 {
+		projections = slices.DeleteFunc(projections, func(p projector.CurrentProjection) bool {
+			return !slices.Contains(collections, p.Collection)
+		})
+	}
+
+	data, err := json.Marshal(currentResponse{Projections: projections})
+	if err != nil {
+		return "", fmt.Errorf("encoding current projections: %w", err)
+	}
+
+	return string(data), nil
+}
+
+// sseRetryDelay returns the reconnection delay sent to a client. A random
+// jitter is added so clients do not reconnect at the same time after a mass
+// disconnect.
+func sseRetryDelay(cfg RuntimeConfig) time.Duration {
+	if cfg.SSERetryJitter <= 0 {
+		return cfg.SSERetry
+	}
+
+	return cfg.SSERetry + rand.N(cfg.SSERetryJitter+1)
+}
+
 func (s *projectorHttp) ProjectorSubscribeHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		id, err := strconv.Atoi(r.PathValue("id"))
 		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			writeResponse(w, `{"error": true, "msg": "Projector id invalid"}`)
+			writeError(w, http.StatusBadRequest, "Projector id invalid")
 			return
 		}
 
-		content, err := s.projector.SubscribeProjectorContent(r.Context(), id, getRequestLanguage(r))
+		// Clients accepting MessagePack but not server sent events receive
+		// the events as a stream of MessagePack maps
+		msgpack := acceptsMsgpack(r.Header.Get("Accept"))
+
+		format := r.URL.Query().Get("format")
+		if format == "" {
+			format = subscribeFormatHTML
+		}
+
+		if format != subscribeFormatHTML && format != subscribeFormatData {
+			writeError(w, http.StatusBadRequest, "Format invalid")
+			return
+		}
+
+		delta := r.URL.Query().Get("delta")
+		if delta != "" && delta != subscribeDeltaJSONPatch {
+			writeError(w, http.StatusBadRequest, "Delta invalid")
+			return
+		}
+
+		if delta != "" && format != subscribeFormatData {
+			writeError(w, http.StatusBadRequest, "Delta is only supported for the data format")
+			return
+		}
+
+		clientID := r.URL.Query().Get("client_id")
+		if len(clientID) > maxClientIDLength {
+			writeError(w, http.StatusBadRequest, "Client id invalid")
+			return
+		}
+
+		var collections []string
+		for _, collection := range strings.Split(r.URL.Query().Get("collections"), ",") {
+			if collection = strings.TrimSpace(collection); collection != "" {
+				collections = append(collections, collection)
+			}
+		}
+
+		if !s.allowProjectorMeeting(w, r, id) {
+			return
+		}
+
+		logger := log.Ctx(r.Context()).With().Int("projector", id).Logger()
+		fields := parsePayloadFields(r.URL.Query().Get("fields"), logger)
+
+		ctx, unregister := s.subscriptions.add(r.Context(), requestUserID(r.Context()), id, "sse", clientID)
+		defer unregister()
+
+		// Browsers send the id of the last received event when they reconnect
+		lastEventID := r.Header.Get("Last-Event-ID")
+		lang := s.projectorLanguage(r, id)
+		content, resumed, err := s.projector.ResumeProjectorContent(ctx, id, lang, collections, lastEventID)
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			writeResponse(w, `{"error": true, "msg": "Error reading projector content"}`)
+			writeError(w, http.StatusInternalServerError, "Error reading projector content")
 			return
 		}
 
 		if content == nil {
-			w.WriteHeader(http.StatusNotFound)
-			writeResponse(w, `{"error": true, "msg": "Projector not found"}`)
+			writeError(w, http.StatusNotFound, "Projector not found")
 			return
 		}
 
-		needsInit := r.URL.Query().Get("init") == "1"
+		// A resumed subscription receives the missed events instead of the
+		// snapshot. If they are not kept anymore the client has to resync.
+		needsInit := r.URL.Query().Get("init") == "1" && fields.includes(payloadFieldContent)
+		needsResync := lastEventID != "" && !resumed
+		if lastEventID != "" {
+			needsInit = needsResync && fields.includes(payloadFieldContent)
+		}
+		initEvent := "projector-replace"
 		var projectorContent string
-		if needsInit {
-			projectorContentRaw, err := s.projector.GetProjectorContent(id, getRequestLanguage(r))
+		if needsInit && format == subscribeFormatData {
+			initEvent = "projector-data"
+			projectorContent, err = s.projectorData(ctx, id, lang, collections)
 			if err != nil {
-				w.WriteHeader(http.StatusInternalServerError)
-				writeResponse(w, `{"error": true, "msg": "Error reading projector content"}`)
+				writeError(w, http.StatusInternalServerError, "Error reading projector content")
 				return
 			}
+		} else if needsInit {
+			projectorContentRaw, err := s.projector.GetProjectorContent(id, lang)
+			if err != nil {
+				writeError(w, http.StatusInternalServerError, "Error reading projector content")
+				return
+			}
+
+			if projectorContentRaw != nil {
+				minified := s.minify(*projectorContentRaw)
+				projectorContentRaw = &minified
+			}
 
 			currentContent, err := json.Marshal(projectorContentRaw)
 			if err != nil {
-				w.WriteHeader(http.StatusInternalServerError)
-				writeResponse(w, `{"error": true, "msg": "Error encoding projector content"}`)
+				writeError(w, http.StatusInternalServerError, "Error encoding projector content")
 				return
 			}
 			projectorContent = string(currentContent)
@@ -52,24 +189,124 @@ func (s *projectorHttp) ProjectorSubscribeHandler() http.HandlerFunc {
 
 		w.Header().Set("X-Accel-Buffering", "no")
 		w.Header().Set("Content-Type", "text/event-stream")
+		if msgpack {
+			w.Header().Set("Content-Type", "application/msgpack")
+		}
 		w.Header().Set("Cache-Control", "no-cache")
 		w.Header().Set("Connection", "keep-alive")
 
+		logger.Info().Str("lifecycle", "open").Msg("subscription opened")
+		closeReason := subscriptionCloseError
+		defer func() {
+			logger.Info().Str("lifecycle", "close").Str("reason", closeReason).Msg("subscription closed")
+		}()
+
+		// Changes of the runtime config apply to the next connection
+		runtimeCfg := s.runtimeConfig()
+		sse := newSSEWriter(w, runtimeCfg.SSEFlushInterval, runtimeCfg.SSEFlushThreshold)
+		sse.msgpack = msgpack
+		var flushTick <-chan time.Time
+		if runtimeCfg.SSEFlushInterval > 0 {
+			ticker := time.NewTicker(runtimeCfg.SSEFlushInterval)
+			defer ticker.Stop()
+			flushTick = ticker.C
+		}
+
+		if retry := sseRetryDelay(runtimeCfg); retry > 0 && !msgpack {
+			if _, err := fmt.Fprintf(w, "retry: %d\n\n", retry.Milliseconds()); err != nil {
+				logger.Err(err).Msg("error sending retry delay")
+			}
+		}
+
+		if needsResync {
+			if err := sse.event("", "resync", ""); err != nil {
+				logger.Err(err).Msg("error sending resync")
+				return
+			}
+			logger.Info().Str("lifecycle", "resync").Msg("subscription resumed beyond the replay buffer")
+		}
+
 		if needsInit {
-			if _, err := fmt.Fprintf(w, "event: projector-replace\ndata: %s\n\n", projectorContent); err != nil {
-				log.Err(err).Msg("error sending event")
+			if err := sse.event("", initEvent, projectorContent); err != nil {
+				logger.Err(err).Msg("error sending event")
+				return
 			}
+			logger.Info().Str("lifecycle", "snapshot").Msg("subscription snapshot sent")
 		}
-		w.(http.Flusher).Flush()
+		sse.flush()
+		endRequestSpan(r.Context())
 
+		revoked := s.watchAccess(ctx, requestUserID(r.Context()), requestMeetingID(r.Context()), id, s.cfg.PermissionRecheck)
+
+		firstUpdate := true
+		lastData := projectorContent
 		for {
 			select {
-			case event := <-content:
-				if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Event, event.Data); err != nil {
-					log.Err(err).Msg("error sending event")
+			case event, ok := <-content:
+				if !ok {
+					if s.ctx.Err() != nil {
+						closeReason = subscriptionCloseMaintenance
+					}
+					return
+				}
+
+				if event = fields.apply(event); event == nil {
+					continue
+				}
+
+				if format == subscribeFormatData && slices.Contains(htmlEvents, event.Event) {
+					data, err := s.projectorData(ctx, id, lang, collections)
+					if err != nil {
+						logger.Err(err).Msg("error reading projector data")
+						continue
+					}
+
+					if data == lastData {
+						continue
+					}
+
+					event = &projector.ProjectorUpdateEvent{ID: event.ID, Event: "projector-data", Data: data}
+					if delta == subscribeDeltaJSONPatch && lastData != "" {
+						// A full snapshot is sent if it is smaller than the patch
+						patch, err := jsonPatch(lastData, data)
+						if err != nil {
+							logger.Err(err).Msg("error creating projector data patch")
+						} else if len(patch) < len(data) {
+							event = &projector.ProjectorUpdateEvent{ID: event.ID, Event: "projector-data-patch", Data: string(patch)}
+						}
+					}
+					lastData = data
+				}
+
+				if err := sse.send(event); err != nil {
+					logger.Err(err).Msg("error sending event")
+					return
+				}
+
+				if firstUpdate {
+					firstUpdate = false
+					logger.Info().Str("lifecycle", "first-update").Str("event", event.Event).Msg("subscription first update sent")
+				}
+
+				if event.Event == "deleted" {
+					closeReason = subscriptionCloseDeleted
+					return
+				}
+			case <-flushTick:
+				sse.flushPending()
+			case <-revoked:
+				closeReason = subscriptionCloseRevoked
+				if err := sse.event("", "permission-revoked", ""); err != nil {
+					logger.Err(err).Msg("error sending event")
+				}
+				return
+			case <-ctx.Done():
+				closeReason = subscriptionCloseClientCancel
+				if errors.Is(context.Cause(ctx), errClosedByAdmin) {
+					closeReason = subscriptionCloseAdmin
+				} else if errors.Is(context.Cause(ctx), errReplacedByClient) {
+					closeReason = subscriptionCloseReplaced
 				}
-				w.(http.Flusher).Flush()
-			case <-r.Context().Done():
 				return
 			}
 		}
@@ -0,0 +1,362 @@
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/OpenSlides/openslides-go/datastore/dskey"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) lines() []map[string]any {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var lines []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(b.buf.String()), "\n") {
+		var entry map[string]any
+		if err := json.Unmarshal([]byte(line), &entry); err == nil {
+			lines = append(lines, entry)
+		}
+	}
+	return lines
+}
+
+func TestSubscriptionLifecycleLogs(t *testing.T) {
+	t.Chdir("../..")
+
+	logs := &syncBuffer{}
+	oldLogger := log.Logger
+	log.Logger = zerolog.New(logs)
+	t.Cleanup(func() { log.Logger = oldLogger })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s, _ := newTestProjectorHttp(t, ctx)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /system/projector/subscribe/{id}", s.ProjectorSubscribeHandler())
+	srv := httptest.NewServer(RequestIDMiddleware(mux))
+	defer srv.Close()
+
+	reqCtx, reqCancel := context.WithCancel(context.Background())
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, srv.URL+"/system/projector/subscribe/1?init=1", nil)
+	if err != nil {
+		t.Fatalf("create request: %v", err)
+	}
+	req.Header.Set(requestIDHeader, "test-request")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if got := resp.Header.Get(requestIDHeader); got != "test-request" {
+		t.Errorf("expected request id header test-request, got %q", got)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		if scanner.Text() == "event: connected" {
+			break
+		}
+	}
+	reqCancel()
+
+	var lifecycle []string
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		lifecycle = nil
+		for _, entry := range logs.lines() {
+			if event, ok := entry["lifecycle"].(string); ok {
+				if entry["request_id"] != "test-request" {
+					t.Errorf("lifecycle event %s without request id: %v", event, entry)
+				}
+
+				if event == "close" {
+					event += ":" + entry["reason"].(string)
+				}
+				lifecycle = append(lifecycle, event)
+			}
+		}
+
+		if len(lifecycle) == 4 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	expected := []string{"open", "snapshot", "first-update", "close:client-cancel"}
+	if strings.Join(lifecycle, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected lifecycle events %v, got %v", expected, lifecycle)
+	}
+}
+
+func TestSubscribeFormat(t *testing.T) {
+	t.Chdir("../..")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s, flow := newTestProjectorHttp(t, ctx)
+	for key, value := range map[string]string{
+		"projector/1/current_projection_ids": "[1]",
+		"projection/1/id":                    "1",
+		"projection/1/meeting_id":            "1",
+		"projection/1/content_object_id":     `"projector_message/3"`,
+		"projector_message/3/id":             "3",
+		"projector_message/3/meeting_id":     "1",
+		"projector_message/3/message":        `"<p>Welcome</p>"`,
+	} {
+		flow.Set(key, []byte(value))
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /system/projector/subscribe/{id}", s.ProjectorSubscribeHandler())
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	subscribe := func(t *testing.T, query string) (*bufio.Scanner, func()) {
+		t.Helper()
+
+		reqCtx, reqCancel := context.WithCancel(ctx)
+		req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, srv.URL+"/system/projector/subscribe/1?"+query, nil)
+		if err != nil {
+			t.Fatalf("create request: %v", err)
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("subscribe: %v", err)
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected 200, got %d", resp.StatusCode)
+		}
+
+		scanner := bufio.NewScanner(resp.Body)
+		scanner.Buffer(nil, 1<<20)
+		return scanner, func() {
+			reqCancel()
+			resp.Body.Close()
+		}
+	}
+
+	// nextEvent returns the data of the next event with the given name
+	nextEvent := func(t *testing.T, scanner *bufio.Scanner, name string) string {
+		t.Helper()
+
+		found := false
+		for scanner.Scan() {
+			line := scanner.Text()
+			if line == "event: "+name {
+				found = true
+			} else if data, ok := strings.CutPrefix(line, "data: "); ok && found {
+				return data
+			}
+		}
+
+		t.Fatalf("no %s event received", name)
+		return ""
+	}
+
+	t.Run("html", func(t *testing.T) {
+		scanner, stop := subscribe(t, "init=1&format=html")
+		defer stop()
+
+		var content string
+		if err := json.Unmarshal([]byte(nextEvent(t, scanner, "projector-replace")), &content); err != nil {
+			t.Fatalf("expected html string as snapshot: %v", err)
+		}
+
+		if !strings.Contains(content, "Welcome") {
+			t.Errorf("expected rendered message in snapshot, got %q", content)
+		}
+	})
+
+	t.Run("data", func(t *testing.T) {
+		scanner, stop := subscribe(t, "init=1&format=data")
+		defer stop()
+
+		var snapshot currentResponse
+		if err := json.Unmarshal([]byte(nextEvent(t, scanner, "projector-data")), &snapshot); err != nil {
+			t.Fatalf("expected projections as snapshot: %v", err)
+		}
+
+		if len(snapshot.Projections) != 1 || snapshot.Projections[0].ContentObjectID != "projector_message/3" {
+			t.Fatalf("unexpected snapshot %+v", snapshot)
+		}
+
+		nextEvent(t, scanner, "connected")
+		flow.Changes <- map[dskey.Key][]byte{
+			dskey.MustKey("projection/1/content_object_id"): []byte(`"projector_message/4"`),
+			dskey.MustKey("projector_message/4/id"):         []byte("4"),
+			dskey.MustKey("projector_message/4/meeting_id"): []byte("1"),
+		}
+
+		var update currentResponse
+		if err := json.Unmarshal([]byte(nextEvent(t, scanner, "projector-data")), &update); err != nil {
+			t.Fatalf("expected projections as update: %v", err)
+		}
+
+		if len(update.Projections) != 1 || update.Projections[0].ContentObjectID != "projector_message/4" {
+			t.Errorf("unexpected update %+v", update)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/system/projector/subscribe/1?format=xml", nil)
+		mux.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected 400, got %d", rec.Code)
+		}
+	})
+}
+
+func TestSubscribeClientIDReplacesSubscription(t *testing.T) {
+	t.Chdir("../..")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s, _ := newTestProjectorHttp(t, ctx)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /system/projector/subscribe/{id}", s.ProjectorSubscribeHandler())
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	// Open streams have to be closed before the server
+	reqCtx, reqCancel := context.WithCancel(ctx)
+	defer reqCancel()
+
+	// subscribe returns a channel closed once the stream ended after it was
+	// connected.
+	subscribe := func(query string) <-chan struct{} {
+		t.Helper()
+
+		req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, srv.URL+"/system/projector/subscribe/1?"+query, nil)
+		if err != nil {
+			t.Fatalf("create request: %v", err)
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("subscribe: %v", err)
+		}
+
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() && scanner.Text() != "event: connected" {
+		}
+
+		closed := make(chan struct{})
+		go func() {
+			defer resp.Body.Close()
+			for scanner.Scan() {
+			}
+			close(closed)
+		}()
+		return closed
+	}
+
+	first := subscribe("client_id=display-1")
+	second := subscribe("client_id=display-1")
+
+	select {
+	case <-first:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("expected the first subscription of the client to be closed")
+	}
+
+	other := subscribe("client_id=display-2")
+	anonymous := subscribe("")
+
+	select {
+	case <-second:
+		t.Errorf("expected the subscription not to be closed by other clients")
+	case <-other:
+		t.Errorf("expected the subscription of another client to stay open")
+	case <-anonymous:
+		t.Errorf("expected the subscription without client id to stay open")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	if count := s.subscriptions.count(); count != 3 {
+		t.Errorf("expected 3 subscriptions, got %d", count)
+	}
+}
+
+func TestSubscribeClosedWhenPermissionRevoked(t *testing.T) {
+	t.Chdir("../..")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s, flow := newTestProjectorHttp(t, ctx)
+	s.cfg.PermissionRecheck = 10 * time.Millisecond
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /system/projector/subscribe/{id}", s.ProjectorSubscribeHandler())
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	reqCtx, reqCancel := context.WithTimeout(ctx, 5*time.Second)
+	defer reqCancel()
+
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, srv.URL+"/system/projector/subscribe/1", nil)
+	if err != nil {
+		t.Fatalf("create request: %v", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+	defer resp.Body.Close()
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() && scanner.Text() != "event: connected" {
+	}
+
+	flow.Set("meeting/1/enable_anonymous", []byte("false"))
+
+	var events []string
+	for scanner.Scan() {
+		if name, ok := strings.CutPrefix(scanner.Text(), "event: "); ok {
+			events = append(events, name)
+		}
+	}
+
+	if reqCtx.Err() != nil {
+		t.Fatalf("expected the subscription to be closed, got events %v", events)
+	}
+
+	if len(events) == 0 || events[len(events)-1] != "permission-revoked" {
+		t.Errorf("expected the stream to end with permission-revoked, got %v", events)
+	}
+}
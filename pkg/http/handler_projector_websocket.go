@@ -0,0 +1,137 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const websocketPingInterval = 30 * time.Second
+
+// ProjectorWebsocketHandler streams the same events as the subscribe
+// handler as JSON text frames over a websocket. The first message always
+// contains the current content of the projector.
+func (s *projectorHttp) ProjectorWebsocketHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "Projector id invalid")
+			return
+		}
+
+		if !websocketOriginAllowed(r, s.cfg.WebsocketOrigins) {
+			writeError(w, http.StatusForbidden, "Origin not allowed")
+			return
+		}
+
+		if !s.allowProjectorMeeting(w, r, id) {
+			return
+		}
+
+		ctx, cancel := context.WithCancel(s.ctx)
+		defer cancel()
+
+		ctx, unregister := s.subscriptions.add(ctx, requestUserID(r.Context()), id, "websocket", "")
+		defer unregister()
+
+		lang := s.projectorLanguage(r, id)
+		content, err := s.projector.SubscribeProjectorContent(ctx, id, lang, nil)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "Error reading projector content")
+			return
+		}
+
+		projectorContent, err := s.projector.GetProjectorContent(id, lang)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "Error reading projector content")
+			return
+		}
+
+		if projectorContent != nil {
+			minified := s.minify(*projectorContent)
+			projectorContent = &minified
+		}
+
+		currentContent, err := json.Marshal(projectorContent)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "Error encoding projector content")
+			return
+		}
+
+		conn, err := upgradeWebsocket(w, r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "Websocket upgrade failed")
+			return
+		}
+		defer conn.Close()
+
+		send := func(event string, data string) bool {
+			msg, err := json.Marshal(websocketMessage{Event: event, Data: data})
+			if err == nil {
+				err = conn.writeFrame(websocketOpText, msg)
+			}
+
+			if err != nil {
+				log.Err(err).Msg("error sending websocket message")
+				return false
+			}
+			return true
+		}
+
+		var lastPong atomic.Int64
+		lastPong.Store(time.Now().Unix())
+		go func() {
+			defer cancel()
+			for {
+				opcode, payload, err := conn.readMessage()
+				if err != nil {
+					return
+				}
+
+				switch opcode {
+				case websocketOpPing:
+					if err := conn.writeFrame(websocketOpPong, payload); err != nil {
+						return
+					}
+				case websocketOpPong:
+					lastPong.Store(time.Now().Unix())
+				case websocketOpClose:
+					_ = conn.writeFrame(websocketOpClose, nil)
+					return
+				}
+			}
+		}()
+
+		if !send("projector-replace", string(currentContent)) {
+			return
+		}
+		endRequestSpan(r.Context())
+
+		pingTicker := time.NewTicker(websocketPingInterval)
+		defer pingTicker.Stop()
+
+		for {
+			select {
+			case event, ok := <-content:
+				if !ok || !send(event.Event, event.Data) || event.Event == "deleted" {
+					return
+				}
+			case <-pingTicker.C:
+				if time.Since(time.Unix(lastPong.Load(), 0)) > 2*websocketPingInterval {
+					return
+				}
+
+				if err := conn.writeFrame(websocketOpPing, nil); err != nil {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/OpenSlides/openslides-go/datastore/dskey"
+)
+
+func readServerFrame(t *testing.T, r *bufio.Reader) (byte, []byte) {
+	t.Helper()
+
+	var head [2]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		t.Fatalf("read frame header: %v", err)
+	}
+
+	length := uint64(head[1] & 0x7F)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			t.Fatalf("read frame length: %v", err)
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			t.Fatalf("read frame length: %v", err)
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		t.Fatalf("read frame payload: %v", err)
+	}
+
+	return head[0] & 0x0F, payload
+}
+
+func readWebsocketMessage(t *testing.T, r *bufio.Reader) websocketMessage {
+	t.Helper()
+
+	for {
+		opcode, payload := readServerFrame(t, r)
+		if opcode != websocketOpText {
+			continue
+		}
+
+		var msg websocketMessage
+		if err := json.Unmarshal(payload, &msg); err != nil {
+			t.Fatalf("parse message: %v", err)
+		}
+		return msg
+	}
+}
+
+func TestProjectorWebsocket(t *testing.T) {
+	t.Chdir("../..")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s, flow := newTestProjectorHttp(t, ctx)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /system/projector/ws/{id}", s.ProjectorWebsocketHandler())
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(srv.URL, "http://"))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		t.Fatalf("set deadline: %v", err)
+	}
+
+	keyBytes := make([]byte, 16)
+	if _, err := rand.Read(keyBytes); err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	key := base64.StdEncoding.EncodeToString(keyBytes)
+
+	fmt.Fprintf(conn, "GET /system/projector/ws/1 HTTP/1.1\r\nHost: localhost\r\nConnection: Upgrade\r\nUpgrade: websocket\r\nSec-WebSocket-Version: 13\r\nSec-WebSocket-Key: %s\r\n\r\n", key)
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatalf("read handshake response: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("expected status 101, got %d", resp.StatusCode)
+	}
+
+	if got := resp.Header.Get("Sec-WebSocket-Accept"); got != websocketAcceptKey(key) {
+		t.Fatalf("invalid accept key %q", got)
+	}
+
+	snapshot := readWebsocketMessage(t, reader)
+	if snapshot.Event != "projector-replace" {
+		t.Fatalf("expected snapshot as first message, got %s", snapshot.Event)
+	}
+
+	flow.Changes <- map[dskey.Key][]byte{
+		dskey.MustKey("meeting/1/name"): []byte(`"Renamed meeting"`),
+	}
+
+	for {
+		msg := readWebsocketMessage(t, reader)
+		if msg.Event == "settings" && strings.Contains(msg.Data, "Renamed meeting") {
+			break
+		}
+	}
+}
+
+// maskedFrame encodes a frame as sent by a client.
+func maskedFrame(fin bool, opcode byte, payload []byte) []byte {
+	head := opcode
+	if fin {
+		head |= 0x80
+	}
+
+	mask := []byte{1, 2, 3, 4}
+	frame := append([]byte{head, 0x80 | byte(len(payload))}, mask...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	return frame
+}
+
+func TestWebsocketReadMessage(t *testing.T) {
+	read := func(frames ...[]byte) *websocketConn {
+		var data []byte
+		for _, frame := range frames {
+			data = append(data, frame...)
+		}
+
+		reader := bufio.NewReader(strings.NewReader(string(data)))
+		return &websocketConn{rw: bufio.NewReadWriter(reader, nil)}
+	}
+
+	conn := read(
+		maskedFrame(false, websocketOpText, []byte("hel")),
+		maskedFrame(true, websocketOpPing, []byte("p")),
+		maskedFrame(false, websocketOpContinuation, []byte("lo ")),
+		maskedFrame(true, websocketOpContinuation, []byte("world")),
+	)
+
+	opcode, payload, err := conn.readMessage()
+	if err != nil || opcode != websocketOpPing || string(payload) != "p" {
+		t.Fatalf("expected the ping between the fragments first, got %d %q %v", opcode, payload, err)
+	}
+
+	opcode, payload, err = conn.readMessage()
+	if err != nil || opcode != websocketOpText || string(payload) != "hello world" {
+		t.Fatalf("expected the joined message, got %d %q %v", opcode, payload, err)
+	}
+
+	conn = read(maskedFrame(true, websocketOpContinuation, []byte("x")))
+	if _, _, err := conn.readMessage(); err == nil {
+		t.Errorf("expected an error for a continuation without message")
+	}
+
+	conn = read(
+		maskedFrame(false, websocketOpText, []byte("a")),
+		maskedFrame(true, websocketOpText, []byte("b")),
+	)
+	if _, _, err := conn.readMessage(); err == nil {
+		t.Errorf("expected an error for a new message before the previous one ended")
+	}
+}
+
+func TestWebsocketOriginAllowed(t *testing.T) {
+	allowed := []string{"https://display.example.com/"}
+
+	for _, tt := range []struct {
+		origin string
+		expect bool
+	}{
+		{"", true},
+		{"https://projector.example.com", true},
+		{"https://display.example.com", true},
+		{"https://evil.example.com", false},
+		{"null", false},
+	} {
+		r := httptest.NewRequest(http.MethodGet, "https://projector.example.com/system/projector/ws/1", nil)
+		if tt.origin != "" {
+			r.Header.Set("Origin", tt.origin)
+		}
+
+		if got := websocketOriginAllowed(r, allowed); got != tt.expect {
+			t.Errorf("origin %q: expected %v, got %v", tt.origin, tt.expect, got)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s, _ := newTestProjectorHttp(t, ctx)
+	rec := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/system/projector/ws/1", nil)
+	r.SetPathValue("id", "1")
+	r.Header.Set("Origin", "https://evil.example.com")
+	s.ProjectorWebsocketHandler()(rec, r)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("expected 403 for a foreign origin, got %d", rec.Code)
+	}
+}
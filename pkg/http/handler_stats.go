@@ -0,0 +1,20 @@
+package http
+
+import "net/http"
+
+// StatsHandler returns the subscriber counts of the projectors together with
+// the memory used by the caches.
+func (s *projectorHttp) StatsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		stats := s.projector.Stats()
+		budget := s.projector.MemoryBudget.Stats()
+		writeJSON(w, http.StatusOK, statsResponse{
+			ActiveProjectors:     stats.ActiveProjectors,
+			Subscribers:          stats.Subscribers,
+			ProjectorSubscribers: stats.ProjectorSubscribers,
+			MemoryBudgetBytes:    budget.MaxBytes,
+			MemoryUsedBytes:      budget.UsedBytes,
+			MemoryEvicted:        budget.Evicted,
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	t.Chdir("../..")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s, _ := newTestProjectorHttp(t, ctx)
+	s.serverMux = http.NewServeMux()
+	s.serverMux.Handle("GET /system/projector/subscribe/{id}", s.ProjectorSubscribeHandler())
+	s.serverMux.Handle("GET /system/projector/stats", adminMiddleware(s.StatsHandler(), "secret"))
+	srv := httptest.NewServer(s.serverMux)
+	defer srv.Close()
+
+	subscribe := func(id string) func() {
+		t.Helper()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/system/projector/subscribe/"+id, nil)
+		if err != nil {
+			t.Fatalf("create request: %v", err)
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("subscribe: %v", err)
+		}
+
+		reader := bufio.NewReader(resp.Body)
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				t.Fatalf("read subscription: %v", err)
+			}
+
+			if line == "event: connected\n" {
+				break
+			}
+		}
+
+		return func() { resp.Body.Close() }
+	}
+
+	getStats := func(token string) (int, statsResponse) {
+		t.Helper()
+
+		req, err := http.NewRequest(http.MethodGet, srv.URL+"/system/projector/stats", nil)
+		if err != nil {
+			t.Fatalf("create request: %v", err)
+		}
+		req.Header.Set(adminTokenHeader, token)
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("get stats: %v", err)
+		}
+		defer resp.Body.Close()
+
+		var stats statsResponse
+		if resp.StatusCode == http.StatusOK {
+			if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+				t.Fatalf("decode stats: %v", err)
+			}
+		}
+		return resp.StatusCode, stats
+	}
+
+	// Subscribers are removed in the background after the connection is
+	// closed.
+	waitForStats := func(expected statsResponse) {
+		t.Helper()
+
+		timeout := time.After(time.Second)
+		for {
+			_, stats := getStats("secret")
+			if stats.ActiveProjectors == expected.ActiveProjectors &&
+				stats.Subscribers == expected.Subscribers &&
+				maps.Equal(stats.ProjectorSubscribers, expected.ProjectorSubscribers) {
+				return
+			}
+
+			select {
+			case <-timeout:
+				t.Fatalf("expected stats %+v, got %+v", expected, stats)
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	}
+
+	if status, _ := getStats("wrong"); status != http.StatusUnauthorized {
+		t.Errorf("expected status 401 with wrong token, got %d", status)
+	}
+
+	waitForStats(statsResponse{ProjectorSubscribers: map[int]int{}})
+
+	closeFirst := subscribe("1")
+	defer closeFirst()
+	closeSecond := subscribe("1")
+	defer closeSecond()
+	closeSide := subscribe("2")
+	defer closeSide()
+
+	waitForStats(statsResponse{
+		ActiveProjectors:     2,
+		Subscribers:          3,
+		ProjectorSubscribers: map[int]int{1: 2, 2: 1},
+	})
+
+	closeSide()
+	closeFirst()
+	waitForStats(statsResponse{
+		ActiveProjectors:     1,
+		Subscribers:          1,
+		ProjectorSubscribers: map[int]int{1: 1},
+	})
+}
@@ -0,0 +1,66 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/rs/zerolog/log"
+)
+
+// WhoamiHandler returns the user the request was authenticated as, to debug
+// authentication problems.
+func (s *projectorHttp) WhoamiHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID := requestUserID(r.Context())
+		resp := whoamiResponse{UserID: userID}
+
+		projectorIDs, err := s.visibleMeetingProjectors(r.Context(), userID)
+		if err != nil {
+			log.Ctx(r.Context()).Warn().Err(err).Msgf("listing projectors of user %d", userID)
+		} else {
+			resp.ProjectorIDs = projectorIDs
+		}
+
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
+
+// visibleMeetingProjectors returns the projectors of all meetings of the user
+// which the restricter allows the user to see. The anonymous user has no
+// meetings, so nil is returned for it.
+func (s *projectorHttp) visibleMeetingProjectors(ctx context.Context, userID int) ([]int, error) {
+	if userID == 0 {
+		return nil, nil
+	}
+
+	meetingIDs, err := s.db.Fetch.User_MeetingIDs(userID).Value(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("fetching meetings: %w", err)
+	}
+
+	projectorIDs := []int{}
+	for _, meetingID := range meetingIDs {
+		ids, err := s.db.Fetch.Meeting_ProjectorIDs(meetingID).Value(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("fetching projectors of meeting %d: %w", meetingID, err)
+		}
+		projectorIDs = append(projectorIDs, ids...)
+	}
+
+	if len(projectorIDs) == 0 {
+		return projectorIDs, nil
+	}
+
+	visible, err := s.restricter.VisibleProjectors(ctx, userID, projectorIDs)
+	if err != nil {
+		return nil, fmt.Errorf("checking projector restrictions: %w", err)
+	}
+
+	projectorIDs = slices.DeleteFunc(projectorIDs, func(id int) bool {
+		return !visible[id]
+	})
+	slices.Sort(projectorIDs)
+	return projectorIDs, nil
+}
@@ -0,0 +1,63 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestWhoami(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s, flow := newTestProjectorHttp(t, ctx)
+	flow.Set("user/5/id", []byte("5"))
+	flow.Set("user/5/meeting_ids", []byte("[1]"))
+	flow.Set("meeting/1/projector_ids", []byte("[1,2]"))
+
+	restricter := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("user_id") != "5" {
+			t.Errorf("expected restriction for user 5, got %s", r.URL.Query().Get("user_id"))
+		}
+		fmt.Fprint(w, `{"projector/2/id":2}`)
+	}))
+	defer restricter.Close()
+	s.restricter = newRestricter([]string{restricter.URL}, 0)
+
+	for _, tt := range []struct {
+		name       string
+		userID     int
+		projectors []int
+	}{
+		{name: "user", userID: 5, projectors: []int{2}},
+		{name: "anonymous", userID: 0, projectors: nil},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			reqCtx := context.WithValue(context.Background(), userIDKey{}, tt.userID)
+			req := httptest.NewRequest(http.MethodGet, "/system/projector/whoami", nil).WithContext(reqCtx)
+			rec := httptest.NewRecorder()
+			s.WhoamiHandler()(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+			}
+
+			var resp whoamiResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("parse response: %v", err)
+			}
+
+			if resp.UserID != tt.userID {
+				t.Errorf("expected user id %d, got %d", tt.userID, resp.UserID)
+			}
+
+			if !slices.Equal(resp.ProjectorIDs, tt.projectors) || (resp.ProjectorIDs == nil) != (tt.projectors == nil) {
+				t.Errorf("expected projectors %v, got %v", tt.projectors, resp.ProjectorIDs)
+			}
+		})
+	}
+}
@@ -3,11 +3,14 @@ package http
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"net/http"
+	"slices"
 	"strconv"
-	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/OpenSlides/openslides-go/auth"
@@ -16,50 +19,238 @@ import (
 	"github.com/OpenSlides/openslides-go/redis"
 	"github.com/OpenSlides/openslides-projector-service/pkg/database"
 	"github.com/OpenSlides/openslides-projector-service/pkg/projector"
+	"github.com/OpenSlides/openslides-projector-service/pkg/projector/slide"
 	"github.com/rs/zerolog/log"
 	"golang.org/x/text/language"
 )
 
 type ProjectorConfig struct {
-	RestricterUrl  string
-	MetricInterval time.Duration
+	RestricterUrl         string
+	RestricterStaleWindow time.Duration
+	MetricInterval        time.Duration
+	AnonymousUserID       int
+	MaxBodySize           int64
+	DefaultLanguage       language.Tag
+	SSERetry              time.Duration
+	SSERetryJitter        time.Duration
+	MediaProxy            bool
+	MediaServiceUrl       string
+	MaxSlideSize          int
+	StaleContentWindow    time.Duration
+
+	// ProjectorStaleAfter marks the content of projectors with subscribers
+	// as stale if the datastore changed but no change notification was
+	// received for this duration. Zero disables it.
+	ProjectorStaleAfter time.Duration
+
+	// RenderCacheSize is the number of rendered slides shared between
+	// projectors. Zero disables the cache.
+	RenderCacheSize int
+
+	// MemoryBudget is the maximum size of the render cache and the events
+	// kept to resume subscriptions in bytes. Zero disables the limit.
+	MemoryBudget int64
+
+	// RestricterUrls are redundant restricter endpoints tried in order. If
+	// empty, RestricterUrl is used.
+	RestricterUrls []string
+
+	// PermissionRecheck is the interval in which the access to the
+	// projector of open subscriptions is checked again. Zero disables it.
+	PermissionRecheck time.Duration
+
+	// SSEFlushInterval batches the countdown events of a subscription and
+	// flushes them at most once per interval. Other events are flushed
+	// immediately. Zero flushes every event immediately.
+	SSEFlushInterval time.Duration
+
+	// SSEReplayBuffer is the number of events kept per projector to resume
+	// subscriptions with Last-Event-ID. Zero uses the default.
+	SSEReplayBuffer int
+
+	// SSEFlushThreshold flushes batched events before the interval has
+	// passed once that many bytes are pending. Zero disables the threshold.
+	SSEFlushThreshold int
+
+	// PollInterval is sent to clients polling get as the interval they
+	// should use. Zero omits the hint.
+	PollInterval time.Duration
+
+	// PublicAccessOnly treats every request as made by the anonymous user.
+	// The auth service and the message bus are not used.
+	PublicAccessOnly bool
+
+	// MinifyHTML removes comments and redundant whitespace from rendered
+	// projectors before they are sent.
+	MinifyHTML bool
+
+	// RequestTimeout is the maximum duration of requests to the non
+	// streaming routes. Zero disables the timeout.
+	RequestTimeout time.Duration
+
+	// MaxConcurrentRequests limits the get and current requests handled at
+	// the same time, MaxConcurrentStreams the open subscribe, mirror and
+	// websocket connections. Zero disables the limit.
+	MaxConcurrentRequests int
+	MaxConcurrentStreams  int
+
+	// RenderMaxConcurrency limits the previews rendered at the same time.
+	// Up to RenderQueueSize further previews wait at most RenderQueueTimeout
+	// for a free slot, others are rejected. Zero disables the limit.
+	RenderMaxConcurrency int
+	RenderQueueSize      int
+	RenderQueueTimeout   time.Duration
+
+	// HealthPath is the prefix of the health endpoints, DefaultHealthPath if
+	// empty. They are never wrapped by the auth middleware.
+	HealthPath string
+
+	// MediaCDNBase is the url mediafile urls in slides are rewritten to if
+	// set, e.g. to serve mediafiles from a CDN.
+	MediaCDNBase string
+
+	// ContentTransforms post-process the html of every rendered slide after
+	// the built-in transforms.
+	ContentTransforms []projector.ContentTransform
+
+	// Fonts are the fonts preloaded per language. Nil preloads none.
+	Fonts projector.FontMapping
+
+	// Sanitizer configures the elements kept in rich text per meeting. Nil
+	// keeps the default elements.
+	Sanitizer *slide.SanitizerConfig
+
+	// DefaultSlide is the template shown on projectors without projections.
+	// Empty leaves them blank.
+	DefaultSlide string
+
+	// ChangeWebhook is called when the current projection of a projector
+	// in ChangeWebhookIDs (or of any active meeting if empty) changes.
+	ChangeWebhook    string
+	ChangeWebhookIDs []int
+
+	// OrganizationMessage is the field of the organization shown on top of
+	// every projector. Empty shows no organization message.
+	OrganizationMessage string
+
+	// AdminToken enables the admin endpoints for requests sending it in
+	// the X-Admin-Token header
+	AdminToken string
+
+	// ServerTiming sends the time spent in the restricter and rendering of
+	// get and preview requests in the Server-Timing header.
+	ServerTiming bool
+
+	// OutboundTLS is used for HTTPS requests to the restricter, the media
+	// service and the change webhook. Nil uses the system roots.
+	OutboundTLS *tls.Config
+
+	// ProjectorAllowlist limits all routes to these projectors, other ids
+	// are answered with 404 before the user is authenticated. Empty allows
+	// all projectors.
+	ProjectorAllowlist []int
+
+	// WebsocketOrigins are origins of other hosts, e.g.
+	// https://display.example.com, whose pages may open websockets.
+	// Pages of the host itself are always allowed.
+	WebsocketOrigins []string
 }
 
 type projectorHttp struct {
-	ctx       context.Context
-	serverMux *http.ServeMux
-	db        *database.Datastore
-	ds        flow.Flow
-	projector *projector.ProjectorPool
-	cfg       ProjectorConfig
-	auth      *auth.Auth
+	ctx           context.Context
+	serverMux     *http.ServeMux
+	db            *database.Datastore
+	ds            flow.Flow
+	projector     *projector.ProjectorPool
+	cfg           ProjectorConfig
+	auth          *authenticator
+	restricter    *restricter
+	subscriptions *subscriptionRegistry
+
+	// runtime holds the reloaded runtime config, see Reload.
+	runtime        atomic.Pointer[RuntimeConfig]
+	requestLimiter *concurrencyLimiter
+	streamLimiter  *concurrencyLimiter
+	renderLimiter  *concurrencyLimiter
+
+	// patterns of the registered routes, compared with the OpenAPI
+	// description in the tests.
+	patterns []string
 }
 
-func New(ctx context.Context, cfg ProjectorConfig, serverMux *http.ServeMux, db *database.Datastore, ds flow.Flow) {
+// New registers the routes of the projector service. The returned reloader
+// changes the runtime config while the service is running.
+func New(ctx context.Context, cfg ProjectorConfig, serverMux *http.ServeMux, db *database.Datastore, ds flow.Flow) Reloader {
 	projectorPool := projector.NewProjectorPool(ctx, db, ds)
+	projectorPool.MaxSlideSize = cfg.MaxSlideSize
+	projectorPool.StaleWindow = cfg.StaleContentWindow
+	projectorPool.StaleAfter = cfg.ProjectorStaleAfter
+	if cfg.SSEReplayBuffer > 0 {
+		projectorPool.ReplayBufferSize = cfg.SSEReplayBuffer
+	}
+	projectorPool.MemoryBudget = slide.NewMemoryBudget(cfg.MemoryBudget)
+	projectorPool.RenderCache = slide.NewRenderCache(cfg.RenderCacheSize)
+	projectorPool.RenderCache.UseBudget(projectorPool.MemoryBudget)
+	if cfg.Fonts != nil {
+		projectorPool.Fonts = cfg.Fonts
+	}
+	projectorPool.DefaultSlide = cfg.DefaultSlide
+	projectorPool.Sanitizer = cfg.Sanitizer
+	if cfg.DefaultLanguage != language.Und {
+		projectorPool.DefaultLanguage = cfg.DefaultLanguage
+	}
+	if cfg.ChangeWebhook != "" {
+		projectorPool.WatchProjectionChanges(projector.NewWebhookNotifier(ctx, cfg.ChangeWebhook, cfg.OutboundTLS), cfg.ChangeWebhookIDs)
+	}
+	if cfg.OrganizationMessage != "" {
+		if err := projectorPool.WatchOrganizationMessage(cfg.OrganizationMessage); err != nil {
+			log.Error().Err(err).Msg("organization message disabled")
+		}
+	}
+	if cfg.MediaProxy {
+		projectorPool.MediaURL = mediaProxyURL
+	}
+	if cfg.MediaCDNBase != "" {
+		projectorPool.ContentTransforms = append(projectorPool.ContentTransforms, projector.MediaCDNTransform(projectorPool.MediaURL, cfg.MediaCDNBase))
+	}
+	projectorPool.ContentTransforms = append(projectorPool.ContentTransforms, cfg.ContentTransforms...)
 	go projector.MetricLoop(ctx, cfg.MetricInterval, projectorPool)
 
-	lookup := new(environment.ForProduction)
-	redis := redis.New(lookup)
-	authService, authBackground, err := auth.New(lookup, redis)
-	if err != nil {
-		log.Err(err).Msg("auth error")
-	}
+	authService := &authenticator{publicAccessOnly: cfg.PublicAccessOnly}
+	if !cfg.PublicAccessOnly {
+		lookup := new(environment.ForProduction)
+		redis := redis.New(lookup)
+		service, authBackground, err := auth.New(lookup, redis)
+		if err != nil {
+			log.Err(err).Msg("auth error")
+		}
 
-	go authBackground(ctx, func(e error) {
-		log.Err(e).Msg("auth background error")
-	})
+		authService.service = service
+		authService.messageBus = newMessageBusMonitor(redis)
 
-	handler := projectorHttp{
-		ctx:       ctx,
-		serverMux: serverMux,
-		db:        db,
-		ds:        ds,
-		projector: projectorPool,
-		auth:      authService,
-		cfg:       cfg,
+		// Logout events are only listened to once the message bus can be
+		// reached, until then authenticated requests are rejected.
+		go authService.messageBus.run(ctx, func() {
+			if authBackground != nil {
+				go authBackground(ctx, func(e error) {
+					log.Err(e).Msg("auth background error")
+				})
+			}
+		})
+	}
+
+	handler := &projectorHttp{
+		ctx:           ctx,
+		serverMux:     serverMux,
+		db:            db,
+		ds:            ds,
+		projector:     projectorPool,
+		auth:          authService,
+		cfg:           cfg,
+		subscriptions: newSubscriptionRegistry(),
 	}
 	handler.registerRoutes(cfg)
+	return handler
 }
 
 func writeResponse(w http.ResponseWriter, resp string) {
@@ -68,11 +259,114 @@ func writeResponse(w http.ResponseWriter, resp string) {
 	}
 }
 
+// serveBuffered writes an already rendered response. Range requests are
+// supported so large previews can be downloaded partially.
+func serveBuffered(w http.ResponseWriter, r *http.Request, contentType string, content []byte) {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Accept-Ranges", "bytes")
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(content))
+}
+
+func writeJSON(w http.ResponseWriter, status int, resp any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		log.Err(err).Msg("writing response")
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, errorResponse{Error: true, Msg: msg})
+}
+
 func (s *projectorHttp) registerRoutes(cfg ProjectorConfig) {
-	s.serverMux.HandleFunc("/system/projector/health", s.HealthHandler())
-	s.serverMux.Handle("/system/projector/get/{id}", authMiddleware(http.HandlerFunc(s.ProjectorGetHandler()), s.auth, cfg))
-	s.serverMux.Handle("/system/projector/subscribe/{id}", authMiddleware(http.HandlerFunc(s.ProjectorSubscribeHandler()), s.auth, cfg))
-	s.serverMux.Handle("/system/projector/preview/{id}", authMiddleware(http.HandlerFunc(s.ProjectorPreviewHandler()), s.auth, cfg))
+	// Requests with a method not matching the pattern are answered by the
+	// mux with 405 Method Not Allowed and an Allow header.
+	restricterUrls := cfg.RestricterUrls
+	if len(restricterUrls) == 0 {
+		restricterUrls = []string{cfg.RestricterUrl}
+	}
+	s.restricter = newRestricter(restricterUrls, cfg.RestricterStaleWindow)
+	s.restricter.client = newOutboundClient(cfg.OutboundTLS)
+
+	s.requestLimiter = newConcurrencyLimiter(cfg.MaxConcurrentRequests, limitQueueWait)
+	// Streams are held open for a long time, waiting for one to close does
+	// not make sense.
+	s.streamLimiter = newConcurrencyLimiter(cfg.MaxConcurrentStreams, 0)
+	s.renderLimiter = newConcurrencyLimiter(cfg.RenderMaxConcurrency, cfg.RenderQueueTimeout)
+	s.renderLimiter.maxQueued = cfg.RenderQueueSize
+
+	// The middlewares of the routes, each route is wrapped from the first
+	// to the last one.
+	allowlist := func(next http.Handler) http.Handler {
+		return projectorAllowlistMiddleware(next, cfg.ProjectorAllowlist)
+	}
+	timing := func(next http.Handler) http.Handler { return serverTimingMiddleware(next, cfg.ServerTiming) }
+	limitRequests := func(next http.Handler) http.Handler { return limitMiddleware(next, s.requestLimiter) }
+	limitStreams := func(next http.Handler) http.Handler { return limitMiddleware(next, s.streamLimiter) }
+	limitRenders := func(next http.Handler) http.Handler { return limitMiddleware(next, s.renderLimiter) }
+	limitBody := func(next http.Handler) http.Handler { return limitBodyMiddleware(next, cfg.MaxBodySize) }
+	user := func(next http.Handler) http.Handler { return userMiddleware(next, s.auth, cfg) }
+	requireAuth := func(next http.Handler) http.Handler { return authMiddleware(next, s.auth, s.restricter, cfg) }
+	admin := func(next http.Handler) http.Handler { return adminMiddleware(next, cfg.AdminToken) }
+
+	timedRequestStack := []middleware{allowlist, timing, limitRequests, s.timeoutMiddleware, requireAuth, s.meetingScopeMiddleware}
+	requestStack := []middleware{allowlist, limitRequests, s.timeoutMiddleware, requireAuth, s.meetingScopeMiddleware}
+	streamStack := []middleware{allowlist, limitStreams, requireAuth, s.meetingScopeMiddleware}
+
+	healthPath := cfg.HealthPath
+	if healthPath == "" {
+		healthPath = DefaultHealthPath
+	}
+	s.handle("GET "+healthPath, s.HealthHandler())
+	s.handle("GET "+healthPath+"/live", s.HealthHandler())
+	s.handle("GET "+healthPath+"/ready", s.ReadyHandler())
+	s.handle("GET /system/projector/openapi.json", s.OpenAPIHandler(healthPath))
+	s.handle("GET /system/projector/openapi.yaml", s.OpenAPIYAMLHandler(healthPath))
+	s.handle("GET /system/projector/metrics", s.MetricsHandler())
+	s.handle("GET /system/projector/position", chain(s.PositionHandler(), s.timeoutMiddleware))
+	s.handle("GET /system/projector/whoami", chain(s.WhoamiHandler(), s.timeoutMiddleware, user))
+	// The projector routes are also served scoped to a meeting, e.g.
+	// /system/projector/meeting/{meeting_id}/get/{id}, for clients showing projectors
+	// of multiple meetings.
+	for _, prefix := range []string{"/system/projector/", "/system/projector/meeting/{meeting_id}/"} {
+		s.handle("GET "+prefix+"get/{id}", chain(s.ProjectorGetHandler(), timedRequestStack...))
+		s.handle("GET "+prefix+"current/{id}", chain(s.ProjectorCurrentHandler(), requestStack...))
+		s.handle("GET "+prefix+"subscribe/{id}", chain(s.ProjectorSubscribeHandler(), streamStack...))
+		s.handle("GET "+prefix+"mirror/{id}", chain(s.ProjectorMirrorHandler(), streamStack...))
+		s.handle("GET "+prefix+"ws/{id}", chain(s.ProjectorWebsocketHandler(), streamStack...))
+	}
+	if cfg.MediaProxy {
+		s.handle("GET "+mediaProxyURL+"{id}", s.MediaProxyHandler())
+	}
+	if cfg.AdminToken != "" {
+		s.handle("GET /system/projector/stats", chain(s.StatsHandler(), admin))
+		s.handle("GET /system/projector/admin/subscriptions", chain(s.AdminSubscriptionsHandler(), admin))
+		s.handle("DELETE /system/projector/admin/subscriptions/{id}", chain(s.AdminCloseSubscriptionHandler(), admin))
+	}
+	// The bulk preview takes a slot of the render limiter per projector
+	s.handle("GET /system/projector/preview", chain(s.ProjectorBulkPreviewHandler(), timing, s.timeoutMiddleware))
+	s.handle("POST /system/projector/preview/{id}", chain(s.ProjectorPreviewHandler(), allowlist, timing, limitBody, s.timeoutMiddleware, requireAuth, limitRenders))
+}
+
+// middleware wraps the handler of a route.
+type middleware func(http.Handler) http.Handler
+
+// chain wraps the handler with the middlewares. The first middleware is the
+// outermost one and sees the request first.
+func chain(handler http.Handler, middlewares ...middleware) http.Handler {
+	for _, m := range slices.Backward(middlewares) {
+		handler = m(handler)
+	}
+
+	return handler
+}
+
+// handle registers the handler of a route. The span of the request is named
+// after the route.
+func (s *projectorHttp) handle(pattern string, handler http.Handler) {
+	s.patterns = append(s.patterns, pattern)
+	s.serverMux.Handle(pattern, routeSpan(handler))
 }
 
 var languageMatcher = language.NewMatcher([]language.Tag{
@@ -86,76 +380,289 @@ var languageMatcher = language.NewMatcher([]language.Tag{
 	language.Russian,
 })
 
-func getRequestLanguage(r *http.Request) language.Tag {
-	lang, _ := r.Cookie("lang")
-	accept := r.Header.Get("Accept-Language")
-	tag, _ := language.MatchStrings(languageMatcher, lang.String(), accept)
+// ParseDefaultLanguage parses a language tag and checks that it is one of
+// the supported languages.
+func ParseDefaultLanguage(lang string) (language.Tag, error) {
+	tag, err := language.Parse(lang)
+	if err != nil {
+		return language.Und, fmt.Errorf("parsing language %q: %w", lang, err)
+	}
 
-	// Overwrite if lang has been provided via query parameter
+	if _, _, confidence := languageMatcher.Match(tag); confidence != language.Exact {
+		return language.Und, fmt.Errorf("language %q is not supported", lang)
+	}
+
+	return tag, nil
+}
+
+// requestedLanguage returns the language explicitly requested by the lang
+// query parameter or, if not set, by the lang cookie.
+func requestedLanguage(r *http.Request) (language.Tag, bool) {
 	langVar := r.URL.Query().Get("lang")
+	if langVar == "" {
+		if cookie, err := r.Cookie("lang"); err == nil {
+			langVar = cookie.Value
+		}
+	}
+
+	if langVar == "" {
+		return language.Und, false
+	}
 
-	if langVar != "" {
-		tag, _ = language.MatchStrings(languageMatcher, langVar, accept)
+	tag, err := language.Parse(langVar)
+	if err != nil {
+		return language.Und, false
+	}
+
+	return tag, true
+}
+
+// projectorLanguage returns the language of a live projector. A language
+// requested by query parameter or cookie takes precedence. Otherwise
+// language.Und is returned so the projector follows the language of its
+// meeting. If the meeting has no valid language, the language preferred by
+// the Accept-Language header or the default language is used.
+func (s *projectorHttp) projectorLanguage(r *http.Request, projectorID int) language.Tag {
+	if tag, ok := requestedLanguage(r); ok {
+		if matched, _, confidence := languageMatcher.Match(tag); confidence != language.No {
+			return matched
+		}
+	}
+
+	if s.meetingHasLanguage(r.Context(), projectorID) {
+		return language.Und
+	}
+
+	return getRequestLanguage(r, s.cfg.DefaultLanguage)
+}
+
+// meetingHasLanguage reports whether the meeting of the projector has a
+// valid language. Errors are reported as no language.
+func (s *projectorHttp) meetingHasLanguage(ctx context.Context, projectorID int) bool {
+	meetingID, err := s.db.Fetch.Projector_MeetingID(projectorID).Value(ctx)
+	if err != nil {
+		return false
+	}
+
+	meetingLanguage, err := s.db.Fetch.Meeting_Language(meetingID).Value(ctx)
+	if err != nil || meetingLanguage == "" {
+		return false
+	}
+
+	_, err = language.Parse(meetingLanguage)
+	return err == nil
+}
+
+// getRequestLanguage returns the language preferred by the request. The
+// query parameter takes precedence over the cookie. If no supported language
+// is requested the default language is used.
+func getRequestLanguage(r *http.Request, defaultLang language.Tag) language.Tag {
+	prefs := []language.Tag{}
+	if tag, ok := requestedLanguage(r); ok {
+		prefs = append(prefs, tag)
+	}
+
+	if accept, _, err := language.ParseAcceptLanguage(r.Header.Get("Accept-Language")); err == nil {
+		prefs = append(prefs, accept...)
+	}
+
+	if len(prefs) == 0 {
+		return defaultLang
+	}
+
+	tag, _, confidence := languageMatcher.Match(prefs...)
+	if confidence == language.No {
+		return defaultLang
 	}
 
 	return tag
 }
 
-func authMiddleware(next http.Handler, auth *auth.Auth, cfg ProjectorConfig) http.Handler {
+// getPreviewLanguage returns the language of a preview. A language given by
+// the lang query parameter is used regardless of cookies and Accept-Language
+// and has to be supported. Otherwise the language preferred by the request
+// is used.
+func getPreviewLanguage(r *http.Request, defaultLang language.Tag) (language.Tag, error) {
+	langVar := r.URL.Query().Get("lang")
+	if langVar == "" {
+		return getRequestLanguage(r, defaultLang), nil
+	}
+
+	tag, err := language.Parse(langVar)
+	if err != nil {
+		return language.Und, fmt.Errorf("parsing language %q: %w", langVar, err)
+	}
+
+	matched, _, confidence := languageMatcher.Match(tag)
+	if confidence < language.High {
+		return language.Und, fmt.Errorf("language %q is not supported", langVar)
+	}
+
+	return matched, nil
+}
+
+// getRequestPosition returns the datastore position requested via the
+// position query parameter or 0 if the current data should be used.
+func getRequestPosition(r *http.Request) (int, error) {
+	positionVar := r.URL.Query().Get("position")
+	if positionVar == "" {
+		return 0, nil
+	}
+
+	position, err := strconv.Atoi(positionVar)
+	if err != nil || position <= 0 {
+		return 0, fmt.Errorf("invalid position %q", positionVar)
+	}
+
+	return position, nil
+}
+
+// limitBodyMiddleware limits the size of request bodies. Reading beyond the
+// limit fails with an *http.MaxBytesError.
+func limitBodyMiddleware(next http.Handler, limit int64) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		ctx, err := auth.Authenticate(w, r)
-		if err != nil {
-			w.WriteHeader(http.StatusUnauthorized)
-			writeResponse(w, `{"error": true, "msg": "authenticate request failed"}`)
-			return
+		if limit > 0 {
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
 		}
 
-		id, err := strconv.Atoi(r.PathValue("id"))
-		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			writeResponse(w, `{"error": true, "msg": "Projector id invalid"}`)
-			return
-		}
+		next.ServeHTTP(w, r)
+	})
+}
 
-		// TODO: Listen for permission changes
-		body := []byte(fmt.Sprintf(`[{"collection": "projector", "ids":[%d], "fields": {"id": null}}]`, id))
-		userID := auth.FromContext(ctx)
-		restrictUrl := fmt.Sprintf("%s?user_id=%d&single=1", cfg.RestricterUrl, userID)
-		req, err := http.NewRequest("POST", restrictUrl, bytes.NewReader(body))
-		if err != nil {
-			writeResponse(w, `{"error": true, "msg": "creating restriction request failed"}`)
+// authenticator resolves the user of a request with the auth service. In
+// public access only mode every request is made by the anonymous user and
+// neither the auth service nor the message bus are needed.
+type authenticator struct {
+	service          *auth.Auth
+	messageBus       *messageBusMonitor
+	publicAccessOnly bool
+}
+
+// authenticate authenticates the request and returns its context and the id
+// of the user, the anonymous user if not logged in. If it fails, the error is
+// written and false returned. While the auth service is not available the
+// request is answered with 503.
+func authenticate(w http.ResponseWriter, r *http.Request, auth *authenticator, cfg ProjectorConfig) (context.Context, int, bool) {
+	if auth != nil && auth.publicAccessOnly {
+		return r.Context(), cfg.AnonymousUserID, true
+	}
+
+	if auth == nil || auth.service == nil || (auth.messageBus != nil && !auth.messageBus.Healthy()) {
+		w.Header().Set("Retry-After", strconv.Itoa(int(limitRetryAfter.Seconds())))
+		writeError(w, http.StatusServiceUnavailable, "authentication unavailable")
+		return nil, 0, false
+	}
+
+	ctx, err := auth.service.Authenticate(w, r)
+	if err != nil {
+		writeUnauthorized(w, "authenticate request failed")
+		return nil, 0, false
+	}
+
+	userID := auth.service.FromContext(ctx)
+	if userID == 0 {
+		userID = cfg.AnonymousUserID
+	}
+
+	return ctx, userID, true
+}
+
+// authChallenge is sent with every 401 response of an authenticated route. The
+// access token of OpenSlides is a bearer token, which clients renew with the
+// refresh cookie.
+const authChallenge = `Bearer realm="OpenSlides"`
+
+// writeUnauthorized writes a 401 error with a WWW-Authenticate header, so API
+// clients know to authenticate again. Frontends use the JSON body.
+func writeUnauthorized(w http.ResponseWriter, msg string) {
+	w.Header().Set("WWW-Authenticate", authChallenge)
+	writeError(w, http.StatusUnauthorized, msg)
+}
+
+// userMiddleware authenticates the request without checking access to a
+// projector.
+func userMiddleware(next http.Handler, auth *authenticator, cfg ProjectorConfig) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, userID, ok := authenticate(w, r, auth, cfg)
+		if !ok {
 			return
 		}
 
-		req.Header = http.Header{
-			"Content-Type": {"application/json"},
+		next.ServeHTTP(w, r.WithContext(context.WithValue(ctx, userIDKey{}, userID)))
+	})
+}
+
+func authMiddleware(next http.Handler, auth *authenticator, restricter *restricter, cfg ProjectorConfig) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, userID, ok := authenticate(w, r, auth, cfg)
+		if !ok {
+			return
 		}
 
-		client := http.Client{}
-		resp, err := client.Do(req)
+		id, err := strconv.Atoi(r.PathValue("id"))
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			writeResponse(w, `{"error": true, "msg": "restriction request failed"}`)
+			writeError(w, http.StatusBadRequest, "Projector id invalid")
 			return
 		}
 
-		if resp.StatusCode != http.StatusOK {
-			w.WriteHeader(resp.StatusCode)
-			writeResponse(w, `{"error": true, "msg": "restriction request failed"}`)
+		meetingID, ok := pathMeetingID(r)
+		if !ok {
+			writeError(w, http.StatusBadRequest, "Meeting id invalid")
 			return
 		}
 
-		b, err := io.ReadAll(resp.Body)
-		if err != nil || !strings.Contains(string(b), fmt.Sprintf(`"projector/%d/id":%d`, id, id)) {
-			w.WriteHeader(http.StatusUnauthorized)
-			writeResponse(w, `{"error": true, "msg": "permissions denied"}`)
+		allowed, err := restricter.CanSeeMeetingProjector(ctx, userID, meetingID, id)
+		if err != nil {
+			var statusErr restricterStatusError
+			if errors.As(err, &statusErr) {
+				writeError(w, statusErr.status, "restriction request failed")
+				return
+			}
+
+			log.Err(err).Msg("restriction request failed")
+			writeError(w, http.StatusInternalServerError, "restriction request failed")
 			return
 		}
 
-		if err := resp.Body.Close(); err != nil {
-			log.Err(err).Msg("error closing response body")
+		if !allowed {
+			writeUnauthorized(w, "permissions denied")
+			return
 		}
 
-		next.ServeHTTP(w, r.WithContext(ctx))
+		ctx = context.WithValue(ctx, meetingIDKey{}, meetingID)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(ctx, userIDKey{}, userID)))
 	})
 }
+
+// pathMeetingID returns the meeting id of routes scoped to a meeting or 0 for
+// unscoped routes. Returns false if the meeting id is invalid.
+func pathMeetingID(r *http.Request) (int, bool) {
+	raw := r.PathValue("meeting_id")
+	if raw == "" {
+		return 0, true
+	}
+
+	meetingID, err := strconv.Atoi(raw)
+	if err != nil || meetingID <= 0 {
+		return 0, false
+	}
+
+	return meetingID, true
+}
+
+type userIDKey struct{}
+
+type meetingIDKey struct{}
+
+// requestMeetingID returns the meeting the request is scoped to by the auth
+// middleware or 0 if it is not scoped.
+func requestMeetingID(ctx context.Context) int {
+	meetingID, _ := ctx.Value(meetingIDKey{}).(int)
+	return meetingID
+}
+
+// requestUserID returns the id of the user set by the auth middleware.
+func requestUserID(ctx context.Context) int {
+	userID, _ := ctx.Value(userIDKey{}).(int)
+	return userID
+}
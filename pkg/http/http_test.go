@@ -0,0 +1,313 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/OpenSlides/openslides-projector-service/pkg/projector"
+	"golang.org/x/text/language"
+)
+
+func TestRoutesRejectWrongMethod(t *testing.T) {
+	s := &projectorHttp{serverMux: http.NewServeMux()}
+	s.registerRoutes(ProjectorConfig{})
+
+	for _, tt := range []struct {
+		method string
+		path   string
+		allow  string
+	}{
+		{http.MethodPost, "/system/projector/get/1", "GET, HEAD"},
+		{http.MethodDelete, "/system/projector/subscribe/1", "GET, HEAD"},
+		{http.MethodGet, "/system/projector/preview/1", "POST"},
+	} {
+		rec := httptest.NewRecorder()
+		s.serverMux.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+
+		if rec.Code != http.StatusMethodNotAllowed {
+			t.Errorf("%s %s: expected status 405, got %d", tt.method, tt.path, rec.Code)
+		}
+
+		if got := rec.Header().Get("Allow"); got != tt.allow {
+			t.Errorf("%s %s: expected Allow header %q, got %q", tt.method, tt.path, tt.allow, got)
+		}
+	}
+}
+
+func TestPreviewsAuthenticateBeforeRenderLimit(t *testing.T) {
+	s := &projectorHttp{serverMux: http.NewServeMux()}
+	s.registerRoutes(ProjectorConfig{RenderMaxConcurrency: 1})
+
+	// All render slots are taken, requests which cannot be authenticated
+	// are rejected before they wait for one.
+	release, ok := s.renderLimiter.acquire(context.Background())
+	if !ok {
+		t.Fatalf("acquire render slot")
+	}
+	defer release()
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/system/projector/preview?meeting_id=1&ids=1", nil),
+		httptest.NewRequest(http.MethodPost, "/system/projector/preview/1", strings.NewReader("{}")),
+	} {
+		rec := httptest.NewRecorder()
+		s.serverMux.ServeHTTP(rec, req)
+
+		if !strings.Contains(rec.Body.String(), "authentication unavailable") {
+			t.Errorf("expected %s %s to be rejected by authentication, got %d: %s", req.Method, req.URL.Path, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestChainOrder(t *testing.T) {
+	var order []string
+	named := func(name string) middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+
+	handler := chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		order = append(order, "handler")
+	}), named("first"), named("second"))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if got := strings.Join(order, ","); got != "first,second,handler" {
+		t.Errorf("expected middlewares in order, got %s", got)
+	}
+}
+
+func TestServeBufferedRange(t *testing.T) {
+	content := []byte("0123456789")
+
+	req := httptest.NewRequest(http.MethodPost, "/system/projector/preview/1", nil)
+	req.Header.Set("Range", "bytes=2-5")
+	rec := httptest.NewRecorder()
+	serveBuffered(rec, req, "text/html; charset=utf-8", content)
+
+	if rec.Code != http.StatusPartialContent {
+		t.Fatalf("expected status 206, got %d", rec.Code)
+	}
+
+	if got := rec.Body.String(); got != "2345" {
+		t.Errorf("expected body %q, got %q", "2345", got)
+	}
+
+	if got := rec.Header().Get("Accept-Ranges"); got != "bytes" {
+		t.Errorf("expected Accept-Ranges bytes, got %q", got)
+	}
+}
+
+func TestPreviewRejectsOversizedBody(t *testing.T) {
+	s := &projectorHttp{}
+	handler := limitBodyMiddleware(s.ProjectorPreviewHandler(), 16)
+
+	body := strings.NewReader(`{"background_color": "` + strings.Repeat("a", 100) + `"}`)
+	req := httptest.NewRequest(http.MethodPost, "/system/projector/preview/1", body)
+	req.SetPathValue("id", "1")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected status 413, got %d", rec.Code)
+	}
+}
+
+func TestRequestLanguageDefault(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/system/projector/get/1", nil)
+	if got := getRequestLanguage(req, language.German); got != language.German {
+		t.Errorf("expected default language de, got %s", got)
+	}
+
+	req.Header.Set("Accept-Language", "fr-FR")
+	if base, _ := getRequestLanguage(req, language.German).Base(); base.String() != "fr" {
+		t.Errorf("expected accept header to override default, got %s", base)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/system/projector/get/1?lang=en", nil)
+	if base, _ := getRequestLanguage(req, language.German).Base(); base.String() != "en" {
+		t.Errorf("expected query parameter to override default, got %s", base)
+	}
+}
+
+func TestParseDefaultLanguage(t *testing.T) {
+	if _, err := ParseDefaultLanguage("de"); err != nil {
+		t.Errorf("expected de to be supported: %v", err)
+	}
+
+	if _, err := ParseDefaultLanguage("ja"); err == nil {
+		t.Errorf("expected ja to be rejected")
+	}
+}
+
+func TestSSERetryDelay(t *testing.T) {
+	cfg := RuntimeConfig{SSERetry: 3 * time.Second}
+	if got := sseRetryDelay(cfg); got != 3*time.Second {
+		t.Errorf("expected retry delay of 3s without jitter, got %s", got)
+	}
+
+	cfg.SSERetryJitter = time.Second
+	for i := 0; i < 100; i++ {
+		got := sseRetryDelay(cfg)
+		if got < 3*time.Second || got > 4*time.Second {
+			t.Fatalf("expected retry delay between 3s and 4s, got %s", got)
+		}
+	}
+}
+
+func TestProjectorLanguagePrecedence(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s, flow := newTestProjectorHttp(t, ctx)
+	s.cfg.DefaultLanguage = language.German
+
+	// Without a meeting language the request and the default language are
+	// used.
+	req := httptest.NewRequest(http.MethodGet, "/system/projector/subscribe/1", nil)
+	req.Header.Set("Accept-Language", "fr")
+	if base, _ := s.projectorLanguage(req, 1).Base(); base.String() != "fr" {
+		t.Errorf("expected language fr from Accept-Language, got %s", base)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/system/projector/subscribe/1", nil)
+	if base, _ := s.projectorLanguage(req, 1).Base(); base.String() != "de" {
+		t.Errorf("expected default language de, got %s", base)
+	}
+
+	flow.Set("meeting/1/language", []byte(`"en"`))
+
+	req = httptest.NewRequest(http.MethodGet, "/system/projector/subscribe/1", nil)
+	req.Header.Set("Accept-Language", "fr")
+	if got := s.projectorLanguage(req, 1); got != language.Und {
+		t.Errorf("expected projector to follow the meeting language, got %s", got)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/system/projector/subscribe/1?lang=de", nil)
+	if base, _ := s.projectorLanguage(req, 1).Base(); base.String() != "de" {
+		t.Errorf("expected requested language de, got %s", base)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/system/projector/subscribe/1", nil)
+	req.AddCookie(&http.Cookie{Name: "lang", Value: "it"})
+	if base, _ := s.projectorLanguage(req, 1).Base(); base.String() != "it" {
+		t.Errorf("expected language it from cookie, got %s", base)
+	}
+}
+
+func TestPreviewLanguageOverride(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/system/projector/preview/1?lang=de", nil)
+	req.Header.Set("Accept-Language", "fr")
+	req.AddCookie(&http.Cookie{Name: "lang", Value: "it"})
+	lang, err := getPreviewLanguage(req, language.English)
+	if err != nil {
+		t.Fatalf("get preview language: %v", err)
+	}
+
+	if base, _ := lang.Base(); base.String() != "de" {
+		t.Errorf("expected forced language de, got %s", lang)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/system/projector/preview/1", nil)
+	req.Header.Set("Accept-Language", "fr")
+	if lang, err := getPreviewLanguage(req, language.English); err != nil || lang != language.French {
+		t.Errorf("expected language fr from Accept-Language, got %s (%v)", lang, err)
+	}
+
+	for _, lang := range []string{"xx", "ja", "not a tag"} {
+		req = httptest.NewRequest(http.MethodPost, "/system/projector/preview/1?lang="+url.QueryEscape(lang), nil)
+		if _, err := getPreviewLanguage(req, language.English); err == nil {
+			t.Errorf("expected language %q to be rejected", lang)
+		}
+	}
+}
+
+func TestPreviewRejectsUnsupportedLanguage(t *testing.T) {
+	s := &projectorHttp{}
+
+	req := httptest.NewRequest(http.MethodPost, "/system/projector/preview/1?lang=xx", strings.NewReader(`{}`))
+	req.SetPathValue("id", "1")
+	rec := httptest.NewRecorder()
+	s.ProjectorPreviewHandler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", rec.Code)
+	}
+
+	if !strings.Contains(rec.Body.String(), "Language not supported") {
+		t.Errorf("unexpected body %q", rec.Body.String())
+	}
+}
+
+func TestPreviewSize(t *testing.T) {
+	settings := projector.ProjectorPreviewSettings{Width: 1200, AspectRatioNumerator: 4, AspectRatioDenominator: 3}
+
+	for _, tt := range []struct {
+		query    string
+		settings projector.ProjectorPreviewSettings
+		width    int
+		height   int
+	}{
+		{"", settings, 1200, 900},
+		{"?width=400", settings, 400, 300},
+		{"?height=300", settings, 400, 300},
+		{"?width=400&height=400", settings, 400, 400},
+		{"?width=320", projector.ProjectorPreviewSettings{}, 320, 180},
+		{"", projector.ProjectorPreviewSettings{}, 0, 0},
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/system/projector/preview/1"+tt.query, nil)
+		width, height, err := previewSize(req, tt.settings)
+		if err != nil || width != tt.width || height != tt.height {
+			t.Errorf("%q: expected %dx%d, got %dx%d, %v", tt.query, tt.width, tt.height, width, height, err)
+		}
+	}
+
+	for _, query := range []string{"?width=0", "?width=-5", "?height=100000", "?width=wide", "?width=8193"} {
+		req := httptest.NewRequest(http.MethodPost, "/system/projector/preview/1"+query, nil)
+		if _, _, err := previewSize(req, settings); err == nil {
+			t.Errorf("expected %s to be rejected", query)
+		}
+	}
+}
+
+func TestPreviewRendersSizeHints(t *testing.T) {
+	t.Chdir("../..")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s, _ := newTestProjectorHttp(t, ctx)
+
+	body := `{"width":1200,"aspect_ratio_numerator":16,"aspect_ratio_denominator":9}`
+	req := httptest.NewRequest(http.MethodPost, "/system/projector/preview/1?width=320", strings.NewReader(body))
+	req.SetPathValue("id", "1")
+	rec := httptest.NewRecorder()
+	s.ProjectorPreviewHandler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	for _, hint := range []string{`content="width=320"`, `style="width: 320px; height: 180px"`, `data-preview-height="180"`} {
+		if !strings.Contains(rec.Body.String(), hint) {
+			t.Errorf("expected preview to contain %s", hint)
+		}
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/system/projector/preview/1?width=100000", strings.NewReader(body))
+	req.SetPathValue("id", "1")
+	rec = httptest.NewRecorder()
+	s.ProjectorPreviewHandler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for an absurd width, got %d", rec.Code)
+	}
+}
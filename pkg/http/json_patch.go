@@ -0,0 +1,127 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// jsonPatchOperation is an operation of a JSON Patch document (RFC 6902).
+// Only add, remove and replace are created.
+type jsonPatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// jsonPatchValue encodes the value of an add or replace operation. Values
+// are decoded JSON and can always be encoded again.
+func jsonPatchValue(value any) json.RawMessage {
+	encoded, _ := json.Marshal(value)
+	return encoded
+}
+
+// jsonPatch returns the JSON Patch document turning the JSON document from
+// into to. Applying it to from gives a document equal to to.
+func jsonPatch(from, to string) ([]byte, error) {
+	fromValue, err := decodeJSONValue(from)
+	if err != nil {
+		return nil, fmt.Errorf("decoding previous document: %w", err)
+	}
+
+	toValue, err := decodeJSONValue(to)
+	if err != nil {
+		return nil, fmt.Errorf("decoding current document: %w", err)
+	}
+
+	operations := diffJSONValue("", fromValue, toValue, []jsonPatchOperation{})
+
+	patch, err := json.Marshal(operations)
+	if err != nil {
+		return nil, fmt.Errorf("encoding patch: %w", err)
+	}
+
+	return patch, nil
+}
+
+// decodeJSONValue decodes a JSON document keeping numbers as they are
+// written.
+func decodeJSONValue(document string) (any, error) {
+	decoder := json.NewDecoder(strings.NewReader(document))
+	decoder.UseNumber()
+
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
+
+// diffJSONValue appends the operations changing from into to at path.
+func diffJSONValue(path string, from, to any, operations []jsonPatchOperation) []jsonPatchOperation {
+	switch fromValue := from.(type) {
+	case map[string]any:
+		toValue, ok := to.(map[string]any)
+		if !ok {
+			break
+		}
+
+		for _, key := range slices.Sorted(maps.Keys(fromValue)) {
+			if _, ok := toValue[key]; !ok {
+				operations = append(operations, jsonPatchOperation{Op: "remove", Path: path + "/" + escapeJSONPointer(key)})
+			}
+		}
+
+		for _, key := range slices.Sorted(maps.Keys(toValue)) {
+			keyPath := path + "/" + escapeJSONPointer(key)
+			if old, ok := fromValue[key]; ok {
+				operations = diffJSONValue(keyPath, old, toValue[key], operations)
+				continue
+			}
+			operations = append(operations, jsonPatchOperation{Op: "add", Path: keyPath, Value: jsonPatchValue(toValue[key])})
+		}
+
+		return operations
+
+	case []any:
+		toValue, ok := to.([]any)
+		if !ok {
+			break
+		}
+
+		common := min(len(fromValue), len(toValue))
+		for i := range common {
+			operations = diffJSONValue(path+"/"+strconv.Itoa(i), fromValue[i], toValue[i], operations)
+		}
+
+		// Removed elements are removed from the end, so the indices of the
+		// remaining ones do not change.
+		for i := len(fromValue) - 1; i >= common; i-- {
+			operations = append(operations, jsonPatchOperation{Op: "remove", Path: path + "/" + strconv.Itoa(i)})
+		}
+
+		for i := common; i < len(toValue); i++ {
+			operations = append(operations, jsonPatchOperation{Op: "add", Path: path + "/" + strconv.Itoa(i), Value: jsonPatchValue(toValue[i])})
+		}
+
+		return operations
+	}
+
+	if bytes.Equal(jsonPatchValue(from), jsonPatchValue(to)) {
+		return operations
+	}
+
+	return append(operations, jsonPatchOperation{Op: "replace", Path: path, Value: jsonPatchValue(to)})
+}
+
+// escapeJSONPointer escapes a key to be used as reference token of a JSON
+// Pointer (RFC 6901).
+func escapeJSONPointer(key string) string {
+	key = strings.ReplaceAll(key, "~", "~0")
+	return strings.ReplaceAll(key, "/", "~1")
+}
@@ -0,0 +1,259 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/OpenSlides/openslides-go/datastore/dskey"
+	"golang.org/x/text/language"
+)
+
+// applyJSONPatch applies the add, remove and replace operations of a JSON
+// Patch document like a client would.
+func applyJSONPatch(t *testing.T, document string, patch string) any {
+	t.Helper()
+
+	value, err := decodeJSONValue(document)
+	if err != nil {
+		t.Fatalf("decode document: %v", err)
+	}
+
+	var operations []jsonPatchOperation
+	if err := json.Unmarshal([]byte(patch), &operations); err != nil {
+		t.Fatalf("decode patch: %v", err)
+	}
+
+	for _, operation := range operations {
+		var opValue any
+		if operation.Op != "remove" {
+			if opValue, err = decodeJSONValue(string(operation.Value)); err != nil {
+				t.Fatalf("decode value of %s %s: %v", operation.Op, operation.Path, err)
+			}
+		}
+
+		tokens := strings.Split(operation.Path, "/")[1:]
+		value = applyJSONPatchOperation(t, value, tokens, operation.Op, opValue)
+	}
+
+	return value
+}
+
+func applyJSONPatchOperation(t *testing.T, target any, tokens []string, op string, value any) any {
+	t.Helper()
+
+	if len(tokens) == 0 {
+		return value
+	}
+
+	token := strings.ReplaceAll(strings.ReplaceAll(tokens[0], "~1", "/"), "~0", "~")
+	switch container := target.(type) {
+	case map[string]any:
+		if len(tokens) > 1 {
+			container[token] = applyJSONPatchOperation(t, container[token], tokens[1:], op, value)
+		} else if op == "remove" {
+			delete(container, token)
+		} else {
+			container[token] = value
+		}
+		return container
+
+	case []any:
+		index, err := strconv.Atoi(token)
+		if err != nil || index > len(container) {
+			t.Fatalf("invalid array index %q", token)
+		}
+
+		switch {
+		case len(tokens) > 1:
+			container[index] = applyJSONPatchOperation(t, container[index], tokens[1:], op, value)
+		case op == "remove":
+			container = append(container[:index], container[index+1:]...)
+		case op == "add":
+			container = append(container[:index], append([]any{value}, container[index:]...)...)
+		default:
+			container[index] = value
+		}
+		return container
+	}
+
+	t.Fatalf("path token %q does not point into an object or array", token)
+	return nil
+}
+
+func TestJSONPatch(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		from string
+		to   string
+	}{
+		{"equal", `{"a":1}`, `{"a":1}`},
+		{"replace", `{"a":1,"b":"x"}`, `{"a":2,"b":"x"}`},
+		{"add and remove keys", `{"a":1,"b":2}`, `{"b":2,"c":{"d":null}}`},
+		{"escaped keys", `{"a/b":1,"c~d":2}`, `{"a/b":3,"c~d":4}`},
+		{"longer array", `{"p":[1,2]}`, `{"p":[1,3,4,5]}`},
+		{"shorter array", `{"p":[1,2,3,4]}`, `{"p":[2]}`},
+		{"type change", `{"p":[1]}`, `{"p":{"0":1}}`},
+		{"replace with null", `{"p":"x"}`, `{"p":null}`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			patch, err := jsonPatch(tt.from, tt.to)
+			if err != nil {
+				t.Fatalf("create patch: %v", err)
+			}
+
+			expected, err := decodeJSONValue(tt.to)
+			if err != nil {
+				t.Fatalf("decode expected document: %v", err)
+			}
+
+			if got := applyJSONPatch(t, tt.from, string(patch)); !reflect.DeepEqual(got, expected) {
+				t.Errorf("patch %s turned %s into %v, expected %s", patch, tt.from, got, tt.to)
+			}
+		})
+	}
+}
+
+func TestSubscribeJSONPatch(t *testing.T) {
+	t.Chdir("../..")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s, flow := newTestProjectorHttp(t, ctx)
+	projectionIDs := []string{}
+	for id := 1; id <= 8; id++ {
+		projectionIDs = append(projectionIDs, strconv.Itoa(id))
+		for key, value := range map[string]string{
+			"projection/%d/id":                strconv.Itoa(id),
+			"projection/%d/meeting_id":        "1",
+			"projection/%d/weight":            strconv.Itoa(id),
+			"projection/%d/content_object_id": fmt.Sprintf(`"projector_message/%d"`, id),
+			"projector_message/%d/id":         strconv.Itoa(id),
+			"projector_message/%d/meeting_id": "1",
+			"projector_message/%d/message":    fmt.Sprintf(`"<p>Message %d</p>"`, id),
+		} {
+			flow.Set(fmt.Sprintf(key, id), []byte(value))
+		}
+	}
+	flow.Set("projector/1/current_projection_ids", []byte("["+strings.Join(projectionIDs, ",")+"]"))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /system/projector/subscribe/{id}", s.ProjectorSubscribeHandler())
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	reqCtx, reqCancel := context.WithTimeout(ctx, 5*time.Second)
+	defer reqCancel()
+
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, srv.URL+"/system/projector/subscribe/1?init=1&format=data&delta=json-patch", nil)
+	if err != nil {
+		t.Fatalf("create request: %v", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+	defer resp.Body.Close()
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(nil, 1<<20)
+	nextEvent := func(names ...string) (string, string) {
+		t.Helper()
+
+		event := ""
+		for scanner.Scan() {
+			line := scanner.Text()
+			if name, ok := strings.CutPrefix(line, "event: "); ok {
+				event = name
+			} else if data, ok := strings.CutPrefix(line, "data: "); ok && event != "" {
+				for _, name := range names {
+					if event == name {
+						return event, data
+					}
+				}
+			}
+		}
+
+		t.Fatalf("no %v event received", names)
+		return "", ""
+	}
+
+	_, state := nextEvent("projector-data")
+	nextEvent("connected")
+
+	for i, change := range []struct{ projection, message int }{{3, 9}, {6, 10}} {
+		message := change.message
+		flow.Changes <- map[dskey.Key][]byte{
+			dskey.MustKey(fmt.Sprintf("projection/%d/content_object_id", change.projection)): []byte(fmt.Sprintf(`"projector_message/%d"`, message)),
+			dskey.MustKey(fmt.Sprintf("projector_message/%d/id", message)):                   []byte(strconv.Itoa(message)),
+			dskey.MustKey(fmt.Sprintf("projector_message/%d/meeting_id", message)):           []byte("1"),
+		}
+
+		event, patch := nextEvent("projector-data", "projector-data-patch")
+		if event != "projector-data-patch" {
+			t.Fatalf("update %d: expected a patch for a small change, got %s", i+1, event)
+		}
+
+		// The rendered content and with it the hashes can change again
+		// after the first event, further events follow in that case.
+		for {
+			patched := applyJSONPatch(t, state, patch)
+			encoded, err := json.Marshal(patched)
+			if err != nil {
+				t.Fatalf("encode patched state: %v", err)
+			}
+			state = string(encoded)
+
+			full, err := s.projectorData(ctx, 1, language.Und, nil)
+			if err != nil {
+				t.Fatalf("fetch projector data: %v", err)
+			}
+
+			expected, err := decodeJSONValue(full)
+			if err != nil {
+				t.Fatalf("decode projector data: %v", err)
+			}
+
+			if reflect.DeepEqual(patched, expected) {
+				break
+			}
+
+			if reqCtx.Err() != nil {
+				t.Fatalf("update %d: patched state %v does not match %s", i+1, patched, full)
+			}
+
+			event, patch = nextEvent("projector-data", "projector-data-patch")
+			if event == "projector-data" {
+				state, patch = patch, `[]`
+			}
+		}
+	}
+}
+
+func TestSubscribeDeltaRequiresDataFormat(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s, _ := newTestProjectorHttp(t, ctx)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /system/projector/subscribe/{id}", s.ProjectorSubscribeHandler())
+
+	for _, query := range []string{"delta=json-patch", "format=data&delta=merge"} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/system/projector/subscribe/1?"+query, nil))
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// limitQueueWait is the longest time a short request waits for a free slot
+// before it is rejected.
+const limitQueueWait = 2 * time.Second
+
+// limitRetryAfter is sent in the Retry-After header of rejected requests.
+const limitRetryAfter = 2 * time.Second
+
+// concurrencyLimiter limits the number of requests handled at the same time.
+// A nil limiter or a limiter without slots does not limit anything.
+type concurrencyLimiter struct {
+	mu    sync.Mutex
+	slots chan struct{}
+	wait  time.Duration
+
+	// maxQueued is the number of requests waiting for a slot at the same
+	// time, further requests are rejected immediately. Zero does not limit
+	// the waiting requests.
+	maxQueued int
+	queued    int
+}
+
+// newConcurrencyLimiter returns a limiter allowing max concurrent requests.
+// Requests wait up to wait for a free slot. The limiter does not limit
+// anything if max is not positive.
+func newConcurrencyLimiter(max int, wait time.Duration) *concurrencyLimiter {
+	l := &concurrencyLimiter{wait: wait}
+	l.resize(max)
+	return l
+}
+
+// resize changes the number of allowed concurrent requests. Requests holding
+// a slot of the previous size release it there and are not counted against
+// the new size.
+func (l *concurrencyLimiter) resize(max int) {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if max <= 0 {
+		l.slots = nil
+		return
+	}
+
+	if l.slots != nil && cap(l.slots) == max {
+		return
+	}
+
+	l.slots = make(chan struct{}, max)
+}
+
+// acquire takes a slot and returns the function releasing it. Returns false
+// if no slot got free in time.
+func (l *concurrencyLimiter) acquire(ctx context.Context) (func(), bool) {
+	if l == nil {
+		return func() {}, true
+	}
+
+	l.mu.Lock()
+	slots := l.slots
+	l.mu.Unlock()
+
+	if slots == nil {
+		return func() {}, true
+	}
+
+	release := func() { <-slots }
+	select {
+	case slots <- struct{}{}:
+		return release, true
+	default:
+	}
+
+	if l.wait <= 0 || !l.enqueue() {
+		return nil, false
+	}
+	defer l.dequeue()
+
+	timer := time.NewTimer(l.wait)
+	defer timer.Stop()
+
+	select {
+	case slots <- struct{}{}:
+		return release, true
+	case <-timer.C:
+		return nil, false
+	case <-ctx.Done():
+		return nil, false
+	}
+}
+
+// enqueue counts a request waiting for a slot. Returns false if the queue is
+// full.
+func (l *concurrencyLimiter) enqueue() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.maxQueued > 0 && l.queued >= l.maxQueued {
+		return false
+	}
+
+	l.queued++
+	return true
+}
+
+func (l *concurrencyLimiter) dequeue() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.queued--
+}
+
+// limitMiddleware answers with 503 and a Retry-After header if the limiter
+// has no free slot.
+func limitMiddleware(next http.Handler, limiter *concurrencyLimiter) http.Handler {
+	if limiter == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		release, ok := limiter.acquire(r.Context())
+		if !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(limitRetryAfter.Seconds())))
+			writeError(w, http.StatusServiceUnavailable, "too many concurrent requests")
+			return
+		}
+		defer release()
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,132 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLimitMiddlewareRejectsBeyondLimit(t *testing.T) {
+	started := make(chan struct{})
+	finish := make(chan struct{})
+	handler := limitMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-finish
+	}), newConcurrencyLimiter(1, 0))
+
+	go handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/system/projector/subscribe/1", nil))
+	<-started
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/system/projector/subscribe/1", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503, got %d", rec.Code)
+	}
+
+	if got := rec.Header().Get("Retry-After"); got != "2" {
+		t.Errorf("expected Retry-After 2, got %q", got)
+	}
+
+	close(finish)
+}
+
+func TestLimitMiddlewareQueuesRequests(t *testing.T) {
+	started := make(chan struct{}, 2)
+	finish := make(chan struct{})
+	handler := limitMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-finish
+	}), newConcurrencyLimiter(1, time.Second))
+
+	go handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/system/projector/get/1", nil))
+	<-started
+
+	done := make(chan int)
+	go func() {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/system/projector/get/1", nil))
+		done <- rec.Code
+	}()
+
+	select {
+	case <-started:
+		t.Fatalf("second request started while the limit was reached")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(finish)
+	if code := <-done; code != http.StatusOK {
+		t.Errorf("expected queued request to succeed, got status %d", code)
+	}
+}
+
+func TestLimitMiddlewareRejectsBeyondQueue(t *testing.T) {
+	limiter := newConcurrencyLimiter(2, time.Second)
+	limiter.maxQueued = 1
+
+	started := make(chan struct{}, 3)
+	finish := make(chan struct{})
+	handler := limitMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-finish
+	}), limiter)
+
+	serve := func() <-chan int {
+		done := make(chan int, 1)
+		go func() {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/system/projector/preview/1", nil))
+			done <- rec.Code
+		}()
+		return done
+	}
+
+	running := []<-chan int{serve(), serve()}
+	<-started
+	<-started
+
+	queued := serve()
+	for waiting := 0; waiting == 0; {
+		time.Sleep(time.Millisecond)
+		limiter.mu.Lock()
+		waiting = limiter.queued
+		limiter.mu.Unlock()
+	}
+
+	if code := <-serve(); code != http.StatusServiceUnavailable {
+		t.Errorf("expected request beyond the queue to be rejected with 503, got %d", code)
+	}
+
+	close(finish)
+	for i, done := range append(running, queued) {
+		if code := <-done; code != http.StatusOK {
+			t.Errorf("expected request %d to succeed, got status %d", i+1, code)
+		}
+	}
+}
+
+func TestConcurrencyLimiterResize(t *testing.T) {
+	limiter := newConcurrencyLimiter(0, 0)
+	if _, ok := limiter.acquire(context.Background()); !ok {
+		t.Fatalf("expected a limiter without slots not to limit")
+	}
+
+	limiter.resize(1)
+	release, ok := limiter.acquire(context.Background())
+	if !ok {
+		t.Fatalf("expected first slot to be free")
+	}
+	if _, ok := limiter.acquire(context.Background()); ok {
+		t.Errorf("expected limit of one to be reached")
+	}
+
+	limiter.resize(2)
+	if _, ok := limiter.acquire(context.Background()); !ok {
+		t.Errorf("expected a slot after raising the limit")
+	}
+
+	// Releasing a slot of the previous size must not block
+	release()
+}
@@ -0,0 +1,186 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/OpenSlides/openslides-go/datastore/dsfetch"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	errProjectorNotFound = errors.New("projector does not exist")
+	errNotInMeeting      = errors.New("projector does not belong to a meeting of the user")
+)
+
+// organizationWideLevels are the organization management levels allowing to
+// see the projectors of all meetings.
+var organizationWideLevels = []string{"superadmin", "can_manage_organization"}
+
+// checkProjectorMeeting makes sure the projector belongs to a meeting the user
+// is part of. The anonymous user may only see projectors of meetings with
+// anonymous access enabled.
+func (s *projectorHttp) checkProjectorMeeting(ctx context.Context, userID int, projectorID int) error {
+	meetingID, err := s.db.Fetch.Projector_MeetingID(projectorID).Value(ctx)
+	if err != nil {
+		var doesNotExist dsfetch.DoesNotExistError
+		if errors.As(err, &doesNotExist) {
+			return errProjectorNotFound
+		}
+
+		return fmt.Errorf("fetching meeting of projector %d: %w", projectorID, err)
+	}
+
+	if userID == 0 {
+		anonymous, err := s.db.Fetch.Meeting_EnableAnonymous(meetingID).Value(ctx)
+		if err != nil {
+			return fmt.Errorf("fetching anonymous access of meeting %d: %w", meetingID, err)
+		}
+
+		if !anonymous {
+			return errNotInMeeting
+		}
+
+		return nil
+	}
+
+	meetingIDs, err := s.db.Fetch.User_MeetingIDs(userID).Value(ctx)
+	if err != nil {
+		var doesNotExist dsfetch.DoesNotExistError
+		if errors.As(err, &doesNotExist) {
+			return errNotInMeeting
+		}
+
+		return fmt.Errorf("fetching meetings of user %d: %w", userID, err)
+	}
+
+	level, err := s.db.Fetch.User_OrganizationManagementLevel(userID).Value(ctx)
+	if err != nil {
+		return fmt.Errorf("fetching organization management level of user %d: %w", userID, err)
+	}
+
+	if !slices.Contains(meetingIDs, meetingID) && !slices.Contains(organizationWideLevels, level) {
+		return errNotInMeeting
+	}
+
+	return nil
+}
+
+// allowProjectorMeeting checks the meeting of the projector for the user of
+// the request. If the check fails, the error is written and false returned.
+func (s *projectorHttp) allowProjectorMeeting(w http.ResponseWriter, r *http.Request, projectorID int) bool {
+	err := s.checkProjectorMeeting(r.Context(), requestUserID(r.Context()), projectorID)
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, errProjectorNotFound):
+		writeError(w, http.StatusNotFound, "Projector not found")
+	case errors.Is(err, errNotInMeeting):
+		writeError(w, http.StatusForbidden, "permissions denied")
+	default:
+		log.Ctx(r.Context()).Err(err).Msg("checking projector meeting failed")
+		writeError(w, http.StatusInternalServerError, "Error reading projector")
+	}
+
+	return false
+}
+
+// meetingScopeMiddleware makes sure the projector of a request scoped to a
+// meeting belongs to this meeting. Projectors of other meetings are reported
+// as not found.
+func (s *projectorHttp) meetingScopeMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		meetingID := requestMeetingID(r.Context())
+		if meetingID == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		id, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "Projector id invalid")
+			return
+		}
+
+		projectorMeetingID, err := s.db.Fetch.Projector_MeetingID(id).Value(r.Context())
+		if err != nil {
+			var doesNotExist dsfetch.DoesNotExistError
+			if !errors.As(err, &doesNotExist) {
+				log.Ctx(r.Context()).Err(err).Msg("checking projector meeting failed")
+				writeError(w, http.StatusInternalServerError, "Error reading projector")
+				return
+			}
+		}
+
+		if projectorMeetingID != meetingID {
+			writeError(w, http.StatusNotFound, "Projector not found")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// watchAccess checks the access of the user to the projector every interval
+// until ctx is done. The returned channel is closed once the access was
+// revoked. A check which fails keeps the access, so an unavailable
+// restricter does not close subscriptions.
+func (s *projectorHttp) watchAccess(ctx context.Context, userID int, meetingID int, projectorID int, interval time.Duration) <-chan struct{} {
+	revoked := make(chan struct{})
+	if interval <= 0 {
+		return revoked
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			allowed, err := s.canStillSeeProjector(ctx, userID, meetingID, projectorID)
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Ctx(ctx).Warn().Err(err).Int("projector", projectorID).Msg("checking projector access failed")
+				}
+				continue
+			}
+
+			if !allowed {
+				close(revoked)
+				return
+			}
+		}
+	}()
+
+	return revoked
+}
+
+// canStillSeeProjector repeats the access checks of a subscription.
+func (s *projectorHttp) canStillSeeProjector(ctx context.Context, userID int, meetingID int, projectorID int) (bool, error) {
+	err := s.checkProjectorMeeting(ctx, userID, projectorID)
+	switch {
+	case errors.Is(err, errNotInMeeting):
+		return false, nil
+	case errors.Is(err, errProjectorNotFound):
+		// Deleted projectors are reported by the subscription itself
+		return true, nil
+	case err != nil:
+		return false, err
+	}
+
+	if s.restricter == nil {
+		return true, nil
+	}
+
+	return s.restricter.CanSeeMeetingProjector(ctx, userID, meetingID, projectorID)
+}
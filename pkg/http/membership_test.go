@@ -0,0 +1,202 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/OpenSlides/openslides-projector-service/pkg/dstest"
+)
+
+func addMeetingTestData(flow *dstest.Flow) {
+	for key, value := range map[string]string{
+		"projector/3/id":                       "3",
+		"projector/3/meeting_id":               "2",
+		"projector/3/sequential_number":        "1",
+		"meeting/2/id":                         "2",
+		"user/7/id":                            "7",
+		"user/7/meeting_ids":                   "[1]",
+		"user/8/id":                            "8",
+		"user/8/organization_management_level": `"superadmin"`,
+	} {
+		flow.Set(key, []byte(value))
+	}
+}
+
+func TestCheckProjectorMeeting(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s, flow := newTestProjectorHttp(t, ctx)
+	addMeetingTestData(flow)
+
+	for _, tt := range []struct {
+		name      string
+		userID    int
+		projector int
+		expected  error
+	}{
+		{"same meeting", 7, 1, nil},
+		{"other meeting", 7, 3, errNotInMeeting},
+		{"superadmin", 8, 3, nil},
+		{"unknown user", 9, 1, errNotInMeeting},
+		{"anonymous with anonymous access", 0, 1, nil},
+		{"anonymous without anonymous access", 0, 3, errNotInMeeting},
+		{"unknown projector", 7, 4, errProjectorNotFound},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.checkProjectorMeeting(ctx, tt.userID, tt.projector)
+			if !errors.Is(err, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestSubscribeOtherMeetingIsForbidden(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s, flow := newTestProjectorHttp(t, ctx)
+	addMeetingTestData(flow)
+
+	reqCtx := context.WithValue(context.Background(), userIDKey{}, 7)
+	req := httptest.NewRequestWithContext(reqCtx, http.MethodGet, "/system/projector/subscribe/3", nil)
+	req.SetPathValue("id", "3")
+	rec := httptest.NewRecorder()
+	s.ProjectorSubscribeHandler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("expected status 403, got %d", rec.Code)
+	}
+}
+
+func TestMeetingScopedRoutes(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s, flow := newTestProjectorHttp(t, ctx)
+	addMeetingTestData(flow)
+
+	var restrictedFields atomic.Value
+	restricterSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		restrictedFields.Store(string(body))
+
+		// Projector 1 belongs to meeting 1 and projector 3 to meeting 2
+		fmt.Fprint(w, `{"projector/1/id":1,"projector/1/meeting_id":1,"projector/3/id":3,"projector/3/meeting_id":2}`)
+	}))
+	defer restricterSrv.Close()
+
+	s.auth = &authenticator{publicAccessOnly: true}
+	s.serverMux = http.NewServeMux()
+	s.registerRoutes(ProjectorConfig{RestricterUrl: restricterSrv.URL})
+
+	for _, tt := range []struct {
+		name   string
+		path   string
+		status int
+	}{
+		{"unscoped", "/system/projector/current/1", http.StatusOK},
+		{"scoped", "/system/projector/meeting/1/current/1", http.StatusOK},
+		{"other meeting", "/system/projector/meeting/2/current/1", http.StatusUnauthorized},
+		{"invalid meeting", "/system/projector/meeting/x/current/1", http.StatusBadRequest},
+		{"negative meeting", "/system/projector/meeting/-1/current/1", http.StatusBadRequest},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			s.serverMux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if rec.Code != tt.status {
+				t.Errorf("expected %d, got %d: %s", tt.status, rec.Code, rec.Body.String())
+			}
+		})
+	}
+
+	s.serverMux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/system/projector/meeting/1/current/1", nil))
+	if fields, _ := restrictedFields.Load().(string); !strings.Contains(fields, `"meeting_id"`) {
+		t.Errorf("expected scoped restriction to request the meeting, got %s", fields)
+	}
+}
+
+func TestUnauthorizedHasChallenge(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s, flow := newTestProjectorHttp(t, ctx)
+	addMeetingTestData(flow)
+
+	restricterSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"projector/3/id":3,"projector/3/meeting_id":2}`)
+	}))
+	defer restricterSrv.Close()
+
+	s.auth = &authenticator{publicAccessOnly: true}
+	s.serverMux = http.NewServeMux()
+	s.registerRoutes(ProjectorConfig{RestricterUrl: restricterSrv.URL})
+
+	rec := httptest.NewRecorder()
+	s.serverMux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/system/projector/meeting/1/current/3", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	if got := rec.Header().Get("WWW-Authenticate"); got != `Bearer realm="OpenSlides"` {
+		t.Errorf("expected bearer challenge, got %q", got)
+	}
+
+	if got := rec.Header().Get("Content-Type"); !strings.HasPrefix(got, "application/json") {
+		t.Errorf("expected JSON body, got content type %q", got)
+	}
+
+	var body errorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode body: %v", err)
+	}
+
+	if !body.Error || body.Msg != "permissions denied" {
+		t.Errorf("expected permissions denied error, got %+v", body)
+	}
+}
+
+func TestMeetingScopeMiddleware(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s, flow := newTestProjectorHttp(t, ctx)
+	addMeetingTestData(flow)
+
+	handler := s.meetingScopeMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for _, tt := range []struct {
+		name      string
+		meetingID int
+		projector string
+		status    int
+	}{
+		{"unscoped", 0, "3", http.StatusOK},
+		{"same meeting", 2, "3", http.StatusOK},
+		{"other meeting", 1, "3", http.StatusNotFound},
+		{"unknown projector", 1, "4", http.StatusNotFound},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.SetPathValue("id", tt.projector)
+			req = req.WithContext(context.WithValue(ctx, meetingIDKey{}, tt.meetingID))
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Errorf("expected %d, got %d", tt.status, rec.Code)
+			}
+		})
+	}
+}
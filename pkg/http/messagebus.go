@@ -0,0 +1,100 @@
+package http
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// messageBusCheckInterval is the time between two checks of a reachable
+	// message bus.
+	messageBusCheckInterval = 10 * time.Second
+
+	// messageBusCheckTimeout limits a single check of the message bus.
+	messageBusCheckTimeout = 2 * time.Second
+
+	// messageBusMinBackoff and messageBusMaxBackoff limit the time between
+	// two checks of an unreachable message bus. The backoff is doubled after
+	// every failed check.
+	messageBusMinBackoff = 500 * time.Millisecond
+	messageBusMaxBackoff = 30 * time.Second
+)
+
+// messageBusPinger checks the connection to the message bus. It is
+// implemented by the redis client.
+type messageBusPinger interface {
+	Wait(ctx context.Context) error
+}
+
+// messageBusMonitor checks the connection to the message bus in the
+// background. Authentication depends on it for logout events, so requests
+// are rejected while it is unreachable.
+type messageBusMonitor struct {
+	bus     messageBusPinger
+	healthy atomic.Bool
+
+	interval   time.Duration
+	minBackoff time.Duration
+	maxBackoff time.Duration
+}
+
+func newMessageBusMonitor(bus messageBusPinger) *messageBusMonitor {
+	return &messageBusMonitor{
+		bus:        bus,
+		interval:   messageBusCheckInterval,
+		minBackoff: messageBusMinBackoff,
+		maxBackoff: messageBusMaxBackoff,
+	}
+}
+
+// Healthy reports whether the message bus was reachable at the last check.
+func (m *messageBusMonitor) Healthy() bool {
+	return m.healthy.Load()
+}
+
+// run checks the message bus until the context is done. onFirstHealthy is
+// called once when the message bus is reachable for the first time.
+func (m *messageBusMonitor) run(ctx context.Context, onFirstHealthy func()) {
+	backoff := m.minBackoff
+	wasHealthy := false
+	for {
+		checkCtx, cancel := context.WithTimeout(ctx, messageBusCheckTimeout)
+		err := m.bus.Wait(checkCtx)
+		cancel()
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		delay := m.interval
+		if err != nil {
+			if m.healthy.Swap(false) {
+				log.Error().Err(err).Msg("message bus not reachable")
+			} else {
+				log.Warn().Err(err).Msgf("message bus not reachable, retrying in %s", backoff)
+			}
+
+			delay = backoff
+			backoff = min(2*backoff, m.maxBackoff)
+		} else {
+			if !m.healthy.Swap(true) {
+				log.Info().Msg("message bus reachable")
+			}
+
+			if !wasHealthy && onFirstHealthy != nil {
+				onFirstHealthy()
+			}
+			wasHealthy = true
+			backoff = m.minBackoff
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+	}
+}
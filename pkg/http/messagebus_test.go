@@ -0,0 +1,149 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type fakeMessageBus struct {
+	mu    sync.Mutex
+	err   error
+	calls int
+}
+
+func (b *fakeMessageBus) Wait(ctx context.Context) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.calls++
+	return b.err
+}
+
+func (b *fakeMessageBus) setErr(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.err = err
+}
+
+func waitFor(t *testing.T, msg string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timeout waiting for %s", msg)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestMessageBusMonitor(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bus := &fakeMessageBus{err: errors.New("connection refused")}
+	monitor := newMessageBusMonitor(bus)
+	monitor.interval = time.Millisecond
+	monitor.minBackoff = time.Millisecond
+	monitor.maxBackoff = 4 * time.Millisecond
+
+	var firstHealthy atomic.Int32
+	go monitor.run(ctx, func() { firstHealthy.Add(1) })
+
+	waitFor(t, "retries", func() bool {
+		bus.mu.Lock()
+		defer bus.mu.Unlock()
+		return bus.calls >= 3
+	})
+	if monitor.Healthy() || firstHealthy.Load() != 0 {
+		t.Fatalf("expected unreachable message bus")
+	}
+
+	bus.setErr(nil)
+	waitFor(t, "healthy", monitor.Healthy)
+
+	bus.setErr(errors.New("connection reset"))
+	waitFor(t, "unhealthy", func() bool { return !monitor.Healthy() })
+
+	bus.setErr(nil)
+	waitFor(t, "healthy again", monitor.Healthy)
+
+	if got := firstHealthy.Load(); got != 1 {
+		t.Errorf("expected onFirstHealthy to be called once, got %d", got)
+	}
+}
+
+func TestAuthenticateUnavailable(t *testing.T) {
+	cfg := ProjectorConfig{AnonymousUserID: 0}
+	for _, tt := range []struct {
+		name string
+		auth *authenticator
+	}{
+		{"no authenticator", nil},
+		{"auth service failed", &authenticator{}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/system/projector/whoami", nil)
+			if _, _, ok := authenticate(rec, req, tt.auth, cfg); ok {
+				t.Fatalf("expected authentication to fail")
+			}
+
+			if rec.Code != http.StatusServiceUnavailable {
+				t.Errorf("expected 503, got %d", rec.Code)
+			}
+			if rec.Header().Get("Retry-After") == "" {
+				t.Errorf("expected Retry-After header")
+			}
+		})
+	}
+}
+
+func TestAuthenticatePublicAccessOnly(t *testing.T) {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/system/projector/whoami", nil)
+	_, userID, ok := authenticate(rec, req, &authenticator{publicAccessOnly: true}, ProjectorConfig{})
+	if !ok {
+		t.Fatalf("expected authentication to succeed, got %d", rec.Code)
+	}
+
+	if userID != 0 {
+		t.Errorf("expected anonymous user, got %d", userID)
+	}
+}
+
+func TestReadinessMessageBus(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s, _ := newTestProjectorHttp(t, ctx)
+	bus := &fakeMessageBus{}
+	s.auth = &authenticator{messageBus: newMessageBusMonitor(bus)}
+	s.serverMux = http.NewServeMux()
+	s.registerRoutes(ProjectorConfig{})
+
+	ready := func() (int, healthResponse) {
+		rec := httptest.NewRecorder()
+		s.serverMux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, DefaultHealthPath+"/ready", nil))
+
+		var resp healthResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("parse response: %v", err)
+		}
+		return rec.Code, resp
+	}
+
+	if code, resp := ready(); code != http.StatusServiceUnavailable || resp.Checks["message_bus"] {
+		t.Errorf("expected unavailable message bus before the first check, got %d %+v", code, resp)
+	}
+
+	s.auth.messageBus.healthy.Store(true)
+	if code, resp := ready(); code != http.StatusOK || !resp.Checks["message_bus"] {
+		t.Errorf("expected healthy message bus, got %d %+v", code, resp)
+	}
+}
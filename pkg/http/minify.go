@@ -0,0 +1,162 @@
+package http
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// rawTextElements keep their content unchanged when minifying.
+var rawTextElements = []string{"pre", "script", "style", "textarea"}
+
+// minify returns the minified content if HTML minification is enabled.
+func (s *projectorHttp) minify(content string) string {
+	if !s.runtimeConfig().MinifyHTML {
+		return content
+	}
+
+	return minifyHTML(content)
+}
+
+// minifyHTML removes comments and collapses runs of whitespace to a single
+// space. Whitespace within quoted attribute values and the content of pre,
+// script, style and textarea elements is kept.
+func minifyHTML(content string) string {
+	var out strings.Builder
+	out.Grow(len(content))
+
+	space := false
+	writeSpace := func() {
+		if space && out.Len() > 0 {
+			out.WriteByte(' ')
+		}
+		space = false
+	}
+
+	for len(content) > 0 {
+		if strings.HasPrefix(content, "<!--") {
+			end := strings.Index(content[4:], "-->")
+			if end == -1 {
+				out.WriteString(content)
+				break
+			}
+			content = content[4+end+3:]
+			continue
+		}
+
+		if content[0] == '<' {
+			end := tagEnd(content)
+			tag := content[:end]
+			content = content[end:]
+
+			writeSpace()
+			out.WriteString(collapseTag(tag))
+
+			if name := rawTextElement(tag); name != "" {
+				closing := strings.Index(strings.ToLower(content), "</"+name)
+				if closing == -1 {
+					closing = len(content)
+				}
+				out.WriteString(content[:closing])
+				content = content[closing:]
+			}
+			continue
+		}
+
+		if isHTMLSpace(content[0]) {
+			space = true
+			content = content[1:]
+			continue
+		}
+
+		end := strings.IndexFunc(content, func(r rune) bool {
+			return r == '<' || r < utf8.RuneSelf && isHTMLSpace(byte(r))
+		})
+		if end == -1 {
+			end = len(content)
+		}
+
+		writeSpace()
+		out.WriteString(content[:end])
+		content = content[end:]
+	}
+
+	return out.String()
+}
+
+// tagEnd returns the index after the end of the tag at the start of content.
+// Quoted attribute values may contain '>'.
+func tagEnd(content string) int {
+	var quote byte
+	for i := 1; i < len(content); i++ {
+		switch c := content[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '>':
+			return i + 1
+		}
+	}
+
+	return len(content)
+}
+
+// collapseTag collapses whitespace between the attributes of a tag.
+func collapseTag(tag string) string {
+	var out strings.Builder
+	var quote byte
+	space := false
+	for i := 0; i < len(tag); i++ {
+		c := tag[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case isHTMLSpace(c):
+			space = true
+			continue
+		}
+
+		if space {
+			if c != '>' {
+				out.WriteByte(' ')
+			}
+			space = false
+		}
+		out.WriteByte(c)
+	}
+
+	return out.String()
+}
+
+// rawTextElement returns the name of the element if tag opens an element
+// whose content must not be changed.
+func rawTextElement(tag string) string {
+	if strings.HasPrefix(tag, "</") {
+		return ""
+	}
+
+	name := strings.ToLower(strings.TrimLeft(tag, "<"))
+	for _, elem := range rawTextElements {
+		if strings.HasPrefix(name, elem) && len(name) > len(elem) && !isTagNameChar(name[len(elem)]) {
+			return elem
+		}
+	}
+
+	return ""
+}
+
+// isHTMLSpace reports whether c is ASCII whitespace. Other whitespace like
+// non-breaking spaces is content.
+func isHTMLSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+func isTagNameChar(c byte) bool {
+	return c == '-' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9'
+}
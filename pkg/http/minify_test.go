@@ -0,0 +1,74 @@
+package http
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+)
+
+var (
+	htmlCommentPattern = regexp.MustCompile(`(?s)<!--.*?-->`)
+	htmlTokenPattern   = regexp.MustCompile(`<[^>]+>|[^<\s]+`)
+)
+
+// htmlStructure returns the tags and words of a html text with the
+// whitespace within tags normalized.
+func htmlStructure(content string) []string {
+	tokens := htmlTokenPattern.FindAllString(htmlCommentPattern.ReplaceAllString(content, ""), -1)
+	for i, token := range tokens {
+		tokens[i] = strings.Join(strings.Fields(token), " ")
+	}
+	return tokens
+}
+
+func TestMinifyHTML(t *testing.T) {
+	content := `
+<div class="content">
+  <!-- Title -->
+  <h1   class="projector_h1"
+        data-title="A  title > with spaces">
+    Motion   <span>A1</span>:
+    Über&nbsp;allem
+  </h1>
+  <br />
+  <pre>
+  keep   this
+    as is
+  </pre>
+  <script type="module">
+    if (a < b) {   console.log("x  y"); }
+  </script>
+</div>
+`
+
+	minified := minifyHTML(content)
+
+	if len(minified) >= len(content) {
+		t.Errorf("expected minified html to be smaller than %d bytes, got %d", len(content), len(minified))
+	}
+
+	if strings.Contains(minified, "<!--") {
+		t.Errorf("expected comments to be removed, got %q", minified)
+	}
+
+	if !slices.Equal(htmlStructure(minified), htmlStructure(content)) {
+		t.Errorf("expected same structure\nwant %q\ngot  %q", htmlStructure(content), htmlStructure(minified))
+	}
+
+	for _, expected := range []string{
+		`<h1 class="projector_h1" data-title="A  title > with spaces">`,
+		"Motion <span>A1</span>: Über&nbsp;allem </h1>",
+		"<br />",
+		"<pre>\n  keep   this\n    as is\n  </pre>",
+		"<script type=\"module\">\n    if (a < b) {   console.log(\"x  y\"); }\n  </script>",
+	} {
+		if !strings.Contains(minified, expected) {
+			t.Errorf("expected minified html to contain %q, got %q", expected, minified)
+		}
+	}
+
+	if again := minifyHTML(minified); again != minified {
+		t.Errorf("expected minifying twice to change nothing, got %q", again)
+	}
+}
@@ -0,0 +1,161 @@
+package http
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/OpenSlides/openslides-projector-service/pkg/projector"
+)
+
+// formatMsgpack is the encoding of subscribe events for clients accepting
+// application/msgpack.
+const formatMsgpack = "msgpack"
+
+// acceptsMsgpack reports whether the client prefers MessagePack over server
+// sent events.
+func acceptsMsgpack(accept string) bool {
+	return strings.Contains(accept, "application/msgpack") && !strings.Contains(accept, "text/event-stream")
+}
+
+// encodeMsgpackEvent encodes an event as MessagePack map with the keys id,
+// event and data. The JSON payload of the event is converted, so clients do
+// not have to parse JSON at all. Payloads which are no JSON are sent as
+// string, empty payloads as nil.
+func encodeMsgpackEvent(event *projector.ProjectorUpdateEvent) ([]byte, error) {
+	var data any
+	if event.Data != "" {
+		decoder := json.NewDecoder(strings.NewReader(event.Data))
+		decoder.UseNumber()
+		if err := decoder.Decode(&data); err != nil || decoder.More() {
+			data = event.Data
+		}
+	}
+
+	frame := map[string]any{
+		"event": event.Event,
+		"data":  data,
+	}
+	if event.ID != "" {
+		frame["id"] = event.ID
+	}
+
+	var buf bytes.Buffer
+	if err := writeMsgpack(&buf, frame); err != nil {
+		return nil, fmt.Errorf("encoding %s event: %w", event.Event, err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// writeMsgpack writes the values produced by decoding JSON with UseNumber.
+// Keys of maps are sorted to get a stable encoding.
+func writeMsgpack(buf *bytes.Buffer, value any) error {
+	switch v := value.(type) {
+	case nil:
+		buf.WriteByte(0xc0)
+
+	case bool:
+		if v {
+			buf.WriteByte(0xc3)
+		} else {
+			buf.WriteByte(0xc2)
+		}
+
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			writeMsgpackInt(buf, i)
+			return nil
+		}
+
+		f, err := v.Float64()
+		if err != nil {
+			return fmt.Errorf("invalid number %s: %w", v, err)
+		}
+		buf.WriteByte(0xcb)
+		buf.Write(binary.BigEndian.AppendUint64(nil, math.Float64bits(f)))
+
+	case string:
+		writeMsgpackHeader(buf, len(v), 0xa0, 32, 0xd9, 0xda, 0xdb)
+		buf.WriteString(v)
+
+	case []any:
+		writeMsgpackHeader(buf, len(v), 0x90, 16, 0, 0xdc, 0xdd)
+		for _, item := range v {
+			if err := writeMsgpack(buf, item); err != nil {
+				return err
+			}
+		}
+
+	case map[string]any:
+		writeMsgpackHeader(buf, len(v), 0x80, 16, 0, 0xde, 0xdf)
+		for _, key := range slices.Sorted(maps.Keys(v)) {
+			if err := writeMsgpack(buf, key); err != nil {
+				return err
+			}
+			if err := writeMsgpack(buf, v[key]); err != nil {
+				return err
+			}
+		}
+
+	default:
+		return fmt.Errorf("unsupported type %T", value)
+	}
+
+	return nil
+}
+
+// writeMsgpackHeader writes the type and length of a string, array or map.
+// Lengths below fixLimit are part of the type byte. A code8 of zero means
+// the type has no 8 bit length.
+func writeMsgpackHeader(buf *bytes.Buffer, length int, fix byte, fixLimit int, code8 byte, code16 byte, code32 byte) {
+	switch {
+	case length < fixLimit:
+		buf.WriteByte(fix | byte(length))
+	case code8 != 0 && length <= math.MaxUint8:
+		buf.WriteByte(code8)
+		buf.WriteByte(byte(length))
+	case length <= math.MaxUint16:
+		buf.WriteByte(code16)
+		buf.Write(binary.BigEndian.AppendUint16(nil, uint16(length)))
+	default:
+		buf.WriteByte(code32)
+		buf.Write(binary.BigEndian.AppendUint32(nil, uint32(length)))
+	}
+}
+
+// writeMsgpackInt writes an integer in its shortest encoding.
+func writeMsgpackInt(buf *bytes.Buffer, i int64) {
+	switch {
+	case i >= 0 && i <= math.MaxInt8:
+		buf.WriteByte(byte(i))
+	case i < 0 && i >= -32:
+		buf.WriteByte(byte(int8(i)))
+	case i >= 0 && i <= math.MaxUint8:
+		buf.WriteByte(0xcc)
+		buf.WriteByte(byte(i))
+	case i >= 0 && i <= math.MaxUint16:
+		buf.WriteByte(0xcd)
+		buf.Write(binary.BigEndian.AppendUint16(nil, uint16(i)))
+	case i >= 0 && i <= math.MaxUint32:
+		buf.WriteByte(0xce)
+		buf.Write(binary.BigEndian.AppendUint32(nil, uint32(i)))
+	case i >= math.MinInt8 && i < 0:
+		buf.WriteByte(0xd0)
+		buf.WriteByte(byte(int8(i)))
+	case i >= math.MinInt16 && i < 0:
+		buf.WriteByte(0xd1)
+		buf.Write(binary.BigEndian.AppendUint16(nil, uint16(int16(i))))
+	case i >= math.MinInt32 && i < 0:
+		buf.WriteByte(0xd2)
+		buf.Write(binary.BigEndian.AppendUint32(nil, uint32(int32(i))))
+	default:
+		buf.WriteByte(0xd3)
+		buf.Write(binary.BigEndian.AppendUint64(nil, uint64(i)))
+	}
+}
@@ -0,0 +1,109 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/OpenSlides/openslides-projector-service/pkg/projector"
+)
+
+func TestEncodeMsgpackEvent(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		event    *projector.ProjectorUpdateEvent
+		expected string
+	}{
+		{
+			"json payload",
+			&projector.ProjectorUpdateEvent{ID: "3", Event: "e", Data: `{"a":[1,-1,-200,300,1.5,null,true,"x"]}`},
+			"83" + "a464617461" + "81a161" + "98" + "01" + "ff" + "d1ff38" + "cd012c" + "cb3ff8000000000000" + "c0" + "c3" + "a178" +
+				"a56576656e74" + "a165" + "a26964" + "a133",
+		},
+		{
+			"empty payload",
+			&projector.ProjectorUpdateEvent{Event: "e"},
+			"82" + "a464617461" + "c0" + "a56576656e74" + "a165",
+		},
+		{
+			"text payload",
+			&projector.ProjectorUpdateEvent{Event: "e", Data: "abc"},
+			"82" + "a464617461" + "a3616263" + "a56576656e74" + "a165",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			encoded, err := encodeMsgpackEvent(tt.event)
+			if err != nil {
+				t.Fatalf("encode event: %v", err)
+			}
+
+			if got := hex.EncodeToString(encoded); got != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestSubscribeMsgpack(t *testing.T) {
+	t.Chdir("../..")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s, _ := newTestProjectorHttp(t, ctx)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /system/projector/subscribe/{id}", s.ProjectorSubscribeHandler())
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/system/projector/subscribe/1", nil)
+	if err != nil {
+		t.Fatalf("create request: %v", err)
+	}
+	req.Header.Set("Accept", "application/msgpack")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if got := resp.Header.Get("Content-Type"); got != "application/msgpack" {
+		t.Fatalf("expected msgpack content type, got %q", got)
+	}
+
+	// The first event is connected with the unix time as data
+	frame := make([]byte, 27)
+	if _, err := io.ReadFull(resp.Body, frame); err != nil {
+		t.Fatalf("read first frame: %v", err)
+	}
+
+	if !bytes.HasPrefix(frame, []byte("\x82\xa4data\xce")) || !bytes.HasSuffix(frame, []byte("\xa5event\xa9connected")) {
+		t.Errorf("expected connected event as msgpack map, got %x", frame)
+	}
+}
+
+func TestEncodedOncePerFormat(t *testing.T) {
+	event := &projector.ProjectorUpdateEvent{Event: "e", Data: "1"}
+
+	calls := 0
+	encode := func(event *projector.ProjectorUpdateEvent) ([]byte, error) {
+		calls++
+		return encodeMsgpackEvent(event)
+	}
+
+	for range 3 {
+		if _, err := event.Encoded(formatMsgpack, encode); err != nil {
+			t.Fatalf("encode event: %v", err)
+		}
+	}
+
+	if calls != 1 {
+		t.Errorf("expected the event to be encoded once, got %d", calls)
+	}
+}
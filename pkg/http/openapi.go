@@ -0,0 +1,485 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"maps"
+	"net/http"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/OpenSlides/openslides-projector-service/pkg/projector"
+)
+
+// openAPIRoute describes a single route of the service. The schemas of the
+// bodies are derived from the go types used by the handlers.
+type openAPIRoute struct {
+	Path        string
+	Method      string
+	Summary     string
+	Query       map[string]string
+	RequestBody any
+	ContentType string
+	Response    any
+
+	// StringID marks the id path parameter as string instead of integer
+	StringID bool
+
+	// MeetingScoped routes are also served below
+	// /system/projector/meeting/{meeting_id}/
+	MeetingScoped bool
+
+	// Status is the status code of a successful response, 200 if unset
+	Status int
+}
+
+// openAPIRoutes are the routes registered by registerRoutes. The health
+// routes are moved to the configured path by openAPIRoutesFor.
+var openAPIRoutes = []openAPIRoute{
+	{
+		Path:        "/system/projector/health",
+		Method:      http.MethodGet,
+		Summary:     "Health check of the service",
+		ContentType: "application/json",
+		Response:    healthResponse{},
+	},
+	{
+		Path:        "/system/projector/health/live",
+		Method:      http.MethodGet,
+		Summary:     "Liveness check, does not check any dependency",
+		ContentType: "application/json",
+		Response:    healthResponse{},
+	},
+	{
+		Path:        "/system/projector/health/ready",
+		Method:      http.MethodGet,
+		Summary:     "Readiness check, answers with 503 if a dependency is not reachable",
+		ContentType: "application/json",
+		Response:    healthResponse{},
+	},
+	{
+		Path:        "/system/projector/openapi.json",
+		Method:      http.MethodGet,
+		Summary:     "This description of the service",
+		ContentType: "application/json",
+	},
+	{
+		Path:        "/system/projector/openapi.yaml",
+		Method:      http.MethodGet,
+		Summary:     "This description of the service as yaml document",
+		ContentType: "application/yaml",
+	},
+	{
+		Path:        "/system/projector/metrics",
+		Method:      http.MethodGet,
+		Summary:     "Update and render metrics of the projectors in the Prometheus text format",
+		ContentType: "text/plain",
+	},
+	{
+		Path:        "/system/projector/position",
+		Method:      http.MethodGet,
+		Summary:     "Newest change position of the datastore",
+		ContentType: "application/json",
+		Response:    positionResponse{},
+	},
+	{
+		Path:        "/system/projector/whoami",
+		Method:      http.MethodGet,
+		Summary:     "User resolved for the request and the projectors it may see, for debugging",
+		ContentType: "application/json",
+		Response:    whoamiResponse{},
+	},
+	{
+		Path:          "/system/projector/get/{id}",
+		Method:        http.MethodGet,
+		MeetingScoped: true,
+		Summary:       "Renders the projector page with its current content",
+		ContentType:   "text/html",
+		Query: map[string]string{
+			"lang":     "Language used for rendering the projector",
+			"position": "Render the projector as it was at this datastore position",
+			"wait":     "Seconds (at most 60) to wait for a change if the If-None-Match header matches, answered with 304 if nothing changed",
+		},
+	},
+	{
+		Path:          "/system/projector/current/{id}",
+		Method:        http.MethodGet,
+		MeetingScoped: true,
+		Summary:       "Projections currently shown on the projector without their rendered content",
+		ContentType:   "application/json",
+		Query: map[string]string{
+			"lang": "Language of the rendered content the hashes are computed from",
+		},
+		Response: currentResponse{},
+	},
+	{
+		Path:          "/system/projector/subscribe/{id}",
+		Method:        http.MethodGet,
+		MeetingScoped: true,
+		Summary:       "Server sent event stream with projector updates, resumed after the event given by the Last-Event-ID header. Clients accepting only application/msgpack receive the events as MessagePack maps",
+		ContentType:   "text/event-stream",
+		Query: map[string]string{
+			"format":      "html (default) for rendered content or data for the current projections in projector-data events",
+			"delta":       "json-patch to send changes of the data format as RFC 6902 JSON Patch in projector-data-patch events",
+			"init":        "Send the current content as first event if set to 1",
+			"client_id":   "Identifies the client, a new subscription of the same client to the projector closes the previous one",
+			"collections": "Comma separated list of collections to receive projection updates for",
+			"fields":      "Comma separated list of payload fields to send out of content, dimensions, theme and server_time",
+			"lang":        "Language used for rendering the projector",
+		},
+	},
+	{
+		Path:          "/system/projector/mirror/{id}",
+		Method:        http.MethodGet,
+		MeetingScoped: true,
+		Summary:       "Read-only mirror of the subscribe stream for satellite displays, starting with the current content",
+		ContentType:   "text/event-stream",
+		Query: map[string]string{
+			"lang": "Language used for rendering the projector",
+		},
+	},
+	{
+		Path:          "/system/projector/ws/{id}",
+		Method:        http.MethodGet,
+		MeetingScoped: true,
+		Summary:       "Websocket stream with projector updates as JSON text frames",
+		ContentType:   "application/json",
+		Response:      websocketMessage{},
+		Query: map[string]string{
+			"lang": "Language used for rendering the projector",
+		},
+	},
+	{
+		Path:        "/system/projector/media/{id}",
+		Method:      http.MethodGet,
+		Summary:     "Streams a mediafile from the media service, only available if MEDIA_PROXY is enabled",
+		ContentType: "application/octet-stream",
+	},
+	{
+		Path:        "/system/projector/stats",
+		Method:      http.MethodGet,
+		Summary:     "Subscriber counts of the projectors and memory usage, requires the X-Admin-Token header",
+		ContentType: "application/json",
+		Response:    statsResponse{},
+	},
+	{
+		Path:        "/system/projector/admin/subscriptions",
+		Method:      http.MethodGet,
+		Summary:     "Lists all active subscriptions, requires the X-Admin-Token header",
+		ContentType: "application/json",
+		Response:    subscriptionsResponse{},
+	},
+	{
+		Path:     "/system/projector/admin/subscriptions/{id}",
+		Method:   http.MethodDelete,
+		Summary:  "Closes a subscription, requires the X-Admin-Token header",
+		StringID: true,
+		Status:   http.StatusNoContent,
+	},
+	{
+		Path:        "/system/projector/preview",
+		Method:      http.MethodGet,
+		Summary:     "Renders the previews of multiple projectors of a meeting",
+		ContentType: "application/json",
+		Response:    bulkPreviewResponse{},
+		Query: map[string]string{
+			"ids":        "Comma separated list of projector ids",
+			"meeting_id": "Meeting all projectors have to belong to",
+			"lang":       "Language used for rendering the projectors, overrides cookies and Accept-Language. Unsupported languages are rejected",
+		},
+	},
+	{
+		Path:        "/system/projector/preview/{id}",
+		Method:      http.MethodPost,
+		Summary:     "Renders the projector with the given settings",
+		ContentType: "text/html",
+		RequestBody: projector.ProjectorPreviewSettings{},
+		Query: map[string]string{
+			"lang":     "Language used for rendering the projector, overrides cookies and Accept-Language. Unsupported languages are rejected",
+			"theme":    "Id of a theme to render the preview with instead of the organization theme",
+			"position": "Render the projector as it was at this datastore position",
+			"width":    "Width of the preview in pixels (16 to 8192), defaults to the width of the projector",
+			"height":   "Height of the preview in pixels (16 to 8192), follows the aspect ratio of the projector if unset",
+		},
+	},
+}
+
+// openAPIRoutesFor returns openAPIRoutes with the health routes below the
+// given health path.
+func openAPIRoutesFor(healthPath string) []openAPIRoute {
+	routes := slices.Clone(openAPIRoutes)
+	for i, route := range routes {
+		if rest, ok := strings.CutPrefix(route.Path, DefaultHealthPath); ok {
+			routes[i].Path = healthPath + rest
+		}
+	}
+
+	return routes
+}
+
+// OpenAPIHandler serves the description of the routes with the health routes
+// below healthPath.
+func (s *projectorHttp) OpenAPIHandler(healthPath string) http.HandlerFunc {
+	spec := buildOpenAPISpec(openAPIRoutesFor(healthPath))
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, spec)
+	}
+}
+
+// OpenAPIYAMLHandler serves the same description as OpenAPIHandler as yaml
+// document.
+func (s *projectorHttp) OpenAPIYAMLHandler(healthPath string) http.HandlerFunc {
+	spec := encodeYAML(buildOpenAPISpec(openAPIRoutesFor(healthPath)))
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/yaml")
+		w.Write(spec)
+	}
+}
+
+// plainYAMLKey matches keys which can be written without quotes.
+var plainYAMLKey = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$-]*$`)
+
+// encodeYAML writes maps, slices and scalars as block yaml with sorted keys.
+// Scalars are quoted like json strings, which is valid yaml.
+func encodeYAML(value any) []byte {
+	var b bytes.Buffer
+	writeYAML(&b, reflect.ValueOf(value), 0)
+	return append(bytes.TrimPrefix(b.Bytes(), []byte("\n")), '\n')
+}
+
+func writeYAML(b *bytes.Buffer, v reflect.Value, indent int) {
+	for v.Kind() == reflect.Interface || v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			b.WriteString(" null")
+			return
+		}
+		v = v.Elem()
+	}
+
+	prefix := "\n" + strings.Repeat(" ", indent)
+	switch v.Kind() {
+	case reflect.Map:
+		if v.Len() == 0 {
+			b.WriteString(" {}")
+			return
+		}
+
+		keys := make([]string, 0, v.Len())
+		for _, key := range v.MapKeys() {
+			keys = append(keys, key.String())
+		}
+		slices.Sort(keys)
+
+		for _, key := range keys {
+			name := key
+			if !plainYAMLKey.MatchString(key) {
+				encoded, _ := json.Marshal(key)
+				name = string(encoded)
+			}
+			b.WriteString(prefix + name + ":")
+			writeYAML(b, v.MapIndex(reflect.ValueOf(key).Convert(v.Type().Key())), indent+2)
+		}
+	case reflect.Slice, reflect.Array:
+		if v.Len() == 0 {
+			b.WriteString(" []")
+			return
+		}
+
+		for i := 0; i < v.Len(); i++ {
+			b.WriteString(prefix + "-")
+			writeYAML(b, v.Index(i), indent+2)
+		}
+	default:
+		encoded, err := json.Marshal(v.Interface())
+		if err != nil {
+			encoded = []byte("null")
+		}
+		b.WriteString(" ")
+		b.Write(encoded)
+	}
+}
+
+func buildOpenAPISpec(routes []openAPIRoute) map[string]any {
+	schemas := map[string]any{
+		"errorResponse": openAPISchema(reflect.TypeOf(errorResponse{})),
+	}
+	errorRef := map[string]any{
+		"description": "Error",
+		"content": map[string]any{
+			"application/json": map[string]any{
+				"schema": map[string]any{"$ref": "#/components/schemas/errorResponse"},
+			},
+		},
+	}
+
+	var expanded []openAPIRoute
+	for _, route := range routes {
+		expanded = append(expanded, route)
+		if route.MeetingScoped {
+			scoped := route
+			scoped.Path = strings.Replace(route.Path, "/system/projector/", "/system/projector/meeting/{meeting_id}/", 1)
+			expanded = append(expanded, scoped)
+		}
+	}
+
+	paths := map[string]map[string]any{}
+	for _, route := range expanded {
+		parameters := []any{}
+		if strings.Contains(route.Path, "{meeting_id}") {
+			parameters = append(parameters, map[string]any{
+				"name":     "meeting_id",
+				"in":       "path",
+				"required": true,
+				"schema":   map[string]any{"type": "integer"},
+			})
+		}
+
+		if strings.Contains(route.Path, "{id}") {
+			idType := "integer"
+			if route.StringID {
+				idType = "string"
+			}
+
+			parameters = append(parameters, map[string]any{
+				"name":     "id",
+				"in":       "path",
+				"required": true,
+				"schema":   map[string]any{"type": idType},
+			})
+		}
+
+		// Sorted so the document does not change between requests
+		for _, name := range slices.Sorted(maps.Keys(route.Query)) {
+			parameters = append(parameters, map[string]any{
+				"name":        name,
+				"in":          "query",
+				"description": route.Query[name],
+				"schema":      map[string]any{"type": "string"},
+			})
+		}
+
+		status := http.StatusOK
+		if route.Status != 0 {
+			status = route.Status
+		}
+
+		success := map[string]any{"description": http.StatusText(status)}
+		content := map[string]any{}
+		if route.Response != nil {
+			name := reflect.TypeOf(route.Response).Name()
+			schemas[name] = openAPISchema(reflect.TypeOf(route.Response))
+			content["schema"] = map[string]any{"$ref": "#/components/schemas/" + name}
+		}
+		if route.ContentType != "" {
+			success["content"] = map[string]any{route.ContentType: content}
+		}
+
+		operation := map[string]any{
+			"summary":    route.Summary,
+			"parameters": parameters,
+			"responses": map[string]any{
+				strconv.Itoa(status): success,
+				"default":            errorRef,
+			},
+		}
+
+		if route.RequestBody != nil {
+			name := reflect.TypeOf(route.RequestBody).Name()
+			schemas[name] = openAPISchema(reflect.TypeOf(route.RequestBody))
+			operation["requestBody"] = map[string]any{
+				"required": true,
+				"content": map[string]any{
+					"application/json": map[string]any{
+						"schema": map[string]any{"$ref": "#/components/schemas/" + name},
+					},
+				},
+			}
+		}
+
+		// Routes of different methods share the path item
+		if _, ok := paths[route.Path]; !ok {
+			paths[route.Path] = map[string]any{}
+		}
+		paths[route.Path][strings.ToLower(route.Method)] = operation
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":   "OpenSlides Projector Service",
+			"version": "1.0.0",
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": schemas,
+		},
+	}
+}
+
+// openAPISchema creates a json schema for the given type by using the same
+// rules as encoding/json.
+func openAPISchema(t reflect.Type) map[string]any {
+	if t == reflect.TypeFor[time.Time]() {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		schema := openAPISchema(t.Elem())
+		schema["nullable"] = true
+		return schema
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": openAPISchema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": openAPISchema(t.Elem())}
+	case reflect.Struct:
+		properties := map[string]any{}
+		required := []string{}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+
+			name := field.Name
+			omitEmpty := false
+			if tag, ok := field.Tag.Lookup("json"); ok {
+				tagName, opts, _ := strings.Cut(tag, ",")
+				if tagName == "-" {
+					continue
+				}
+
+				if tagName != "" {
+					name = tagName
+				}
+				omitEmpty = strings.Contains(opts, "omitempty")
+			}
+
+			properties[name] = openAPISchema(field.Type)
+			if !omitEmpty {
+				required = append(required, name)
+			}
+		}
+
+		schema := map[string]any{"type": "object", "properties": properties}
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+		return schema
+	}
+
+	return map[string]any{}
+}
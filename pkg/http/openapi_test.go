@@ -0,0 +1,216 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func loadOpenAPISpec(t *testing.T) map[string]any {
+	rec := httptest.NewRecorder()
+	s := &projectorHttp{}
+	s.OpenAPIHandler(DefaultHealthPath)(rec, httptest.NewRequest(http.MethodGet, "/system/projector/openapi.json", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+
+	var spec map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &spec); err != nil {
+		t.Fatalf("parse spec: %v", err)
+	}
+
+	return spec
+}
+
+// validateAgainstSchema checks the value against the schema. References are
+// resolved in the components of the spec.
+func validateAgainstSchema(t *testing.T, spec map[string]any, schema map[string]any, value any) {
+	t.Helper()
+
+	if ref, ok := schema["$ref"].(string); ok {
+		name := strings.TrimPrefix(ref, "#/components/schemas/")
+		schema, ok = spec["components"].(map[string]any)["schemas"].(map[string]any)[name].(map[string]any)
+		if !ok {
+			t.Fatalf("schema %s missing", ref)
+		}
+	}
+
+	if value == nil && schema["nullable"] == true {
+		return
+	}
+
+	switch schema["type"] {
+	case "object":
+		obj, ok := value.(map[string]any)
+		if !ok {
+			t.Fatalf("expected object, got %T", value)
+		}
+
+		properties, _ := schema["properties"].(map[string]any)
+		required, _ := schema["required"].([]any)
+		for _, name := range required {
+			if _, ok := obj[name.(string)]; !ok {
+				t.Errorf("required property %s missing", name)
+			}
+		}
+
+		for name, val := range obj {
+			propSchema, ok := properties[name].(map[string]any)
+			if !ok {
+				t.Errorf("property %s not described in schema", name)
+				continue
+			}
+			validateAgainstSchema(t, spec, propSchema, val)
+		}
+	case "array":
+		items, ok := value.([]any)
+		if !ok {
+			t.Fatalf("expected array, got %T", value)
+		}
+
+		itemSchema, _ := schema["items"].(map[string]any)
+		for _, item := range items {
+			validateAgainstSchema(t, spec, itemSchema, item)
+		}
+	case "string":
+		if _, ok := value.(string); !ok {
+			t.Errorf("expected string, got %T", value)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			t.Errorf("expected boolean, got %T", value)
+		}
+	case "integer", "number":
+		if _, ok := value.(float64); !ok {
+			t.Errorf("expected number, got %T", value)
+		}
+	}
+}
+
+func TestOpenAPISpecDescribesRoutes(t *testing.T) {
+	s := &projectorHttp{serverMux: http.NewServeMux()}
+	s.registerRoutes(ProjectorConfig{HealthPath: "/status", MediaProxy: true, AdminToken: "secret"})
+
+	rec := httptest.NewRecorder()
+	s.serverMux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/system/projector/openapi.json", nil))
+
+	var spec struct {
+		Paths map[string]map[string]any `json:"paths"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &spec); err != nil {
+		t.Fatalf("parse spec: %v", err)
+	}
+
+	described := map[string]bool{}
+	for path, item := range spec.Paths {
+		for method := range item {
+			described[strings.ToUpper(method)+" "+path] = true
+		}
+	}
+
+	registered := map[string]bool{}
+	for _, pattern := range s.patterns {
+		registered[pattern] = true
+		if !described[pattern] {
+			t.Errorf("route %s missing in spec", pattern)
+		}
+	}
+
+	for route := range described {
+		if !registered[route] {
+			t.Errorf("route %s in spec is not registered", route)
+		}
+	}
+}
+
+func TestOpenAPISpecCurrentResponse(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s, flow := newTestProjectorHttp(t, ctx)
+	for key, value := range map[string]string{
+		"projector/1/current_projection_ids": "[5]",
+		"projection/5/id":                    "5",
+		"projection/5/meeting_id":            "1",
+		"projection/5/content_object_id":     `"meeting/1"`,
+		"projection/5/type":                  `"agenda_item_list"`,
+	} {
+		flow.Set(key, []byte(value))
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /system/projector/current/{id}", s.ProjectorCurrentHandler())
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/system/projector/current/1", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var body any
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("parse response: %v", err)
+	}
+
+	spec := loadOpenAPISpec(t)
+	operation := spec["paths"].(map[string]any)["/system/projector/current/{id}"].(map[string]any)["get"].(map[string]any)
+	content := operation["responses"].(map[string]any)["200"].(map[string]any)["content"].(map[string]any)
+	schema := content["application/json"].(map[string]any)["schema"].(map[string]any)
+
+	if projections, _ := body.(map[string]any)["projections"].([]any); len(projections) != 1 {
+		t.Fatalf("expected one projection, got %s", rec.Body.String())
+	}
+	validateAgainstSchema(t, spec, schema, body)
+}
+
+func TestOpenAPISpecErrorResponse(t *testing.T) {
+	spec := loadOpenAPISpec(t)
+	schemas := spec["components"].(map[string]any)["schemas"].(map[string]any)
+	schema, ok := schemas["errorResponse"].(map[string]any)
+	if !ok {
+		t.Fatalf("error response schema missing")
+	}
+
+	rec := httptest.NewRecorder()
+	writeError(rec, http.StatusNotFound, "Projector not found")
+
+	var body any
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("parse response: %v", err)
+	}
+
+	validateAgainstSchema(t, spec, schema, body)
+}
+
+func TestOpenAPISpecYAML(t *testing.T) {
+	rec := httptest.NewRecorder()
+	s := &projectorHttp{}
+	s.OpenAPIYAMLHandler(DefaultHealthPath)(rec, httptest.NewRequest(http.MethodGet, "/system/projector/openapi.yaml", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/yaml" {
+		t.Fatalf("expected yaml with status 200, got %d %s", rec.Code, rec.Header().Get("Content-Type"))
+	}
+
+	body := rec.Body.String()
+	for _, line := range []string{
+		`openapi: "3.0.3"`,
+		`  "/system/projector/subscribe/{id}":`,
+		`                $ref: "#/components/schemas/errorResponse"`,
+	} {
+		if !strings.Contains(body, "\n"+line+"\n") && !strings.HasPrefix(body, line+"\n") {
+			t.Errorf("expected line %q in spec", line)
+		}
+	}
+
+	encoded := string(encodeYAML(map[string]any{
+		"b":     []any{map[string]any{"name": "id", "required": true}, "x"},
+		"a":     map[string]int{"200": 1},
+		"empty": []string{},
+	}))
+	expected := "a:\n  \"200\": 1\nb:\n  -\n    name: \"id\"\n    required: true\n  - \"x\"\nempty: []\n"
+	if encoded != expected {
+		t.Errorf("expected yaml\n%s\ngot\n%s", expected, encoded)
+	}
+}
@@ -0,0 +1,57 @@
+package http
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// LoadOutboundTLSConfig returns the TLS config of requests to the restricter,
+// the media service and the change webhook. The certificates in caFile are
+// trusted in addition to the system roots, e.g. for services using an
+// internal CA. insecureSkipVerify disables the verification of certificates
+// and must only be used in development. Returns nil if neither is set, so
+// the defaults are used.
+func LoadOutboundTLSConfig(caFile string, insecureSkipVerify bool) (*tls.Config, error) {
+	if caFile == "" && !insecureSkipVerify {
+		return nil, nil
+	}
+
+	config := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: insecureSkipVerify,
+	}
+
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading CA file: %w", err)
+		}
+
+		roots, err := x509.SystemCertPool()
+		if err != nil {
+			roots = x509.NewCertPool()
+		}
+
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", caFile)
+		}
+		config.RootCAs = roots
+	}
+
+	return config, nil
+}
+
+// newOutboundClient returns a client using the TLS config for HTTPS
+// requests. Without a TLS config the default transport is used.
+func newOutboundClient(tlsConfig *tls.Config) *http.Client {
+	if tlsConfig == nil {
+		return &http.Client{}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	return &http.Client{Transport: transport}
+}
@@ -0,0 +1,66 @@
+package http
+
+import (
+	"context"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestOutboundTLSConfig(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"projector/1/id":1}`)
+	}))
+	defer srv.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(caFile, ca, 0o600); err != nil {
+		t.Fatalf("write CA file: %v", err)
+	}
+
+	tlsConfig, err := LoadOutboundTLSConfig(caFile, false)
+	if err != nil {
+		t.Fatalf("load CA file: %v", err)
+	}
+
+	client := newOutboundClient(tlsConfig)
+	if transport, ok := client.Transport.(*http.Transport); !ok || transport.TLSClientConfig != tlsConfig {
+		t.Fatalf("expected the client transport to use the loaded TLS config")
+	}
+
+	restricter := newRestricter([]string{srv.URL}, time.Minute)
+	restricter.retryDelay = 0
+	if allowed, err := restricter.CanSeeProjector(context.Background(), 1, 1); err == nil || allowed {
+		t.Errorf("expected the internal CA not to be trusted by default, got %v, %v", allowed, err)
+	}
+
+	// A new restricter, the failed endpoint is skipped for a while
+	restricter = newRestricter([]string{srv.URL}, time.Minute)
+	restricter.client = client
+	if allowed, err := restricter.CanSeeProjector(context.Background(), 1, 1); err != nil || !allowed {
+		t.Errorf("expected the restricter to trust the internal CA, got %v, %v", allowed, err)
+	}
+
+	insecure, err := LoadOutboundTLSConfig("", true)
+	if err != nil || insecure == nil || !insecure.InsecureSkipVerify {
+		t.Errorf("expected a config skipping verification, got %v, %v", insecure, err)
+	}
+
+	if config, err := LoadOutboundTLSConfig("", false); err != nil || config != nil {
+		t.Errorf("expected no config without CA file, got %v, %v", config, err)
+	}
+
+	invalid := filepath.Join(t.TempDir(), "invalid.pem")
+	if err := os.WriteFile(invalid, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatalf("write invalid CA file: %v", err)
+	}
+	if _, err := LoadOutboundTLSConfig(invalid, false); err == nil {
+		t.Errorf("expected a CA file without certificates to be rejected")
+	}
+}
@@ -0,0 +1,146 @@
+package http
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+
+	"github.com/OpenSlides/openslides-projector-service/pkg/projector"
+	"github.com/rs/zerolog"
+)
+
+// Top level fields of the subscribe payload which can be selected with the
+// fields query parameter.
+const (
+	payloadFieldContent    = "content"
+	payloadFieldDimensions = "dimensions"
+	payloadFieldTheme      = "theme"
+	payloadFieldServerTime = "server_time"
+)
+
+var payloadFields = []string{payloadFieldContent, payloadFieldDimensions, payloadFieldTheme, payloadFieldServerTime}
+
+// contentEvents carry the content of the projector.
+var contentEvents = append([]string{"projection-view", "projector-data", "slide_error", "organization-message"}, htmlEvents...)
+
+// Keys of the settings event belonging to the dimensions and theme fields.
+var (
+	dimensionSettings = []string{"Scale", "Scroll", "Width", "AspectRatioNumerator", "AspectRatioDenominator"}
+	themeSettings     = []string{
+		"Color", "BackgroundColor", "HeaderBackgroundColor", "HeaderFontColor", "HeaderH1Color",
+		"ChyronBackgroundColor", "ChyronBackgroundColor2", "ChyronFontColor", "ChyronFontColor2",
+		"Theme", "Palette",
+	}
+)
+
+// payloadFilter removes the payload fields a client did not select. A nil
+// filter keeps all fields.
+type payloadFilter map[string]bool
+
+// parsePayloadFields parses a comma separated list of payload fields. Unknown
+// fields are ignored with a warning. Returns nil if no fields are given.
+func parsePayloadFields(raw string, logger zerolog.Logger) payloadFilter {
+	if strings.TrimSpace(raw) == "" {
+		return nil
+	}
+
+	filter := payloadFilter{}
+	for _, field := range strings.Split(raw, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+
+		if !slices.Contains(payloadFields, field) {
+			logger.Warn().Str("field", field).Msg("ignoring unknown payload field")
+			continue
+		}
+
+		filter[field] = true
+	}
+
+	return filter
+}
+
+func (f payloadFilter) includes(field string) bool {
+	return f == nil || f[field]
+}
+
+// apply returns the event with only the selected fields. Returns nil if
+// nothing of the event is left to send.
+func (f payloadFilter) apply(event *projector.ProjectorUpdateEvent) *projector.ProjectorUpdateEvent {
+	if f == nil {
+		return event
+	}
+
+	switch {
+	case slices.Contains(contentEvents, event.Event):
+		if !f.includes(payloadFieldContent) {
+			return nil
+		}
+	case event.Event == "transform":
+		if !f.includes(payloadFieldDimensions) {
+			return nil
+		}
+	case event.Event == "settings":
+		var removed []string
+		if !f.includes(payloadFieldDimensions) {
+			removed = append(removed, dimensionSettings...)
+		}
+		if !f.includes(payloadFieldTheme) {
+			removed = append(removed, themeSettings...)
+		}
+		return withoutKeys(event, removed)
+	case event.Event == "speaker-countdown" || event.Event == "tick":
+		if !f.includes(payloadFieldServerTime) {
+			return withoutKeys(event, []string{"server_time"})
+		}
+	case event.Event == "countdowns":
+		if !f.includes(payloadFieldServerTime) {
+			return withoutNestedKeys(event, []string{"server_time"})
+		}
+	}
+
+	return event
+}
+
+// withoutKeys removes keys from the json object of the event data. The event
+// is returned unchanged if the data is no json object.
+func withoutKeys(event *projector.ProjectorUpdateEvent, keys []string) *projector.ProjectorUpdateEvent {
+	var data map[string]json.RawMessage
+	if len(keys) == 0 || json.Unmarshal([]byte(event.Data), &data) != nil || data == nil {
+		return event
+	}
+
+	for _, key := range keys {
+		delete(data, key)
+	}
+
+	return withData(event, data)
+}
+
+// withoutNestedKeys removes keys from all json objects within the json object
+// of the event data.
+func withoutNestedKeys(event *projector.ProjectorUpdateEvent, keys []string) *projector.ProjectorUpdateEvent {
+	var data map[string]map[string]json.RawMessage
+	if json.Unmarshal([]byte(event.Data), &data) != nil || data == nil {
+		return event
+	}
+
+	for _, nested := range data {
+		for _, key := range keys {
+			delete(nested, key)
+		}
+	}
+
+	return withData(event, data)
+}
+
+func withData(event *projector.ProjectorUpdateEvent, data any) *projector.ProjectorUpdateEvent {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return event
+	}
+
+	return &projector.ProjectorUpdateEvent{ID: event.ID, Event: event.Event, Data: string(encoded)}
+}
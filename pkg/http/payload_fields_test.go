@@ -0,0 +1,85 @@
+package http
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/OpenSlides/openslides-projector-service/pkg/projector"
+	"github.com/rs/zerolog"
+)
+
+func TestParsePayloadFields(t *testing.T) {
+	if filter := parsePayloadFields("", zerolog.Nop()); filter != nil {
+		t.Errorf("expected no filter without fields, got %v", filter)
+	}
+
+	filter := parsePayloadFields("theme, unknown,,server_time", zerolog.Nop())
+	if len(filter) != 2 || !filter.includes(payloadFieldTheme) || !filter.includes(payloadFieldServerTime) {
+		t.Errorf("expected theme and server_time, got %v", filter)
+	}
+}
+
+func TestPayloadFilter(t *testing.T) {
+	settings := `{"Name":"Main","Width":1200,"Scroll":0,"Color":"#000000","Palette":{"Primary":"#317796"}}`
+	countdowns := `{"1":{"countdown_time":60,"default_time":60,"running":false,"server_time":1700000000}}`
+
+	for _, tt := range []struct {
+		name   string
+		fields string
+		event  *projector.ProjectorUpdateEvent
+		keys   []string
+	}{
+		{"all fields", "", &projector.ProjectorUpdateEvent{Event: "settings", Data: settings}, []string{"Name", "Width", "Scroll", "Color", "Palette"}},
+		{"settings without theme", "content,dimensions", &projector.ProjectorUpdateEvent{Event: "settings", Data: settings}, []string{"Name", "Width", "Scroll"}},
+		{"settings without dimensions", "theme", &projector.ProjectorUpdateEvent{Event: "settings", Data: settings}, []string{"Name", "Color", "Palette"}},
+		{"speaker countdown without server time", "content", &projector.ProjectorUpdateEvent{Event: "speaker-countdown", Data: `{"running":true,"server_time":1700000000}`}, []string{"running"}},
+		{"speaker countdown ended", "content", &projector.ProjectorUpdateEvent{Event: "speaker-countdown", Data: `null`}, nil},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			event := parsePayloadFields(tt.fields, zerolog.Nop()).apply(tt.event)
+			if event == nil {
+				t.Fatalf("expected event to be sent")
+			}
+
+			var data map[string]json.RawMessage
+			if err := json.Unmarshal([]byte(event.Data), &data); err != nil {
+				t.Fatalf("parse event data: %v", err)
+			}
+
+			if len(data) != len(tt.keys) {
+				t.Errorf("expected keys %v, got %s", tt.keys, event.Data)
+			}
+			for _, key := range tt.keys {
+				if _, ok := data[key]; !ok {
+					t.Errorf("expected key %s, got %s", key, event.Data)
+				}
+			}
+		})
+	}
+
+	t.Run("countdowns without server time", func(t *testing.T) {
+		event := parsePayloadFields("content", zerolog.Nop()).apply(&projector.ProjectorUpdateEvent{Event: "countdowns", Data: countdowns})
+
+		var data map[int]map[string]json.RawMessage
+		if err := json.Unmarshal([]byte(event.Data), &data); err != nil {
+			t.Fatalf("parse event data: %v", err)
+		}
+
+		if _, ok := data[1]["server_time"]; ok || len(data[1]) != 3 {
+			t.Errorf("expected countdown without server time, got %s", event.Data)
+		}
+	})
+
+	t.Run("dropped events", func(t *testing.T) {
+		filter := parsePayloadFields("server_time", zerolog.Nop())
+		for _, name := range []string{"projector-replace", "projection-updated", "projector-data", "transform"} {
+			if event := filter.apply(&projector.ProjectorUpdateEvent{Event: name, Data: "{}"}); event != nil {
+				t.Errorf("expected %s to be dropped", name)
+			}
+		}
+
+		if event := filter.apply(&projector.ProjectorUpdateEvent{Event: "stale", Data: `{"stale":true}`}); event == nil {
+			t.Errorf("expected stale event to be sent")
+		}
+	})
+}
@@ -0,0 +1,40 @@
+package http
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	requestIDHeader    = "X-Request-ID"
+	requestIDMaxLength = 128
+)
+
+// RequestIDMiddleware assigns a request id to every request. An id sent by a
+// proxy is reused, otherwise a new one is generated. The id is returned in the
+// response header and attached to the logger of the request context, which
+// can be retrieved with log.Ctx.
+func RequestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(requestIDHeader)
+		if requestID == "" || len(requestID) > requestIDMaxLength {
+			requestID = newRequestID()
+		}
+
+		w.Header().Set(requestIDHeader, requestID)
+		logger := log.With().Str("request_id", requestID).Logger()
+		next.ServeHTTP(w, r.WithContext(logger.WithContext(r.Context())))
+	})
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,425 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/OpenSlides/openslides-projector-service/pkg/tracing"
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	restricterRetries    = 2
+	restricterRetryDelay = 200 * time.Millisecond
+
+	// restricterMaxResponseSize is the largest restricter response that is
+	// read. The response only contains the ids of the requested projectors.
+	restricterMaxResponseSize = 1 << 20
+
+	// restricterEndpointTimeout limits a single request to one restricter
+	// endpoint so a hanging instance does not delay the failover.
+	restricterEndpointTimeout = 2 * time.Second
+
+	// After restricterBreakerThreshold consecutive failures an endpoint is
+	// skipped for restricterBreakerCooldown.
+	restricterBreakerThreshold = 3
+	restricterBreakerCooldown  = 10 * time.Second
+
+	// restricterMaxRemembered is the number of allow decisions kept for
+	// outages. If more are allowed the oldest is forgotten.
+	restricterMaxRemembered = 10_000
+)
+
+// errRestricterUnavailable is returned if the restricter could not be reached
+// and no recent allow decision is cached.
+var errRestricterUnavailable = errors.New("restricter unavailable")
+
+// restricterStatusError is returned if the restricter answered with a status
+// that is neither a success nor a server error.
+type restricterStatusError struct {
+	status int
+}
+
+func (e restricterStatusError) Error() string {
+	return fmt.Sprintf("restricter responded with status %d", e.status)
+}
+
+// restricterEndpoint is one instance of the restricter with its circuit
+// breaker state. The state is guarded by the mutex of the restricter.
+type restricterEndpoint struct {
+	url       string
+	failures  int
+	openUntil time.Time
+}
+
+type restrictionKey struct {
+	userID      int
+	meetingID   int
+	projectorID int
+}
+
+// restricter asks the autoupdate service whether a user can see a projector.
+//
+// The endpoints are tried in order. If an endpoint cannot be reached or
+// answers with a server error the next one is asked. Endpoints failing
+// repeatedly are skipped for a cooldown.
+//
+// Allow decisions are remembered so they can be reused for staleWindow if the
+// restricter is temporarily unreachable. Deny decisions are never cached and
+// remove a remembered allow decision. Expired decisions are removed at least
+// once per staleWindow and at most maxRemembered decisions are kept.
+type restricter struct {
+	endpoints       []*restricterEndpoint
+	client          *http.Client
+	staleWindow     time.Duration
+	retryDelay      time.Duration
+	endpointTimeout time.Duration
+	now             func() time.Time
+
+	// maxResponseSize is the largest response body in bytes that is read.
+	// Larger responses are rejected.
+	maxResponseSize int64
+
+	mu            sync.Mutex
+	allowed       map[restrictionKey]time.Time
+	maxRemembered int
+	lastSweep     time.Time
+}
+
+func newRestricter(urls []string, staleWindow time.Duration) *restricter {
+	endpoints := make([]*restricterEndpoint, len(urls))
+	for i, url := range urls {
+		endpoints[i] = &restricterEndpoint{url: url}
+	}
+
+	return &restricter{
+		endpoints:       endpoints,
+		client:          &http.Client{},
+		staleWindow:     staleWindow,
+		retryDelay:      restricterRetryDelay,
+		endpointTimeout: restricterEndpointTimeout,
+		now:             time.Now,
+		maxResponseSize: restricterMaxResponseSize,
+		allowed:         make(map[restrictionKey]time.Time),
+		maxRemembered:   restricterMaxRemembered,
+	}
+}
+
+// CanSeeProjector returns true if the user is allowed to see the projector.
+func (r *restricter) CanSeeProjector(ctx context.Context, userID int, projectorID int) (bool, error) {
+	visible, err := r.VisibleProjectors(ctx, userID, []int{projectorID})
+	if err != nil {
+		return false, err
+	}
+
+	return visible[projectorID], nil
+}
+
+// CanSeeMeetingProjector returns true if the user is allowed to see the
+// projector and the projector belongs to the meeting.
+func (r *restricter) CanSeeMeetingProjector(ctx context.Context, userID int, meetingID int, projectorID int) (bool, error) {
+	visible, err := r.visibleProjectors(ctx, userID, meetingID, []int{projectorID})
+	if err != nil {
+		return false, err
+	}
+
+	return visible[projectorID], nil
+}
+
+// VisibleProjectors checks with a single request which of the projectors the
+// user is allowed to see.
+func (r *restricter) VisibleProjectors(ctx context.Context, userID int, projectorIDs []int) (map[int]bool, error) {
+	return r.visibleProjectors(ctx, userID, 0, projectorIDs)
+}
+
+// visibleProjectors checks which of the projectors the user is allowed to
+// see. If meetingID is not 0, only projectors of this meeting are visible.
+func (r *restricter) visibleProjectors(ctx context.Context, userID int, meetingID int, projectorIDs []int) (map[int]bool, error) {
+	var visible map[int]bool
+	var err error
+	for attempt := 0; attempt <= restricterRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(r.retryDelay):
+			}
+		}
+
+		visible, err = r.request(ctx, userID, meetingID, projectorIDs)
+		if !errors.Is(err, errRestricterUnavailable) {
+			break
+		}
+	}
+
+	if err == nil {
+		for _, id := range projectorIDs {
+			r.remember(restrictionKey{userID: userID, meetingID: meetingID, projectorID: id}, visible[id])
+		}
+		return visible, nil
+	}
+
+	if !errors.Is(err, errRestricterUnavailable) {
+		return nil, err
+	}
+
+	visible = make(map[int]bool, len(projectorIDs))
+	for _, id := range projectorIDs {
+		if !r.recentlyAllowed(restrictionKey{userID: userID, meetingID: meetingID, projectorID: id}) {
+			return nil, err
+		}
+		visible[id] = true
+	}
+
+	log.Warn().Err(err).Msgf("using cached restriction for projectors %v and user %d", projectorIDs, userID)
+	return visible, nil
+}
+
+// request asks the endpoints in order until one of them is available.
+func (r *restricter) request(ctx context.Context, userID int, meetingID int, projectorIDs []int) (map[int]bool, error) {
+	err := fmt.Errorf("%w: all endpoints are skipped after repeated failures", errRestricterUnavailable)
+	for _, endpoint := range r.endpoints {
+		if !r.endpointReady(endpoint) {
+			continue
+		}
+
+		var visible map[int]bool
+		visible, err = r.requestEndpoint(ctx, endpoint.url, userID, meetingID, projectorIDs)
+		if ctx.Err() != nil {
+			return nil, err
+		}
+
+		r.recordEndpoint(endpoint, err)
+		if !errors.Is(err, errRestricterUnavailable) {
+			return visible, err
+		}
+
+		log.Warn().Err(err).Str("url", endpoint.url).Msg("restricter endpoint failed")
+	}
+
+	return nil, err
+}
+
+func (r *restricter) requestEndpoint(ctx context.Context, url string, userID int, meetingID int, projectorIDs []int) (_ map[int]bool, err error) {
+	ctx, span := tracing.Start(ctx, "restricter", attribute.String("url", url), attribute.IntSlice("projector_ids", projectorIDs))
+	defer func() { tracing.End(span, err) }()
+	defer tracing.Measure(ctx, "restricter")()
+
+	ctx, cancel := context.WithTimeout(ctx, r.endpointTimeout)
+	defer cancel()
+
+	ids, err := json.Marshal(projectorIDs)
+	if err != nil {
+		return nil, fmt.Errorf("encoding projector ids: %w", err)
+	}
+
+	// Scoped requests also need the meeting of the projectors as seen by
+	// the user.
+	fields := `{"id": null}`
+	if meetingID != 0 {
+		fields = `{"id": null, "meeting_id": null}`
+	}
+
+	// TODO: Listen for permission changes
+	body := []byte(fmt.Sprintf(`[{"collection": "projector", "ids":%s, "fields": %s}]`, ids, fields))
+	restrictUrl := fmt.Sprintf("%s?user_id=%d&single=1", url, userID)
+	req, err := http.NewRequestWithContext(ctx, "POST", restrictUrl, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("creating restriction request: %w", err)
+	}
+
+	req.Header = http.Header{
+		"Content-Type": {"application/json"},
+	}
+	tracing.Inject(ctx, req.Header)
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errRestricterUnavailable, err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			log.Err(err).Msg("error closing response body")
+		}
+	}()
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return nil, fmt.Errorf("%w: status %d", errRestricterUnavailable, resp.StatusCode)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, restricterStatusError{status: resp.StatusCode}
+	}
+
+	b, err := io.ReadAll(io.LimitReader(resp.Body, r.maxResponseSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: reading response: %w", errRestricterUnavailable, err)
+	}
+
+	if int64(len(b)) > r.maxResponseSize {
+		log.Warn().Int64("limit", r.maxResponseSize).Msg("restricter response is too large")
+		return nil, fmt.Errorf("restricter response exceeds %d bytes", r.maxResponseSize)
+	}
+
+	restricted, err := decodeRestrictedFields(b)
+	if err != nil {
+		return nil, fmt.Errorf("decoding restricter response: %w", err)
+	}
+
+	restrictedValue := func(key string, expected int) bool {
+		var value int
+		raw, ok := restricted[key]
+		return ok && json.Unmarshal(raw, &value) == nil && value == expected
+	}
+
+	visible := make(map[int]bool, len(projectorIDs))
+	for _, id := range projectorIDs {
+		visible[id] = restrictedValue(fmt.Sprintf("projector/%d/id", id), id)
+		if meetingID != 0 {
+			visible[id] = visible[id] && restrictedValue(fmt.Sprintf("projector/%d/meeting_id", id), meetingID)
+		}
+	}
+
+	return visible, nil
+}
+
+// decodeRestrictedFields returns the visible fields of a restricter response
+// by their key, e.g. "projector/1/id". The restricter answers with an object
+// keyed by collection/id/field. A list of such objects, as sent for batched
+// requests, and fields nested by collection and id, e.g.
+// {"projector":{"1":{"id":1}}}, are decoded to the same keys. Only the
+// structure is interpreted, a key appearing inside a value never makes a
+// field visible.
+func decodeRestrictedFields(b []byte) (map[string]json.RawMessage, error) {
+	var objects []map[string]json.RawMessage
+	if err := json.Unmarshal(b, &objects); err != nil {
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal(b, &object); err != nil {
+			return nil, err
+		}
+		objects = []map[string]json.RawMessage{object}
+	}
+
+	restricted := make(map[string]json.RawMessage)
+	for _, object := range objects {
+		collectRestrictedFields("", object, restricted)
+	}
+	return restricted, nil
+}
+
+// collectRestrictedFields adds the fields of object below prefix to
+// restricted. Keys are joined until they consist of collection, id and
+// field, values of shorter keys that are no objects are ignored.
+func collectRestrictedFields(prefix string, object map[string]json.RawMessage, restricted map[string]json.RawMessage) {
+	for key, value := range object {
+		if prefix != "" {
+			key = prefix + "/" + key
+		}
+
+		if strings.Count(key, "/") == 2 {
+			restricted[key] = value
+			continue
+		}
+
+		var nested map[string]json.RawMessage
+		if strings.Count(key, "/") < 2 && json.Unmarshal(value, &nested) == nil {
+			collectRestrictedFields(key, nested, restricted)
+		}
+	}
+}
+
+// endpointReady returns false while the circuit breaker of the endpoint is
+// open. After the cooldown one request is let through to probe the endpoint.
+func (r *restricter) endpointReady(endpoint *restricterEndpoint) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if endpoint.failures < restricterBreakerThreshold {
+		return true
+	}
+
+	now := r.now()
+	if now.Before(endpoint.openUntil) {
+		return false
+	}
+
+	endpoint.openUntil = now.Add(restricterBreakerCooldown)
+	return true
+}
+
+// recordEndpoint updates the circuit breaker of the endpoint with the result
+// of a request.
+func (r *restricter) recordEndpoint(endpoint *restricterEndpoint, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !errors.Is(err, errRestricterUnavailable) {
+		endpoint.failures = 0
+		return
+	}
+
+	endpoint.failures++
+	if endpoint.failures == restricterBreakerThreshold {
+		endpoint.openUntil = r.now().Add(restricterBreakerCooldown)
+		log.Warn().Str("url", endpoint.url).Msgf("skipping restricter endpoint for %s after repeated failures", restricterBreakerCooldown)
+	}
+}
+
+func (r *restricter) remember(key restrictionKey, allowed bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !allowed {
+		delete(r.allowed, key)
+		return
+	}
+
+	now := r.now()
+	if now.Sub(r.lastSweep) > r.staleWindow || len(r.allowed) >= r.maxRemembered {
+		r.lastSweep = now
+		for k, allowedAt := range r.allowed {
+			if now.Sub(allowedAt) > r.staleWindow {
+				delete(r.allowed, k)
+			}
+		}
+	}
+
+	if _, ok := r.allowed[key]; !ok && len(r.allowed) >= r.maxRemembered {
+		var oldest restrictionKey
+		var oldestAt time.Time
+		for k, allowedAt := range r.allowed {
+			if oldestAt.IsZero() || allowedAt.Before(oldestAt) {
+				oldest, oldestAt = k, allowedAt
+			}
+		}
+		delete(r.allowed, oldest)
+	}
+
+	r.allowed[key] = now
+}
+
+func (r *restricter) recentlyAllowed(key restrictionKey) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	allowedAt, ok := r.allowed[key]
+	if !ok {
+		return false
+	}
+
+	if r.now().Sub(allowedAt) > r.staleWindow {
+		delete(r.allowed, key)
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,303 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRestricterStaleAllow(t *testing.T) {
+	var available atomic.Bool
+	available.Store(true)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !available.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		// User 1 can see projector 1, everyone else is denied.
+		if r.URL.Query().Get("user_id") == strconv.Itoa(1) {
+			fmt.Fprint(w, `{"projector/1/id":1}`)
+			return
+		}
+		fmt.Fprint(w, `{}`)
+	}))
+	defer srv.Close()
+
+	now := time.Now()
+	restricter := newRestricter([]string{srv.URL}, 30*time.Second)
+	restricter.retryDelay = 0
+	restricter.now = func() time.Time { return now }
+
+	ctx := context.Background()
+	if allowed, err := restricter.CanSeeProjector(ctx, 1, 1); err != nil || !allowed {
+		t.Fatalf("expected user 1 to be allowed, got %v, %v", allowed, err)
+	}
+
+	if allowed, err := restricter.CanSeeProjector(ctx, 2, 1); err != nil || allowed {
+		t.Fatalf("expected user 2 to be denied, got %v, %v", allowed, err)
+	}
+
+	available.Store(false)
+	now = now.Add(10 * time.Second)
+
+	if allowed, err := restricter.CanSeeProjector(ctx, 1, 1); err != nil || !allowed {
+		t.Errorf("expected cached allow during outage, got %v, %v", allowed, err)
+	}
+
+	if allowed, err := restricter.CanSeeProjector(ctx, 2, 1); !errors.Is(err, errRestricterUnavailable) || allowed {
+		t.Errorf("expected denied user to stay denied during outage, got %v, %v", allowed, err)
+	}
+
+	if allowed, err := restricter.CanSeeProjector(ctx, 3, 1); !errors.Is(err, errRestricterUnavailable) || allowed {
+		t.Errorf("expected unknown user to get no access during outage, got %v, %v", allowed, err)
+	}
+
+	now = now.Add(30 * time.Second)
+	if allowed, err := restricter.CanSeeProjector(ctx, 1, 1); !errors.Is(err, errRestricterUnavailable) || allowed {
+		t.Errorf("expected cached allow to expire after the stale window, got %v, %v", allowed, err)
+	}
+}
+
+func TestRestricterForgetsOldAllows(t *testing.T) {
+	now := time.Now()
+	restricter := newRestricter(nil, 30*time.Second)
+	restricter.now = func() time.Time { return now }
+	restricter.maxRemembered = 3
+
+	for userID := 1; userID <= 2; userID++ {
+		restricter.remember(restrictionKey{userID: userID, projectorID: 1}, true)
+	}
+
+	// Expired allows are removed once the stale window passed
+	now = now.Add(time.Minute)
+	restricter.remember(restrictionKey{userID: 3, projectorID: 1}, true)
+	if count := len(restricter.allowed); count != 1 {
+		t.Errorf("expected the expired allows to be removed, got %d", count)
+	}
+
+	// The oldest allow is forgotten once the limit is reached
+	for userID := 4; userID <= 6; userID++ {
+		now = now.Add(time.Second)
+		restricter.remember(restrictionKey{userID: userID, projectorID: 1}, true)
+	}
+
+	if count := len(restricter.allowed); count != 3 {
+		t.Errorf("expected at most 3 remembered allows, got %d", count)
+	}
+	if restricter.recentlyAllowed(restrictionKey{userID: 3, projectorID: 1}) {
+		t.Errorf("expected the oldest allow to be forgotten")
+	}
+	if !restricter.recentlyAllowed(restrictionKey{userID: 6, projectorID: 1}) {
+		t.Errorf("expected the newest allow to be kept")
+	}
+}
+
+func TestRestricterDenyRemovesCachedAllow(t *testing.T) {
+	var response atomic.Value
+	response.Store(`{"projector/1/id":1}`)
+	var available atomic.Bool
+	available.Store(true)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !available.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		fmt.Fprint(w, response.Load().(string))
+	}))
+	defer srv.Close()
+
+	restricter := newRestricter([]string{srv.URL}, time.Minute)
+	restricter.retryDelay = 0
+
+	ctx := context.Background()
+	if allowed, err := restricter.CanSeeProjector(ctx, 1, 1); err != nil || !allowed {
+		t.Fatalf("expected user to be allowed, got %v, %v", allowed, err)
+	}
+
+	response.Store(`{}`)
+	if allowed, err := restricter.CanSeeProjector(ctx, 1, 1); err != nil || allowed {
+		t.Fatalf("expected user to be denied, got %v, %v", allowed, err)
+	}
+
+	available.Store(false)
+	if allowed, err := restricter.CanSeeProjector(ctx, 1, 1); err == nil || allowed {
+		t.Errorf("expected no stale allow after a deny, got %v, %v", allowed, err)
+	}
+}
+
+func TestRestricterRejectsOversizedResponse(t *testing.T) {
+	var oversized atomic.Bool
+	padding := []byte(strings.Repeat("x", 1<<16))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !oversized.Load() {
+			fmt.Fprint(w, `{"projector/1/id":1}`)
+			return
+		}
+
+		// 64 MiB of padding in front of a valid allow.
+		fmt.Fprint(w, `{"padding":"`)
+		for range 1 << 10 {
+			if _, err := w.Write(padding); err != nil {
+				return
+			}
+		}
+		fmt.Fprint(w, `","projector/1/id":1}`)
+	}))
+	defer srv.Close()
+
+	restricter := newRestricter([]string{srv.URL}, time.Minute)
+	restricter.retryDelay = 0
+
+	ctx := context.Background()
+	if allowed, err := restricter.CanSeeProjector(ctx, 1, 1); err != nil || !allowed {
+		t.Fatalf("expected user to be allowed, got %v, %v", allowed, err)
+	}
+
+	oversized.Store(true)
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	allowed, err := restricter.CanSeeProjector(ctx, 1, 1)
+	runtime.ReadMemStats(&after)
+
+	if err == nil || allowed {
+		t.Errorf("expected oversized response to be rejected, got %v, %v", allowed, err)
+	}
+
+	if errors.Is(err, errRestricterUnavailable) {
+		t.Errorf("expected oversized response not to fall back to the cached allow, got %v", err)
+	}
+
+	if alloc := after.TotalAlloc - before.TotalAlloc; alloc > 16<<20 {
+		t.Errorf("expected the response to be read up to the limit, allocated %d bytes", alloc)
+	}
+}
+
+func TestRestricterRejectsInvalidResponse(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `"projector/1/id":1`)
+	}))
+	defer srv.Close()
+
+	restricter := newRestricter([]string{srv.URL}, time.Minute)
+	restricter.retryDelay = 0
+
+	if allowed, err := restricter.CanSeeProjector(context.Background(), 1, 1); err == nil || allowed {
+		t.Errorf("expected invalid response to be rejected, got %v, %v", allowed, err)
+	}
+}
+
+func TestRestricterResponses(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		response  string
+		meetingID int
+		expected  bool
+	}{
+		{"visible", `{"projector/1/id":1}`, 0, true},
+		{"empty", `{}`, 0, false},
+		{"null", `{"projector/1/id":null}`, 0, false},
+		{"other projector", `{"projector/11/id":11,"projector/21/id":21}`, 0, false},
+		{"other id", `{"projector/1/id":2}`, 0, false},
+		{"key in value", `{"projector/2/name":"projector/1/id","projector/2/id":2}`, 0, false},
+		{"visible in meeting", `{"projector/1/id":1,"projector/1/meeting_id":1}`, 1, true},
+		{"other meeting", `{"projector/1/id":1,"projector/1/meeting_id":2}`, 1, false},
+		{"meeting hidden", `{"projector/1/id":1}`, 1, false},
+		{"list", `[{"projector/1/id":1,"projector/1/meeting_id":1}]`, 1, true},
+		{"nested", `{"projector":{"1":{"id":1,"meeting_id":1}}}`, 1, true},
+		{"nested other projector", `{"projector":{"11":{"id":11}}}`, 0, false},
+		{"nested null", `{"projector":{"1":null}}`, 0, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, tt.response)
+			}))
+			defer srv.Close()
+
+			restricter := newRestricter([]string{srv.URL}, time.Minute)
+			allowed, err := restricter.CanSeeMeetingProjector(context.Background(), 1, tt.meetingID, 1)
+			if err != nil {
+				t.Fatalf("check projector: %v", err)
+			}
+
+			if allowed != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, allowed)
+			}
+		})
+	}
+}
+
+func TestRestricterFailover(t *testing.T) {
+	var failingHits atomic.Int32
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		failingHits.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"projector/1/id":1}`)
+	}))
+	defer healthy.Close()
+
+	// Closed after the other servers are started, so none of them can get
+	// its port
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	down.Close()
+
+	now := time.Now()
+	restricter := newRestricter([]string{down.URL, failing.URL, healthy.URL}, 0)
+	restricter.retryDelay = 0
+	restricter.now = func() time.Time { return now }
+
+	ctx := context.Background()
+	for range restricterBreakerThreshold + 2 {
+		if allowed, err := restricter.CanSeeProjector(ctx, 1, 1); err != nil || !allowed {
+			t.Fatalf("expected failover to the healthy endpoint, got %v, %v", allowed, err)
+		}
+	}
+
+	if hits := failingHits.Load(); hits != restricterBreakerThreshold {
+		t.Errorf("expected failing endpoint to be skipped after %d failures, got %d requests", restricterBreakerThreshold, hits)
+	}
+
+	now = now.Add(restricterBreakerCooldown)
+	if _, err := restricter.CanSeeProjector(ctx, 1, 1); err != nil {
+		t.Fatalf("can see projector: %v", err)
+	}
+
+	if hits := failingHits.Load(); hits != restricterBreakerThreshold+1 {
+		t.Errorf("expected failing endpoint to be probed after the cooldown, got %d requests", hits)
+	}
+}
+
+func TestRestricterEndpointTimeout(t *testing.T) {
+	release := make(chan struct{})
+	hanging := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer hanging.Close()
+	defer close(release)
+
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"projector/1/id":1}`)
+	}))
+	defer healthy.Close()
+
+	restricter := newRestricter([]string{hanging.URL, healthy.URL}, 0)
+	restricter.endpointTimeout = 50 * time.Millisecond
+
+	if allowed, err := restricter.CanSeeProjector(context.Background(), 1, 1); err != nil || !allowed {
+		t.Errorf("expected failover after the endpoint timeout, got %v, %v", allowed, err)
+	}
+}
@@ -0,0 +1,68 @@
+package http
+
+import (
+	"net/http"
+	"time"
+)
+
+// RuntimeConfig is the part of the config which can be changed while the
+// service is running. Open connections are kept when it changes, new values
+// are used by the next request or, for streams, the next connection.
+type RuntimeConfig struct {
+	SSERetry              time.Duration
+	SSERetryJitter        time.Duration
+	SSEFlushInterval      time.Duration
+	SSEFlushThreshold     int
+	PollInterval          time.Duration
+	MinifyHTML            bool
+	RequestTimeout        time.Duration
+	MaxConcurrentRequests int
+	MaxConcurrentStreams  int
+}
+
+// Reloader applies a changed runtime config to a running service.
+type Reloader interface {
+	Reload(cfg RuntimeConfig)
+}
+
+// runtimeConfig returns the values of cfg which can be reloaded.
+func (cfg ProjectorConfig) runtimeConfig() RuntimeConfig {
+	return RuntimeConfig{
+		SSERetry:              cfg.SSERetry,
+		SSERetryJitter:        cfg.SSERetryJitter,
+		SSEFlushInterval:      cfg.SSEFlushInterval,
+		SSEFlushThreshold:     cfg.SSEFlushThreshold,
+		PollInterval:          cfg.PollInterval,
+		MinifyHTML:            cfg.MinifyHTML,
+		RequestTimeout:        cfg.RequestTimeout,
+		MaxConcurrentRequests: cfg.MaxConcurrentRequests,
+		MaxConcurrentStreams:  cfg.MaxConcurrentStreams,
+	}
+}
+
+// Reload replaces the runtime config. The concurrency limits count from zero
+// after a change, requests admitted before are not counted against the new
+// limits.
+func (s *projectorHttp) Reload(cfg RuntimeConfig) {
+	s.runtime.Store(&cfg)
+	s.requestLimiter.resize(cfg.MaxConcurrentRequests)
+	s.streamLimiter.resize(cfg.MaxConcurrentStreams)
+}
+
+// runtimeConfig returns the current runtime config. Until it is reloaded the
+// values of the initial config are used.
+func (s *projectorHttp) runtimeConfig() RuntimeConfig {
+	if cfg := s.runtime.Load(); cfg != nil {
+		return *cfg
+	}
+
+	return s.cfg.runtimeConfig()
+}
+
+// timeoutMiddleware wraps next with the request timeout of the current
+// runtime config.
+func (s *projectorHttp) timeoutMiddleware(next http.Handler) http.Handler {
+	return reloadableTimeoutMiddleware(next, func() time.Duration {
+		return s.runtimeConfig().RequestTimeout
+	})
+}
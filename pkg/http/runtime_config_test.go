@@ -0,0 +1,121 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/OpenSlides/openslides-go/datastore/dskey"
+)
+
+func TestReloadKeepsConnections(t *testing.T) {
+	t.Chdir("../..")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s, flow := newTestProjectorHttp(t, ctx)
+	s.cfg.PollInterval = 5 * time.Second
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /system/projector/get/{id}", s.ProjectorGetHandler())
+	mux.HandleFunc("GET /system/projector/subscribe/{id}", s.ProjectorSubscribeHandler())
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	subscribe := func() (<-chan string, func()) {
+		t.Helper()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/system/projector/subscribe/1", nil)
+		if err != nil {
+			t.Fatalf("create request: %v", err)
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("subscribe: %v", err)
+		}
+
+		lines := make(chan string, 100)
+		go func() {
+			scanner := bufio.NewScanner(resp.Body)
+			scanner.Buffer(nil, 1<<20)
+			for scanner.Scan() {
+				lines <- scanner.Text()
+			}
+			close(lines)
+		}()
+
+		return lines, func() { resp.Body.Close() }
+	}
+
+	waitFor := func(lines <-chan string, prefix string) string {
+		t.Helper()
+
+		timeout := time.After(time.Second)
+		for {
+			select {
+			case line, ok := <-lines:
+				if !ok {
+					t.Fatalf("stream closed while waiting for %q", prefix)
+				}
+				if strings.HasPrefix(line, prefix) {
+					return line
+				}
+			case <-timeout:
+				t.Fatalf("no line starting with %q received", prefix)
+			}
+		}
+	}
+
+	pollInterval := func() string {
+		t.Helper()
+
+		resp, err := http.Get(srv.URL + "/system/projector/get/1")
+		if err != nil {
+			t.Fatalf("get projector: %v", err)
+		}
+		defer resp.Body.Close()
+
+		return resp.Header.Get("X-Poll-Interval")
+	}
+
+	lines, closeStream := subscribe()
+	defer closeStream()
+	waitFor(lines, "event: connected")
+
+	if got := pollInterval(); got != "5" {
+		t.Fatalf("expected poll interval 5 before reload, got %q", got)
+	}
+
+	cfg := s.runtimeConfig()
+	cfg.PollInterval = 10 * time.Second
+	cfg.SSERetry = 2 * time.Second
+	s.Reload(cfg)
+
+	if got := pollInterval(); got != "10" {
+		t.Errorf("expected poll interval 10 after reload, got %q", got)
+	}
+
+	if count := s.subscriptions.count(); count != 1 {
+		t.Errorf("expected the subscription to be kept, got %d subscriptions", count)
+	}
+
+	flow.Changes <- map[dskey.Key][]byte{
+		dskey.MustKey("projector/1/name"): []byte(`"Reloaded"`),
+	}
+	waitFor(lines, "event: settings")
+	if line := waitFor(lines, "data: "); !strings.Contains(line, `"Name":"Reloaded"`) {
+		t.Errorf("expected update on the open subscription, got %s", line)
+	}
+
+	newLines, closeNewStream := subscribe()
+	defer closeNewStream()
+	if line := waitFor(newLines, "retry: "); line != "retry: 2000" {
+		t.Errorf("expected reloaded retry delay for a new subscription, got %q", line)
+	}
+}
@@ -0,0 +1,52 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/OpenSlides/openslides-projector-service/pkg/tracing"
+)
+
+// serverTimingMiddleware sends the time spent in the restricter and rendering
+// in the Server-Timing header, so it can be inspected in the developer tools
+// of browsers. Datastore reads are not included, most of them are made by
+// projectors rendering in the background and not by the request. Without
+// enabled the request is passed on unchanged.
+func serverTimingMiddleware(next http.Handler, enabled bool) http.Handler {
+	if !enabled {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, timings := tracing.WithTimings(r.Context())
+		next.ServeHTTP(&timingWriter{ResponseWriter: w, timings: timings}, r.WithContext(ctx))
+	})
+}
+
+// timingWriter adds the Server-Timing header before the header of the
+// response is written.
+type timingWriter struct {
+	http.ResponseWriter
+	timings     *tracing.Timings
+	wroteHeader bool
+}
+
+func (tw *timingWriter) WriteHeader(status int) {
+	if !tw.wroteHeader {
+		tw.wroteHeader = true
+		if value := tw.timings.Header(); value != "" {
+			tw.Header().Set("Server-Timing", value)
+		}
+	}
+	tw.ResponseWriter.WriteHeader(status)
+}
+
+func (tw *timingWriter) Write(p []byte) (int, error) {
+	if !tw.wroteHeader {
+		tw.WriteHeader(http.StatusOK)
+	}
+	return tw.ResponseWriter.Write(p)
+}
+
+func (tw *timingWriter) Unwrap() http.ResponseWriter {
+	return tw.ResponseWriter
+}
@@ -0,0 +1,59 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestServerTiming(t *testing.T) {
+	t.Chdir("../..")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	restricterSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"projector/1/id":1,"projector/1/meeting_id":1}`)
+	}))
+	defer restricterSrv.Close()
+
+	s, _ := newTestProjectorHttp(t, ctx)
+
+	get := func(enabled bool) *httptest.ResponseRecorder {
+		t.Helper()
+
+		// Checks the projector like the auth middleware
+		restricter := newRestricter([]string{restricterSrv.URL}, time.Minute)
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, err := restricter.CanSeeProjector(r.Context(), 1, 1); err != nil {
+				writeError(w, http.StatusBadGateway, err.Error())
+				return
+			}
+
+			s.ProjectorGetHandler().ServeHTTP(w, r)
+		})
+
+		mux := http.NewServeMux()
+		mux.Handle("GET /system/projector/get/{id}", serverTimingMiddleware(handler, enabled))
+
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/system/projector/get/1", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		return rec
+	}
+
+	if got := get(false).Header().Get("Server-Timing"); got != "" {
+		t.Errorf("expected no Server-Timing header when disabled, got %q", got)
+	}
+
+	expected := regexp.MustCompile(`^restricter;dur=\d+\.\d, render;dur=\d+\.\d$`)
+	if got := get(true).Header().Get("Server-Timing"); !expected.MatchString(got) {
+		t.Errorf("expected restricter and render timings, got %q", got)
+	}
+}
@@ -0,0 +1,101 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/OpenSlides/openslides-projector-service/pkg/projector"
+)
+
+// SSE flush policies selectable by configuration.
+const (
+	SSEFlushImmediate = "immediate"
+	SSEFlushInterval  = "interval"
+)
+
+// batchedEvents are sent up to several times per second while countdowns
+// are running. With a flush interval they are batched, all other events are
+// rare and flushed immediately.
+var batchedEvents = []string{"tick", "countdowns", "speaker-countdown"}
+
+// sseWriter writes server sent events, or MessagePack frames if msgpack is
+// set. With a flush interval of zero every
+// event is flushed immediately. Otherwise countdown events are flushed at
+// most once per interval or when more than threshold bytes are pending,
+// events written in between stay buffered until flushPending is called or
+// the next event is due. Other events flush the buffer immediately.
+type sseWriter struct {
+	w         http.ResponseWriter
+	flusher   http.Flusher
+	interval  time.Duration
+	threshold int
+	msgpack   bool
+	lastFlush time.Time
+	pending   int
+}
+
+func newSSEWriter(w http.ResponseWriter, interval time.Duration, threshold int) *sseWriter {
+	flusher, _ := w.(http.Flusher)
+	return &sseWriter{w: w, flusher: flusher, interval: interval, threshold: threshold}
+}
+
+// event writes a single event. The id is omitted if empty.
+func (s *sseWriter) event(id string, name string, data string) error {
+	return s.send(&projector.ProjectorUpdateEvent{ID: id, Event: name, Data: data})
+}
+
+// send writes a single event of the projector.
+func (s *sseWriter) send(event *projector.ProjectorUpdateEvent) error {
+	if s.msgpack {
+		frame, err := event.Encoded(formatMsgpack, encodeMsgpackEvent)
+		if err != nil {
+			return err
+		}
+
+		n, err := s.w.Write(frame)
+		s.pending += n
+		if err != nil {
+			return err
+		}
+	} else {
+		if event.ID != "" {
+			n, err := fmt.Fprintf(s.w, "id: %s\n", event.ID)
+			s.pending += n
+			if err != nil {
+				return err
+			}
+		}
+
+		n, err := fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", event.Event, event.Data)
+		s.pending += n
+		if err != nil {
+			return err
+		}
+	}
+
+	if s.interval <= 0 ||
+		!slices.Contains(batchedEvents, event.Event) ||
+		time.Since(s.lastFlush) >= s.interval ||
+		(s.threshold > 0 && s.pending >= s.threshold) {
+		s.flush()
+	}
+
+	return nil
+}
+
+// flushPending flushes events not sent yet.
+func (s *sseWriter) flushPending() {
+	if s.pending > 0 {
+		s.flush()
+	}
+}
+
+func (s *sseWriter) flush() {
+	if s.flusher != nil {
+		s.flusher.Flush()
+	}
+	s.pending = 0
+	s.lastFlush = time.Now()
+}
@@ -0,0 +1,290 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/OpenSlides/openslides-go/datastore/dskey"
+	"github.com/OpenSlides/openslides-projector-service/pkg/projector"
+)
+
+// flushCounter counts the flushes, each of them results in a write syscall
+// on a real connection.
+type flushCounter struct {
+	*httptest.ResponseRecorder
+	flushes int
+}
+
+func (f *flushCounter) Flush() {
+	f.flushes++
+}
+
+func BenchmarkSSEFlush(b *testing.B) {
+	for _, tt := range []struct {
+		name     string
+		interval time.Duration
+	}{
+		{SSEFlushImmediate, 0},
+		{SSEFlushInterval, 50 * time.Millisecond},
+	} {
+		b.Run(tt.name, func(b *testing.B) {
+			w := &flushCounter{ResponseRecorder: httptest.NewRecorder()}
+			sse := newSSEWriter(w, tt.interval, 0)
+			for i := 0; b.Loop(); i++ {
+				if err := sse.event("", "tick", `{"countdowns":{"1":59.5}}`); err != nil {
+					b.Fatalf("write event: %v", err)
+				}
+
+				if i%1000 == 0 {
+					w.Body.Reset()
+				}
+			}
+			sse.flushPending()
+
+			b.ReportMetric(float64(w.flushes)/float64(b.N), "flushes/op")
+		})
+	}
+}
+
+func TestSSEFlushStrategy(t *testing.T) {
+	w := &flushCounter{ResponseRecorder: httptest.NewRecorder()}
+	sse := newSSEWriter(w, time.Hour, 100)
+	sse.flush()
+	w.flushes = 0
+
+	write := func(name string, data string) {
+		t.Helper()
+		if err := sse.event("", name, data); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+
+	write("tick", `{"countdowns":{"1":59}}`)
+	write("speaker-countdown", `{"1":30}`)
+	if w.flushes != 0 {
+		t.Errorf("expected countdown events to be batched, got %d flushes", w.flushes)
+	}
+
+	write("projection-updated", `{"1":"<div></div>"}`)
+	if w.flushes != 1 {
+		t.Errorf("expected a slide change to flush immediately, got %d flushes", w.flushes)
+	}
+
+	write("tick", `{"countdowns":{"1":58}}`)
+	write("tick", strings.Repeat("x", 100))
+	if w.flushes != 2 {
+		t.Errorf("expected a flush once the threshold is reached, got %d flushes", w.flushes)
+	}
+
+	write("tick", `{"countdowns":{"1":57}}`)
+	sse.flushPending()
+	sse.flushPending()
+	if w.flushes != 3 {
+		t.Errorf("expected pending events to be flushed once, got %d flushes", w.flushes)
+	}
+
+	sse.lastFlush = time.Now().Add(-time.Hour)
+	write("tick", `{"countdowns":{"1":56}}`)
+	if w.flushes != 4 {
+		t.Errorf("expected a flush once the interval has passed, got %d flushes", w.flushes)
+	}
+}
+
+func TestSubscribeIntervalFlushDeliversAllEvents(t *testing.T) {
+	t.Chdir("../..")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s, flow := newTestProjectorHttp(t, ctx)
+	s.cfg.SSEFlushInterval = 50 * time.Millisecond
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /system/projector/subscribe/{id}", s.ProjectorSubscribeHandler())
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/system/projector/subscribe/1", nil)
+	if err != nil {
+		t.Fatalf("create request: %v", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+	defer resp.Body.Close()
+
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		scanner.Buffer(nil, 1<<20)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+
+	waitFor := func(prefix string) string {
+		t.Helper()
+
+		timeout := time.After(time.Second)
+		for {
+			select {
+			case line, ok := <-lines:
+				if !ok {
+					t.Fatalf("stream closed while waiting for %q", prefix)
+				}
+				if strings.HasPrefix(line, prefix) {
+					return line
+				}
+			case <-timeout:
+				t.Fatalf("no line starting with %q received", prefix)
+			}
+		}
+	}
+
+	waitFor("event: connected")
+
+	// All changes are sent within one flush interval
+	for i := range 3 {
+		flow.Changes <- map[dskey.Key][]byte{
+			dskey.MustKey("projector/1/name"): fmt.Appendf(nil, `"Main %d"`, i),
+		}
+	}
+
+	for i := range 3 {
+		waitFor("event: settings")
+		if line := waitFor("data: "); !strings.Contains(line, fmt.Sprintf(`"Name":"Main %d"`, i)) {
+			t.Errorf("expected settings of change %d, got %s", i, line)
+		}
+	}
+}
+
+func TestSubscribeResumeReplayBuffer(t *testing.T) {
+	t.Chdir("../..")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s, flow := newTestProjectorHttp(t, ctx)
+	s.projector.ReplayBufferSize = 4
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /system/projector/subscribe/{id}", s.ProjectorSubscribeHandler())
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	// Open subscriptions have to be closed before the server
+	defer cancel()
+
+	// subscribe returns the lines of a new subscription, it is closed with
+	// the returned function.
+	subscribe := func(lastEventID string) (<-chan string, func()) {
+		t.Helper()
+
+		reqCtx, reqCancel := context.WithCancel(ctx)
+		req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, srv.URL+"/system/projector/subscribe/1", nil)
+		if err != nil {
+			t.Fatalf("create request: %v", err)
+		}
+		if lastEventID != "" {
+			req.Header.Set("Last-Event-ID", lastEventID)
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("subscribe: %v", err)
+		}
+
+		lines := make(chan string, 100)
+		go func() {
+			defer close(lines)
+			defer resp.Body.Close()
+
+			scanner := bufio.NewScanner(resp.Body)
+			scanner.Buffer(nil, 1<<20)
+			for scanner.Scan() {
+				lines <- scanner.Text()
+			}
+		}()
+
+		return lines, reqCancel
+	}
+
+	// nextEvent returns the id and name of the next event of one of the
+	// given names and fails on any other event of interest.
+	nextEvent := func(lines <-chan string, names ...string) (string, string) {
+		t.Helper()
+
+		id := ""
+		timeout := time.After(time.Second)
+		for {
+			select {
+			case line, ok := <-lines:
+				if !ok {
+					t.Fatalf("stream closed while waiting for %v", names)
+				}
+
+				if value, ok := strings.CutPrefix(line, "id: "); ok {
+					id = value
+				} else if name, ok := strings.CutPrefix(line, "event: "); ok {
+					for _, expected := range names {
+						if name == expected {
+							return id, name
+						}
+					}
+					id = ""
+				}
+			case <-timeout:
+				t.Fatalf("no %v event received", names)
+			}
+		}
+	}
+
+	// Each rename sends a settings and a projector-replace event
+	rename := func(i int) {
+		flow.Changes <- map[dskey.Key][]byte{
+			dskey.MustKey("projector/1/name"): fmt.Appendf(nil, `"Main %d"`, i),
+		}
+	}
+
+	lines, unsubscribe := subscribe("")
+	nextEvent(lines, "connected")
+	rename(1)
+	lastID, _ := nextEvent(lines, "settings")
+	if lastID == "" {
+		t.Fatalf("expected the settings event to have an id")
+	}
+	unsubscribe()
+
+	// Within the buffer the missed events are replayed
+	rename(2)
+	lines, unsubscribe = subscribe(lastID)
+	if _, event := nextEvent(lines, "settings", "resync"); event != "settings" {
+		t.Fatalf("expected the missed settings to be replayed, got %s", event)
+	}
+	rename(3)
+	lastID, _ = nextEvent(lines, "settings")
+	unsubscribe()
+
+	// Beyond it the client has to resync
+	for i := 4; i <= 6; i++ {
+		rename(i)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	lines, unsubscribe = subscribe(lastID)
+	defer unsubscribe()
+	nextEvent(lines, "resync")
+	nextEvent(lines, "projector-replace")
+
+	if replayed, resynced := projector.Resumes(); replayed == 0 || resynced == 0 {
+		t.Errorf("expected replayed and resynced resumes to be counted, got %d and %d", replayed, resynced)
+	}
+}
@@ -0,0 +1,70 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/rs/zerolog/log"
+)
+
+// staticURL is the path the static files are served at.
+const staticURL = "/system/projector/static/"
+
+// RegisterStatic serves the files of dir at /system/projector/static/.
+// Missing files are answered with a JSON 404.
+//
+// If dir does not exist or is not readable an error is returned if required
+// is set, otherwise a warning is logged and every request answered with 404.
+func RegisterStatic(serverMux *http.ServeMux, dir string, required bool) error {
+	resolved, err := checkStaticDir(dir)
+	if err != nil {
+		if required {
+			return err
+		}
+
+		log.Warn().Err(err).Str("dir", resolved).Msg("static directory is not available, static files are answered with 404")
+	}
+
+	fileServer := http.FileServer(http.Dir(resolved))
+	serverMux.Handle("GET "+staticURL, routeSpan(http.StripPrefix(staticURL, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := filepath.FromSlash(path.Clean("/" + r.URL.Path))
+		if _, err := os.Stat(filepath.Join(resolved, name)); err != nil {
+			writeError(w, http.StatusNotFound, "File not found")
+			return
+		}
+
+		fileServer.ServeHTTP(w, r)
+	}))))
+
+	return nil
+}
+
+// checkStaticDir resolves dir to an absolute path and checks that it is a
+// readable directory.
+func checkStaticDir(dir string) (string, error) {
+	resolved, err := filepath.Abs(dir)
+	if err != nil {
+		return dir, fmt.Errorf("resolving static directory %s: %w", dir, err)
+	}
+
+	f, err := os.Open(resolved)
+	if err != nil {
+		return resolved, fmt.Errorf("opening static directory %s: %w", resolved, err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return resolved, fmt.Errorf("reading static directory %s: %w", resolved, err)
+	}
+
+	if !info.IsDir() {
+		return resolved, errors.New("static directory " + resolved + " is not a directory")
+	}
+
+	return resolved, nil
+}
@@ -0,0 +1,125 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/OpenSlides/openslides-projector-service/pkg/database"
+	"github.com/OpenSlides/openslides-projector-service/pkg/dstest"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/text/language"
+)
+
+func TestStaticFiles(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "projector.js"), []byte("console.log(1)"), 0o644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	if err := RegisterStatic(mux, dir, true); err != nil {
+		t.Fatalf("register static: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/system/projector/static/projector.js", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "console.log(1)" {
+		t.Errorf("expected file content, got %d %q", rec.Code, rec.Body.String())
+	}
+
+	assertJSONNotFound(t, mux, "/system/projector/static/missing.js")
+}
+
+func TestStaticDirMissing(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "static")
+
+	if err := RegisterStatic(http.NewServeMux(), dir, true); err == nil {
+		t.Errorf("expected missing static directory to fail if required")
+	}
+
+	logs := &syncBuffer{}
+	oldLogger := log.Logger
+	log.Logger = zerolog.New(logs)
+	t.Cleanup(func() { log.Logger = oldLogger })
+
+	mux := http.NewServeMux()
+	if err := RegisterStatic(mux, dir, false); err != nil {
+		t.Fatalf("register static: %v", err)
+	}
+
+	lines := logs.lines()
+	if !slices.ContainsFunc(lines, func(line map[string]any) bool {
+		return line["level"] == "warn" && line["dir"] == dir
+	}) {
+		t.Errorf("expected warning about the static directory, got %v", lines)
+	}
+
+	assertJSONNotFound(t, mux, "/system/projector/static/projector.js")
+}
+
+func assertJSONNotFound(t *testing.T, mux *http.ServeMux, path string) {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("%s: expected 404, got %d", path, rec.Code)
+	}
+
+	var resp errorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || !resp.Error {
+		t.Errorf("%s: expected json error, got %q", path, rec.Body.String())
+	}
+}
+
+func TestProductionMuxRegistersAllRoutes(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	flow := dstest.NewFlow(map[string]string{})
+	db, err := database.New("", "", flow)
+	if err != nil {
+		t.Fatalf("create datastore: %v", err)
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "get"), []byte("static"), 0o644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+
+	// Built like in cmd/projectord, conflicting patterns panic here
+	mux := http.NewServeMux()
+	New(ctx, ProjectorConfig{
+		PublicAccessOnly: true,
+		MediaProxy:       true,
+		AdminToken:       "secret",
+		DefaultLanguage:  language.English,
+		MetricInterval:   time.Minute,
+	}, mux, db, flow)
+	if err := RegisterStatic(mux, dir, true); err != nil {
+		t.Fatalf("register static: %v", err)
+	}
+
+	for _, tt := range []struct {
+		path    string
+		pattern string
+	}{
+		{"/system/projector/static/get", "GET /system/projector/static/"},
+		{"/system/projector/get/1", "GET /system/projector/get/{id}"},
+		{"/system/projector/meeting/1/get/1", "GET /system/projector/meeting/{meeting_id}/get/{id}"},
+		{"/system/projector/meeting/1/subscribe/1", "GET /system/projector/meeting/{meeting_id}/subscribe/{id}"},
+	} {
+		_, pattern := mux.Handler(httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if pattern != tt.pattern {
+			t.Errorf("%s: expected pattern %q, got %q", tt.path, tt.pattern, pattern)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+)
+
+// errClosedByAdmin is the cause of the context of a subscription closed with
+// the admin endpoint.
+var errClosedByAdmin = errors.New("subscription closed by admin")
+
+// errReplacedByClient is the cause of the context of a subscription closed
+// because the same client subscribed to the projector again.
+var errReplacedByClient = errors.New("subscription replaced by the client")
+
+// subscriptionInfo describes an active subscription.
+type subscriptionInfo struct {
+	ID          string    `json:"id"`
+	UserID      int       `json:"user_id"`
+	ProjectorID int       `json:"projector_id"`
+	Transport   string    `json:"transport"`
+	ClientID    string    `json:"client_id,omitempty"`
+	ConnectedAt time.Time `json:"connected_at"`
+}
+
+type activeSubscription struct {
+	info   subscriptionInfo
+	cancel context.CancelCauseFunc
+}
+
+// subscriptionRegistry keeps track of all open subscribe and websocket
+// connections so they can be listed and closed by operators.
+type subscriptionRegistry struct {
+	mu            sync.Mutex
+	subscriptions map[string]*activeSubscription
+}
+
+func newSubscriptionRegistry() *subscriptionRegistry {
+	return &subscriptionRegistry{subscriptions: make(map[string]*activeSubscription)}
+}
+
+// add registers a subscription. The returned context is canceled with
+// errClosedByAdmin if the subscription is closed by an operator. The returned
+// function has to be called when the subscription ends.
+//
+// If clientID is set, a subscription of the same client, user and projector
+// is closed with errReplacedByClient, so a client reconnecting quickly does
+// not hold two subscriptions.
+func (reg *subscriptionRegistry) add(ctx context.Context, userID int, projectorID int, transport string, clientID string) (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	sub := &activeSubscription{
+		info: subscriptionInfo{
+			ID:          newRequestID(),
+			UserID:      userID,
+			ProjectorID: projectorID,
+			Transport:   transport,
+			ClientID:    clientID,
+			ConnectedAt: time.Now(),
+		},
+		cancel: cancel,
+	}
+
+	reg.mu.Lock()
+	if clientID != "" {
+		for id, previous := range reg.subscriptions {
+			if previous.info.ClientID == clientID && previous.info.UserID == userID && previous.info.ProjectorID == projectorID {
+				previous.cancel(errReplacedByClient)
+				delete(reg.subscriptions, id)
+			}
+		}
+	}
+	reg.subscriptions[sub.info.ID] = sub
+	reg.mu.Unlock()
+
+	return ctx, func() {
+		reg.mu.Lock()
+		delete(reg.subscriptions, sub.info.ID)
+		reg.mu.Unlock()
+
+		cancel(nil)
+	}
+}
+
+// list returns all active subscriptions ordered by their connection time.
+func (reg *subscriptionRegistry) list() []subscriptionInfo {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	infos := make([]subscriptionInfo, 0, len(reg.subscriptions))
+	for _, sub := range reg.subscriptions {
+		infos = append(infos, sub.info)
+	}
+
+	slices.SortFunc(infos, func(a, b subscriptionInfo) int {
+		return a.ConnectedAt.Compare(b.ConnectedAt)
+	})
+
+	return infos
+}
+
+// count returns the number of active subscriptions.
+func (reg *subscriptionRegistry) count() int {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	return len(reg.subscriptions)
+}
+
+// close ends the subscription with the given id. Returns false if there is
+// no such subscription.
+func (reg *subscriptionRegistry) close(id string) bool {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	sub, ok := reg.subscriptions[id]
+	if !ok {
+		return false
+	}
+
+	sub.cancel(errClosedByAdmin)
+	delete(reg.subscriptions, id)
+	return true
+}
@@ -0,0 +1,133 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"maps"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// timeoutExemptPrefixes are the routes keeping their connection open. They
+// are never cut off by the timeout middleware.
+var timeoutExemptPrefixes = []string{
+	"/system/projector/subscribe/",
+	"/system/projector/mirror/",
+	"/system/projector/ws/",
+}
+
+// timeoutMiddleware answers with 504 if the handler does not finish within
+// the timeout. The response of the handler is buffered until it returns, so
+// it must not be used for streaming routes. Long polls get their wait
+// duration in addition to the timeout. Zero disables the timeout.
+func timeoutMiddleware(next http.Handler, timeout time.Duration) http.Handler {
+	if timeout <= 0 {
+		return next
+	}
+
+	return reloadableTimeoutMiddleware(next, func() time.Duration { return timeout })
+}
+
+// reloadableTimeoutMiddleware is the timeoutMiddleware with a timeout looked
+// up for every request, so it can be changed at runtime.
+func reloadableTimeoutMiddleware(next http.Handler, getTimeout func() time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		timeout := getTimeout()
+		if timeout <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		for _, prefix := range timeoutExemptPrefixes {
+			if strings.HasPrefix(r.URL.Path, prefix) {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		wait, _ := getPollWait(r)
+		ctx, cancel := context.WithTimeout(r.Context(), timeout+wait)
+		defer cancel()
+
+		tw := &timeoutWriter{header: make(http.Header)}
+		done := make(chan struct{})
+		panicChan := make(chan any, 1)
+		go func() {
+			defer func() {
+				if p := recover(); p != nil {
+					panicChan <- p
+				}
+			}()
+			next.ServeHTTP(tw, r.WithContext(ctx))
+			close(done)
+		}()
+
+		select {
+		case p := <-panicChan:
+			panic(p)
+
+		case <-done:
+			tw.mu.Lock()
+			defer tw.mu.Unlock()
+
+			maps.Copy(w.Header(), tw.header)
+			if tw.status == 0 {
+				tw.status = http.StatusOK
+			}
+			w.WriteHeader(tw.status)
+			if _, err := w.Write(tw.buf.Bytes()); err != nil {
+				log.Err(err).Msg("writing response")
+			}
+
+		case <-ctx.Done():
+			tw.mu.Lock()
+			defer tw.mu.Unlock()
+
+			tw.timedOut = true
+			log.Ctx(r.Context()).Warn().Str("path", r.URL.Path).Msgf("request exceeded timeout of %s", timeout)
+			writeError(w, http.StatusGatewayTimeout, "request timed out")
+		}
+	})
+}
+
+// timeoutWriter buffers the response of a handler run by the timeout
+// middleware. Writes after the timeout fail with http.ErrHandlerTimeout.
+type timeoutWriter struct {
+	mu       sync.Mutex
+	header   http.Header
+	buf      bytes.Buffer
+	status   int
+	timedOut bool
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.header
+}
+
+func (tw *timeoutWriter) Write(p []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+
+	if tw.status == 0 {
+		tw.status = http.StatusOK
+	}
+	return tw.buf.Write(p)
+}
+
+func (tw *timeoutWriter) WriteHeader(status int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.timedOut || tw.status != 0 {
+		return
+	}
+	tw.status = status
+}
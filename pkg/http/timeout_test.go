@@ -0,0 +1,97 @@
+package http
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTimeoutMiddleware(t *testing.T) {
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+			writeResponse(w, "too late")
+		case <-r.Context().Done():
+		}
+	})
+
+	rec := httptest.NewRecorder()
+	timeoutMiddleware(slow, 10*time.Millisecond).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/system/projector/get/1", nil))
+
+	if rec.Code != http.StatusGatewayTimeout {
+		t.Fatalf("expected status 504, got %d", rec.Code)
+	}
+
+	var resp errorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+
+	if !resp.Error || resp.Msg != "request timed out" {
+		t.Errorf("unexpected response %+v", resp)
+	}
+}
+
+func TestTimeoutMiddlewarePassesFastResponse(t *testing.T) {
+	fast := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Test", "1")
+		writeJSON(w, http.StatusCreated, positionResponse{Position: 5})
+	})
+
+	rec := httptest.NewRecorder()
+	timeoutMiddleware(fast, time.Second).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/system/projector/position", nil))
+
+	if rec.Code != http.StatusCreated {
+		t.Errorf("expected status 201, got %d", rec.Code)
+	}
+
+	if got := rec.Header().Get("X-Test"); got != "1" {
+		t.Errorf("expected header X-Test to be passed on, got %q", got)
+	}
+
+	if got := rec.Body.String(); got != "{\"position\":5}\n" {
+		t.Errorf("unexpected body %q", got)
+	}
+}
+
+func TestTimeoutMiddlewareExemptsSubscribe(t *testing.T) {
+	stream := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i := range 3 {
+			fmt.Fprintf(w, "data: %d\n\n", i)
+			w.(http.Flusher).Flush()
+			time.Sleep(20 * time.Millisecond)
+		}
+	})
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /system/projector/subscribe/{id}", timeoutMiddleware(stream, 10*time.Millisecond))
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/system/projector/subscribe/1")
+	if err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", resp.StatusCode)
+	}
+
+	var events int
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if scanner.Text() != "" {
+			events++
+		}
+	}
+
+	if events != 3 {
+		t.Errorf("expected 3 events, got %d", events)
+	}
+}
@@ -0,0 +1,121 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/OpenSlides/openslides-projector-service/pkg/tracing"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type requestSpanKey struct{}
+
+// TracingMiddleware creates a span for every request. The span records the
+// status of the response and is named after the route by routeSpan, since
+// only handlers inside the mux see the matched pattern. If tracing is not set
+// up, the request is passed on unchanged.
+func TracingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := tracing.StartServer(r)
+		if !span.IsRecording() {
+			defer span.End()
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		rs := &requestSpan{span: span, sw: &statusWriter{ResponseWriter: w}}
+		defer rs.end()
+
+		next.ServeHTTP(rs.sw, r.WithContext(context.WithValue(ctx, requestSpanKey{}, rs)))
+	})
+}
+
+// requestSpan is the span of a request kept in the request context, so it
+// can be named and ended by the handlers inside the mux.
+type requestSpan struct {
+	once sync.Once
+	span trace.Span
+	sw   *statusWriter
+}
+
+// end records the status of the response and ends the span. Later calls do
+// nothing.
+func (rs *requestSpan) end() {
+	rs.once.Do(func() {
+		status := rs.sw.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		rs.span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if status >= http.StatusInternalServerError {
+			rs.span.SetStatus(codes.Error, http.StatusText(status))
+		}
+		rs.span.End()
+	})
+}
+
+// routeSpan names the span of the request after the route matched by the
+// mux. It has to wrap the handlers registered at the mux.
+func routeSpan(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if rs, ok := r.Context().Value(requestSpanKey{}).(*requestSpan); ok && r.Pattern != "" {
+			rs.span.SetName(r.Pattern)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// endRequestSpan ends the span of a stream once it is set up. Otherwise the
+// span would last until the client disconnects.
+func endRequestSpan(ctx context.Context) {
+	if rs, ok := ctx.Value(requestSpanKey{}).(*requestSpan); ok {
+		rs.end()
+	}
+}
+
+// statusWriter remembers the status of a response. Streams and websockets
+// still get access to the underlying connection.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (sw *statusWriter) WriteHeader(status int) {
+	if sw.status == 0 {
+		sw.status = status
+	}
+	sw.ResponseWriter.WriteHeader(status)
+}
+
+func (sw *statusWriter) Write(p []byte) (int, error) {
+	if sw.status == 0 {
+		sw.status = http.StatusOK
+	}
+	return sw.ResponseWriter.Write(p)
+}
+
+func (sw *statusWriter) Flush() {
+	if flusher, ok := sw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (sw *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := sw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("connection does not support hijacking")
+	}
+
+	sw.status = http.StatusSwitchingProtocols
+	return hijacker.Hijack()
+}
+
+func (sw *statusWriter) Unwrap() http.ResponseWriter {
+	return sw.ResponseWriter
+}
@@ -0,0 +1,178 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/OpenSlides/openslides-projector-service/pkg/database"
+	"github.com/OpenSlides/openslides-projector-service/pkg/dstest"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+	"golang.org/x/text/language"
+)
+
+func TestTracingPropagatesToRestricter(t *testing.T) {
+	var traceparent atomic.Value
+	traceparent.Store("")
+	restricterSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent.Store(r.Header.Get("traceparent"))
+		fmt.Fprint(w, `{"projector/1/id":1}`)
+	}))
+	defer restricterSrv.Close()
+
+	restricter := newRestricter([]string{restricterSrv.URL}, time.Minute)
+	mux := http.NewServeMux()
+	mux.Handle("GET /system/projector/get/{id}", routeSpan(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := restricter.CanSeeProjector(r.Context(), 1, 1); err != nil {
+			writeError(w, http.StatusBadGateway, err.Error())
+		}
+	})))
+	srv := httptest.NewServer(TracingMiddleware(mux))
+	defer srv.Close()
+
+	get := func() {
+		t.Helper()
+
+		resp, err := http.Get(srv.URL + "/system/projector/get/1")
+		if err != nil {
+			t.Fatalf("get projector: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	get()
+	if got := traceparent.Load().(string); got != "" {
+		t.Errorf("expected no trace context without tracing, got %q", got)
+	}
+
+	exporter := tracetest.NewInMemoryExporter()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	defer otel.SetTracerProvider(noop.NewTracerProvider())
+
+	get()
+
+	spans := exporter.GetSpans()
+	var server, restricterSpan tracetest.SpanStub
+	for _, span := range spans {
+		switch span.Name {
+		case "GET /system/projector/get/{id}":
+			server = span
+		case "restricter":
+			restricterSpan = span
+		}
+	}
+
+	if !server.SpanContext.IsValid() || !restricterSpan.SpanContext.IsValid() {
+		t.Fatalf("expected a request and a restricter span, got %d spans", len(spans))
+	}
+
+	if restricterSpan.Parent.SpanID() != server.SpanContext.SpanID() {
+		t.Errorf("expected the restricter span to be a child of the request span")
+	}
+
+	expected := fmt.Sprintf("00-%s-", server.SpanContext.TraceID())
+	if got := traceparent.Load().(string); !strings.HasPrefix(got, expected) {
+		t.Errorf("expected restricter request with trace %s, got traceparent %q", server.SpanContext.TraceID(), got)
+	}
+}
+
+func TestTracingSpanNamesInProductionChain(t *testing.T) {
+	t.Chdir("../..")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	flow := dstest.NewFlow(map[string]string{
+		"projector/1/id":                "1",
+		"projector/1/meeting_id":        "1",
+		"projector/1/sequential_number": "1",
+		"projector/1/name":              `"Main"`,
+		"meeting/1/id":                  "1",
+		"meeting/1/name":                `"Meeting"`,
+		"meeting/1/enable_anonymous":    "true",
+		"organization/1/id":             "1",
+		"organization/1/theme_id":       "1",
+		"theme/1/id":                    "1",
+		"theme/1/name":                  `"Theme"`,
+		"theme/1/organization_id":       "1",
+	})
+	db, err := database.New("", "", flow)
+	if err != nil {
+		t.Fatalf("create datastore: %v", err)
+	}
+
+	restricterSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"projector/1/id":1}`)
+	}))
+	defer restricterSrv.Close()
+
+	// Built like in cmd/projectord
+	mux := http.NewServeMux()
+	New(ctx, ProjectorConfig{
+		RestricterUrl:    restricterSrv.URL,
+		PublicAccessOnly: true,
+		DefaultLanguage:  language.English,
+		MetricInterval:   time.Minute,
+	}, mux, db, flow)
+	srv := httptest.NewServer(TracingMiddleware(RequestIDMiddleware(mux)))
+	defer srv.Close()
+
+	exporter := tracetest.NewInMemoryExporter()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	defer otel.SetTracerProvider(noop.NewTracerProvider())
+
+	resp, err := http.Get(srv.URL + "/system/projector/position")
+	if err != nil {
+		t.Fatalf("get position: %v", err)
+	}
+	resp.Body.Close()
+
+	reqCtx, reqCancel := context.WithCancel(ctx)
+	defer reqCancel()
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, srv.URL+"/system/projector/subscribe/1?init=1", nil)
+	if err != nil {
+		t.Fatalf("create request: %v", err)
+	}
+	stream, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("open stream: %v", err)
+	}
+	defer stream.Body.Close()
+	if stream.StatusCode != http.StatusOK {
+		t.Fatalf("expected stream to open, got status %d", stream.StatusCode)
+	}
+
+	// The span of the stream ends once it is set up, while it is still open
+	ended := func(name string) bool {
+		for _, span := range exporter.GetSpans() {
+			if span.Name == name {
+				return true
+			}
+		}
+		return false
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for !ended("GET /system/projector/subscribe/{id}") {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected span of the open stream to be ended")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if !ended("GET /system/projector/position") {
+		t.Errorf("expected span named after the route, got %d spans", len(exporter.GetSpans()))
+	}
+
+	if ended("GET") {
+		t.Errorf("expected no span named after the method only")
+	}
+}
@@ -0,0 +1,78 @@
+package http
+
+import "github.com/OpenSlides/openslides-projector-service/pkg/projector"
+
+// errorResponse is the body returned by all handlers when a request fails.
+type errorResponse struct {
+	Error  bool   `json:"error"`
+	Msg    string `json:"msg"`
+	Detail string `json:"detail,omitempty"`
+}
+
+type healthResponse struct {
+	Healthy bool   `json:"healthy"`
+	Service string `json:"service"`
+
+	// Checks holds the result per dependency, only set by the readiness check
+	Checks map[string]bool `json:"checks,omitempty"`
+
+	// Stats is only set with ?verbose=1 to keep the response small for probes
+	Stats *healthStats `json:"stats,omitempty"`
+}
+
+type healthStats struct {
+	UptimeSeconds     int64  `json:"uptime_seconds"`
+	Subscriptions     int    `json:"subscriptions"`
+	DatastorePosition uint64 `json:"datastore_position"`
+
+	// RenderCacheBytes is the size of the cached slides, MemoryBudgetBytes
+	// the limit of all caches or zero if they are not limited.
+	RenderCacheBytes  int64 `json:"render_cache_bytes"`
+	MemoryBudgetBytes int64 `json:"memory_budget_bytes"`
+	MemoryUsedBytes   int64 `json:"memory_used_bytes"`
+}
+
+type statsResponse struct {
+	ActiveProjectors     int         `json:"active_projectors"`
+	Subscribers          int         `json:"subscribers"`
+	ProjectorSubscribers map[int]int `json:"projector_subscribers"`
+
+	// MemoryBudgetBytes is zero if the caches are not limited
+	MemoryBudgetBytes int64 `json:"memory_budget_bytes"`
+	MemoryUsedBytes   int64 `json:"memory_used_bytes"`
+	MemoryEvicted     int   `json:"memory_evicted"`
+}
+
+type positionResponse struct {
+	Position uint64 `json:"position"`
+}
+
+// websocketMessage is a single projector event sent over a websocket. It
+// carries the same event names and data as the server sent events.
+type websocketMessage struct {
+	Event string `json:"event"`
+	Data  string `json:"data"`
+}
+
+type whoamiResponse struct {
+	UserID int `json:"user_id"`
+
+	// ProjectorIDs are the projectors of the meetings of the user which the
+	// user may see. It is null for the anonymous user or if they could not
+	// be determined.
+	ProjectorIDs []int `json:"projector_ids"`
+}
+
+type currentResponse struct {
+	Projections []projector.CurrentProjection `json:"projections"`
+}
+
+type bulkPreviewResponse struct {
+	Previews map[int]string `json:"previews"`
+	Denied   []int          `json:"denied"`
+	Failed   []int          `json:"failed"`
+}
+
+type subscriptionsResponse struct {
+	Subscriptions []subscriptionInfo `json:"subscriptions"`
+}
@@ -0,0 +1,246 @@
+package http
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+	websocketOpContinuation byte = 0x0
+	websocketOpText         byte = 0x1
+	websocketOpClose        byte = 0x8
+	websocketOpPing         byte = 0x9
+	websocketOpPong         byte = 0xA
+
+	websocketMaxReadSize  = 1 << 16
+	websocketWriteTimeout = 10 * time.Second
+)
+
+// websocketConn is a minimal server side implementation of RFC 6455. It
+// supports sending text frames and answers control frames. Messages sent by
+// the client are read, fragmented ones are joined, but ignored.
+type websocketConn struct {
+	conn net.Conn
+	rw   *bufio.ReadWriter
+	mu   sync.Mutex
+
+	// The message read so far of a fragmented message. Only used by the
+	// reading goroutine.
+	fragmentOpcode byte
+	fragments      []byte
+}
+
+func headerContainsToken(h http.Header, name string, token string) bool {
+	for _, value := range h.Values(name) {
+		for _, part := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func websocketAcceptKey(key string) string {
+	hash := sha1.Sum([]byte(key + websocketGUID))
+	return base64.StdEncoding.EncodeToString(hash[:])
+}
+
+// websocketOriginAllowed reports whether a browser on the origin of the
+// request may open a websocket. Requests from the host itself or one of the
+// allowed origins are accepted, as are requests without an origin, which
+// are not sent by browsers.
+func websocketOriginAllowed(r *http.Request, allowed []string) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+
+	return slices.ContainsFunc(allowed, func(a string) bool {
+		return strings.EqualFold(strings.TrimSuffix(a, "/"), origin)
+	})
+}
+
+func upgradeWebsocket(w http.ResponseWriter, r *http.Request) (*websocketConn, error) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if !headerContainsToken(r.Header, "Connection", "upgrade") ||
+		!headerContainsToken(r.Header, "Upgrade", "websocket") ||
+		r.Header.Get("Sec-WebSocket-Version") != "13" ||
+		key == "" {
+		return nil, fmt.Errorf("not a websocket handshake")
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		return nil, fmt.Errorf("connection does not support hijacking")
+	}
+
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, fmt.Errorf("hijacking connection: %w", err)
+	}
+
+	_, err = fmt.Fprintf(rw, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n", websocketAcceptKey(key))
+	if err == nil {
+		err = rw.Flush()
+	}
+
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("writing handshake: %w", err)
+	}
+
+	return &websocketConn{conn: conn, rw: rw}, nil
+}
+
+func (c *websocketConn) writeFrame(opcode byte, payload []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	header := []byte{0x80 | opcode}
+	switch {
+	case len(payload) < 126:
+		header = append(header, byte(len(payload)))
+	case len(payload) <= 0xFFFF:
+		header = append(header, 126)
+		header = binary.BigEndian.AppendUint16(header, uint16(len(payload)))
+	default:
+		header = append(header, 127)
+		header = binary.BigEndian.AppendUint64(header, uint64(len(payload)))
+	}
+
+	if err := c.conn.SetWriteDeadline(time.Now().Add(websocketWriteTimeout)); err != nil {
+		return fmt.Errorf("setting write deadline: %w", err)
+	}
+
+	if _, err := c.rw.Write(header); err != nil {
+		return fmt.Errorf("writing frame header: %w", err)
+	}
+
+	if _, err := c.rw.Write(payload); err != nil {
+		return fmt.Errorf("writing frame payload: %w", err)
+	}
+
+	return c.rw.Flush()
+}
+
+// readMessage returns the next control frame or complete message sent by
+// the client. Fragments of a message are joined, control frames sent between
+// them are returned first.
+func (c *websocketConn) readMessage() (byte, []byte, error) {
+	for {
+		fin, opcode, payload, err := c.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		if opcode >= websocketOpClose {
+			if !fin || len(payload) > 125 {
+				return 0, nil, errors.New("invalid control frame")
+			}
+			return opcode, payload, nil
+		}
+
+		if opcode == websocketOpContinuation {
+			if c.fragmentOpcode == 0 {
+				return 0, nil, errors.New("continuation frame without message")
+			}
+
+			if len(c.fragments)+len(payload) > websocketMaxReadSize {
+				return 0, nil, fmt.Errorf("message exceeds limit of %d bytes", websocketMaxReadSize)
+			}
+			c.fragments = append(c.fragments, payload...)
+		} else {
+			if c.fragmentOpcode != 0 {
+				return 0, nil, errors.New("new message before the previous one ended")
+			}
+			c.fragmentOpcode = opcode
+			c.fragments = payload
+		}
+
+		if fin {
+			opcode, message := c.fragmentOpcode, c.fragments
+			c.fragmentOpcode, c.fragments = 0, nil
+			return opcode, message, nil
+		}
+	}
+}
+
+// readFrame reads the next frame sent by the client and returns whether it
+// is the last frame of its message, its opcode and unmasked payload.
+func (c *websocketConn) readFrame() (bool, byte, []byte, error) {
+	var head [2]byte
+	if _, err := io.ReadFull(c.rw, head[:]); err != nil {
+		return false, 0, nil, err
+	}
+
+	fin := head[0]&0x80 != 0
+	opcode := head[0] & 0x0F
+	if head[1]&0x80 == 0 {
+		return false, 0, nil, errors.New("client frames must be masked")
+	}
+
+	length := uint64(head[1] & 0x7F)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.rw, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.rw, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+
+	if length > websocketMaxReadSize {
+		return false, 0, nil, fmt.Errorf("frame of %d bytes exceeds limit", length)
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(c.rw, mask[:]); err != nil {
+		return false, 0, nil, err
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(c.rw, payload); err != nil {
+		return false, 0, nil, err
+	}
+
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+
+	return fin, opcode, payload, nil
+}
+
+func (c *websocketConn) Close() error {
+	return c.conn.Close()
+}
@@ -1,26 +1,46 @@
 package i18n
 
 import (
+	"crypto/sha256"
+	"slices"
+	"sync"
+
 	"github.com/leonelquinteros/gotext"
 	"golang.org/x/text/language"
 )
 
 type ProjectorLocale struct {
+	mu                 sync.RWMutex
+	lang               language.Tag
 	locale             *gotext.Locale
 	customTranslations map[string]string
+
+	// fingerprint identifies the language together with the custom
+	// translations.
+	fingerprint [sha256.Size]byte
 }
 
 func NewLocale(lang language.Tag) *ProjectorLocale {
+	p := &ProjectorLocale{
+		lang:   lang,
+		locale: newGotextLocale(lang),
+	}
+	p.fingerprint = p.computeFingerprint()
+
+	return p
+}
+
+func newGotextLocale(lang language.Tag) *gotext.Locale {
 	langName, _ := lang.Base()
 	locale := gotext.NewLocale("locale", langName.String())
 	locale.AddDomain("default")
-
-	return &ProjectorLocale{
-		locale: locale,
-	}
+	return locale
 }
 
 func (p *ProjectorLocale) Get(str string, vars ...any) string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
 	str = p.locale.Get(str, vars...)
 
 	if custom, ok := p.customTranslations[str]; ok {
@@ -30,6 +50,59 @@ func (p *ProjectorLocale) Get(str string, vars ...any) string {
 	return str
 }
 
+// Language returns the language translations are loaded for.
+func (p *ProjectorLocale) Language() language.Tag {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.lang
+}
+
+// SetLanguage switches the translations to the given language.
+func (p *ProjectorLocale) SetLanguage(lang language.Tag) {
+	locale := newGotextLocale(lang)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.lang = lang
+	p.locale = locale
+	p.fingerprint = p.computeFingerprint()
+}
+
 func (p *ProjectorLocale) SetCustomTranslations(translation map[string]string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
 	p.customTranslations = translation
+	p.fingerprint = p.computeFingerprint()
+}
+
+// Fingerprint returns a hash of the language and the custom translations.
+// Locales with the same fingerprint translate every string the same way.
+func (p *ProjectorLocale) Fingerprint() [sha256.Size]byte {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.fingerprint
+}
+
+func (p *ProjectorLocale) computeFingerprint() [sha256.Size]byte {
+	h := sha256.New()
+	h.Write([]byte(p.lang.String()))
+
+	keys := make([]string, 0, len(p.customTranslations))
+	for key := range p.customTranslations {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		h.Write([]byte{0})
+		h.Write([]byte(key))
+		h.Write([]byte{0})
+		h.Write([]byte(p.customTranslations[key]))
+	}
+
+	return [sha256.Size]byte(h.Sum(nil))
 }
@@ -0,0 +1,167 @@
+package projector
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/OpenSlides/openslides-go/datastore/dsmodels"
+	"github.com/rs/zerolog/log"
+)
+
+// countdownState is the state of a countdown shown on the projector as sent
+// to clients with the countdowns event.
+type countdownState struct {
+	CountdownTime float64 `json:"countdown_time"`
+	DefaultTime   int     `json:"default_time"`
+	Running       bool    `json:"running"`
+
+	// ServerTime is the unix time the state was sent at. Clients use it to
+	// sync the countdown with the server clock.
+	ServerTime int64 `json:"server_time"`
+}
+
+// getCountdownSubscription returns a channel receiving the states of all
+// countdowns referenced by the current projections of the projector whenever
+// one of them changes.
+func (p *projector) getCountdownSubscription(ctx context.Context) <-chan map[int]countdownState {
+	updateChannel := make(chan map[int]countdownState)
+
+	go func() {
+		var last map[int]countdownState
+		p.db.NewContext(ctx, func(f *dsmodels.Fetch) {
+			projectionIDs, err := f.Projector_CurrentProjectionIDs(p.projector.ID).Value(ctx)
+			if err != nil {
+				log.Error().Err(err).Msg("failed to subscribe projection ids for countdowns")
+				return
+			}
+
+			contentObjectIDs := make([]string, len(projectionIDs))
+			for i, id := range projectionIDs {
+				f.Projection_ContentObjectID(id).Lazy(&contentObjectIDs[i])
+			}
+
+			if err := f.Execute(ctx); err != nil {
+				log.Error().Err(err).Msg("failed to load projections for countdowns")
+				return
+			}
+
+			countdowns := map[int]countdownState{}
+			for _, contentObjectID := range contentObjectIDs {
+				collection, rawID, found := strings.Cut(contentObjectID, "/")
+				if !found || collection != "projector_countdown" {
+					continue
+				}
+
+				id, err := strconv.Atoi(rawID)
+				if err != nil {
+					continue
+				}
+
+				var state countdownState
+				f.ProjectorCountdown_CountdownTime(id).Lazy(&state.CountdownTime)
+				f.ProjectorCountdown_DefaultTime(id).Lazy(&state.DefaultTime)
+				f.ProjectorCountdown_Running(id).Lazy(&state.Running)
+				if err := f.Execute(ctx); err != nil {
+					log.Error().Err(err).Msgf("failed to load countdown %d", id)
+					continue
+				}
+				countdowns[id] = state
+			}
+
+			if maps.Equal(last, countdowns) {
+				return
+			}
+			last = countdowns
+
+			select {
+			case updateChannel <- countdowns:
+			case <-ctx.Done():
+			}
+		})
+	}()
+
+	return updateChannel
+}
+
+// countdownsEvent stamps the countdown states with the current server time.
+// Returns nil if no countdown is shown.
+func countdownsEvent(countdowns map[int]countdownState) *ProjectorUpdateEvent {
+	if len(countdowns) == 0 {
+		return nil
+	}
+
+	now := time.Now().Unix()
+	stamped := make(map[int]countdownState, len(countdowns))
+	for id, state := range countdowns {
+		state.ServerTime = now
+		stamped[id] = state
+	}
+
+	data, err := json.Marshal(stamped)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to encode countdowns event")
+		return nil
+	}
+
+	return &ProjectorUpdateEvent{Event: "countdowns", Data: string(data)}
+}
+
+// DefaultTickInterval is the default interval of tick events.
+const DefaultTickInterval = time.Second
+
+// projectorTick is sent with the tick event while a countdown is running or
+// the projector shows the clock, so all projectors show the same time.
+type projectorTick struct {
+	// ServerTime is the current unix time of the server
+	ServerTime int64 `json:"server_time"`
+
+	// Countdowns are the seconds remaining of every countdown on the
+	// projector. Running countdowns are negative once they ran out.
+	Countdowns map[int]float64 `json:"countdowns"`
+}
+
+// tickEvent returns the remaining time of the countdowns at now. Returns nil
+// if no countdown is running and the clock is not shown.
+func tickEvent(countdowns map[int]countdownState, clock bool, now time.Time) *ProjectorUpdateEvent {
+	running := false
+	for _, state := range countdowns {
+		running = running || state.Running
+	}
+
+	if !running && !clock {
+		return nil
+	}
+
+	tick := projectorTick{
+		ServerTime: now.Unix(),
+		Countdowns: make(map[int]float64, len(countdowns)),
+	}
+	for id, state := range countdowns {
+		tick.Countdowns[id] = remainingTime(state, now)
+	}
+
+	data, err := json.Marshal(tick)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to encode tick event")
+		return nil
+	}
+
+	return &ProjectorUpdateEvent{Event: "tick", Data: string(data)}
+}
+
+// remainingTime returns the seconds remaining of a countdown at now. A
+// running countdown stores the unix time it ends at, a stopped or paused one
+// the seconds remaining.
+func remainingTime(state countdownState, now time.Time) float64 {
+	if !state.Running {
+		return state.CountdownTime
+	}
+
+	remaining := state.CountdownTime - float64(now.UnixMilli())/1000
+	return math.Round(remaining*1000) / 1000
+}
@@ -0,0 +1,92 @@
+package projector
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// CurrentProjection describes what a projection shows without rendering it.
+// ObjectID is the id part of the content object id.
+type CurrentProjection struct {
+	ID              int    `json:"id"`
+	Collection      string `json:"collection"`
+	ContentObjectID string `json:"content_object_id"`
+	ObjectID        int    `json:"object_id"`
+	Type            string `json:"type,omitempty"`
+	Stable          bool   `json:"stable"`
+	Hash            string `json:"hash"`
+}
+
+// GetCurrentProjections returns the projections currently shown on the
+// projector from bottom to top. The hash changes whenever one of the
+// projection fields or the rendered content of the projection in the given
+// language changes.
+func (pool *ProjectorPool) GetCurrentProjections(ctx context.Context, id int, lang language.Tag) ([]CurrentProjection, error) {
+	q := pool.db.Fetch.Projector(id)
+	data, err := q.Preload(q.CurrentProjectionList()).First(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching projector %d: %w", id, err)
+	}
+
+	rendered, err := pool.readOrCreateProjector(id, lang)
+	if err != nil {
+		return nil, fmt.Errorf("error reading rendered projector %d: %w", id, err)
+	}
+
+	rendered.mu.Lock()
+	contentHashes := maps.Clone(rendered.ProjectionsHash)
+	rendered.mu.Unlock()
+
+	type orderedProjection struct {
+		CurrentProjection
+		weight int
+	}
+
+	projections := make([]orderedProjection, 0, len(data.CurrentProjectionList))
+	for _, projection := range data.CurrentProjectionList {
+		// The object id lets clients link to the projected element.
+		collection, rawObjectID, _ := strings.Cut(projection.ContentObjectID, "/")
+		objectID, _ := strconv.Atoi(rawObjectID)
+		hash := djb2(fmt.Sprintf("%s|%s|%t|%d|%s|%x", projection.ContentObjectID, projection.Type, projection.Stable, projection.Weight, projection.Options, contentHashes[projection.ID]))
+		projections = append(projections, orderedProjection{
+			CurrentProjection: CurrentProjection{
+				ID:              projection.ID,
+				Collection:      collection,
+				ContentObjectID: projection.ContentObjectID,
+				ObjectID:        objectID,
+				Type:            projection.Type,
+				Stable:          projection.Stable,
+				Hash:            strconv.FormatUint(hash, 16),
+			},
+			weight: projection.Weight,
+		})
+	}
+
+	slices.SortFunc(projections, func(a, b orderedProjection) int {
+		if a.Stable != b.Stable {
+			if a.Stable {
+				return 1
+			}
+			return -1
+		}
+
+		if a.weight != b.weight {
+			return a.weight - b.weight
+		}
+
+		return a.ID - b.ID
+	})
+
+	result := make([]CurrentProjection, len(projections))
+	for i, projection := range projections {
+		result[i] = projection.CurrentProjection
+	}
+
+	return result, nil
+}
@@ -0,0 +1,70 @@
+package projector
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"strconv"
+
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultSlideTemplate is the built-in slide shown on projectors without
+// projections. It shows the logo, name and description of the meeting.
+const DefaultSlideTemplate = "templates/default-slide.html"
+
+// defaultSlideID is the id the default slide is sent with. Projections always
+// have positive ids.
+const defaultSlideID = 0
+
+// renderDefaultSlide renders the slide shown while the projector has no
+// projections. Returns an empty string if no default slide is configured.
+func (p *projector) renderDefaultSlide() (template.HTML, error) {
+	if p.defaultSlide == "" {
+		return "", nil
+	}
+
+	tmpl, err := template.ParseFiles(p.defaultSlide)
+	if err != nil {
+		return "", fmt.Errorf("reading default slide template: %w", err)
+	}
+
+	var content bytes.Buffer
+	err = tmpl.Execute(&content, map[string]any{
+		"Projector": p.pSettings,
+		"MediaURL":  p.mediaURL,
+	})
+	if err != nil {
+		return "", fmt.Errorf("executing default slide template: %w", err)
+	}
+
+	return template.HTML(content.String()), nil
+}
+
+// sendDefaultSlide adds the default slide to the projector of all clients or
+// removes it once content is projected.
+func (p *projector) sendDefaultSlide(shown bool) {
+	if p.defaultSlide == "" {
+		return
+	}
+
+	if !shown {
+		p.sendToAll(&ProjectorUpdateEvent{Event: "projection-deleted", Data: strconv.Itoa(defaultSlideID)})
+		return
+	}
+
+	content, err := p.renderDefaultSlide()
+	if err != nil {
+		log.Error().Err(err).Msg("failed to render default slide")
+		return
+	}
+
+	eventContent, err := json.Marshal(map[int]string{defaultSlideID: string(content)})
+	if err != nil {
+		log.Error().Err(err).Msg("failed to encode default slide event")
+		return
+	}
+
+	p.sendToAll(&ProjectorUpdateEvent{Event: "projection-updated", Data: string(eventContent)})
+}
@@ -0,0 +1,52 @@
+package projector
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"golang.org/x/text/language"
+)
+
+// FontAsset is a font file needed to render a language. It is preloaded by
+// the projector and added to the font family with the given unicode range.
+type FontAsset struct {
+	Family       string `json:"family"`
+	URL          string `json:"url"`
+	Weight       string `json:"weight"`
+	Style        string `json:"style"`
+	UnicodeRange string `json:"unicode_range"`
+}
+
+// FontMapping maps base languages like "ru" to the font assets preloaded for
+// projectors rendered in them.
+type FontMapping map[string][]FontAsset
+
+// LoadFontMapping reads a font mapping from a json file.
+func LoadFontMapping(path string) (FontMapping, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading font mapping: %w", err)
+	}
+
+	var mapping FontMapping
+	if err := json.Unmarshal(data, &mapping); err != nil {
+		return nil, fmt.Errorf("parsing font mapping: %w", err)
+	}
+
+	for lang, fonts := range mapping {
+		for _, font := range fonts {
+			if font.Family == "" || font.URL == "" {
+				return nil, fmt.Errorf("font of language %s needs a family and an url", lang)
+			}
+		}
+	}
+
+	return mapping, nil
+}
+
+// forLanguage returns the font assets of the base language of lang.
+func (m FontMapping) forLanguage(lang language.Tag) []FontAsset {
+	base, _ := lang.Base()
+	return m[base.String()]
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"time"
 
+	"github.com/OpenSlides/openslides-projector-service/pkg/projector/slide"
 	"github.com/rs/zerolog/log"
 )
 
@@ -27,6 +28,9 @@ func MetricLoop(ctx context.Context, d time.Duration, pool *ProjectorPool) {
 }
 
 func logMetricMessage(pool *ProjectorPool) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
 	renderedProjections := 0
 	listeners := 0
 	for _, projector := range pool.projectors {
@@ -34,14 +38,24 @@ func logMetricMessage(pool *ProjectorPool) {
 		listeners += len(projector.listeners)
 	}
 
-	metrics := map[string]int{
+	metrics := map[string]any{
 		"projectors":          len(pool.projectors),
 		"renderedProjections": renderedProjections,
 		"subscribers":         listeners,
 		"dbListeners":         pool.db.NumDsListeners(),
+		"renderPanics":        slide.RenderPanics(),
+		"oversizedSlides":     slide.OversizedSlides(),
+		"renderCacheBytes":    pool.RenderCache.Stats().Bytes,
+	}
+
+	if pool.MemoryBudget != nil {
+		budget := pool.MemoryBudget.Stats()
+		metrics["memoryBudgetBytes"] = budget.MaxBytes
+		metrics["memoryUsedBytes"] = budget.UsedBytes
+		metrics["memoryBudgetEvictions"] = budget.Evicted
 	}
 
-	if data, err := json.Marshal(metrics); err != nil {
+	if data, err := json.Marshal(metrics); err == nil {
 		log.Info().Msg(string(data))
 	}
 }
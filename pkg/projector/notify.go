@@ -0,0 +1,241 @@
+package projector
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/OpenSlides/openslides-go/datastore/dsmodels"
+	"github.com/rs/zerolog/log"
+)
+
+// ProjectionChange describes the projection shown on a projector after its
+// current content switched. Collection and ContentObjectID are empty if
+// nothing is projected anymore.
+type ProjectionChange struct {
+	ProjectorID     int    `json:"projector_id"`
+	Collection      string `json:"collection"`
+	ContentObjectID string `json:"content_object_id"`
+	Timestamp       int64  `json:"timestamp"`
+}
+
+// ChangeNotifier is informed whenever the current projection of a projector
+// changes. It is called from the datastore update loop and must not block.
+type ChangeNotifier interface {
+	ProjectionChanged(change ProjectionChange)
+}
+
+// WatchProjectionChanges informs the notifier whenever the topmost non
+// stable projection of a projector shows another object than before. The
+// projectors are watched in the datastore, independent of the projectors
+// rendered by the pool, so every change is reported once. If projectorIDs is
+// empty all projectors of active meetings are watched. The current state is
+// not reported.
+func (pool *ProjectorPool) WatchProjectionChanges(notifier ChangeNotifier, projectorIDs []int) {
+	var current map[int]string
+	pool.db.NewContext(pool.ctx, func(f *dsmodels.Fetch) {
+		shown, err := currentContentObjects(pool.ctx, f, projectorIDs)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to read projections for change notifications")
+			return
+		}
+
+		for id, contentObjectID := range shown {
+			previous, known := current[id]
+			if !known || previous == contentObjectID {
+				continue
+			}
+
+			collection, _, _ := strings.Cut(contentObjectID, "/")
+			notifier.ProjectionChanged(ProjectionChange{
+				ProjectorID:     id,
+				Collection:      collection,
+				ContentObjectID: contentObjectID,
+				Timestamp:       time.Now().Unix(),
+			})
+		}
+		current = shown
+	})
+}
+
+// currentContentObjects returns the content object of the topmost non
+// stable projection of each projector. Without projectorIDs the projectors
+// of all active meetings are read.
+func currentContentObjects(ctx context.Context, f *dsmodels.Fetch, projectorIDs []int) (map[int]string, error) {
+	if len(projectorIDs) == 0 {
+		meetingIDs, err := f.Organization_ActiveMeetingIDs(1).Value(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("reading active meetings: %w", err)
+		}
+
+		meetingProjectors := make([][]int, len(meetingIDs))
+		for i, id := range meetingIDs {
+			f.Meeting_ProjectorIDs(id).Lazy(&meetingProjectors[i])
+		}
+		if err := f.Execute(ctx); err != nil {
+			return nil, fmt.Errorf("reading projectors of active meetings: %w", err)
+		}
+
+		projectorIDs = slices.Concat(meetingProjectors...)
+	}
+
+	projectionIDs := make([][]int, len(projectorIDs))
+	for i, id := range projectorIDs {
+		f.Projector_CurrentProjectionIDs(id).Lazy(&projectionIDs[i])
+	}
+	if err := f.Execute(ctx); err != nil {
+		return nil, fmt.Errorf("reading current projections: %w", err)
+	}
+
+	metas := make([][]projectionMeta, len(projectorIDs))
+	for i := range projectorIDs {
+		metas[i] = make([]projectionMeta, len(projectionIDs[i]))
+		for j, id := range projectionIDs[i] {
+			f.Projection_ContentObjectID(id).Lazy(&metas[i][j].ContentObjectID)
+			f.Projection_Stable(id).Lazy(&metas[i][j].Stable)
+			f.Projection_Weight(id).Lazy(&metas[i][j].Weight)
+		}
+	}
+	if err := f.Execute(ctx); err != nil {
+		return nil, fmt.Errorf("reading projections: %w", err)
+	}
+
+	shown := make(map[int]string, len(projectorIDs))
+	for i, id := range projectorIDs {
+		// Projections are stacked by weight and id like on the projector,
+		// the current projection is the last non stable one.
+		var topmost *projectionMeta
+		var topmostID int
+		for j, projectionID := range projectionIDs[i] {
+			meta := &metas[i][j]
+			if meta.Stable {
+				continue
+			}
+
+			if topmost == nil || meta.Weight > topmost.Weight || (meta.Weight == topmost.Weight && projectionID > topmostID) {
+				topmost = meta
+				topmostID = projectionID
+			}
+		}
+
+		shown[id] = ""
+		if topmost != nil {
+			shown[id] = topmost.ContentObjectID
+		}
+	}
+
+	return shown, nil
+}
+
+const (
+	webhookQueueSize = 100
+	webhookAttempts  = 4
+	webhookBackoff   = 500 * time.Millisecond
+	webhookTimeout   = 5 * time.Second
+)
+
+// WebhookNotifier posts projection changes as json to an url. Changes are
+// queued and sent in the background, failed requests are retried with an
+// exponential backoff.
+type WebhookNotifier struct {
+	url     string
+	client  *http.Client
+	queue   chan ProjectionChange
+	backoff time.Duration
+}
+
+// NewWebhookNotifier creates a notifier posting to url and starts sending
+// in the background until ctx is done. HTTPS requests use tlsConfig if it is
+// not nil.
+func NewWebhookNotifier(ctx context.Context, url string, tlsConfig *tls.Config) *WebhookNotifier {
+	client := &http.Client{Timeout: webhookTimeout}
+	if tlsConfig != nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = tlsConfig
+		client.Transport = transport
+	}
+
+	n := &WebhookNotifier{
+		url:     url,
+		client:  client,
+		queue:   make(chan ProjectionChange, webhookQueueSize),
+		backoff: webhookBackoff,
+	}
+
+	go n.run(ctx)
+
+	return n
+}
+
+func (n *WebhookNotifier) ProjectionChanged(change ProjectionChange) {
+	select {
+	case n.queue <- change:
+	default:
+		log.Warn().Int("projector", change.ProjectorID).Msg("projection change webhook queue is full, dropping notification")
+	}
+}
+
+func (n *WebhookNotifier) run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case change := <-n.queue:
+			if err := n.send(ctx, change); err != nil {
+				log.Error().Err(err).Int("projector", change.ProjectorID).Msg("projection change webhook failed")
+			}
+		}
+	}
+}
+
+func (n *WebhookNotifier) send(ctx context.Context, change ProjectionChange) error {
+	body, err := json.Marshal(change)
+	if err != nil {
+		return fmt.Errorf("encoding projection change: %w", err)
+	}
+
+	backoff := n.backoff
+	for attempt := 1; ; attempt++ {
+		err = n.post(ctx, body)
+		if err == nil || attempt == webhookAttempts {
+			return err
+		}
+
+		log.Debug().Err(err).Msgf("projection change webhook attempt %d failed", attempt)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func (n *WebhookNotifier) post(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("sending request: %w", err)
+	}
+
+	if err := resp.Body.Close(); err != nil {
+		log.Err(err).Msg("error closing response body")
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,50 @@
+package projector
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWebhookNotifierRetries(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var requests atomic.Int32
+	received := make(chan ProjectionChange, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+
+		var change ProjectionChange
+		if err := json.NewDecoder(r.Body).Decode(&change); err != nil {
+			t.Errorf("decode payload: %v", err)
+		}
+		received <- change
+	}))
+	defer srv.Close()
+
+	notifier := NewWebhookNotifier(ctx, srv.URL, nil)
+	notifier.backoff = time.Millisecond
+
+	notifier.ProjectionChanged(ProjectionChange{ProjectorID: 1, Collection: "motion", ContentObjectID: "motion/3"})
+
+	select {
+	case change := <-received:
+		if change.ProjectorID != 1 || change.ContentObjectID != "motion/3" {
+			t.Errorf("unexpected change %v", change)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("webhook was not called")
+	}
+
+	if got := requests.Load(); got != 3 {
+		t.Errorf("expected 3 requests, got %d", got)
+	}
+}
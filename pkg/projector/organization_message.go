@@ -0,0 +1,133 @@
+package projector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/OpenSlides/openslides-go/datastore/dskey"
+	"github.com/OpenSlides/openslides-go/datastore/dsmodels"
+	"github.com/rs/zerolog/log"
+)
+
+// OrganizationMessage is a message of the organization shown on top of every
+// live projector of every meeting, e.g. "Lunch in the foyer". It is read from
+// a text field of the organization in the datastore, see
+// ProjectorPool.WatchOrganizationMessage, and does not depend on the content
+// or permissions of a meeting.
+//
+// A nil message is never shown.
+type OrganizationMessage struct {
+	mu        sync.Mutex
+	text      string
+	listeners []chan string
+}
+
+// WatchOrganizationMessage shows the value of the given text field of the
+// organization as organization message on all projectors of the pool and
+// follows its changes in the datastore. An empty field clears the message.
+func (pool *ProjectorPool) WatchOrganizationMessage(field string) error {
+	key, err := dskey.FromParts("organization", 1, field)
+	if err != nil {
+		return fmt.Errorf("invalid organization message field: %w", err)
+	}
+
+	pool.db.NewContext(pool.ctx, func(f *dsmodels.Fetch) {
+		data, err := f.Get(pool.ctx, key)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to read organization message")
+			return
+		}
+
+		var text string
+		if value := data[key]; value != nil {
+			if err := json.Unmarshal(value, &text); err != nil {
+				log.Error().Err(err).Str("field", field).Msg("organization message is no text")
+				return
+			}
+		}
+
+		pool.OrganizationMessage.set(text)
+	})
+
+	return nil
+}
+
+// set shows the text on all projectors. An empty text clears the message.
+func (m *OrganizationMessage) set(text string) {
+	if m == nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.text = text
+	for _, listener := range m.listeners {
+		// Only the latest text is of interest to slow listeners
+		select {
+		case <-listener:
+		default:
+		}
+		listener <- text
+	}
+}
+
+// Text returns the message currently shown.
+func (m *OrganizationMessage) Text() string {
+	if m == nil {
+		return ""
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.text
+}
+
+// subscribe returns a channel receiving every change of the message until the
+// context is done.
+func (m *OrganizationMessage) subscribe(ctx context.Context) <-chan string {
+	if m == nil {
+		return nil
+	}
+
+	listener := make(chan string, 1)
+
+	m.mu.Lock()
+	m.listeners = append(m.listeners, listener)
+	m.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		m.listeners = slices.DeleteFunc(m.listeners, func(el chan string) bool { return el == listener })
+	}()
+
+	return listener
+}
+
+// organizationMessageEvent sends the message of the organization. The data is
+// null if the message was cleared.
+func organizationMessageEvent(text string) *ProjectorUpdateEvent {
+	var message *organizationMessage
+	if text != "" {
+		message = &organizationMessage{Text: text}
+	}
+
+	data, err := json.Marshal(message)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to encode organization message event")
+		return nil
+	}
+
+	return &ProjectorUpdateEvent{Event: "organization-message", Data: string(data)}
+}
+
+type organizationMessage struct {
+	Text string `json:"text"`
+}
@@ -2,36 +2,153 @@ package projector
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"html/template"
+	"slices"
 	"sync"
+	"time"
 
+	"github.com/OpenSlides/openslides-go/datastore/dsfetch"
 	"github.com/OpenSlides/openslides-go/datastore/flow"
 	"github.com/OpenSlides/openslides-projector-service/pkg/database"
+	"github.com/OpenSlides/openslides-projector-service/pkg/projector/slide"
+	"github.com/rs/zerolog/log"
 	"golang.org/x/text/language"
 )
 
 type ProjectorPool struct {
-	ctx        context.Context
-	mu         sync.Mutex
-	projectors map[string]*projector
-	db         *database.Datastore
-	ds         flow.Flow
+	ctx         context.Context
+	mu          sync.Mutex
+	projectors  map[string]*projector
+	lastContent map[string]cachedContent
+	db          *database.Datastore
+	ds          flow.Flow
+	subscribers subscriberCounter
+
+	// MediaURL is the prefix of mediafile urls in rendered content. Has to
+	// be set before the pool is used.
+	MediaURL string
+
+	// MaxSlideSize is the maximum size of a rendered slide in bytes. Zero
+	// disables the limit. Has to be set before the pool is used.
+	MaxSlideSize int
+
+	// StaleWindow is the duration the last rendered content of a projector
+	// is served if the projector cannot be read from the datastore. Has to
+	// be set before the pool is used.
+	StaleWindow time.Duration
+
+	// RetryDelay is the delay before a projection is read again after the
+	// datastore was unavailable. It doubles with every further failure. Has
+	// to be set before the pool is used.
+	RetryDelay time.Duration
+
+	// Fonts are the font assets preloaded per language. Has to be set before
+	// the pool is used.
+	Fonts FontMapping
+
+	// ContentTransforms post-process the html of every rendered projection
+	// in order before it is sent. Has to be set before the pool is used.
+	ContentTransforms []ContentTransform
+
+	// TemplateFuncs are added to the functions available in slide
+	// templates. Has to be set before the pool is used.
+	TemplateFuncs template.FuncMap
+
+	// RenderCache shares rendered slides between the projectors of the pool.
+	// Nil disables caching. Has to be set before the pool is used.
+	RenderCache *slide.RenderCache
+
+	// MemoryBudget limits the memory of the render cache and the events kept
+	// to resume subscriptions. Nil disables the limit. Has to be set before
+	// the pool is used.
+	MemoryBudget *slide.MemoryBudget
+
+	// OrganizationMessage is shown on top of all projectors of the pool
+	OrganizationMessage *OrganizationMessage
+
+	// TickInterval is the interval of tick events sent while a countdown
+	// runs or the clock is shown. Zero disables them. Has to be set before
+	// the pool is used.
+	TickInterval time.Duration
+
+	// DefaultSlide is the template file rendered on projectors without
+	// projections, e.g. DefaultSlideTemplate. Empty leaves them blank. Has
+	// to be set before the pool is used.
+	DefaultSlide string
+
+	// StaleAfter marks the content of projectors with subscribers as
+	// possibly outdated if the datastore changed but no change notification
+	// was received for this duration. Zero disables it. Has to be set before
+	// the pool is used.
+	StaleAfter time.Duration
+
+	// ReplayBufferSize is the number of events kept per projector to resume
+	// subscriptions, at least one. Has to be set before the pool is used.
+	ReplayBufferSize int
+
+	// Sanitizer configures the elements kept in rich text like motion texts
+	// per meeting. Nil keeps the default elements. Has to be set before the
+	// pool is used.
+	Sanitizer *slide.SanitizerConfig
+
+	// DefaultLanguage renders projectors following the language of their
+	// meeting while the meeting has no valid language. Has to be set before
+	// the pool is used.
+	DefaultLanguage language.Tag
+}
+
+// cachedContent is the last content of a projector successfully served.
+type cachedContent struct {
+	content    string
+	renderedAt time.Time
 }
 
 func NewProjectorPool(ctx context.Context, db *database.Datastore, ds flow.Flow) *ProjectorPool {
 	return &ProjectorPool{
-		ctx:        ctx,
-		db:         db,
-		ds:         ds,
-		projectors: make(map[string]*projector),
+		ctx:                 ctx,
+		db:                  db,
+		ds:                  ds,
+		projectors:          make(map[string]*projector),
+		lastContent:         make(map[string]cachedContent),
+		MediaURL:            slide.DefaultMediaURL,
+		RenderCache:         slide.NewRenderCache(slide.DefaultRenderCacheSize),
+		OrganizationMessage: &OrganizationMessage{},
+		TickInterval:        DefaultTickInterval,
+		ReplayBufferSize:    DefaultReplayBufferSize,
+		DefaultLanguage:     language.English,
+		RetryDelay:          slide.DefaultRetryDelay,
 	}
 }
 
-func (pool *ProjectorPool) readOrCreateProjector(id int, lang language.Tag) (*projector, error) {
-	projectorId := fmt.Sprintf("%d_%s", id, lang)
-	if projector, ok := pool.projectors[projectorId]; ok {
-		return projector, nil
+func (pool *ProjectorPool) renderOptions() renderOptions {
+	return renderOptions{
+		MediaURL:            pool.MediaURL,
+		MaxContentSize:      pool.MaxSlideSize,
+		Fonts:               pool.Fonts,
+		Transforms:          pool.ContentTransforms,
+		TemplateFuncs:       pool.TemplateFuncs,
+		RenderCache:         pool.RenderCache,
+		OrganizationMessage: pool.OrganizationMessage,
+		TickInterval:        pool.TickInterval,
+		DefaultSlide:        pool.DefaultSlide,
+		StaleAfter:          pool.StaleAfter,
+		ReplayBufferSize:    pool.ReplayBufferSize,
+		MemoryBudget:        pool.MemoryBudget,
+		Sanitizer:           pool.Sanitizer,
+		DefaultLanguage:     pool.DefaultLanguage,
+		RetryDelay:          pool.RetryDelay,
 	}
+}
+
+func projectorKey(id int, lang language.Tag) string {
+	return fmt.Sprintf("%d_%s", id, lang)
+}
+
+func (pool *ProjectorPool) readOrCreateProjector(id int, lang language.Tag) (*projector, error) {
+	projectorId := projectorKey(id, lang)
 
 	pool.mu.Lock()
 	defer pool.mu.Unlock()
@@ -39,26 +156,110 @@ func (pool *ProjectorPool) readOrCreateProjector(id int, lang language.Tag) (*pr
 		return projector, nil
 	}
 
-	projector, err := newProjector(pool.ctx, id, lang, pool.db, pool.ds)
+	projector, err := newProjector(pool.ctx, id, lang, pool.db, pool.ds, pool.renderOptions())
 	if err != nil {
 		return nil, fmt.Errorf("error creating new projector: %w", err)
 	}
 
 	pool.projectors[projectorId] = projector
+	go func() {
+		// The projector stops when it was deleted
+		<-projector.done
+		projector.replay.close()
+
+		pool.mu.Lock()
+		defer pool.mu.Unlock()
+		if pool.projectors[projectorId] == projector {
+			delete(pool.projectors, projectorId)
+		}
+	}()
+
 	return projector, nil
 }
 
 func (pool *ProjectorPool) GetProjectorContent(id int, lang language.Tag) (*string, error) {
+	content, _, err := pool.GetProjectorContentState(id, lang)
+	return content, err
+}
+
+// GetProjectorContentState returns the content of the projector and whether
+// it is stale. If the projector cannot be read from the datastore, the content
+// served last is returned as stale for the duration of the stale window.
+func (pool *ProjectorPool) GetProjectorContentState(id int, lang language.Tag) (*string, bool, error) {
+	key := projectorKey(id, lang)
 	projector, err := pool.readOrCreateProjector(id, lang)
 	if err != nil {
-		return nil, fmt.Errorf("error retrieving projector content: %w", err)
+		var doesNotExist dsfetch.DoesNotExistError
+		if !errors.As(err, &doesNotExist) {
+			if content, ok := pool.cachedContent(key); ok {
+				log.Warn().Err(err).Msgf("serving stale content of projector %d", id)
+				return &content, true, nil
+			}
+		}
+
+		pool.mu.Lock()
+		delete(pool.lastContent, key)
+		pool.mu.Unlock()
+
+		return nil, false, fmt.Errorf("error retrieving projector content: %w", err)
+	}
+
+	content := projector.currentContent()
+	pool.mu.Lock()
+	pool.lastContent[key] = cachedContent{content: content, renderedAt: time.Now()}
+	pool.mu.Unlock()
+
+	return &content, projector.stale.Load(), nil
+}
+
+// cachedContent returns the content served last for the projector if it is
+// still within the stale window.
+func (pool *ProjectorPool) cachedContent(key string) (string, bool) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	cached, ok := pool.lastContent[key]
+	if !ok {
+		return "", false
+	}
+
+	if time.Since(cached.renderedAt) >= pool.StaleWindow {
+		delete(pool.lastContent, key)
+		return "", false
 	}
 
-	return &projector.Content, nil
+	return cached.content, true
 }
 
-func (pool *ProjectorPool) GetProjectorPreview(id int, lang language.Tag, settings ProjectorPreviewSettings) (*string, error) {
-	content, err := projectorPreview(pool.ctx, id, lang, pool.db, pool.ds, settings)
+// GetProjectorContentAtPosition renders the projector as it was at the given
+// datastore position.
+func (pool *ProjectorPool) GetProjectorContentAtPosition(id int, lang language.Tag, position int) (*string, error) {
+	db, err := pool.db.AtPosition(position)
+	if err != nil {
+		return nil, fmt.Errorf("error reading datastore at position %d: %w", position, err)
+	}
+
+	content, err := projectorPreview(pool.ctx, id, lang, db, pool.ds, pool.renderOptions(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving projector content at position %d: %w", position, err)
+	}
+
+	return &content, nil
+}
+
+// GetProjectorPreview renders the projector with the given settings. If
+// position is set the data of this datastore position is used.
+func (pool *ProjectorPool) GetProjectorPreview(id int, lang language.Tag, settings ProjectorPreviewSettings, position int) (*string, error) {
+	db := pool.db
+	if position > 0 {
+		var err error
+		db, err = pool.db.AtPosition(position)
+		if err != nil {
+			return nil, fmt.Errorf("error reading datastore at position %d: %w", position, err)
+		}
+	}
+
+	content, err := projectorPreview(pool.ctx, id, lang, db, pool.ds, pool.renderOptions(), &settings)
 	if err != nil {
 		return nil, fmt.Errorf("error retrieving projector preview content: %w", err)
 	}
@@ -66,18 +267,170 @@ func (pool *ProjectorPool) GetProjectorPreview(id int, lang language.Tag, settin
 	return &content, err
 }
 
-func (pool *ProjectorPool) SubscribeProjectorContent(ctx context.Context, id int, lang language.Tag) (<-chan *ProjectorUpdateEvent, error) {
+// RenderProjector renders the projector once with its stored settings
+// without keeping it in the pool.
+func (pool *ProjectorPool) RenderProjector(id int, lang language.Tag) (*string, error) {
+	content, err := projectorPreview(pool.ctx, id, lang, pool.db, pool.ds, pool.renderOptions(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("error rendering projector: %w", err)
+	}
+
+	return &content, nil
+}
+
+// SubscribeProjectorContent returns a channel receiving all updates of the
+// projector. If lang is language.Und the projector is rendered in the
+// language of its meeting and follows changes of it. If collections are
+// given only projection events of projections showing an object of these
+// collections are passed on.
+func (pool *ProjectorPool) SubscribeProjectorContent(ctx context.Context, id int, lang language.Tag, collections []string) (<-chan *ProjectorUpdateEvent, error) {
+	channel, _, err := pool.ResumeProjectorContent(ctx, id, lang, collections, "")
+	return channel, err
+}
+
+// ResumeProjectorContent subscribes to the projector like
+// SubscribeProjectorContent. If lastEventID is the id of an event received
+// by an earlier subscription, the events sent after it are received first.
+// Returns false if the events are not kept anymore and the client has to
+// replace its content.
+func (pool *ProjectorPool) ResumeProjectorContent(ctx context.Context, id int, lang language.Tag, collections []string, lastEventID string) (<-chan *ProjectorUpdateEvent, bool, error) {
 	projector, err := pool.readOrCreateProjector(id, lang)
 	if err != nil {
-		return nil, fmt.Errorf("error retrieving projector channel: %w", err)
+		return nil, false, fmt.Errorf("error retrieving projector channel: %w", err)
+	}
+
+	added := make(chan addedListener, 1)
+	select {
+	case projector.AddListener <- projectorListener{lastEventID: lastEventID, added: added}:
+	case <-projector.done:
+		return nil, false, fmt.Errorf("projector %d is not available anymore", id)
+	}
+
+	var listener addedListener
+	select {
+	case listener = <-added:
+	case <-projector.done:
+		return nil, false, fmt.Errorf("projector %d is not available anymore", id)
 	}
+	channel, replayed := listener.events, listener.resumed
+
+	if lastEventID != "" {
+		if replayed {
+			resumesReplayed.Add(1)
+		} else {
+			resumesResynced.Add(1)
+		}
+	}
+
+	log.Ctx(ctx).Debug().Int("projector", id).Msg("listener added to projector")
+	pool.subscribers.add(id, 1)
 
-	channel := make(chan *ProjectorUpdateEvent, 10)
-	projector.AddListener <- channel
 	go func() {
-		<-ctx.Done()
-		projector.RemoveListener <- channel
+		defer pool.subscribers.add(id, -1)
+
+		select {
+		case <-ctx.Done():
+		case <-projector.done:
+			log.Ctx(ctx).Debug().Int("projector", id).Msg("projector stopped with listener attached")
+			return
+		}
+
+		select {
+		case projector.RemoveListener <- channel:
+			log.Ctx(ctx).Debug().Int("projector", id).Msg("listener removed from projector")
+		case <-projector.done:
+		}
 	}()
 
-	return channel, nil
+	if len(collections) == 0 {
+		return channel, replayed, nil
+	}
+
+	filtered := make(chan *ProjectorUpdateEvent, 10)
+	go func() {
+		defer close(filtered)
+
+		forward := func(event *ProjectorUpdateEvent) {
+			if event = filterProjectorEvent(event, collections); event != nil {
+				select {
+				case filtered <- event:
+				case <-ctx.Done():
+				}
+			}
+		}
+
+		for {
+			select {
+			case event, ok := <-channel:
+				if !ok {
+					return
+				}
+				forward(event)
+			case <-projector.done:
+				// Pass on events queued before the projector stopped
+				for {
+					select {
+					case event, ok := <-channel:
+						if !ok {
+							return
+						}
+						forward(event)
+					default:
+						return
+					}
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return filtered, replayed, nil
+}
+
+// filterProjectorEvent strips all projections not belonging to one of the
+// given collections from the event. Returns nil if nothing is left to send.
+func filterProjectorEvent(event *ProjectorUpdateEvent, collections []string) *ProjectorUpdateEvent {
+	if event.collections == nil {
+		return event
+	}
+
+	if event.projections == nil {
+		for _, collection := range event.collections {
+			if slices.Contains(collections, collection) {
+				return event
+			}
+		}
+
+		return nil
+	}
+
+	projections := map[int]string{}
+	for id, content := range event.projections {
+		if slices.Contains(collections, event.collections[id]) {
+			projections[id] = content
+		}
+	}
+
+	if len(projections) == 0 {
+		return nil
+	}
+
+	if len(projections) == len(event.projections) {
+		return event
+	}
+
+	data, err := json.Marshal(projections)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to encode filtered update event")
+		return nil
+	}
+
+	return &ProjectorUpdateEvent{
+		ID:          event.ID,
+		Event:       event.Event,
+		Data:        string(data),
+		projections: projections,
+		collections: event.collections,
+	}
 }
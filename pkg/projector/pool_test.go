@@ -0,0 +1,205 @@
+package projector
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/OpenSlides/openslides-go/datastore/dskey"
+	"github.com/OpenSlides/openslides-projector-service/pkg/dstest"
+	"github.com/OpenSlides/openslides-projector-service/pkg/projector/slide"
+	"golang.org/x/text/language"
+)
+
+func TestFilterProjectorEvent(t *testing.T) {
+	collections := []string{"agenda_item", "motion"}
+
+	updated := &ProjectorUpdateEvent{
+		Event:       "projection-updated",
+		Data:        `{"1":"agenda","2":"poll"}`,
+		projections: map[int]string{1: "agenda", 2: "poll"},
+		collections: map[int]string{1: "agenda_item", 2: "poll"},
+	}
+
+	filtered := filterProjectorEvent(updated, collections)
+	if filtered == nil {
+		t.Fatalf("expected update of included collection to pass")
+	}
+
+	var data map[int]string
+	if err := json.Unmarshal([]byte(filtered.Data), &data); err != nil {
+		t.Fatalf("parse filtered event: %v", err)
+	}
+
+	if len(data) != 1 || data[1] != "agenda" {
+		t.Errorf("expected only projection 1 in event, got %v", data)
+	}
+
+	excluded := &ProjectorUpdateEvent{
+		Event:       "projection-updated",
+		Data:        `{"2":"poll"}`,
+		projections: map[int]string{2: "poll"},
+		collections: map[int]string{2: "poll"},
+	}
+
+	if filterProjectorEvent(excluded, collections) != nil {
+		t.Errorf("expected update of excluded collection to be suppressed")
+	}
+
+	deleted := &ProjectorUpdateEvent{
+		Event:       "projection-deleted",
+		Data:        "3",
+		collections: map[int]string{3: "topic"},
+	}
+
+	if filterProjectorEvent(deleted, collections) != nil {
+		t.Errorf("expected deletion of excluded collection to be suppressed")
+	}
+
+	settings := &ProjectorUpdateEvent{Event: "settings", Data: "{}"}
+	if filterProjectorEvent(settings, collections) != settings {
+		t.Errorf("expected non projection events to pass")
+	}
+}
+
+func TestSubscribeFilteredByCollection(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	data := testProjectorData()
+	data["projector/1/current_projection_ids"] = "[1,2]"
+	data["projection/1/id"] = "1"
+	data["projection/1/meeting_id"] = "1"
+	data["projection/1/content_object_id"] = `"topic/5"`
+	data["projection/2/id"] = "2"
+	data["projection/2/meeting_id"] = "1"
+	data["projection/2/type"] = `"wifi_access_data"`
+	data["projection/2/content_object_id"] = `"meeting/1"`
+	data["meeting/1/users_pdf_wlan_ssid"] = `"Venue"`
+	data["topic/5/id"] = "5"
+	data["topic/5/meeting_id"] = "1"
+	data["topic/5/sequential_number"] = "5"
+	data["topic/5/list_of_speakers_id"] = "1"
+	data["topic/5/title"] = `"Lunch"`
+	data["topic/5/agenda_item_id"] = "3"
+	data["agenda_item/3/id"] = "3"
+	data["agenda_item/3/meeting_id"] = "1"
+	data["agenda_item/3/content_object_id"] = `"topic/5"`
+	flow := dstest.NewFlow(data)
+	pool := newTestPool(t, ctx, flow)
+
+	all := subscribe(t, ctx, pool, language.English)
+	topics, err := pool.SubscribeProjectorContent(ctx, 1, language.English, []string{"topic"})
+	if err != nil {
+		t.Fatalf("subscribe topics: %v", err)
+	}
+
+	// nextUpdate returns the projections of the next projection update
+	nextUpdate := func(events <-chan *ProjectorUpdateEvent) map[int]string {
+		t.Helper()
+
+		timeout := time.After(2 * time.Second)
+		for {
+			select {
+			case event := <-events:
+				if event.Event == "projection-deleted" {
+					t.Fatalf("unexpected deletion %s", event.Data)
+				}
+				if event.Event != "projection-updated" {
+					continue
+				}
+
+				var projections map[int]string
+				if err := json.Unmarshal([]byte(event.Data), &projections); err != nil {
+					t.Fatalf("parse update: %v", err)
+				}
+				return projections
+			case <-timeout:
+				t.Fatalf("no projection update received")
+			}
+		}
+	}
+
+	flow.Changes <- map[dskey.Key][]byte{
+		dskey.MustKey("meeting/1/users_pdf_wlan_ssid"): []byte(`"Lobby"`),
+	}
+	if projections := nextUpdate(all); !strings.Contains(projections[2], "Lobby") {
+		t.Fatalf("expected the wifi projection to be updated, got %v", projections)
+	}
+
+	flow.Changes <- map[dskey.Key][]byte{
+		dskey.MustKey("topic/5/title"): []byte(`"Dinner"`),
+	}
+	if projections := nextUpdate(all); !strings.Contains(projections[1], "Dinner") {
+		t.Fatalf("expected the topic projection to be updated, got %v", projections)
+	}
+
+	// The update of the wifi projection is not sent to the filtered
+	// subscription, its first update is the one of the topic.
+	projections := nextUpdate(topics)
+	if _, ok := projections[2]; ok {
+		t.Errorf("expected the wifi projection to be excluded, got %v", projections)
+	}
+	if !strings.Contains(projections[1], "Dinner") {
+		t.Errorf("expected the topic update, got %v", projections)
+	}
+}
+
+func TestRenderCacheSharedBetweenProjectors(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	data := testProjectorData()
+	data["projector/1/current_projection_ids"] = "[1]"
+	data["projector/2/id"] = "2"
+	data["projector/2/meeting_id"] = "1"
+	data["projector/2/sequential_number"] = "2"
+	data["projector/2/name"] = `"Side"`
+	data["projector/2/current_projection_ids"] = "[2]"
+	for _, id := range []string{"1", "2"} {
+		data["projection/"+id+"/id"] = id
+		data["projection/"+id+"/meeting_id"] = "1"
+		data["projection/"+id+"/content_object_id"] = `"topic/5"`
+	}
+	data["topic/5/id"] = "5"
+	data["topic/5/meeting_id"] = "1"
+	data["topic/5/sequential_number"] = "5"
+	data["topic/5/list_of_speakers_id"] = "1"
+	data["topic/5/title"] = `"Lunch"`
+	data["topic/5/agenda_item_id"] = "3"
+	data["agenda_item/3/id"] = "3"
+	data["agenda_item/3/meeting_id"] = "1"
+	data["agenda_item/3/content_object_id"] = `"topic/5"`
+	flow := dstest.NewFlow(data)
+	pool := newTestPool(t, ctx, flow)
+	pool.RenderCache = slide.NewRenderCache(10)
+
+	render := func(id int, expected string) {
+		t.Helper()
+
+		content, err := pool.RenderProjector(id, language.English)
+		if err != nil {
+			t.Fatalf("render projector %d: %v", id, err)
+		}
+
+		if !strings.Contains(*content, expected) {
+			t.Errorf("expected projector %d to show %s, got %q", id, expected, *content)
+		}
+	}
+
+	render(1, "Lunch")
+	render(2, "Lunch")
+	if stats := pool.RenderCache.Stats(); stats.Entries != 1 || stats.Hits != 1 {
+		t.Errorf("expected both projectors to share one render, got %+v", stats)
+	}
+
+	flow.Set("topic/5/title", []byte(`"Dinner"`))
+
+	render(1, "Dinner")
+	render(2, "Dinner")
+	if stats := pool.RenderCache.Stats(); stats.Entries != 2 || stats.Hits != 2 {
+		t.Errorf("expected a new shared render after the change, got %+v", stats)
+	}
+}
@@ -7,9 +7,12 @@ import (
 	"errors"
 	"fmt"
 	"html/template"
+	"maps"
 	"runtime/debug"
 	"slices"
 	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/OpenSlides/openslides-go/datastore/dsfetch"
@@ -41,6 +44,7 @@ type ProjectorPreviewSettings struct {
 	ShowTitle              bool   `json:"show_title"`
 	ShowLogo               bool   `json:"show_logo"`
 	ShowClock              bool   `json:"show_clock"`
+	ThemeID                int    `json:"theme_id,omitempty"`
 }
 
 type ProjectorSettings struct {
@@ -69,10 +73,14 @@ type ProjectorSettings struct {
 	ShowLogo               bool
 	ShowClock              bool
 	Theme                  dsmodels.Theme
+	Palette                ThemePalette
 }
 
 type projector struct {
+	// mu guards the state shared by the projector loop and settings updates
+	mu                 sync.Mutex
 	ctxCancel          context.CancelFunc
+	done               <-chan struct{}
 	db                 *database.Datastore
 	slideRouter        *slide.SlideRouter
 	projector          *dsmodels.Projector
@@ -80,39 +88,249 @@ type projector struct {
 	pSettingsOverwrite *ProjectorPreviewSettings
 	listeners          []chan *ProjectorUpdateEvent
 	locale             *i18n.ProjectorLocale
+	followMeetingLang  bool
+	defaultLang        language.Tag
+	mediaURL           string
+	staleAfter         time.Duration
+	tickInterval       time.Duration
+	fonts              FontMapping
+	contentTransforms  []ContentTransform
+	defaultSlide       string
+	initialized        atomic.Bool
+	stale              atomic.Bool
+	lastUpdate         time.Time
+	updatesStalled     bool
 	Content            string
 	Projections        map[int]template.HTML
 	ProjectionsHash    map[int]uint64
-	AddListener        chan chan *ProjectorUpdateEvent
+	ProjectionsMeta    map[int]projectionMeta
+	settingsBase       []byte
+	transform          projectorTransform
+	countdowns         map[int]countdownState
+	speakerCountdown   *speakerCountdownState
+	orgMessage         *OrganizationMessage
+	orgMessageText     string
+	replay             *replayBuffer
+	AddListener        chan projectorListener
 	RemoveListener     chan (<-chan *ProjectorUpdateEvent)
 }
 
 type ProjectorUpdateEvent struct {
+	// ID identifies the event to resume a subscription after it. Empty for
+	// events which are not replayed.
+	ID    string
 	Event string
 	Data  string
+
+	// Set for projection events to allow filtering them per subscriber
+	projections map[int]string
+	collections map[int]string
+
+	// Encodings of the event other than the JSON data, shared by all
+	// subscribers of the same format
+	encodedMu sync.Mutex
+	encoded   map[string][]byte
+}
+
+// Encoded returns the event in the given format. It is encoded by the first
+// subscriber asking for the format, later subscribers get the same bytes.
+func (e *ProjectorUpdateEvent) Encoded(format string, encode func(*ProjectorUpdateEvent) ([]byte, error)) ([]byte, error) {
+	e.encodedMu.Lock()
+	defer e.encodedMu.Unlock()
+
+	if encoded, ok := e.encoded[format]; ok {
+		return encoded, nil
+	}
+
+	encoded, err := encode(e)
+	if err != nil {
+		return nil, err
+	}
+
+	if e.encoded == nil {
+		e.encoded = make(map[string][]byte)
+	}
+	e.encoded[format] = encoded
+	return encoded, nil
+}
+
+// projectorTransform is sent on changes of only the scroll or scale of the
+// projector.
+type projectorTransform struct {
+	Scroll int `json:"scroll"`
+	Scale  int `json:"scale"`
+}
+
+type projectionContent struct {
+	Content string
+	projectionMeta
+
+	// Stale is set while the projection could not be rendered because the
+	// datastore was unavailable. Content holds the last rendered state
+	// until the projection is rendered again.
+	Stale bool
+}
+
+// slideError is sent with the slide_error event if a projection could not be
+// rendered. The connection stays open, only the projection is affected.
+type slideError struct {
+	ProjectionID int    `json:"projection_id"`
+	Collection   string `json:"collection"`
+	Message      string `json:"message"`
+}
+
+// projectionMeta holds the attributes deciding the stacking order of a
+// projection. Stable projections (overlays) are rendered above the others,
+// within each group projections are ordered by weight.
+type projectionMeta struct {
+	Weight          int
+	Stable          bool
+	Collection      string
+	ContentObjectID string
+	View            slide.ProjectionView
+
+	// Error is the message for users if the slide could not be rendered
+	Error string
+}
+
+type renderedProjection struct {
+	ID      int
+	Content template.HTML
+	Style   template.CSS
+}
+
+// Base z-index values of projections. Stable projections (overlays) are
+// placed above the header and footer of the projector.
+const (
+	projectionZIndex = 1
+	overlayZIndex    = 20
+)
+
+// projectionStacking is the position of a projection on the projector as sent
+// to clients with the projection-order event.
+type projectionStacking struct {
+	ID     int `json:"id"`
+	ZIndex int `json:"z_index"`
+}
+
+// listenerBuffer is the number of events a listener can fall behind before
+// events sent to all listeners are dropped for it.
+const listenerBuffer = 10
+
+// projectorListener asks the projector for a new listener. If lastEventID is
+// set the events sent after it are replayed. The channel of the listener is
+// sent to added once it holds all events a new listener starts with.
+type projectorListener struct {
+	lastEventID string
+	added       chan<- addedListener
+}
+
+// addedListener is a listener registered with the projector. Resumed tells
+// whether the missed events could be replayed.
+type addedListener struct {
+	events  chan *ProjectorUpdateEvent
+	resumed bool
+}
+
+// renderOptions configure how the slides of a projector are rendered.
+type renderOptions struct {
+	MediaURL       string
+	MaxContentSize int
+
+	// Fonts are the font assets preloaded per language
+	Fonts FontMapping
+
+	// Transforms post-process the html of every rendered projection
+	Transforms []ContentTransform
+
+	// TemplateFuncs are added to the functions of the slide templates
+	TemplateFuncs template.FuncMap
+
+	// RenderCache is shared by the slide routers of all projectors
+	RenderCache *slide.RenderCache
+
+	// OrganizationMessage is shown on top of the projector. Not used for
+	// previews.
+	OrganizationMessage *OrganizationMessage
+
+	// TickInterval is the interval of tick events while a countdown runs or
+	// the clock is shown. Zero disables them. Not used for previews.
+	TickInterval time.Duration
+
+	// DefaultSlide is the template rendered while the projector has no
+	// projections. Empty shows nothing.
+	DefaultSlide string
+
+	// StaleAfter is the duration without datastore updates after which the
+	// content of a projector with listeners is marked as stale, if the
+	// datastore changed in the meantime. Zero disables it. Not used for
+	// previews.
+	StaleAfter time.Duration
+
+	// ReplayBufferSize is the number of events kept to resume
+	// subscriptions. Values below one keep a single event.
+	ReplayBufferSize int
+
+	// MemoryBudget limits the events kept to resume subscriptions together
+	// with the render cache. Nil does not limit them. Not used for previews.
+	MemoryBudget *slide.MemoryBudget
+
+	// Sanitizer configures the elements kept in rich text per meeting. Nil
+	// keeps the default elements.
+	Sanitizer *slide.SanitizerConfig
+
+	// DefaultLanguage is used by projectors following the language of their
+	// meeting while the meeting has no valid language.
+	DefaultLanguage language.Tag
+
+	// RetryDelay is the first delay before a projection is read again after
+	// the datastore was unavailable.
+	RetryDelay time.Duration
 }
 
-func newProjector(parentCtx context.Context, id int, lang language.Tag, db *database.Datastore, ds flow.Flow) (*projector, error) {
+func newProjector(parentCtx context.Context, id int, lang language.Tag, db *database.Datastore, ds flow.Flow, opts renderOptions) (*projector, error) {
 	ctx, cancel := context.WithCancel(parentCtx)
 
-	data, err := db.Fetch.Projector(id).First(ctx)
+	data, err := db.SnapshotFetch().Projector(id).First(ctx)
 	if err != nil {
 		cancel()
 		return nil, fmt.Errorf("error fetching projector from db %w", err)
 	}
 
 	locale := i18n.NewLocale(lang)
+	slideRouter := slide.New(ctx, db, ds, locale)
+	slideRouter.MediaURL = opts.MediaURL
+	slideRouter.MaxContentSize = opts.MaxContentSize
+	slideRouter.Funcs = opts.TemplateFuncs
+	slideRouter.Cache = opts.RenderCache
+	slideRouter.Sanitizer = opts.Sanitizer
+	if opts.RetryDelay > 0 {
+		slideRouter.RetryDelay = opts.RetryDelay
+	}
 	p := &projector{
-		ctxCancel:       cancel,
-		db:              db,
-		projector:       &data,
-		pSettings:       &ProjectorSettings{},
-		slideRouter:     slide.New(ctx, db, ds, locale),
-		locale:          locale,
-		Projections:     make(map[int]template.HTML),
-		ProjectionsHash: make(map[int]uint64),
-		AddListener:     make(chan chan *ProjectorUpdateEvent),
-		RemoveListener:  make(chan (<-chan *ProjectorUpdateEvent)),
+		ctxCancel:         cancel,
+		done:              ctx.Done(),
+		db:                db,
+		projector:         &data,
+		pSettings:         &ProjectorSettings{},
+		slideRouter:       slideRouter,
+		mediaURL:          opts.MediaURL,
+		staleAfter:        opts.StaleAfter,
+		tickInterval:      opts.TickInterval,
+		fonts:             opts.Fonts,
+		contentTransforms: opts.Transforms,
+		defaultSlide:      opts.DefaultSlide,
+		orgMessage:        opts.OrganizationMessage,
+		locale:            locale,
+		followMeetingLang: lang == language.Und,
+		defaultLang:       opts.DefaultLanguage,
+		Projections:       make(map[int]template.HTML),
+		ProjectionsHash:   make(map[int]uint64),
+		ProjectionsMeta:   make(map[int]projectionMeta),
+		replay:            newReplayBuffer(opts.ReplayBufferSize, opts.MemoryBudget),
+		AddListener:       make(chan projectorListener),
+		RemoveListener:    make(chan (<-chan *ProjectorUpdateEvent)),
+		lastUpdate:        time.Now(),
 	}
 
 	p.initProjector(ctx)
@@ -120,27 +338,47 @@ func newProjector(parentCtx context.Context, id int, lang language.Tag, db *data
 	return p, nil
 }
 
-func projectorPreview(ctx context.Context, id int, lang language.Tag, db *database.Datastore, ds flow.Flow, settings ProjectorPreviewSettings) (string, error) {
+// projectorPreview renders the projector once. If settings is nil the stored
+// projector settings are used.
+func projectorPreview(ctx context.Context, id int, lang language.Tag, db *database.Datastore, ds flow.Flow, opts renderOptions, settings *ProjectorPreviewSettings) (string, error) {
 	ctx, cancel := context.WithCancel(ctx)
 
-	data, err := db.Fetch.Projector(id).First(ctx)
+	data, err := db.SnapshotFetch().Projector(id).First(ctx)
 	if err != nil {
 		cancel()
 		return "", fmt.Errorf("error fetching projector from db %w", err)
 	}
 
 	locale := i18n.NewLocale(lang)
+	slideRouter := slide.New(ctx, db, ds, locale)
+	slideRouter.MediaURL = opts.MediaURL
+	slideRouter.MaxContentSize = opts.MaxContentSize
+	slideRouter.Funcs = opts.TemplateFuncs
+	slideRouter.Cache = opts.RenderCache
+	slideRouter.Sanitizer = opts.Sanitizer
+	if opts.RetryDelay > 0 {
+		slideRouter.RetryDelay = opts.RetryDelay
+	}
 	p := &projector{
 		ctxCancel:          cancel,
+		done:               ctx.Done(),
 		db:                 db,
 		projector:          &data,
 		pSettings:          &ProjectorSettings{},
-		pSettingsOverwrite: &settings,
-		slideRouter:        slide.New(ctx, db, ds, locale),
+		pSettingsOverwrite: settings,
+		slideRouter:        slideRouter,
+		mediaURL:           opts.MediaURL,
+		fonts:              opts.Fonts,
+		contentTransforms:  opts.Transforms,
+		defaultSlide:       opts.DefaultSlide,
 		locale:             locale,
+		followMeetingLang:  lang == language.Und,
+		defaultLang:        opts.DefaultLanguage,
 		Projections:        make(map[int]template.HTML),
 		ProjectionsHash:    make(map[int]uint64),
-		AddListener:        make(chan chan *ProjectorUpdateEvent),
+		ProjectionsMeta:    make(map[int]projectionMeta),
+		replay:             newReplayBuffer(1, nil),
+		AddListener:        make(chan projectorListener),
 		RemoveListener:     make(chan (<-chan *ProjectorUpdateEvent)),
 	}
 
@@ -154,24 +392,28 @@ func projectorPreview(ctx context.Context, id int, lang language.Tag, db *databa
 func (p *projector) initProjector(ctx context.Context) {
 	go p.subscribeProjector(ctx)
 
-	initListener := make(chan *ProjectorUpdateEvent)
-	p.AddListener <- initListener
-	updateCnt := 0
-	for event := range initListener {
-		if event.Event == "projection-updated" {
-			updateCnt++
-			if updateCnt >= len(p.projector.CurrentProjectionIDs) {
-				break
-			}
-		} else if len(p.projector.CurrentProjectionIDs) == 0 {
+	added := make(chan addedListener, 1)
+	p.AddListener <- projectorListener{added: added}
+	initListener := (<-added).events
+
+	// The projections can be rendered before the listener is added, so the
+	// rendered projections are counted instead of the received events.
+	for range initListener {
+		p.mu.Lock()
+		rendered := len(p.ProjectionsHash)
+		p.mu.Unlock()
+
+		if rendered >= len(p.projector.CurrentProjectionIDs) {
 			break
 		}
 	}
 	p.RemoveListener <- initListener
+	p.initialized.Store(true)
 }
 
 func (p *projector) subscribeProjector(ctx context.Context) {
 	defer p.ctxCancel()
+	defer p.closeListeners()
 	defer func() {
 		if r := recover(); r != nil {
 			var ok bool
@@ -186,40 +428,127 @@ func (p *projector) subscribeProjector(ctx context.Context) {
 
 	p.subscribeSettings(ctx)
 
-	projectionUpdate, projections, err := p.getProjectionSubscription(ctx)
+	projectionUpdate, err := p.getProjectionSubscription(ctx)
 	if err != nil {
 		log.Fatal().Err(err).Msg("could not open projection subscription")
 	}
 
+	countdownUpdate := p.getCountdownSubscription(ctx)
+	speakerCountdownUpdate := p.getSpeakerCountdownSubscription(ctx)
+	orgMessageUpdate := p.orgMessage.subscribe(ctx)
+	p.mu.Lock()
+	p.orgMessageText = p.orgMessage.Text()
+	p.mu.Unlock()
+
+	// Ticks keep the countdowns and clocks of all projectors in sync
+	var tick <-chan time.Time
+	if p.tickInterval > 0 {
+		ticker := time.NewTicker(p.tickInterval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	// The datastore flow might stall and stop delivering changes
+	var staleCheck <-chan time.Time
+	if p.staleAfter > 0 {
+		ticker := time.NewTicker(p.staleAfter / 2)
+		defer ticker.Stop()
+		staleCheck = ticker.C
+	}
+
 	for {
 		select {
 		case <-ctx.Done():
 			return
-		case listener := <-p.AddListener:
-			p.listeners = append(p.listeners, listener)
-			listener <- &ProjectorUpdateEvent{
-				Event: "connected",
-				Data:  strconv.Itoa(int(time.Now().Unix())),
+		case <-staleCheck:
+			stalled := p.db.Stalled(ctx, p.staleAfter)
+			p.mu.Lock()
+			if stalled && !p.updatesStalled && len(p.listeners) > 0 {
+				log.Warn().Msgf("no datastore update for %s, marking content of projector %d as stale", p.staleAfter, p.projector.ID)
+				p.updatesStalled = true
+				p.sendToAll(p.staleEvent())
+			} else if !stalled && p.updatesStalled {
+				p.updatesStalled = false
+				p.sendToAll(p.staleEvent())
+			}
+			p.mu.Unlock()
+		case request := <-p.AddListener:
+			p.mu.Lock()
+			prelude := p.listenerPrelude()
+			resumed := false
+			if request.lastEventID != "" {
+				var missed []*ProjectorUpdateEvent
+				missed, resumed = p.replay.since(request.lastEventID)
+				prelude = append(prelude, missed...)
 			}
+
+			// The channel holds the whole prelude, so adding a listener
+			// never blocks the projector
+			listener := make(chan *ProjectorUpdateEvent, len(prelude)+listenerBuffer)
+			for _, event := range prelude {
+				listener <- event
+			}
+			p.listeners = append(p.listeners, listener)
+			request.added <- addedListener{events: listener, resumed: resumed}
+			p.mu.Unlock()
 		case listener := <-p.RemoveListener:
+			p.mu.Lock()
 			i := slices.IndexFunc(p.listeners, func(el chan *ProjectorUpdateEvent) bool { return el == listener })
 			if i > -1 {
 				close(p.listeners[i])
 				p.listeners[i] = p.listeners[len(p.listeners)-1]
 				p.listeners = p.listeners[:len(p.listeners)-1]
 			}
-		case data, ok := <-projectionUpdate:
+			p.mu.Unlock()
+		case update, ok := <-projectionUpdate:
 			if !ok {
 				return
 			}
 
-			p.processProjectionUpdate(data, projections)
+			p.mu.Lock()
+			p.processProjectionUpdate(update.updated, update.projections)
+			p.mu.Unlock()
+		case countdowns := <-countdownUpdate:
+			p.mu.Lock()
+			p.countdowns = countdowns
+			if event := countdownsEvent(countdowns); event != nil {
+				p.sendToAll(event)
+			}
+			p.mu.Unlock()
+		case now := <-tick:
+			p.mu.Lock()
+			if len(p.listeners) > 0 {
+				if event := tickEvent(p.countdowns, p.pSettings.ShowClock, now); event != nil {
+					p.sendToAll(event)
+				}
+			}
+			p.mu.Unlock()
+		case state := <-speakerCountdownUpdate:
+			p.mu.Lock()
+			p.speakerCountdown = state
+			if event := speakerCountdownEvent(state); event != nil {
+				p.sendToAll(event)
+			}
+			p.mu.Unlock()
+		case text := <-orgMessageUpdate:
+			p.mu.Lock()
+			if text != p.orgMessageText {
+				p.orgMessageText = text
+				if event := organizationMessageEvent(text); event != nil {
+					p.sendToAll(event)
+				}
+			}
+			p.mu.Unlock()
 		}
 	}
 }
 
 func (p *projector) subscribeSettings(ctx context.Context) {
 	p.db.NewContext(ctx, func(f *dsmodels.Fetch) {
+		// Settings updates are called from the datastore update loop
+		p.mu.Lock()
+		defer p.mu.Unlock()
+
 		f.Projector_Name(p.projector.ID).Lazy(&p.pSettings.Name)
 		f.Projector_IsInternal(p.projector.ID).Lazy(&p.pSettings.IsInternal)
 		if p.pSettingsOverwrite == nil {
@@ -265,6 +594,11 @@ func (p *projector) subscribeSettings(ctx context.Context) {
 		f.Meeting_Name(p.projector.MeetingID).Lazy(&p.pSettings.MeetingName)
 		f.Meeting_Description(p.projector.MeetingID).Lazy(&p.pSettings.MeetingDescription)
 
+		var meetingLanguage string
+		if p.followMeetingLang {
+			f.Meeting_Language(p.projector.MeetingID).Lazy(&meetingLanguage)
+		}
+
 		var customTranslationsRaw json.RawMessage
 		f.Meeting_CustomTranslations(p.projector.MeetingID).Lazy(&customTranslationsRaw)
 
@@ -279,13 +613,18 @@ func (p *projector) subscribeSettings(ctx context.Context) {
 		err := f.Execute(ctx)
 		var doesNotExist dsfetch.DoesNotExistError
 		if errors.As(err, &doesNotExist) {
-			p.sendToAll(&ProjectorUpdateEvent{"deleted", ""})
+			p.sendToAll(&ProjectorUpdateEvent{Event: "deleted", Data: ""})
 			p.ctxCancel()
 			return
 		} else if err != nil {
 			log.Error().Err(err).Msg("failed to update projector data")
 			return
 		}
+		p.markUpdated()
+
+		if p.followMeetingLang {
+			p.applyMeetingLanguage(meetingLanguage)
+		}
 
 		if len(customTranslationsRaw) > 0 {
 			var customTranslations map[string]string
@@ -304,17 +643,29 @@ func (p *projector) subscribeSettings(ctx context.Context) {
 			p.pSettings.HeaderImage = val
 		}
 
+		if p.pSettingsOverwrite != nil && p.pSettingsOverwrite.ThemeID != 0 {
+			themeId = p.pSettingsOverwrite.ThemeID
+		}
+
 		p.pSettings.Theme, err = f.Theme(themeId).First(ctx)
 		if err != nil {
 			log.Error().Err(err).Msg("failed to load theme")
 			return
 		}
+		p.pSettings.Palette = newThemePalette(p.pSettings.Theme)
+		p.pSettings.sanitizeColors()
+
+		// Scroll and scale change often while presenting. If nothing else
+		// changed, clients only need to apply the new values.
+		if p.applyTransform() {
+			return
+		}
 
 		encodedData, err := json.Marshal(p.pSettings)
 		if err != nil {
 			log.Error().Err(err).Msg("could not encode projector data")
 		} else {
-			p.sendToAll(&ProjectorUpdateEvent{"settings", string(encodedData)})
+			p.sendToAll(&ProjectorUpdateEvent{Event: "settings", Data: string(encodedData)})
 		}
 
 		if err = p.updateFullContent(); err != nil {
@@ -325,31 +676,107 @@ func (p *projector) subscribeSettings(ctx context.Context) {
 		if err != nil {
 			log.Error().Err(err).Msg("error marshalling projector replace content")
 		}
-		p.sendToAll(&ProjectorUpdateEvent{"projector-replace", string(currentContent)})
+		p.sendToAll(&ProjectorUpdateEvent{Event: "projector-replace", Data: string(currentContent)})
 	})
 }
 
-func (p *projector) processProjectionUpdate(updated []int, projections map[int]string) {
+// applyTransform compares the settings with the ones sent last. If only scroll
+// or scale differ, a transform event is sent and true is returned.
+func (p *projector) applyTransform() bool {
+	settings := *p.pSettings
+	transform := projectorTransform{Scroll: settings.Scroll, Scale: settings.Scale}
+	settings.Scroll = 0
+	settings.Scale = 0
+	base, err := json.Marshal(settings)
+	if err != nil {
+		log.Error().Err(err).Msg("could not encode projector data")
+		return false
+	}
+
+	if p.settingsBase == nil || !bytes.Equal(base, p.settingsBase) || transform == p.transform {
+		p.settingsBase = base
+		p.transform = transform
+		return false
+	}
+
+	p.transform = transform
+	encodedData, err := json.Marshal(transform)
+	if err != nil {
+		log.Error().Err(err).Msg("could not encode transform event")
+		return false
+	}
+
+	if err := p.updateFullContent(); err != nil {
+		log.Error().Err(err).Msg("error generating projector content after transform update")
+	}
+	p.sendToAll(&ProjectorUpdateEvent{Event: "transform", Data: string(encodedData)})
+
+	return true
+}
+
+// applyMeetingLanguage switches the locale to the language of the meeting and
+// renders all projections again if it changed. Without a valid meeting
+// language the default language is used.
+func (p *projector) applyMeetingLanguage(meetingLanguage string) {
+	lang := p.defaultLang
+	if meetingLanguage != "" {
+		parsed, err := language.Parse(meetingLanguage)
+		if err != nil {
+			log.Warn().Err(err).Msgf("invalid language of meeting %d", p.projector.MeetingID)
+		} else {
+			lang = parsed
+		}
+	}
+
+	if lang == p.locale.Language() {
+		return
+	}
+
+	p.locale.SetLanguage(lang)
+	p.slideRouter.Rerender()
+}
+
+func (p *projector) processProjectionUpdate(updated []int, projections map[int]projectionContent) {
 	if updated == nil {
 		return
 	}
 
+	oldOrder := p.projectionStacking()
+	wasEmpty := len(p.Projections) == 0
 	updatedProjections := map[int]string{}
+	updatedViews := map[int]slide.ProjectionView{}
 	deletionOccured := false
 	for _, projectionId := range updated {
 		if projection, ok := projections[projectionId]; ok {
-			newHash := djb2(projection)
+			newHash := djb2(projection.Content)
 			oldHash, exists := p.ProjectionsHash[projectionId]
 
+			if !exists || !p.ProjectionsMeta[projectionId].View.Equal(projection.View) {
+				updatedViews[projectionId] = projection.View
+			}
+
 			if !exists || oldHash != newHash {
-				p.Projections[projectionId] = template.HTML(projection)
+				p.Projections[projectionId] = template.HTML(projection.Content)
 				p.ProjectionsHash[projectionId] = newHash
-				updatedProjections[projectionId] = projection
+				updatedProjections[projectionId] = projection.Content
+			}
+			p.ProjectionsMeta[projectionId] = projection.projectionMeta
+
+			if projection.Error != "" {
+				if event := slideErrorEvent(projectionId, projection.projectionMeta); event != nil {
+					defer p.sendToAll(event)
+				}
 			}
 		} else {
+			deletedEvent := &ProjectorUpdateEvent{
+				Event:       "projection-deleted",
+				Data:        strconv.Itoa(projectionId),
+				collections: map[int]string{projectionId: p.ProjectionsMeta[projectionId].Collection},
+			}
 			delete(p.Projections, projectionId)
 			delete(p.ProjectionsHash, projectionId)
-			defer p.sendToAll(&ProjectorUpdateEvent{"projection-deleted", strconv.Itoa(projectionId)})
+			delete(p.ProjectionsMeta, projectionId)
+			defer p.sendToAll(deletedEvent)
 			deletionOccured = true
 		}
 	}
@@ -359,18 +786,216 @@ func (p *projector) processProjectionUpdate(updated []int, projections map[int]s
 		if err != nil {
 			log.Error().Err(err).Msg("failed to encode update event")
 		} else {
-			p.sendToAll(&ProjectorUpdateEvent{"projection-updated", string(eventContent)})
+			collections := map[int]string{}
+			for id := range updatedProjections {
+				collections[id] = p.ProjectionsMeta[id].Collection
+			}
+
+			p.sendToAll(&ProjectorUpdateEvent{
+				Event:       "projection-updated",
+				Data:        string(eventContent),
+				projections: updatedProjections,
+				collections: collections,
+			})
+		}
+	}
+
+	// Scroll and scale changes are sent separately so clients do not need
+	// to replace the content of the projection.
+	if len(updatedViews) > 0 {
+		eventContent, err := json.Marshal(updatedViews)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to encode view event")
+		} else {
+			p.sendToAll(&ProjectorUpdateEvent{Event: "projection-view", Data: string(eventContent)})
+		}
+	}
+
+	newOrder := p.projectionStacking()
+	orderChanged := !slices.Equal(oldOrder, newOrder)
+	if orderChanged && len(newOrder) > 0 {
+		eventContent, err := json.Marshal(newOrder)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to encode order event")
+		} else {
+			p.sendToAll(&ProjectorUpdateEvent{Event: "projection-order", Data: string(eventContent)})
 		}
 	}
 
-	if len(updatedProjections) > 0 || deletionOccured {
+	// The default slide is shown until the first projection is added and
+	// again once the last one is removed.
+	if isEmpty := len(p.Projections) == 0; isEmpty != wasEmpty {
+		p.sendDefaultSlide(isEmpty)
+	}
+
+	if len(updatedProjections) > 0 || len(updatedViews) > 0 || deletionOccured || orderChanged {
+		projectorUpdates.inc(p.projector.ID)
 		if err := p.updateFullContent(); err != nil {
 			log.Error().Err(err).Msg("failed to generate projector content")
 		}
 	}
+
+	stale := false
+	for _, projection := range projections {
+		if projection.Stale {
+			stale = true
+			break
+		}
+	}
+	if p.stale.Swap(stale) != stale {
+		if !stale {
+			p.lastUpdate = time.Now()
+			p.updatesStalled = false
+		}
+		p.sendToAll(p.staleEvent())
+	}
+
+	if !stale {
+		p.markUpdated()
+	}
+}
+
+// markUpdated records a successful update of the projector. If the content
+// was marked as stale for missing updates, clients are told it is current
+// again. Has to be called with the mutex held.
+func (p *projector) markUpdated() {
+	p.lastUpdate = time.Now()
+	if p.updatesStalled {
+		p.updatesStalled = false
+		p.sendToAll(p.staleEvent())
+	}
+}
+
+// slideErrorEvent tells clients that the projection could not be rendered.
+func slideErrorEvent(id int, meta projectionMeta) *ProjectorUpdateEvent {
+	eventContent, err := json.Marshal(slideError{
+		ProjectionID: id,
+		Collection:   meta.Collection,
+		Message:      meta.Error,
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("failed to encode slide error event")
+		return nil
+	}
+
+	return &ProjectorUpdateEvent{
+		Event:       "slide_error",
+		Data:        string(eventContent),
+		collections: map[int]string{id: meta.Collection},
+	}
+}
+
+// projectionIDsOrdered returns the ids of all rendered projections in the
+// order they are stacked on the projector from bottom to top.
+func (p *projector) projectionIDsOrdered() []int {
+	ids := make([]int, 0, len(p.Projections))
+	for id := range p.Projections {
+		ids = append(ids, id)
+	}
+
+	slices.SortFunc(ids, func(a, b int) int {
+		orderA := p.ProjectionsMeta[a]
+		orderB := p.ProjectionsMeta[b]
+		if orderA.Stable != orderB.Stable {
+			if orderA.Stable {
+				return 1
+			}
+			return -1
+		}
+
+		if orderA.Weight != orderB.Weight {
+			return orderA.Weight - orderB.Weight
+		}
+
+		return a - b
+	})
+
+	return ids
+}
+
+// projectionStacking returns the rendered projections from bottom to top
+// together with the z-index they are displayed with.
+func (p *projector) projectionStacking() []projectionStacking {
+	ids := p.projectionIDsOrdered()
+	stacking := make([]projectionStacking, 0, len(ids))
+	for i, id := range ids {
+		zIndex := projectionZIndex + i
+		if p.ProjectionsMeta[id].Stable {
+			zIndex = overlayZIndex + i
+		}
+
+		stacking = append(stacking, projectionStacking{ID: id, ZIndex: zIndex})
+	}
+
+	return stacking
+}
+
+// listenerPrelude returns the events a new listener starts with: the
+// connection, the stale state, the countdowns, the slide errors and the
+// organization message. Has to be called with the mutex held.
+func (p *projector) listenerPrelude() []*ProjectorUpdateEvent {
+	prelude := []*ProjectorUpdateEvent{{
+		Event: "connected",
+		Data:  strconv.Itoa(int(time.Now().Unix())),
+	}}
+
+	if p.stale.Load() || p.updatesStalled {
+		prelude = append(prelude, p.staleEvent())
+	}
+
+	if event := countdownsEvent(p.countdowns); event != nil {
+		prelude = append(prelude, event)
+	}
+
+	if p.speakerCountdown != nil {
+		if event := speakerCountdownEvent(p.speakerCountdown); event != nil {
+			prelude = append(prelude, event)
+		}
+	}
+
+	for _, id := range p.projectionIDsOrdered() {
+		if meta := p.ProjectionsMeta[id]; meta.Error != "" {
+			if event := slideErrorEvent(id, meta); event != nil {
+				prelude = append(prelude, event)
+			}
+		}
+	}
+
+	if p.orgMessageText != "" {
+		if event := organizationMessageEvent(p.orgMessageText); event != nil {
+			prelude = append(prelude, event)
+		}
+	}
+
+	return prelude
+}
+
+// staleEvent tells clients whether the shown content is outdated because the
+// datastore is unavailable or its flow stalled, together
+// with the time of the last update. Has to be called with the mutex held.
+func (p *projector) staleEvent() *ProjectorUpdateEvent {
+	stale := p.stale.Load() || p.updatesStalled
+	return &ProjectorUpdateEvent{Event: "stale", Data: fmt.Sprintf(`{"stale":%t,"last_update":%d}`, stale, p.lastUpdate.Unix())}
+}
+
+// closeListeners ends all subscriptions once the projector stopped, e.g.
+// because it was deleted or panicked. Listeners which could not receive the
+// deleted event end too.
+func (p *projector) closeListeners() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, listener := range p.listeners {
+		close(listener)
+	}
+	p.listeners = nil
 }
 
 func (p *projector) sendToAll(event *ProjectorUpdateEvent) {
+	if !slices.Contains(unreplayedEvents, event.Event) {
+		p.replay.add(event)
+	}
+
 	for _, listener := range p.listeners {
 		select {
 		case listener <- event:
@@ -381,16 +1006,43 @@ func (p *projector) sendToAll(event *ProjectorUpdateEvent) {
 	}
 }
 
+// currentContent returns the last rendered content of the projector.
+func (p *projector) currentContent() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.Content
+}
+
 func (p *projector) updateFullContent() error {
 	tmpl, err := template.ParseFiles("templates/projector-content.html")
 	if err != nil {
 		return fmt.Errorf("error reading projector template %w", err)
 	}
 
+	projections := []renderedProjection{}
+	for _, stacking := range p.projectionStacking() {
+		projections = append(projections, renderedProjection{
+			ID:      stacking.ID,
+			Content: p.Projections[stacking.ID],
+			Style:   projectionStyle(p.ProjectionsMeta[stacking.ID].View, stacking.ZIndex),
+		})
+	}
+
+	var defaultSlide template.HTML
+	if len(projections) == 0 {
+		defaultSlide, err = p.renderDefaultSlide()
+		if err != nil {
+			log.Error().Err(err).Msg("failed to render default slide")
+		}
+	}
+
 	var content bytes.Buffer
 	err = tmpl.Execute(&content, map[string]any{
-		"Projector":   p.pSettings,
-		"Projections": p.Projections,
+		"Projector":    p.pSettings,
+		"Projections":  projections,
+		"DefaultSlide": defaultSlide,
+		"MediaURL":     p.mediaURL,
+		"Fonts":        p.fonts.forLanguage(p.locale.Language()),
 	})
 	if err != nil {
 		return fmt.Errorf("error generating projector template %w", err)
@@ -401,9 +1053,21 @@ func (p *projector) updateFullContent() error {
 	return nil
 }
 
-func (p *projector) getProjectionSubscription(ctx context.Context) (<-chan []int, map[int]string, error) {
-	updateChannel := make(chan []int)
-	projections := make(map[int]string)
+// projectionsUpdate lists the projections changed by an update together with
+// a copy of the state of all projections after it.
+type projectionsUpdate struct {
+	updated     []int
+	projections map[int]projectionContent
+}
+
+func (p *projector) getProjectionSubscription(ctx context.Context) (<-chan projectionsUpdate, error) {
+	updateChannel := make(chan projectionsUpdate)
+	projections := make(map[int]projectionContent)
+	// The projections are copied, the subscription keeps changing them while
+	// the update is processed.
+	sendUpdate := func(updated []int) {
+		updateChannel <- projectionsUpdate{updated: updated, projections: maps.Clone(projections)}
+	}
 	addProjection := make(chan int)
 	removeProjection := make(chan int)
 
@@ -436,7 +1100,7 @@ func (p *projector) getProjectionSubscription(ctx context.Context) (<-chan []int
 			}
 
 			if len(updated) > 0 || len(projectionIDs) == 0 {
-				updateChannel <- updated
+				sendUpdate(updated)
 			}
 		})
 
@@ -445,15 +1109,57 @@ func (p *projector) getProjectionSubscription(ctx context.Context) (<-chan []int
 			case <-ctx.Done():
 				return
 			case update := <-projectionChannel:
-				if update != nil {
-					projections[update.ID] = update.Content
-					updateChannel <- []int{update.ID}
+				if update == nil {
+					continue
 				}
+
+				if update.Unavailable {
+					// Keep the last rendered content instead of blanking
+					// the projection, the slide router reads it again.
+					projection := projections[update.ID]
+					projection.Stale = true
+					projections[update.ID] = projection
+					sendUpdate([]int{update.ID})
+					continue
+				}
+
+				content := update.Content
+				if content != "" {
+					content = applyContentTransforms(p.contentTransforms, update.Collection, content)
+				}
+
+				projections[update.ID] = projectionContent{
+					Content: content,
+					projectionMeta: projectionMeta{
+						Weight:          update.Weight,
+						Stable:          update.Stable,
+						Collection:      update.Collection,
+						ContentObjectID: update.ContentObjectID,
+						View:            update.View,
+						Error:           update.Error,
+					},
+				}
+				sendUpdate([]int{update.ID})
 			}
 		}
 	}()
 
-	return updateChannel, projections, nil
+	return updateChannel, nil
+}
+
+// projectionStyle sets the z-index of a projection and overwrites the scroll
+// and scale css variables of the projector for it.
+func projectionStyle(view slide.ProjectionView, zIndex int) template.CSS {
+	style := fmt.Sprintf("z-index: %d;", zIndex)
+	if view.Scroll != nil {
+		style += fmt.Sprintf("--projector-scroll: %d;", *view.Scroll)
+	}
+
+	if view.Scale != nil {
+		style += fmt.Sprintf("--projector-scale: %d;", *view.Scale)
+	}
+
+	return template.CSS(style)
 }
 
 func djb2(str string) uint64 {
@@ -0,0 +1,1445 @@
+package projector
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/OpenSlides/openslides-go/datastore/dskey"
+	"github.com/OpenSlides/openslides-projector-service/pkg/database"
+	"github.com/OpenSlides/openslides-projector-service/pkg/dstest"
+	"github.com/OpenSlides/openslides-projector-service/pkg/i18n"
+	"golang.org/x/text/language"
+)
+
+func newTestPool(t *testing.T, ctx context.Context, flow *dstest.Flow) *ProjectorPool {
+	t.Helper()
+	t.Chdir("../..")
+
+	db, err := database.New("", "", flow)
+	if err != nil {
+		t.Fatalf("create datastore: %v", err)
+	}
+
+	return NewProjectorPool(ctx, db, flow)
+}
+
+// subscribe subscribes to the projector and waits until the listeners are
+// registered, so changes made afterwards are not missed.
+func subscribe(t *testing.T, ctx context.Context, pool *ProjectorPool, lang language.Tag) <-chan *ProjectorUpdateEvent {
+	t.Helper()
+
+	events, err := pool.SubscribeProjectorContent(ctx, 1, lang, nil)
+	if err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+
+	select {
+	case event := <-events:
+		if event.Event != "connected" {
+			t.Fatalf("expected connected event, got %s", event.Event)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("no connected event received")
+	}
+
+	// The datastore listeners are registered after the first read. Settings,
+	// projection ids, countdowns, the speaker countdown and every projection
+	// have to listen before changes are sent.
+	pool.mu.Lock()
+	expected := 4 + len(pool.projectors[projectorKey(1, lang)].projector.CurrentProjectionIDs)
+	pool.mu.Unlock()
+
+	timeout := time.After(time.Second)
+	for pool.db.NumDsListeners() < expected {
+		select {
+		case <-timeout:
+			t.Fatalf("expected %d datastore listeners, got %d", expected, pool.db.NumDsListeners())
+		case <-time.After(time.Millisecond):
+		}
+	}
+
+	return events
+}
+
+func testProjectorData() map[string]string {
+	return map[string]string{
+		"projector/1/id":                "1",
+		"projector/1/meeting_id":        "1",
+		"projector/1/sequential_number": "1",
+		"projector/1/name":              `"Main"`,
+		"meeting/1/id":                  "1",
+		"meeting/1/name":                `"Meeting"`,
+		"organization/1/id":             "1",
+		"organization/1/theme_id":       "1",
+		"theme/1/id":                    "1",
+		"theme/1/name":                  `"Theme"`,
+		"theme/1/organization_id":       "1",
+	}
+}
+
+func TestProjectorDeletionEndsSubscription(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	flow := dstest.NewFlow(testProjectorData())
+	pool := newTestPool(t, ctx, flow)
+
+	events := subscribe(t, ctx, pool, language.English)
+
+	flow.Changes <- map[dskey.Key][]byte{
+		dskey.MustKey("projector/1/id"):         nil,
+		dskey.MustKey("projector/1/meeting_id"): nil,
+		dskey.MustKey("projector/1/name"):       nil,
+	}
+
+	timeout := time.After(time.Second)
+	for deleted := false; !deleted; {
+		select {
+		case event := <-events:
+			deleted = event.Event == "deleted"
+		case <-timeout:
+			t.Fatalf("no deleted event received")
+		}
+	}
+
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Errorf("expected no event after the deleted event")
+		}
+	case <-timeout:
+		t.Fatalf("subscription was not closed after the deletion")
+	}
+
+	for {
+		pool.mu.Lock()
+		numProjectors := len(pool.projectors)
+		pool.mu.Unlock()
+
+		if numProjectors == 0 {
+			break
+		}
+
+		select {
+		case <-timeout:
+			t.Fatalf("deleted projector was not removed from the pool")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func TestProjectorDeletionClosesFullSubscription(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	flow := dstest.NewFlow(testProjectorData())
+	pool := newTestPool(t, ctx, flow)
+
+	events := subscribe(t, ctx, pool, language.English)
+
+	// The listener is not read until its queue is full, so the deleted event
+	// is dropped
+	for i := range 2 * listenerBuffer {
+		flow.Changes <- map[dskey.Key][]byte{
+			dskey.MustKey("projector/1/name"): []byte(strconv.Quote(fmt.Sprintf("Main %d", i))),
+		}
+	}
+	flow.Changes <- map[dskey.Key][]byte{
+		dskey.MustKey("projector/1/id"):         nil,
+		dskey.MustKey("projector/1/meeting_id"): nil,
+		dskey.MustKey("projector/1/name"):       nil,
+	}
+
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case _, ok := <-events:
+			if !ok {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("subscription was not closed after the deletion")
+		}
+	}
+}
+
+func TestProjectorDeletionClosesFilteredSubscription(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	flow := dstest.NewFlow(testProjectorData())
+	pool := newTestPool(t, ctx, flow)
+
+	// The projector is created and listens to the datastore before the
+	// filtered subscription is added
+	subscribe(t, ctx, pool, language.English)
+	events, err := pool.SubscribeProjectorContent(ctx, 1, language.English, []string{"motion"})
+	if err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+
+	flow.Changes <- map[dskey.Key][]byte{
+		dskey.MustKey("projector/1/id"):         nil,
+		dskey.MustKey("projector/1/meeting_id"): nil,
+		dskey.MustKey("projector/1/name"):       nil,
+	}
+
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case _, ok := <-events:
+			if !ok {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("filtered subscription was not closed after the deletion")
+		}
+	}
+}
+
+func TestProjectionStackingIsDeterministic(t *testing.T) {
+	t.Chdir("../..")
+
+	p := &projector{
+		locale:          i18n.NewLocale(language.English),
+		pSettings:       &ProjectorSettings{},
+		Projections:     map[int]template.HTML{},
+		ProjectionsHash: map[int]uint64{},
+		ProjectionsMeta: map[int]projectionMeta{},
+	}
+
+	// Inserted out of order: overlays first, equal weights in reverse id order
+	for _, tt := range []struct {
+		id     int
+		weight int
+		stable bool
+	}{
+		{7, 1, true},
+		{3, 5, false},
+		{9, 2, false},
+		{4, 2, false},
+		{2, 0, true},
+	} {
+		p.Projections[tt.id] = template.HTML(fmt.Sprintf("projection %d", tt.id))
+		p.ProjectionsMeta[tt.id] = projectionMeta{Weight: tt.weight, Stable: tt.stable}
+	}
+
+	expected := []projectionStacking{
+		{ID: 4, ZIndex: projectionZIndex},
+		{ID: 9, ZIndex: projectionZIndex + 1},
+		{ID: 3, ZIndex: projectionZIndex + 2},
+		{ID: 2, ZIndex: overlayZIndex + 3},
+		{ID: 7, ZIndex: overlayZIndex + 4},
+	}
+
+	for i := 0; i < 10; i++ {
+		if got := p.projectionStacking(); !slices.Equal(got, expected) {
+			t.Fatalf("expected stacking %v, got %v", expected, got)
+		}
+	}
+
+	if err := p.updateFullContent(); err != nil {
+		t.Fatalf("render content: %v", err)
+	}
+
+	last := -1
+	for _, stacking := range expected {
+		tag := fmt.Sprintf(`data-id="%d" style="z-index: %d;"`, stacking.ID, stacking.ZIndex)
+		pos := strings.Index(p.Content, tag)
+		if pos == -1 {
+			t.Fatalf("projection %d not rendered with z-index %d", stacking.ID, stacking.ZIndex)
+		}
+
+		if pos < last {
+			t.Errorf("projection %d rendered out of order", stacking.ID)
+		}
+		last = pos
+	}
+}
+
+func TestGetCurrentProjections(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	data := testProjectorData()
+	data["projector/1/current_projection_ids"] = "[1,2]"
+	data["projection/1/id"] = "1"
+	data["projection/1/meeting_id"] = "1"
+	data["projection/1/stable"] = "true"
+	data["projection/1/content_object_id"] = `"projector_countdown/3"`
+	data["projection/2/id"] = "2"
+	data["projection/2/meeting_id"] = "1"
+	data["projection/2/content_object_id"] = `"motion/5"`
+	flow := dstest.NewFlow(data)
+	pool := newTestPool(t, ctx, flow)
+
+	projections, err := pool.GetCurrentProjections(ctx, 1, language.English)
+	if err != nil {
+		t.Fatalf("get current projections: %v", err)
+	}
+
+	if len(projections) != 2 || projections[0].ID != 2 || projections[1].ID != 1 {
+		t.Fatalf("expected projections 2 and 1, got %v", projections)
+	}
+
+	if projections[0].Collection != "motion" || projections[0].ContentObjectID != "motion/5" {
+		t.Errorf("unexpected metadata of projection 2: %v", projections[0])
+	}
+
+	flow.Set("projection/2/content_object_id", []byte(`"motion/6"`))
+
+	changed, err := pool.GetCurrentProjections(ctx, 1, language.English)
+	if err != nil {
+		t.Fatalf("get current projections: %v", err)
+	}
+
+	if changed[0].Hash == projections[0].Hash {
+		t.Errorf("expected hash of projection 2 to change")
+	}
+
+	if changed[1].Hash != projections[1].Hash {
+		t.Errorf("expected hash of projection 1 to stay the same")
+	}
+}
+
+func TestGetCurrentProjectionsHashesContent(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	data := testProjectorData()
+	data["projector/1/current_projection_ids"] = "[1]"
+	data["projection/1/id"] = "1"
+	data["projection/1/meeting_id"] = "1"
+	data["projection/1/content_object_id"] = `"projector_message/3"`
+	data["projector_message/3/id"] = "3"
+	data["projector_message/3/meeting_id"] = "1"
+	data["projector_message/3/message"] = `"<p>Welcome</p>"`
+	flow := dstest.NewFlow(data)
+	pool := newTestPool(t, ctx, flow)
+
+	projections, err := pool.GetCurrentProjections(ctx, 1, language.English)
+	if err != nil {
+		t.Fatalf("get current projections: %v", err)
+	}
+
+	flow.Changes <- map[dskey.Key][]byte{
+		dskey.MustKey("projector_message/3/message"): []byte(`"<p>Goodbye</p>"`),
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		changed, err := pool.GetCurrentProjections(ctx, 1, language.English)
+		if err != nil {
+			t.Fatalf("get current projections: %v", err)
+		}
+
+		if changed[0].Hash != projections[0].Hash {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("expected hash to change with the rendered content")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestProjectorFollowsMeetingLanguage(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	data := testProjectorData()
+	data["meeting/1/language"] = `"en"`
+	data["meeting/1/agenda_item_ids"] = "[]"
+	data["projector/1/current_projection_ids"] = "[1]"
+	data["projection/1/id"] = "1"
+	data["projection/1/meeting_id"] = "1"
+	data["projection/1/type"] = `"agenda_item_list"`
+	data["projection/1/content_object_id"] = `"meeting/1"`
+	flow := dstest.NewFlow(data)
+	pool := newTestPool(t, ctx, flow)
+
+	content, err := pool.GetProjectorContent(1, language.Und)
+	if err != nil {
+		t.Fatalf("get content: %v", err)
+	}
+
+	if !strings.Contains(*content, "Agenda") {
+		t.Fatalf("expected english agenda slide, got %q", *content)
+	}
+
+	events := subscribe(t, ctx, pool, language.Und)
+
+	flow.Changes <- map[dskey.Key][]byte{
+		dskey.MustKey("meeting/1/language"): []byte(`"de"`),
+	}
+
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case event := <-events:
+			if event.Event == "projection-updated" && strings.Contains(event.Data, "Tagesordnung") {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("projection was not rendered in the new language")
+		}
+	}
+}
+
+func TestProjectorUsesDefaultLanguageWithoutMeetingLanguage(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	data := testProjectorData()
+	data["meeting/1/agenda_item_ids"] = "[]"
+	data["projector/1/current_projection_ids"] = "[1]"
+	data["projection/1/id"] = "1"
+	data["projection/1/meeting_id"] = "1"
+	data["projection/1/type"] = `"agenda_item_list"`
+	data["projection/1/content_object_id"] = `"meeting/1"`
+	flow := dstest.NewFlow(data)
+	pool := newTestPool(t, ctx, flow)
+	pool.DefaultLanguage = language.German
+
+	content, err := pool.GetProjectorContent(1, language.Und)
+	if err != nil {
+		t.Fatalf("get content: %v", err)
+	}
+
+	if !strings.Contains(*content, "Tagesordnung") {
+		t.Fatalf("expected agenda slide in the default language, got %q", *content)
+	}
+}
+
+func TestStaleContentOnDatastoreError(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	data := testProjectorData()
+	data["projector/1/current_projection_ids"] = "[1]"
+	data["projection/1/id"] = "1"
+	data["projection/1/meeting_id"] = "1"
+	data["projection/1/content_object_id"] = `"topic/5"`
+	data["topic/5/id"] = "5"
+	data["topic/5/meeting_id"] = "1"
+	data["topic/5/sequential_number"] = "5"
+	data["topic/5/list_of_speakers_id"] = "1"
+	data["topic/5/title"] = `"Old title"`
+	data["topic/5/agenda_item_id"] = "3"
+	data["agenda_item/3/id"] = "3"
+	data["agenda_item/3/meeting_id"] = "1"
+	data["agenda_item/3/content_object_id"] = `"topic/5"`
+	flow := dstest.NewFlow(data)
+	pool := newTestPool(t, ctx, flow)
+	pool.StaleWindow = time.Minute
+
+	events := subscribe(t, ctx, pool, language.English)
+
+	waitForStale := func(stale bool) {
+		t.Helper()
+
+		expected := fmt.Sprintf(`{"stale":%t,`, stale)
+		timeout := time.After(2 * time.Second)
+		for {
+			select {
+			case event := <-events:
+				if event.Event == "stale" && strings.HasPrefix(event.Data, expected) {
+					return
+				}
+			case <-timeout:
+				t.Fatalf("no stale event with %s received", expected)
+			}
+		}
+	}
+
+	flow.SetError(errors.New("connection refused"))
+	flow.Changes <- map[dskey.Key][]byte{
+		dskey.MustKey("topic/5/title"): []byte(`"New title"`),
+	}
+	waitForStale(true)
+
+	content, stale, err := pool.GetProjectorContentState(1, language.English)
+	if err != nil || !stale || !strings.Contains(*content, "Old title") {
+		t.Fatalf("expected stale content with old title, got %v, %v", stale, err)
+	}
+
+	// Only the keys of the failed request are watched until the projection
+	// was rendered again
+	flow.SetError(nil)
+	flow.Changes <- map[dskey.Key][]byte{
+		dskey.MustKey("projection/1/content_object_id"): []byte(`"topic/5"`),
+		dskey.MustKey("topic/5/title"):                  []byte(`"New title"`),
+	}
+	waitForStale(false)
+
+	content, stale, err = pool.GetProjectorContentState(1, language.English)
+	if err != nil || stale || !strings.Contains(*content, "New title") {
+		t.Fatalf("expected fresh content with new title, got %v, %v", stale, err)
+	}
+
+	// Projectors which can not be created are served from the cache
+	flow.SetError(errors.New("connection refused"))
+	pool.mu.Lock()
+	delete(pool.projectors, projectorKey(1, language.English))
+	pool.mu.Unlock()
+
+	content, stale, err = pool.GetProjectorContentState(1, language.English)
+	if err != nil || !stale || !strings.Contains(*content, "New title") {
+		t.Fatalf("expected cached content, got %v, %v", stale, err)
+	}
+}
+
+func TestStaleProjectionIsReadAgain(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	data := testProjectorData()
+	data["projector/1/current_projection_ids"] = "[1]"
+	data["projection/1/id"] = "1"
+	data["projection/1/meeting_id"] = "1"
+	data["projection/1/content_object_id"] = `"projector_message/3"`
+	data["projector_message/3/id"] = "3"
+	data["projector_message/3/meeting_id"] = "1"
+	data["projector_message/3/message"] = `"<p>Welcome</p>"`
+	flow := dstest.NewFlow(data)
+	pool := newTestPool(t, ctx, flow)
+	pool.StaleWindow = 10 * time.Millisecond
+	pool.RetryDelay = 20 * time.Millisecond
+
+	events := subscribe(t, ctx, pool, language.English)
+
+	waitForStale := func(stale bool) {
+		t.Helper()
+
+		expected := fmt.Sprintf(`{"stale":%t,`, stale)
+		timeout := time.After(2 * time.Second)
+		for {
+			select {
+			case event := <-events:
+				if event.Event == "stale" && strings.HasPrefix(event.Data, expected) {
+					return
+				}
+			case <-timeout:
+				t.Fatalf("no stale event with %s received", expected)
+			}
+		}
+	}
+
+	flow.SetError(errors.New("connection refused"))
+	flow.Changes <- map[dskey.Key][]byte{
+		dskey.MustKey("projector_message/3/message"): []byte(`"<p>Goodbye</p>"`),
+	}
+	waitForStale(true)
+
+	// The content is kept while the datastore stays unavailable, even
+	// after several retries.
+	time.Sleep(100 * time.Millisecond)
+	content, stale, err := pool.GetProjectorContentState(1, language.English)
+	if err != nil || !stale || !strings.Contains(*content, "Welcome") {
+		t.Fatalf("expected stale content with the old message, got %v, %v", stale, err)
+	}
+
+	// The projection is read again without a further change
+	flow.SetError(nil)
+	waitForStale(false)
+
+	content, stale, err = pool.GetProjectorContentState(1, language.English)
+	if err != nil || stale || !strings.Contains(*content, "Goodbye") {
+		t.Fatalf("expected fresh content with the new message, got %v, %v", stale, err)
+	}
+}
+
+func TestFontPreloadForLanguage(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cyrillicRange := "U+0400-045F"
+	flow := dstest.NewFlow(testProjectorData())
+	pool := newTestPool(t, ctx, flow)
+	pool.Fonts = FontMapping{
+		"ru": {
+			{Family: "OSFont", URL: "/fonts/cyrillic-400.woff2", Weight: "400", Style: "normal", UnicodeRange: cyrillicRange},
+			{Family: "OSFont", URL: "/fonts/cyrillic-500.woff2", Weight: "500", Style: "normal", UnicodeRange: cyrillicRange},
+		},
+	}
+
+	content, err := pool.GetProjectorContent(1, language.Russian)
+	if err != nil {
+		t.Fatalf("get content: %v", err)
+	}
+
+	for _, font := range pool.Fonts["ru"] {
+		if !strings.Contains(*content, fmt.Sprintf(`<link rel="preload" href="%s" as="font"`, font.URL)) {
+			t.Errorf("expected preload link for %s", font.URL)
+		}
+	}
+
+	if !strings.Contains(*content, "unicode-range: "+cyrillicRange) {
+		t.Errorf("expected cyrillic font face in content")
+	}
+
+	content, err = pool.GetProjectorContent(1, language.English)
+	if err != nil {
+		t.Fatalf("get content: %v", err)
+	}
+
+	if strings.Contains(*content, `rel="preload"`) {
+		t.Errorf("expected no font preload for english")
+	}
+}
+
+type fakeNotifier chan ProjectionChange
+
+func (n fakeNotifier) ProjectionChanged(change ProjectionChange) {
+	n <- change
+}
+
+func TestProjectionChangeNotification(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	data := testProjectorData()
+	data["projector/1/current_projection_ids"] = "[1]"
+	data["projection/1/id"] = "1"
+	data["projection/1/meeting_id"] = "1"
+	data["projection/1/content_object_id"] = `"topic/5"`
+	data["topic/5/id"] = "5"
+	data["topic/5/meeting_id"] = "1"
+	data["topic/5/sequential_number"] = "5"
+	data["topic/5/list_of_speakers_id"] = "1"
+	data["topic/5/title"] = `"First"`
+	data["topic/5/agenda_item_id"] = "3"
+	data["topic/6/id"] = "6"
+	data["topic/6/meeting_id"] = "1"
+	data["topic/6/sequential_number"] = "6"
+	data["topic/6/list_of_speakers_id"] = "1"
+	data["topic/6/title"] = `"Second"`
+	data["topic/6/agenda_item_id"] = "3"
+	data["agenda_item/3/id"] = "3"
+	data["agenda_item/3/meeting_id"] = "1"
+	data["agenda_item/3/content_object_id"] = `"topic/5"`
+	flow := dstest.NewFlow(data)
+	pool := newTestPool(t, ctx, flow)
+	notifier := make(fakeNotifier, 10)
+	pool.WatchProjectionChanges(notifier, []int{1})
+
+	// Projectors rendered in several languages are reported once
+	subscribe(t, ctx, pool, language.English)
+	subscribe(t, ctx, pool, language.German)
+
+	select {
+	case change := <-notifier:
+		t.Fatalf("expected no notification on startup, got %v", change)
+	default:
+	}
+
+	flow.Changes <- map[dskey.Key][]byte{
+		dskey.MustKey("projection/1/content_object_id"): []byte(`"topic/6"`),
+	}
+
+	select {
+	case change := <-notifier:
+		if change.ProjectorID != 1 || change.Collection != "topic" || change.ContentObjectID != "topic/6" {
+			t.Errorf("unexpected change %v", change)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("projection change was not notified")
+	}
+
+	select {
+	case change := <-notifier:
+		t.Errorf("expected a single notification, got another %v", change)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestProjectionChangeNotificationWithoutSubscribers(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	data := testProjectorData()
+	data["organization/1/active_meeting_ids"] = "[1]"
+	data["meeting/1/projector_ids"] = "[1]"
+	data["projector/1/current_projection_ids"] = "[1]"
+	data["projection/1/id"] = "1"
+	data["projection/1/meeting_id"] = "1"
+	data["projection/1/content_object_id"] = `"topic/5"`
+	flow := dstest.NewFlow(data)
+	pool := newTestPool(t, ctx, flow)
+	notifier := make(fakeNotifier, 10)
+	pool.WatchProjectionChanges(notifier, nil)
+
+	flow.Changes <- map[dskey.Key][]byte{
+		dskey.MustKey("projector/1/current_projection_ids"): []byte("[1,2]"),
+		dskey.MustKey("projection/2/id"):                    []byte("2"),
+		dskey.MustKey("projection/2/meeting_id"):            []byte("1"),
+		dskey.MustKey("projection/2/content_object_id"):     []byte(`"motion/7"`),
+		dskey.MustKey("projection/2/weight"):                []byte("5"),
+	}
+
+	select {
+	case change := <-notifier:
+		if change.ProjectorID != 1 || change.Collection != "motion" || change.ContentObjectID != "motion/7" {
+			t.Errorf("unexpected change %v", change)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("projection change was not notified")
+	}
+}
+
+func TestScrollChangeSendsTransform(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	data := testProjectorData()
+	data["projector/1/scroll"] = "0"
+	flow := dstest.NewFlow(data)
+	pool := newTestPool(t, ctx, flow)
+
+	events := subscribe(t, ctx, pool, language.English)
+
+	flow.Changes <- map[dskey.Key][]byte{
+		dskey.MustKey("projector/1/scroll"): []byte("3"),
+	}
+
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case event := <-events:
+			switch event.Event {
+			case "settings", "projector-replace":
+				t.Fatalf("expected transform event, got %s", event.Event)
+			case "transform":
+				if event.Data != `{"scroll":3,"scale":0}` {
+					t.Errorf("unexpected transform event data %s", event.Data)
+				}
+
+				content, err := pool.GetProjectorContent(1, language.English)
+				if err != nil {
+					t.Fatalf("get content: %v", err)
+				}
+
+				if !strings.Contains(*content, "--projector-scroll: 3;") {
+					t.Errorf("expected content with new scroll, got %q", *content)
+				}
+				return
+			}
+		case <-timeout:
+			t.Fatalf("no transform event received")
+		}
+	}
+}
+
+func TestCountdownsEvent(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	data := testProjectorData()
+	data["projector/1/current_projection_ids"] = "[1,2]"
+	for id, countdown := range map[int]int{1: 3, 2: 4} {
+		data[fmt.Sprintf("projection/%d/id", id)] = strconv.Itoa(id)
+		data[fmt.Sprintf("projection/%d/meeting_id", id)] = "1"
+		data[fmt.Sprintf("projection/%d/stable", id)] = "true"
+		data[fmt.Sprintf("projection/%d/content_object_id", id)] = fmt.Sprintf(`"projector_countdown/%d"`, countdown)
+		data[fmt.Sprintf("projector_countdown/%d/id", countdown)] = strconv.Itoa(countdown)
+		data[fmt.Sprintf("projector_countdown/%d/meeting_id", countdown)] = "1"
+		data[fmt.Sprintf("projector_countdown/%d/title", countdown)] = `"Countdown"`
+		data[fmt.Sprintf("projector_countdown/%d/default_time", countdown)] = "60"
+		data[fmt.Sprintf("projector_countdown/%d/countdown_time", countdown)] = "60"
+	}
+	flow := dstest.NewFlow(data)
+	pool := newTestPool(t, ctx, flow)
+
+	readCountdowns := func(events <-chan *ProjectorUpdateEvent) map[int]countdownState {
+		t.Helper()
+
+		timeout := time.After(time.Second)
+		for {
+			select {
+			case event := <-events:
+				if event.Event != "countdowns" {
+					continue
+				}
+
+				var countdowns map[int]countdownState
+				if err := json.Unmarshal([]byte(event.Data), &countdowns); err != nil {
+					t.Fatalf("decode countdowns event: %v", err)
+				}
+				return countdowns
+			case <-timeout:
+				t.Fatalf("no countdowns event received")
+			}
+		}
+	}
+
+	events := subscribe(t, ctx, pool, language.English)
+	initial := readCountdowns(events)
+	if len(initial) != 2 || initial[3].Running || initial[4].Running {
+		t.Fatalf("expected two stopped countdowns, got %v", initial)
+	}
+
+	if initial[3].ServerTime == 0 || initial[4].ServerTime == 0 {
+		t.Errorf("expected server time on every countdown, got %v", initial)
+	}
+
+	flow.Changes <- map[dskey.Key][]byte{
+		dskey.MustKey("projector_countdown/4/running"):        []byte("true"),
+		dskey.MustKey("projector_countdown/4/countdown_time"): []byte("1700000060"),
+	}
+
+	updated := readCountdowns(events)
+	if !updated[4].Running || updated[4].CountdownTime != 1700000060 {
+		t.Errorf("expected countdown 4 to run, got %v", updated[4])
+	}
+
+	if updated[3].Running || updated[3].CountdownTime != 60 {
+		t.Errorf("expected countdown 3 to be unchanged, got %v", updated[3])
+	}
+}
+
+func TestTickEvents(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	data := testProjectorData()
+	data["projector/1/current_projection_ids"] = "[1]"
+	data["projection/1/id"] = "1"
+	data["projection/1/meeting_id"] = "1"
+	data["projection/1/stable"] = "true"
+	data["projection/1/content_object_id"] = `"projector_countdown/3"`
+	data["projector_countdown/3/id"] = "3"
+	data["projector_countdown/3/meeting_id"] = "1"
+	data["projector_countdown/3/title"] = `"Countdown"`
+	data["projector_countdown/3/default_time"] = "60"
+	data["projector_countdown/3/running"] = "true"
+	data["projector_countdown/3/countdown_time"] = strconv.FormatInt(time.Now().Unix()+60, 10)
+	flow := dstest.NewFlow(data)
+	pool := newTestPool(t, ctx, flow)
+	pool.TickInterval = 20 * time.Millisecond
+
+	readTick := func(events <-chan *ProjectorUpdateEvent) (projectorTick, bool) {
+		t.Helper()
+
+		timeout := time.After(200 * time.Millisecond)
+		for {
+			select {
+			case event := <-events:
+				if event.Event != "tick" {
+					continue
+				}
+
+				var tick projectorTick
+				if err := json.Unmarshal([]byte(event.Data), &tick); err != nil {
+					t.Fatalf("decode tick event: %v", err)
+				}
+				return tick, true
+			case <-timeout:
+				return projectorTick{}, false
+			}
+		}
+	}
+
+	events := subscribe(t, ctx, pool, language.English)
+	last := 61.0
+	for range 3 {
+		tick, ok := readTick(events)
+		if !ok {
+			t.Fatalf("no tick received for running countdown")
+		}
+
+		remaining := tick.Countdowns[3]
+		if remaining >= last || remaining < 58 {
+			t.Errorf("expected remaining time to decrease from %v, got %v", last, remaining)
+		}
+		last = remaining
+
+		if tick.ServerTime == 0 {
+			t.Errorf("expected server time in tick")
+		}
+	}
+
+	// Pausing stores the remaining seconds instead of the end time
+	flow.Changes <- map[dskey.Key][]byte{
+		dskey.MustKey("projector_countdown/3/running"):        []byte("false"),
+		dskey.MustKey("projector_countdown/3/countdown_time"): []byte("42"),
+	}
+
+	paused := time.After(time.Second)
+	for waiting := true; waiting; {
+		select {
+		case event := <-events:
+			waiting = event.Event != "countdowns"
+		case <-paused:
+			t.Fatalf("no countdowns event received after pausing")
+		}
+	}
+
+	if tick, ok := readTick(events); ok {
+		t.Errorf("expected no ticks for a paused countdown, got %v", tick)
+	}
+
+	// Resuming starts the ticks again
+	flow.Changes <- map[dskey.Key][]byte{
+		dskey.MustKey("projector_countdown/3/running"):        []byte("true"),
+		dskey.MustKey("projector_countdown/3/countdown_time"): []byte(strconv.FormatInt(time.Now().Unix()+42, 10)),
+	}
+
+	tick, ok := readTick(events)
+	if !ok {
+		t.Fatalf("no tick received after resuming the countdown")
+	}
+	if remaining := tick.Countdowns[3]; remaining > 42 || remaining < 40 {
+		t.Errorf("expected about 42 seconds remaining after resuming, got %v", remaining)
+	}
+}
+
+func TestTickEventWithClock(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	countdowns := map[int]countdownState{3: {CountdownTime: 30, DefaultTime: 60}}
+
+	if event := tickEvent(countdowns, false, now); event != nil {
+		t.Errorf("expected no tick without running countdown and clock, got %v", event.Data)
+	}
+
+	event := tickEvent(countdowns, true, now)
+	if event == nil {
+		t.Fatalf("expected a tick while the clock is shown")
+	}
+
+	if event.Data != `{"server_time":1700000000,"countdowns":{"3":30}}` {
+		t.Errorf("unexpected tick data %s", event.Data)
+	}
+}
+
+func TestSpeakerCountdownEvent(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	data := testProjectorData()
+	data["meeting/1/list_of_speakers_couple_countdown"] = "true"
+	data["meeting/1/projector_countdown_default_time"] = "120"
+	data["projector/1/current_projection_ids"] = "[1]"
+	data["projection/1/id"] = "1"
+	data["projection/1/meeting_id"] = "1"
+	data["projection/1/content_object_id"] = `"list_of_speakers/7"`
+	data["list_of_speakers/7/id"] = "7"
+	data["list_of_speakers/7/meeting_id"] = "1"
+	data["list_of_speakers/7/sequential_number"] = "1"
+	data["list_of_speakers/7/content_object_id"] = `"topic/5"`
+	data["list_of_speakers/7/speaker_ids"] = "[9]"
+	data["speaker/9/id"] = "9"
+	data["speaker/9/meeting_id"] = "1"
+	data["speaker/9/list_of_speakers_id"] = "7"
+	data["speaker/9/begin_time"] = "1700000000"
+	flow := dstest.NewFlow(data)
+	pool := newTestPool(t, ctx, flow)
+
+	readSpeakerCountdown := func(events <-chan *ProjectorUpdateEvent) *speakerCountdownState {
+		t.Helper()
+
+		timeout := time.After(time.Second)
+		for {
+			select {
+			case event := <-events:
+				if event.Event != "speaker-countdown" {
+					continue
+				}
+
+				var state *speakerCountdownState
+				if err := json.Unmarshal([]byte(event.Data), &state); err != nil {
+					t.Fatalf("decode speaker countdown event: %v", err)
+				}
+				return state
+			case <-timeout:
+				t.Fatalf("no speaker countdown event received")
+			}
+		}
+	}
+
+	events := subscribe(t, ctx, pool, language.English)
+	state := readSpeakerCountdown(events)
+	if state == nil || state.SpeakerID != 9 || !state.Running || state.CountdownTime != 1700000120 || state.DefaultTime != 120 {
+		t.Fatalf("expected running countdown of speaker 9, got %+v", state)
+	}
+
+	if state.ServerTime == 0 {
+		t.Errorf("expected server time, got %+v", state)
+	}
+
+	flow.Changes <- map[dskey.Key][]byte{
+		dskey.MustKey("speaker/9/pause_time"): []byte("1700000030"),
+	}
+
+	if state = readSpeakerCountdown(events); state == nil || state.Running || state.CountdownTime != 90 {
+		t.Errorf("expected paused countdown with 90 seconds left, got %+v", state)
+	}
+
+	flow.Changes <- map[dskey.Key][]byte{
+		dskey.MustKey("speaker/9/pause_time"):  nil,
+		dskey.MustKey("speaker/9/total_pause"): []byte("20"),
+	}
+
+	if state = readSpeakerCountdown(events); state == nil || !state.Running || state.CountdownTime != 1700000140 {
+		t.Errorf("expected resumed countdown, got %+v", state)
+	}
+
+	flow.Changes <- map[dskey.Key][]byte{
+		dskey.MustKey("speaker/9/end_time"): []byte("1700000100"),
+	}
+
+	if state = readSpeakerCountdown(events); state != nil {
+		t.Errorf("expected no countdown after the speech ended, got %+v", state)
+	}
+}
+
+func TestListOfSpeakersLiveUpdates(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	data := testProjectorData()
+	data["projector/1/current_projection_ids"] = "[1,2]"
+	data["projection/1/id"] = "1"
+	data["projection/1/meeting_id"] = "1"
+	data["projection/1/type"] = `"list_of_speakers"`
+	data["projection/1/content_object_id"] = `"list_of_speakers/7"`
+	data["projection/2/id"] = "2"
+	data["projection/2/meeting_id"] = "1"
+	data["projection/2/content_object_id"] = `"topic/5"`
+	data["list_of_speakers/7/id"] = "7"
+	data["list_of_speakers/7/meeting_id"] = "1"
+	data["list_of_speakers/7/sequential_number"] = "1"
+	data["list_of_speakers/7/content_object_id"] = `"topic/5"`
+	data["meeting/1/list_of_speakers_amount_last_on_projector"] = "-1"
+	data["meeting/1/list_of_speakers_amount_next_on_projector"] = "-1"
+	data["topic/5/id"] = "5"
+	data["topic/5/meeting_id"] = "1"
+	data["topic/5/sequential_number"] = "1"
+	data["topic/5/list_of_speakers_id"] = "7"
+	data["topic/5/title"] = `"Budget"`
+	data["topic/5/agenda_item_id"] = "4"
+	data["agenda_item/4/id"] = "4"
+	data["agenda_item/4/meeting_id"] = "1"
+	data["agenda_item/4/content_object_id"] = `"topic/5"`
+	data["meeting_user/3/id"] = "3"
+	data["meeting_user/3/meeting_id"] = "1"
+	data["meeting_user/3/user_id"] = "3"
+	data["meeting_user/3/group_ids"] = "[1]"
+	data["user/3/id"] = "3"
+	data["user/3/organization_id"] = "1"
+	data["user/3/username"] = `"ada"`
+	data["user/3/first_name"] = `"Ada"`
+	flow := dstest.NewFlow(data)
+	pool := newTestPool(t, ctx, flow)
+
+	events := subscribe(t, ctx, pool, language.English)
+
+	// readUpdate returns the content of the list of speakers from the next
+	// update and fails if another projection is sent again.
+	readUpdate := func() string {
+		t.Helper()
+
+		timeout := time.After(time.Second)
+		for {
+			select {
+			case event := <-events:
+				if event.Event != "projection-updated" {
+					continue
+				}
+
+				var updated map[int]string
+				if err := json.Unmarshal([]byte(event.Data), &updated); err != nil {
+					t.Fatalf("decode projection update: %v", err)
+				}
+
+				if _, ok := updated[2]; ok {
+					t.Errorf("expected unrelated projection not to be sent again")
+				}
+
+				if content, ok := updated[1]; ok {
+					return content
+				}
+			case <-timeout:
+				t.Fatalf("no update of the list of speakers received")
+			}
+		}
+	}
+
+	flow.Changes <- map[dskey.Key][]byte{
+		dskey.MustKey("list_of_speakers/7/speaker_ids"): []byte("[9]"),
+		dskey.MustKey("speaker/9/id"):                   []byte("9"),
+		dskey.MustKey("speaker/9/meeting_id"):           []byte("1"),
+		dskey.MustKey("speaker/9/list_of_speakers_id"):  []byte("7"),
+		dskey.MustKey("speaker/9/meeting_user_id"):      []byte("3"),
+	}
+
+	content := readUpdate()
+	if !strings.Contains(content, "Ada") || strings.Contains(content, `aria-current`) {
+		t.Errorf("expected waiting speaker, got %q", content)
+	}
+
+	flow.Changes <- map[dskey.Key][]byte{
+		dskey.MustKey("speaker/9/begin_time"): []byte("1700000000"),
+	}
+
+	content = readUpdate()
+	current, _, found := strings.Cut(content, `<div class="last-speakers">`)
+	if !found || !strings.Contains(current, `aria-current="true"`) || !strings.Contains(current, "Ada") {
+		t.Errorf("expected Ada to be marked as current speaker, got %q", content)
+	}
+
+	flow.Changes <- map[dskey.Key][]byte{
+		dskey.MustKey("speaker/9/end_time"): []byte("1700000100"),
+	}
+
+	content = readUpdate()
+	_, last, _ := strings.Cut(content, `<div class="last-speakers">`)
+	if strings.Contains(content, `aria-current`) || !strings.Contains(last, "Ada") {
+		t.Errorf("expected Ada to be a finished speaker, got %q", content)
+	}
+
+	flow.Changes <- map[dskey.Key][]byte{
+		dskey.MustKey("list_of_speakers/7/speaker_ids"): []byte("[]"),
+		dskey.MustKey("speaker/9/id"):                   nil,
+		dskey.MustKey("speaker/9/meeting_id"):           nil,
+		dskey.MustKey("speaker/9/list_of_speakers_id"):  nil,
+		dskey.MustKey("speaker/9/meeting_user_id"):      nil,
+		dskey.MustKey("speaker/9/begin_time"):           nil,
+		dskey.MustKey("speaker/9/end_time"):             nil,
+	}
+
+	if content = readUpdate(); strings.Contains(content, "Ada") {
+		t.Errorf("expected removed speaker not to be shown, got %q", content)
+	}
+}
+
+func TestSlideErrorEvent(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	data := testProjectorData()
+	data["projector/1/current_projection_ids"] = "[1]"
+	data["projection/1/id"] = "1"
+	data["projection/1/meeting_id"] = "1"
+	data["projection/1/content_object_id"] = `"projector_message/3"`
+	data["projector_message/3/id"] = "3"
+	data["projector_message/3/meeting_id"] = "1"
+	data["projector_message/3/message"] = `"<p>Welcome</p>"`
+	flow := dstest.NewFlow(data)
+	pool := newTestPool(t, ctx, flow)
+	pool.MaxSlideSize = 1
+
+	events := subscribe(t, ctx, pool, language.English)
+	for {
+		select {
+		case event := <-events:
+			if event.Event != "slide_error" {
+				continue
+			}
+
+			var slideErr slideError
+			if err := json.Unmarshal([]byte(event.Data), &slideErr); err != nil {
+				t.Fatalf("parse slide error: %v", err)
+			}
+
+			expected := slideError{ProjectionID: 1, Collection: "projector_message", Message: "This content is too large to be displayed"}
+			if slideErr != expected {
+				t.Errorf("expected %+v, got %+v", expected, slideErr)
+			}
+			return
+		case <-time.After(2 * time.Second):
+			t.Fatalf("no slide_error event received")
+		}
+	}
+}
+
+func TestResumeWithManySlideErrors(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	const numProjections = 20
+	data := testProjectorData()
+	projectionIDs := make([]string, numProjections)
+	for i := range numProjections {
+		id := strconv.Itoa(i + 1)
+		projectionIDs[i] = id
+		data["projection/"+id+"/id"] = id
+		data["projection/"+id+"/meeting_id"] = "1"
+		data["projection/"+id+"/content_object_id"] = `"projector_message/` + id + `"`
+		data["projector_message/"+id+"/id"] = id
+		data["projector_message/"+id+"/meeting_id"] = "1"
+		data["projector_message/"+id+"/message"] = `"<p>Welcome</p>"`
+	}
+	data["projector/1/current_projection_ids"] = "[" + strings.Join(projectionIDs, ",") + "]"
+
+	flow := dstest.NewFlow(data)
+	pool := newTestPool(t, ctx, flow)
+	pool.MaxSlideSize = 1
+	pool.ReplayBufferSize = 4
+
+	events := subscribe(t, ctx, pool, language.English)
+
+	// Slide errors of changed messages are kept for resuming
+	for _, id := range []string{"1", "2", "3"} {
+		flow.Changes <- map[dskey.Key][]byte{
+			dskey.MustKey("projector_message/" + id + "/message"): []byte(`"<p>Welcome again</p>"`),
+		}
+	}
+
+	var lastEventID string
+	timeout := time.After(2 * time.Second)
+	for lastEventID == "" {
+		select {
+		case event := <-events:
+			if event.Event == "slide_error" && event.ID != "" {
+				lastEventID = event.ID
+			}
+		case <-timeout:
+			t.Fatalf("no slide error of a changed message received")
+		}
+	}
+
+	type result struct {
+		events <-chan *ProjectorUpdateEvent
+		err    error
+	}
+	resumed := make(chan result, 1)
+	go func() {
+		events, _, err := pool.ResumeProjectorContent(ctx, 1, language.English, nil, lastEventID)
+		resumed <- result{events, err}
+	}()
+
+	var resumedEvents <-chan *ProjectorUpdateEvent
+	select {
+	case res := <-resumed:
+		if res.err != nil {
+			t.Fatalf("resume: %v", res.err)
+		}
+		resumedEvents = res.events
+	case <-time.After(2 * time.Second):
+		t.Fatalf("resuming with many slide errors blocked the projector")
+	}
+
+	// All slide errors are sent to the resumed listener, followed by the
+	// missed events
+	slideErrors := 0
+	for len(resumedEvents) > 0 {
+		if event := <-resumedEvents; event.Event == "slide_error" {
+			slideErrors++
+		}
+	}
+	if slideErrors < numProjections {
+		t.Errorf("expected at least %d slide errors, got %d", numProjections, slideErrors)
+	}
+
+	if _, err := pool.GetProjectorContent(1, language.English); err != nil {
+		t.Errorf("expected the projector to serve its content after the resume, got %v", err)
+	}
+}
+
+func TestOrganizationMessageEvent(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	flow := dstest.NewFlow(testProjectorData())
+	pool := newTestPool(t, ctx, flow)
+	if err := pool.WatchOrganizationMessage("login_text"); err != nil {
+		t.Fatalf("watch organization message: %v", err)
+	}
+
+	readMessage := func(events <-chan *ProjectorUpdateEvent) *organizationMessage {
+		t.Helper()
+
+		timeout := time.After(time.Second)
+		for {
+			select {
+			case event := <-events:
+				if event.Event != "organization-message" {
+					continue
+				}
+
+				var message *organizationMessage
+				if err := json.Unmarshal([]byte(event.Data), &message); err != nil {
+					t.Fatalf("decode organization message event: %v", err)
+				}
+				return message
+			case <-timeout:
+				t.Fatalf("no organization message event received")
+			}
+		}
+	}
+
+	events := subscribe(t, ctx, pool, language.English)
+	flow.Changes <- map[dskey.Key][]byte{dskey.MustKey("organization/1/login_text"): []byte(`"Lunch in the foyer"`)}
+	if message := readMessage(events); message == nil || message.Text != "Lunch in the foyer" {
+		t.Fatalf("expected organization message, got %+v", message)
+	}
+
+	// Subscribers connecting later receive the message on connect
+	if message := readMessage(subscribe(t, ctx, pool, language.German)); message == nil || message.Text != "Lunch in the foyer" {
+		t.Errorf("expected organization message on connect, got %+v", message)
+	}
+
+	flow.Changes <- map[dskey.Key][]byte{dskey.MustKey("organization/1/login_text"): nil}
+	if message := readMessage(events); message != nil {
+		t.Errorf("expected cleared organization message, got %+v", message)
+	}
+}
+
+func TestOrganizationMessageInvalidField(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	pool := newTestPool(t, ctx, dstest.NewFlow(testProjectorData()))
+	if err := pool.WatchOrganizationMessage("unknown_field"); err == nil {
+		t.Errorf("expected error for unknown organization field")
+	}
+}
+
+func TestDefaultSlide(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	data := testProjectorData()
+	data["meeting/1/description"] = `"Annual assembly"`
+	flow := dstest.NewFlow(data)
+	pool := newTestPool(t, ctx, flow)
+	pool.DefaultSlide = DefaultSlideTemplate
+
+	waitFor := func(events <-chan *ProjectorUpdateEvent, name string) *ProjectorUpdateEvent {
+		t.Helper()
+
+		timeout := time.After(time.Second)
+		for {
+			select {
+			case event := <-events:
+				if event.Event == name {
+					return event
+				}
+			case <-timeout:
+				t.Fatalf("no %s event received", name)
+			}
+		}
+	}
+
+	content, err := pool.GetProjectorContent(1, language.English)
+	if err != nil {
+		t.Fatalf("get content: %v", err)
+	}
+	if !strings.Contains(*content, `class="content default-slide"`) || !strings.Contains(*content, "Annual assembly") {
+		t.Fatalf("expected the default slide on an empty projector, got %s", *content)
+	}
+
+	events := subscribe(t, ctx, pool, language.English)
+	flow.Changes <- map[dskey.Key][]byte{
+		dskey.MustKey("projector/1/current_projection_ids"): []byte("[1]"),
+		dskey.MustKey("projection/1/id"):                    []byte("1"),
+		dskey.MustKey("projection/1/meeting_id"):            []byte("1"),
+		dskey.MustKey("projection/1/content_object_id"):     []byte(`"topic/5"`),
+		dskey.MustKey("topic/5/id"):                         []byte("5"),
+		dskey.MustKey("topic/5/meeting_id"):                 []byte("1"),
+		dskey.MustKey("topic/5/sequential_number"):          []byte("5"),
+		dskey.MustKey("topic/5/list_of_speakers_id"):        []byte("1"),
+		dskey.MustKey("topic/5/title"):                      []byte(`"Welcome"`),
+		dskey.MustKey("topic/5/agenda_item_id"):             []byte("3"),
+		dskey.MustKey("agenda_item/3/id"):                   []byte("3"),
+		dskey.MustKey("agenda_item/3/meeting_id"):           []byte("1"),
+		dskey.MustKey("agenda_item/3/content_object_id"):    []byte(`"topic/5"`),
+	}
+
+	if event := waitFor(events, "projection-deleted"); event.Data != "0" {
+		t.Fatalf("expected the default slide to be removed, got %s", event.Data)
+	}
+
+	content, err = pool.GetProjectorContent(1, language.English)
+	if err != nil {
+		t.Fatalf("get content: %v", err)
+	}
+	if strings.Contains(*content, "default-slide") || !strings.Contains(*content, "Welcome") {
+		t.Errorf("expected the projection instead of the default slide, got %s", *content)
+	}
+
+	flow.Changes <- map[dskey.Key][]byte{
+		dskey.MustKey("projector/1/current_projection_ids"): []byte("[]"),
+	}
+
+	var shown map[string]string
+	if err := json.Unmarshal([]byte(waitFor(events, "projection-updated").Data), &shown); err != nil {
+		t.Fatalf("decode update event: %v", err)
+	}
+	if !strings.Contains(shown["0"], "default-slide") {
+		t.Errorf("expected the default slide after the last projection was removed, got %v", shown)
+	}
+}
+
+func TestStaleWhenFlowStalls(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	flow := dstest.NewFlow(testProjectorData())
+	pool := newTestPool(t, ctx, flow)
+	pool.StaleAfter = 100 * time.Millisecond
+
+	start := time.Now().Unix()
+	events := subscribe(t, ctx, pool, language.English)
+
+	// waitForStale returns the last update of the first stale event with
+	// the given state received within the timeout.
+	waitForStale := func(stale bool, timeout time.Duration) (int64, bool) {
+		t.Helper()
+
+		deadline := time.After(timeout)
+		for {
+			select {
+			case event := <-events:
+				if event.Event != "stale" {
+					continue
+				}
+
+				var payload struct {
+					Stale      bool  `json:"stale"`
+					LastUpdate int64 `json:"last_update"`
+				}
+				if err := json.Unmarshal([]byte(event.Data), &payload); err != nil {
+					t.Fatalf("decode stale event %s: %v", event.Data, err)
+				}
+
+				if payload.Stale == stale {
+					return payload.LastUpdate, true
+				}
+			case <-deadline:
+				return 0, false
+			}
+		}
+	}
+
+	// Nothing changed in the datastore, the content is current
+	if _, ok := waitForStale(true, 500*time.Millisecond); ok {
+		t.Fatalf("expected content of an unchanged datastore not to be stale")
+	}
+
+	// The datastore changed without notifying the flow
+	flow.SetPosition(5)
+	lastUpdate, ok := waitForStale(true, 2*time.Second)
+	if !ok {
+		t.Fatalf("expected content to be stale once the flow stalled")
+	}
+	if lastUpdate < start {
+		t.Errorf("expected last update at start of the projector, got %d before %d", lastUpdate, start)
+	}
+
+	flow.Changes <- map[dskey.Key][]byte{
+		dskey.MustKey("projector/1/name"): []byte(`"Renamed"`),
+	}
+	if _, ok := waitForStale(false, 2*time.Second); !ok {
+		t.Errorf("expected content to be current again once the flow delivers changes")
+	}
+}
@@ -0,0 +1,184 @@
+package projector
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/OpenSlides/openslides-projector-service/pkg/projector/slide"
+)
+
+// DefaultReplayBufferSize is the number of events kept per projector to
+// resume subscriptions.
+const DefaultReplayBufferSize = 64
+
+// unreplayedEvents are not kept for resumed subscriptions. They only repeat
+// the current state, which is sent to every new listener anyway.
+var unreplayedEvents = []string{"tick", "connected", "stale"}
+
+// replayBuffer keeps the last events sent to the listeners of a projector in
+// a ring, so a subscription resumed with the id of the last received event
+// gets the events it missed. The events count against the memory budget of
+// the pool, which evicts the oldest events of all buffers under pressure.
+type replayBuffer struct {
+	// epoch distinguishes the ids of projectors created at different times,
+	// ids of an earlier projector cannot be resumed.
+	epoch string
+
+	mu     sync.Mutex
+	budget *slide.MemoryBudget
+	events []replayEntry
+	last   uint64
+
+	// first is the id of the oldest event still kept
+	first uint64
+	bytes int64
+}
+
+type replayEntry struct {
+	event *ProjectorUpdateEvent
+	added time.Time
+}
+
+// newReplayBuffer returns a buffer of size events registered with the
+// budget. A nil budget does not limit the buffer.
+func newReplayBuffer(size int, budget *slide.MemoryBudget) *replayBuffer {
+	b := &replayBuffer{
+		epoch:  strconv.FormatInt(time.Now().UnixNano(), 36),
+		budget: budget,
+		events: make([]replayEntry, max(size, 1)),
+		first:  1,
+	}
+	budget.Register(b)
+
+	return b
+}
+
+// add assigns the next id to the event and keeps it.
+func (b *replayBuffer) add(event *ProjectorUpdateEvent) {
+	// The budget evicts events of this buffer, so it is only called after
+	// the lock is released.
+	grown, budget := b.store(event)
+	if grown > 0 {
+		budget.Grow(grown)
+	} else if grown < 0 {
+		budget.Release(-grown)
+	}
+}
+
+// store keeps the event in place of the oldest one of the ring. Returns the
+// change of the kept bytes and the budget to report it to.
+func (b *replayBuffer) store(event *ProjectorUpdateEvent) (int64, *slide.MemoryBudget) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	before := b.bytes
+	b.last++
+	event.ID = fmt.Sprintf("%s-%d", b.epoch, b.last)
+
+	slot := &b.events[b.last%uint64(len(b.events))]
+	if slot.event != nil {
+		b.bytes -= replayEventSize(slot.event)
+	}
+	*slot = replayEntry{event: event, added: time.Now()}
+	b.bytes += replayEventSize(event)
+	if size := uint64(len(b.events)); b.last > size {
+		b.first = max(b.first, b.last-size+1)
+	}
+
+	return b.bytes - before, b.budget
+}
+
+// since returns the events sent after the event with the given id. Returns
+// false if the id is unknown or the events are not kept anymore.
+func (b *replayBuffer) since(id string) ([]*ProjectorUpdateEvent, bool) {
+	epoch, seq, found := strings.Cut(id, "-")
+	if !found || epoch != b.epoch {
+		return nil, false
+	}
+
+	n, err := strconv.ParseUint(seq, 10, 64)
+	if err != nil {
+		return nil, false
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if n > b.last || n+1 < b.first {
+		return nil, false
+	}
+
+	events := make([]*ProjectorUpdateEvent, 0, b.last-n)
+	for i := n + 1; i <= b.last; i++ {
+		events = append(events, b.events[i%uint64(len(b.events))].event)
+	}
+
+	return events, true
+}
+
+// close drops all events and leaves the budget once the projector stopped.
+func (b *replayBuffer) close() {
+	b.mu.Lock()
+	budget, bytes := b.budget, b.bytes
+	b.budget = nil
+	b.bytes = 0
+	b.first = b.last + 1
+	clear(b.events)
+	b.mu.Unlock()
+
+	budget.Unregister(b)
+	budget.Release(bytes)
+}
+
+// OldestUse returns the time the oldest kept event was sent. It is called by
+// the memory budget of the buffer.
+func (b *replayBuffer) OldestUse() (time.Time, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.first > b.last {
+		return time.Time{}, false
+	}
+
+	return b.events[b.first%uint64(len(b.events))].added, true
+}
+
+// EvictOldest drops the oldest kept event and returns its size. Resuming
+// from an event before it needs a full resync afterwards. It is called by the
+// memory budget of the buffer.
+func (b *replayBuffer) EvictOldest() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.first > b.last {
+		return 0
+	}
+
+	slot := &b.events[b.first%uint64(len(b.events))]
+	size := replayEventSize(slot.event)
+	*slot = replayEntry{}
+	b.first++
+	b.bytes -= size
+	return size
+}
+
+// replayEventSize estimates the memory of a kept event by its payload.
+func replayEventSize(event *ProjectorUpdateEvent) int64 {
+	return int64(len(event.ID) + len(event.Event) + len(event.Data))
+}
+
+var (
+	resumesReplayed atomic.Uint64
+	resumesResynced atomic.Uint64
+)
+
+// Resumes returns the number of subscriptions resumed by replaying the
+// missed events and the number of them which needed a full resync because
+// the events were not kept anymore.
+func Resumes() (replayed uint64, resynced uint64) {
+	return resumesReplayed.Load(), resumesResynced.Load()
+}
@@ -0,0 +1,47 @@
+package projector
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/OpenSlides/openslides-projector-service/pkg/projector/slide"
+)
+
+func TestReplayBufferMemoryBudget(t *testing.T) {
+	budget := slide.NewMemoryBudget(250)
+	first := newReplayBuffer(10, budget)
+	second := newReplayBuffer(10, budget)
+
+	events := make([]*ProjectorUpdateEvent, 3)
+	for i := range events {
+		events[i] = &ProjectorUpdateEvent{Event: "projection-updated", Data: strings.Repeat("a", 40)}
+		first.add(events[i])
+		time.Sleep(time.Millisecond)
+	}
+
+	if missed, ok := first.since(events[0].ID); !ok || len(missed) != 2 {
+		t.Fatalf("expected two missed events within the budget, got %d, %t", len(missed), ok)
+	}
+
+	// Exceeding the budget evicts the oldest events of all buffers
+	second.add(&ProjectorUpdateEvent{Event: "projection-updated", Data: strings.Repeat("b", 100)})
+	if stats := budget.Stats(); stats.UsedBytes > stats.MaxBytes || stats.Evicted == 0 {
+		t.Fatalf("expected events to be evicted to keep the budget, got %+v", stats)
+	}
+
+	if _, ok := first.since(events[0].ID); ok {
+		t.Errorf("expected resume after an evicted event to need a resync")
+	}
+
+	if missed, ok := first.since(events[1].ID); !ok || len(missed) != 1 || missed[0] != events[2] {
+		t.Errorf("expected newest event to be kept, got %v, %t", missed, ok)
+	}
+
+	// Stopped projectors release their events
+	first.close()
+	second.close()
+	if used := budget.Stats().UsedBytes; used != 0 {
+		t.Errorf("expected closed buffers to release their memory, got %d bytes", used)
+	}
+}
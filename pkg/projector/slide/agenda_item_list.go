@@ -4,22 +4,33 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"maps"
 	"slices"
 
 	"github.com/OpenSlides/openslides-go/datastore/dsmodels"
 	"github.com/OpenSlides/openslides-projector-service/pkg/viewmodels"
+	"github.com/rs/zerolog/log"
 )
 
 type agendaListEntry struct {
+	ID           int
 	Number       string
 	TitleInfo    viewmodels.TitleInformation
 	Weight       int
+	Closed       bool
 	ChildEntries []agendaListEntry
 }
 
 type agendaItemListSlideOptions struct {
-	OnlyMainItems bool `json:"only_main_items"`
-	ShowInternal  bool `json:"-"`
+	OnlyMainItems  bool `json:"only_main_items"`
+	CollapseClosed bool `json:"collapse_closed"`
+	ShowInternal   bool `json:"-"`
+	ShowNumber     bool `json:"-"`
+	itemNumberOverride
+}
+
+func init() {
+	RegisterSlideRenderer("agenda_item_list", AgendaItemListSlideHandler)
 }
 
 func AgendaItemListSlideHandler(ctx context.Context, req *projectionRequest) (map[string]any, error) {
@@ -39,9 +50,11 @@ func AgendaItemListSlideHandler(ctx context.Context, req *projectionRequest) (ma
 	}
 
 	req.Fetch.Meeting_AgendaShowInternalItemsOnProjector(req.Projection.MeetingID).Lazy(&options.ShowInternal)
+	lazyShowItemNumber(req.Fetch, req.Projection.MeetingID, &options.ShowNumber)
 	if err := req.Fetch.Execute(ctx); err != nil {
-		return nil, fmt.Errorf("failed fetching agenda show internal option: %w", err)
+		return nil, fmt.Errorf("failed fetching agenda projector options: %w", err)
 	}
+	options.itemNumberOverride.apply(&options.ShowNumber)
 
 	agendaItemIds, err := req.Fetch.Meeting_AgendaItemIDs(*req.ContentObjectID).Value(ctx)
 	if err != nil {
@@ -53,7 +66,7 @@ func AgendaItemListSlideHandler(ctx context.Context, req *projectionRequest) (ma
 		return nil, fmt.Errorf("could not load agenda items %w", err)
 	}
 
-	agenda, err := recBuildAgendaList(ctx, req.Fetch, agendaItems, 0, options)
+	agenda, err := buildAgendaTree(ctx, req.Fetch, agendaItems, options)
 	if err != nil {
 		return nil, fmt.Errorf("could process agenda items %w", err)
 	}
@@ -63,36 +76,109 @@ func AgendaItemListSlideHandler(ctx context.Context, req *projectionRequest) (ma
 	}, nil
 }
 
-func recBuildAgendaList(ctx context.Context, fetch *dsmodels.Fetch, agendaItems []dsmodels.AgendaItem, currentParent int, options agendaItemListSlideOptions) ([]agendaListEntry, error) {
-	agenda := []agendaListEntry{}
+// buildAgendaTree builds the agenda from the parents of the items, children
+// are ordered by weight and id. Items whose parent is not part of the agenda
+// are shown on the top level. With the option collapse_closed, sub items of
+// closed items are collapsed.
+// Parents forming a cycle are never written by the backend, if they are
+// anyway the item of the cycle with the lowest id is shown on the top level.
+func buildAgendaTree(ctx context.Context, fetch *dsmodels.Fetch, agendaItems []dsmodels.AgendaItem, options agendaItemListSlideOptions) ([]agendaListEntry, error) {
+	items := make(map[int]dsmodels.AgendaItem, len(agendaItems))
 	for _, agendaItem := range agendaItems {
-		parentId, _ := agendaItem.ParentID.Value()
-		if parentId == currentParent && (options.ShowInternal || agendaItem.Type != "internal") && agendaItem.Type != "hidden" {
+		items[agendaItem.ID] = agendaItem
+	}
+
+	parents := make(map[int]int, len(agendaItems))
+	children := map[int][]int{}
+	for _, id := range slices.Sorted(maps.Keys(items)) {
+		parentID, _ := items[id].ParentID.Value()
+		if _, ok := items[parentID]; !ok {
+			parentID = 0
+		}
+		parents[id] = parentID
+		children[parentID] = append(children[parentID], id)
+	}
+
+	reached := map[int]bool{}
+	var reach func(id int)
+	reach = func(id int) {
+		reached[id] = true
+		for _, child := range children[id] {
+			if !reached[child] {
+				reach(child)
+			}
+		}
+	}
+
+	roots := children[0]
+	for _, id := range roots {
+		reach(id)
+	}
+
+	// Items not reached from the top level descend from a cycle
+	for _, id := range slices.Sorted(maps.Keys(items)) {
+		if reached[id] {
+			continue
+		}
+
+		seen := map[int]bool{}
+		for !seen[id] {
+			seen[id] = true
+			id = parents[id]
+		}
+
+		root := id
+		for next := parents[id]; next != id; next = parents[next] {
+			root = min(root, next)
+		}
+
+		log.Warn().Int("agenda_item", root).Msg("agenda items with cyclic parents")
+		roots = append(roots, root)
+		reach(root)
+	}
+
+	built := map[int]bool{}
+	var build func(ids []int) ([]agendaListEntry, error)
+	build = func(ids []int) ([]agendaListEntry, error) {
+		agenda := []agendaListEntry{}
+		for _, id := range ids {
+			agendaItem := items[id]
+			if built[id] || (!options.ShowInternal && agendaItem.Type == "internal") || agendaItem.Type == "hidden" {
+				continue
+			}
+			built[id] = true
+
 			titleInfo, err := viewmodels.GetTitleInformationByContentObject(ctx, fetch, agendaItem.ContentObjectID)
 			if err != nil {
 				return nil, fmt.Errorf("could not get title information: %w", err)
 			}
 
-			var childEntries []agendaListEntry
-			if !options.OnlyMainItems {
-				childEntries, err = recBuildAgendaList(ctx, fetch, agendaItems, agendaItem.ID, options)
+			entry := agendaListEntry{
+				ID:        agendaItem.ID,
+				TitleInfo: titleInfo,
+				Weight:    agendaItem.Weight,
+				Closed:    agendaItem.Closed,
+			}
+			if options.ShowNumber {
+				entry.Number = agendaItem.ItemNumber
+			}
+
+			if !options.OnlyMainItems && !(options.CollapseClosed && agendaItem.Closed) {
+				entry.ChildEntries, err = build(children[id])
 				if err != nil {
 					return nil, fmt.Errorf("could not get child entries: %w", err)
 				}
 			}
 
-			agenda = append(agenda, agendaListEntry{
-				Number:       agendaItem.ItemNumber,
-				TitleInfo:    titleInfo,
-				Weight:       agendaItem.Weight,
-				ChildEntries: childEntries,
-			})
+			agenda = append(agenda, entry)
 		}
-	}
 
-	slices.SortFunc(agenda, func(a, b agendaListEntry) int {
-		return a.Weight - b.Weight
-	})
+		slices.SortFunc(agenda, func(a, b agendaListEntry) int {
+			return viewmodels.CompareWeight(a.Weight, a.ID, b.Weight, b.ID)
+		})
+
+		return agenda, nil
+	}
 
-	return agenda, nil
+	return build(roots)
 }
@@ -3,12 +3,15 @@ package slide
 import (
 	"context"
 	"fmt"
-	"html/template"
 
 	"github.com/OpenSlides/openslides-go/datastore/dsmodels"
 	"github.com/OpenSlides/openslides-projector-service/pkg/viewmodels"
 )
 
+func init() {
+	RegisterSlideRenderer("assignment", AssignmentSlideHandler)
+}
+
 func AssignmentSlideHandler(ctx context.Context, req *projectionRequest) (map[string]any, error) {
 	aQ := req.Fetch.Assignment(*req.ContentObjectID)
 	assignment, err := aQ.Preload(aQ.CandidateList().MeetingUser().StructureLevelList()).Preload(aQ.CandidateList().MeetingUser().User()).First(ctx)
@@ -16,25 +19,31 @@ func AssignmentSlideHandler(ctx context.Context, req *projectionRequest) (map[st
 		return nil, fmt.Errorf("could not load assignment id %w", err)
 	}
 
+	names, err := req.userNames(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	candidates := []viewmodels.WeightedListEntry{}
 	for _, candidate := range assignment.CandidateList {
+		// Candidates of deleted users lose their meeting user
 		var meetingUser *dsmodels.MeetingUser
 		if val, isSet := candidate.MeetingUser.Value(); isSet {
 			meetingUser = &val
 		}
 
 		candidates = append(candidates, viewmodels.WeightedListEntry{
-			Name:        req.Locale.Get("Unknown user"),
-			MeetingUser: meetingUser,
-			Weight:      candidate.Weight,
+			ID:     candidate.ID,
+			Name:   names.name(meetingUser, true, req.Locale.Get("Unknown user")),
+			Weight: candidate.Weight,
 		})
 	}
 
-	viewmodels.CalcWeightedListNames(candidates)
+	viewmodels.SortWeightedList(candidates)
 
 	return map[string]any{
 		"Assignment":  assignment,
-		"Description": template.HTML(assignment.Description),
+		"Description": req.sanitizeHTML(assignment.Description),
 		"Candidates":  candidates,
 	}, nil
 }
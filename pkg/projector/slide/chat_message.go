@@ -0,0 +1,59 @@
+package slide
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/OpenSlides/openslides-go/datastore/dsfetch"
+)
+
+func init() {
+	RegisterSlideRenderer("chat_message", ChatMessageSlideHandler)
+}
+
+// ChatMessageSlideHandler renders a chat message as announcement on top of
+// the other projections. Messages without content are not rendered.
+func ChatMessageSlideHandler(ctx context.Context, req *projectionRequest) (map[string]any, error) {
+	if req.ContentObjectID == nil {
+		return nil, fmt.Errorf("no chat message id provided for slide")
+	}
+
+	message, err := req.Fetch.ChatMessage(*req.ContentObjectID).First(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not load chat message %w", err)
+	}
+
+	if strings.TrimSpace(message.Content) == "" {
+		return nil, nil
+	}
+
+	groupName, err := req.Fetch.ChatGroup_Name(message.ChatGroupID).Value(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not load chat group %w", err)
+	}
+
+	names, err := req.userNames(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	author := ""
+	if meetingUserID, ok := message.MeetingUserID.Value(); ok {
+		muQ := req.Fetch.MeetingUser(meetingUserID)
+		meetingUser, err := muQ.Preload(muQ.User()).First(ctx)
+		var doesNotExist dsfetch.DoesNotExistError
+		if err == nil {
+			author = names.name(&meetingUser, false, "")
+		} else if !errors.As(err, &doesNotExist) {
+			return nil, fmt.Errorf("could not load chat message author %w", err)
+		}
+	}
+
+	return map[string]any{
+		"Message":   message,
+		"GroupName": groupName,
+		"Author":    author,
+	}, nil
+}
@@ -14,6 +14,10 @@ type currentSpeakerChyronSlideOptions struct {
 	AgendaItem bool   `json:"agenda_item"`
 }
 
+func init() {
+	RegisterSlideRenderer("current_speaker_chyron", CurrentSpeakerChyronSlideHandler)
+}
+
 func CurrentSpeakerChyronSlideHandler(ctx context.Context, req *projectionRequest) (map[string]any, error) {
 	referenceProjectorId, err := req.Fetch.Meeting_ReferenceProjectorID(*req.ContentObjectID).Value(ctx)
 	if err != nil {
@@ -66,13 +70,13 @@ func CurrentSpeakerChyronSlideHandler(ctx context.Context, req *projectionReques
 	slideStructureLevel := ""
 	slideAgendaItem := ""
 	if currentSpeaker != nil {
-		speakerName, err := viewmodels.Speaker_FullName(ctx, currentSpeaker)
+		names, err := req.userNames(ctx)
 		if err != nil {
-			return nil, fmt.Errorf("could not get speaker name: %w", err)
+			return nil, err
 		}
 
-		if speakerName != nil {
-			slideSpeakerName = *speakerName
+		if meetingUser, isSet := currentSpeaker.MeetingUser.Value(); isSet && meetingUser.User != nil {
+			slideSpeakerName = names.name(&meetingUser, false, "")
 
 			structureLevelDefaultTime, err := req.Fetch.Meeting_ListOfSpeakersDefaultStructureLevelTime(los.MeetingID).Value(ctx)
 			if err != nil {
@@ -107,7 +111,7 @@ func CurrentSpeakerChyronSlideHandler(ctx context.Context, req *projectionReques
 
 	co, err := viewmodels.GetTitleInformationByContentObject(ctx, req.Fetch, los.ContentObjectID)
 	if err != nil {
-		return nil, fmt.Errorf("could not fetch coTitle information")
+		return nil, fmt.Errorf("could not fetch coTitle information: %w", err)
 	}
 
 	var parts []string
@@ -7,6 +7,10 @@ import (
 	"github.com/OpenSlides/openslides-projector-service/pkg/viewmodels"
 )
 
+func init() {
+	RegisterSlideRenderer("current_speaking_structure_level", CurrentSpeakingStructureLevelSlideHandler)
+}
+
 func CurrentSpeakingStructureLevelSlideHandler(ctx context.Context, req *projectionRequest) (map[string]any, error) {
 	if req.ContentObjectID == nil {
 		return nil, fmt.Errorf("no meeting id provided for slide")
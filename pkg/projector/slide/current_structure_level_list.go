@@ -1,13 +1,19 @@
 package slide
 
 import (
+	"cmp"
 	"context"
 	"fmt"
+	"slices"
 
 	"github.com/OpenSlides/openslides-go/datastore/dsmodels"
 	"github.com/OpenSlides/openslides-projector-service/pkg/viewmodels"
 )
 
+func init() {
+	RegisterSlideRenderer("current_structure_level_list", CurrentStructureLevelListSlideHandler)
+}
+
 func CurrentStructureLevelListSlideHandler(ctx context.Context, req *projectionRequest) (map[string]any, error) {
 	if req.ContentObjectID == nil {
 		return nil, fmt.Errorf("no meeting id provided for slide")
@@ -46,6 +52,11 @@ func CurrentStructureLevelListSlideHandler(ctx context.Context, req *projectionR
 		Intervention  bool
 		Answer        bool
 	}
+	// Structure levels have no weight, they are shown in the order of their ids
+	slices.SortFunc(los.StructureLevelListOfSpeakersList, func(a, b dsmodels.StructureLevelListOfSpeakers) int {
+		return cmp.Compare(a.StructureLevelID, b.StructureLevelID)
+	})
+
 	structureLevels := []structureLevelEntry{}
 	for _, sllos := range los.StructureLevelListOfSpeakersList {
 		totalTime := float64(sllos.InitialTime) + sllos.AdditionalTime
@@ -126,6 +137,9 @@ func CurrentStructureLevelListSlideHandler(ctx context.Context, req *projectionR
 		structureLevels = append(structureLevels, interventionEntry)
 	}
 
+	slices.SortFunc(answerSpeakers, func(a, b dsmodels.Speaker) int {
+		return viewmodels.CompareWeight(a.Weight, a.ID, b.Weight, b.ID)
+	})
 	for _, answerSpeaker := range answerSpeakers {
 		isCurrent := viewmodels.Speaker_IsCurrent(&answerSpeaker)
 		running := answerSpeaker.PauseTime == 0 && isCurrent
@@ -0,0 +1,15 @@
+package slide
+
+type ProjectionRequest = projectionRequest
+
+type RenderCacheKey = renderCacheKey
+
+func (c *RenderCache) Get(key RenderCacheKey) (string, bool) {
+	return c.get(key)
+}
+
+func (c *RenderCache) Add(key RenderCacheKey, content string) {
+	c.add(key, content)
+}
+
+var RenderKey = renderKey
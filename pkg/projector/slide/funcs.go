@@ -0,0 +1,112 @@
+package slide
+
+import (
+	"fmt"
+	"html/template"
+	"time"
+
+	"github.com/OpenSlides/openslides-projector-service/pkg/i18n"
+	"golang.org/x/text/message"
+	"golang.org/x/text/number"
+)
+
+// dateLayouts are the date formats per base language. Languages without an
+// entry use ISO 8601 dates.
+var dateLayouts = map[string]string{
+	"de": "02.01.2006",
+	"en": "01/02/2006",
+	"es": "02/01/2006",
+	"fr": "02/01/2006",
+	"it": "02/01/2006",
+	"nl": "02-01-2006",
+}
+
+const defaultDateLayout = "2006-01-02"
+
+// TemplateFuncs returns the functions available in slide templates. Output
+// depending on the language uses the current language of the locale.
+//
+//   - t translates a string, e.g. {{ t "Motion" }}
+//   - Loc returns the locale
+//   - FormatNumber formats a number with the separators of the language
+//   - FormatPercent formats a share, e.g. 0.75, as percentage of the language
+//   - FormatDate, FormatDateTime and FormatTime format a unix timestamp
+//   - FormatDuration formats seconds as minutes and seconds, e.g. 4:05
+//   - Truncate shortens a string to a number of characters
+//   - Plural translates the singular or plural form depending on a count
+//   - SafeHTML marks a string as trusted html, only for sanitized content
+//   - MediaURL returns the url of a mediafile
+//   - RenderIndex returns the one based position of a zero based index
+func TemplateFuncs(locale *i18n.ProjectorLocale, mediaURL string) template.FuncMap {
+	return template.FuncMap{
+		"t": func(str string, vars ...any) string {
+			return locale.Get(str, vars...)
+		},
+		"Loc": func() *i18n.ProjectorLocale {
+			return locale
+		},
+		"FormatNumber": func(v any) string {
+			return message.NewPrinter(locale.Language()).Sprint(number.Decimal(v))
+		},
+		"FormatPercent": func(share float64) string {
+			return message.NewPrinter(locale.Language()).Sprint(number.Percent(share, number.MaxFractionDigits(1)))
+		},
+		"FormatDate": func(timestamp int) string {
+			return time.Unix(int64(timestamp), 0).Format(dateLayout(locale))
+		},
+		"FormatDateTime": func(timestamp int) string {
+			return time.Unix(int64(timestamp), 0).Format(dateLayout(locale) + " 15:04")
+		},
+		"FormatTime": func(timestamp int) string {
+			return time.Unix(int64(timestamp), 0).Format("15:04")
+		},
+		"FormatDuration": formatDuration,
+		"Truncate":       truncate,
+		"Plural": func(count int, singular string, plural string) string {
+			if count == 1 {
+				return locale.Get(singular)
+			}
+			return locale.Get(plural)
+		},
+		"SafeHTML": func(html string) template.HTML {
+			return template.HTML(html)
+		},
+		"MediaURL": func(id int) string {
+			return fmt.Sprintf("%s%d", mediaURL, id)
+		},
+		"RenderIndex": func(i int) int {
+			return i + 1
+		},
+	}
+}
+
+func dateLayout(locale *i18n.ProjectorLocale) string {
+	base, _ := locale.Language().Base()
+	if layout, ok := dateLayouts[base.String()]; ok {
+		return layout
+	}
+
+	return defaultDateLayout
+}
+
+// formatDuration formats seconds as m:ss or, from one hour on, as h:mm:ss.
+func formatDuration(seconds int) string {
+	seconds = max(seconds, 0)
+	if seconds >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+	}
+
+	return fmt.Sprintf("%d:%02d", seconds/60, seconds%60)
+}
+
+// truncate shortens s to at most length characters. An ellipsis replaces the
+// last character of shortened strings. A length of zero or less keeps the
+// string.
+func truncate(s string, length int) string {
+	runes := []rune(s)
+	if length <= 0 || len(runes) <= length {
+		return s
+	}
+
+	return string(runes[:length-1]) + "…"
+}
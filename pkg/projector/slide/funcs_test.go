@@ -0,0 +1,84 @@
+package slide_test
+
+import (
+	"bytes"
+	"html/template"
+	"testing"
+	"time"
+
+	"github.com/OpenSlides/openslides-projector-service/pkg/i18n"
+	"github.com/OpenSlides/openslides-projector-service/pkg/projector/slide"
+	"golang.org/x/text/language"
+)
+
+func TestTemplateFuncs(t *testing.T) {
+	funcs := slide.TemplateFuncs(i18n.NewLocale(language.English), slide.DefaultMediaURL)
+
+	for _, name := range []string{
+		"t",
+		"Loc",
+		"FormatNumber",
+		"FormatPercent",
+		"FormatDate",
+		"FormatDateTime",
+		"FormatTime",
+		"FormatDuration",
+		"Truncate",
+		"Plural",
+		"SafeHTML",
+		"MediaURL",
+		"RenderIndex",
+	} {
+		if _, ok := funcs[name]; !ok {
+			t.Errorf("expected template function %s to be registered", name)
+		}
+	}
+}
+
+func TestTemplateFuncsOutput(t *testing.T) {
+	timestamp := int(time.Date(2024, time.March, 5, 14, 30, 0, 0, time.Local).Unix())
+
+	for _, tt := range []struct {
+		name     string
+		lang     language.Tag
+		tmpl     string
+		data     any
+		expected string
+	}{
+		{"translation", language.English, `{{ t "Motion" }}`, nil, "Motion"},
+		{"number english", language.English, `{{ FormatNumber . }}`, 1234567.5, "1,234,567.5"},
+		{"number german", language.German, `{{ FormatNumber . }}`, 1234567, "1.234.567"},
+		{"percent english", language.English, `{{ FormatPercent . }}`, 0.7564, "75.6%"},
+		{"percent german", language.German, `{{ FormatPercent . }}`, 0.7564, "75,6\u00a0%"},
+		{"date english", language.English, `{{ FormatDate . }}`, timestamp, "03/05/2024"},
+		{"date german", language.German, `{{ FormatDateTime . }}`, timestamp, "05.03.2024 14:30"},
+		{"date fallback", language.Japanese, `{{ FormatDate . }}`, timestamp, "2024-03-05"},
+		{"time", language.English, `{{ FormatTime . }}`, timestamp, "14:30"},
+		{"duration", language.English, `{{ FormatDuration . }}`, 245, "4:05"},
+		{"long duration", language.English, `{{ FormatDuration . }}`, 3725, "1:02:05"},
+		{"truncate", language.English, `{{ Truncate . 5 }}`, "Agenda item", "Agen…"},
+		{"truncate short", language.English, `{{ Truncate . 20 }}`, "Agenda item", "Agenda item"},
+		{"plural one", language.English, `{{ Plural . "vote" "votes" }}`, 1, "vote"},
+		{"plural many", language.English, `{{ Plural . "vote" "votes" }}`, 3, "votes"},
+		{"safe html", language.English, `{{ SafeHTML . }}`, "<b>Yes</b>", "<b>Yes</b>"},
+		{"escaped html", language.English, `{{ . }}`, "<b>Yes</b>", "&lt;b&gt;Yes&lt;/b&gt;"},
+		{"media url", language.English, `{{ MediaURL 5 }}`, nil, "/system/media/get/5"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			funcs := slide.TemplateFuncs(i18n.NewLocale(tt.lang), slide.DefaultMediaURL)
+			tmpl, err := template.New("test").Funcs(funcs).Parse(tt.tmpl)
+			if err != nil {
+				t.Fatalf("parse template: %v", err)
+			}
+
+			var out bytes.Buffer
+			if err := tmpl.Execute(&out, tt.data); err != nil {
+				t.Fatalf("execute template: %v", err)
+			}
+
+			if out.String() != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, out.String())
+			}
+		})
+	}
+}
@@ -3,9 +3,12 @@ package slide
 import (
 	"context"
 	"fmt"
-	"html/template"
 )
 
+func init() {
+	RegisterSlideRenderer("home", HomeSlideHandler)
+}
+
 func HomeSlideHandler(ctx context.Context, req *projectionRequest) (map[string]any, error) {
 	if req.ContentObjectID == nil {
 		return nil, fmt.Errorf("no meeting id provided for slide")
@@ -16,11 +19,11 @@ func HomeSlideHandler(ctx context.Context, req *projectionRequest) (map[string]a
 	req.Fetch.Meeting_WelcomeTitle(*req.ContentObjectID).Lazy(&welcomeTitle)
 	req.Fetch.Meeting_WelcomeText(*req.ContentObjectID).Lazy(&welcomeText)
 	if err := req.Fetch.Execute(ctx); err != nil {
-		return nil, fmt.Errorf("could not fetch wlan data")
+		return nil, fmt.Errorf("could not fetch welcome data: %w", err)
 	}
 
 	return map[string]any{
 		"Title": welcomeTitle,
-		"Text":  template.HTML(welcomeText),
+		"Text":  req.sanitizeHTML(welcomeText),
 	}, nil
 }
@@ -2,17 +2,37 @@ package slide
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"strings"
 
+	"github.com/OpenSlides/openslides-go/perm"
 	"github.com/OpenSlides/openslides-projector-service/pkg/viewmodels"
 )
 
+type listOfSpeakersSlideOptions struct {
+	// ShowFinished shows all finished speakers with their speaking times
+	// instead of the last ones configured in the meeting.
+	ShowFinished bool `json:"show_finished"`
+}
+
+func init() {
+	RegisterSlideRenderer("list_of_speakers", ListOfSpeakersSlideHandler)
+	RegisterSlideRenderer("current_los", ListOfSpeakersSlideHandler)
+}
+
 func ListOfSpeakersSlideHandler(ctx context.Context, req *projectionRequest) (map[string]any, error) {
 	if req.ContentObjectID == nil {
 		return nil, fmt.Errorf("no list of speakers id provided for slide")
 	}
 
+	var options listOfSpeakersSlideOptions
+	if len(req.Projection.Options) > 0 {
+		if err := json.Unmarshal(req.Projection.Options, &options); err != nil {
+			return nil, fmt.Errorf("could not parse list of speakers slide options: %w", err)
+		}
+	}
+
 	losID := *req.ContentObjectID
 	if strings.HasPrefix(req.Projection.ContentObjectID, "meeting") {
 		referenceProjectorId, err := req.Fetch.Meeting_ReferenceProjectorID(*req.ContentObjectID).Value(ctx)
@@ -42,7 +62,12 @@ func ListOfSpeakersSlideHandler(ctx context.Context, req *projectionRequest) (ma
 		return nil, fmt.Errorf("could not load los title info %w", err)
 	}
 
-	speakers, err := viewmodels.ListOfSpeakers_CategorizedLists(ctx, req.Fetch, los.ID)
+	names, err := req.userNames(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	speakers, err := viewmodels.ListOfSpeakers_CategorizedLists(ctx, req.Fetch, req.Locale, los.ID, names.name)
 	if err != nil {
 		return nil, fmt.Errorf("could not categorize speakers %w", err)
 	}
@@ -67,7 +92,15 @@ func ListOfSpeakersSlideHandler(ctx context.Context, req *projectionRequest) (ma
 		return nil, fmt.Errorf("could not fetch los amount of speakers setting %w", err)
 	}
 
-	if maxLastSpeakers >= 0 && len(speakers.FinishedSpeakers) > maxLastSpeakers {
+	// Speaking times are only shown if everyone seeing the projector may see
+	// the list of speakers
+	var showSpeakerTimes bool
+	if options.ShowFinished {
+		showSpeakerTimes, err = viewmodels.Meeting_AudienceHasPermission(ctx, req.Fetch, req.Projection.MeetingID, perm.ListOfSpeakersCanSee, perm.ListOfSpeakersCanManage)
+		if err != nil {
+			return nil, fmt.Errorf("could not check permission to see speaking times %w", err)
+		}
+	} else if maxLastSpeakers >= 0 && len(speakers.FinishedSpeakers) > maxLastSpeakers {
 		speakers.FinishedSpeakers = speakers.FinishedSpeakers[len(speakers.FinishedSpeakers)-maxLastSpeakers:]
 	}
 
@@ -76,12 +109,13 @@ func ListOfSpeakersSlideHandler(ctx context.Context, req *projectionRequest) (ma
 	}
 
 	return map[string]any{
-		"_template":       "list_of_speakers",
-		"LoS":             los,
-		"ShowNumSpeakers": showNumSpeakers,
-		"Speakers":        speakers,
-		"WaitingSpeakers": numWaitingSpeakers,
-		"ContentTitle":    titleInfo,
-		"Overlay":         req.Projection.Stable,
+		"_template":        "list_of_speakers",
+		"LoS":              los,
+		"ShowNumSpeakers":  showNumSpeakers,
+		"ShowSpeakerTimes": showSpeakerTimes,
+		"Speakers":         speakers,
+		"WaitingSpeakers":  numWaitingSpeakers,
+		"ContentTitle":     titleInfo,
+		"Overlay":          req.Projection.Stable,
 	}, nil
 }
@@ -3,8 +3,11 @@ package slide
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
+
+	"github.com/OpenSlides/openslides-go/datastore/dsfetch"
 )
 
 type meetingMediafileSlideOptions struct {
@@ -12,15 +15,31 @@ type meetingMediafileSlideOptions struct {
 	Fullscreen bool `json:"fullscreen"`
 }
 
+func init() {
+	RegisterSlideRenderer("meeting_mediafile", MeetingMediafileSlideHandler)
+}
+
 func MeetingMediafileSlideHandler(ctx context.Context, req *projectionRequest) (map[string]any, error) {
 	if req.ContentObjectID == nil {
 		return nil, fmt.Errorf("no meeting mediafile id provided for slide")
 	}
 
+	// Deleted mediafiles and mediafiles of other meetings are shown as a
+	// placeholder instead of a broken link. The media service only serves
+	// files of the meeting the projector belongs to.
 	mQ := req.Fetch.MeetingMediafile(*req.ContentObjectID)
 	meetingMediafile, err := mQ.Preload(mQ.Mediafile()).First(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("could not load meeting mediafile %w", err)
+		var doesNotExist dsfetch.DoesNotExistError
+		if !errors.As(err, &doesNotExist) {
+			return nil, fmt.Errorf("could not load meeting mediafile %w", err)
+		}
+
+		return map[string]any{"Missing": true}, nil
+	}
+
+	if meetingMediafile.MeetingID != req.Projection.MeetingID || meetingMediafile.Mediafile.IsDirectory {
+		return map[string]any{"Missing": true}, nil
 	}
 
 	options := meetingMediafileSlideOptions{
@@ -0,0 +1,98 @@
+package slide
+
+import (
+	"slices"
+
+	"github.com/OpenSlides/openslides-go/datastore/dsmodels"
+)
+
+// Line numbering modes of motion texts. The numbers are inserted by the same
+// library as in the client, the mode only decides where they are shown.
+const (
+	lineNumberingOutside = "outside"
+	lineNumberingInline  = "inline"
+	lineNumberingNone    = "none"
+)
+
+// motionSettings are the meeting wide settings deciding how motions are
+// shown on the projector.
+type motionSettings struct {
+	LineNumbering      string
+	LineLength         int
+	Preamble           string
+	Recommender        string
+	ShowReason         bool
+	ShowRecommendation bool
+	ShowSidebox        bool
+	ShowText           bool
+	HideMetaBackground bool
+}
+
+// motionSettingsOverride holds the motion settings which can be changed per
+// projection. Unset values fall back to the meeting settings.
+type motionSettingsOverride struct {
+	LineNumbering      *string `json:"line_numbering"`
+	ShowReason         *bool   `json:"show_reason"`
+	ShowRecommendation *bool   `json:"show_recommendation"`
+	ShowSidebox        *bool   `json:"show_sidebox"`
+	ShowText           *bool   `json:"show_text"`
+}
+
+// itemNumberOverride holds the projection option showing or hiding the
+// leading number in the title of a slide, e.g. the agenda item number of a
+// topic. Unset falls back to the default of the slide.
+type itemNumberOverride struct {
+	ShowNumber *bool `json:"show_number"`
+}
+
+// apply replaces show with the projection option if it is set.
+func (o itemNumberOverride) apply(show *bool) {
+	if o.ShowNumber != nil {
+		*show = *o.ShowNumber
+	}
+}
+
+// lazyShowItemNumber registers the numbering setting of the agenda, which is
+// the default for showing agenda item numbers, on the fetcher.
+func lazyShowItemNumber(fetch *dsmodels.Fetch, meetingID int, show *bool) {
+	fetch.Meeting_AgendaEnableNumbering(meetingID).Lazy(show)
+}
+
+// lazyMotionSettings registers the motion settings of the meeting on the
+// fetcher. The values are set after the fetcher was executed.
+func lazyMotionSettings(fetch *dsmodels.Fetch, meetingID int, settings *motionSettings) {
+	fetch.Meeting_MotionsDefaultLineNumbering(meetingID).Lazy(&settings.LineNumbering)
+	fetch.Meeting_MotionsEnableReasonOnProjector(meetingID).Lazy(&settings.ShowReason)
+	fetch.Meeting_MotionsEnableRecommendationOnProjector(meetingID).Lazy(&settings.ShowRecommendation)
+	fetch.Meeting_MotionsRecommendationsBy(meetingID).Lazy(&settings.Recommender)
+	fetch.Meeting_MotionsEnableSideboxOnProjector(meetingID).Lazy(&settings.ShowSidebox)
+	fetch.Meeting_MotionsEnableTextOnProjector(meetingID).Lazy(&settings.ShowText)
+	fetch.Meeting_MotionsLineLength(meetingID).Lazy(&settings.LineLength)
+	fetch.Meeting_MotionsPreamble(meetingID).Lazy(&settings.Preamble)
+	fetch.Meeting_MotionsHideMetadataBackground(meetingID).Lazy(&settings.HideMetaBackground)
+}
+
+func (s *motionSettings) apply(override motionSettingsOverride) {
+	if override.LineNumbering != nil {
+		s.LineNumbering = *override.LineNumbering
+	}
+
+	// Unknown modes are shown like the default of the meeting settings
+	if !slices.Contains([]string{lineNumberingOutside, lineNumberingInline, lineNumberingNone}, s.LineNumbering) {
+		s.LineNumbering = lineNumberingOutside
+	}
+
+	for _, setting := range []struct {
+		value    *bool
+		override *bool
+	}{
+		{&s.ShowReason, override.ShowReason},
+		{&s.ShowRecommendation, override.ShowRecommendation},
+		{&s.ShowSidebox, override.ShowSidebox},
+		{&s.ShowText, override.ShowText},
+	} {
+		if setting.override != nil {
+			*setting.value = *setting.override
+		}
+	}
+}
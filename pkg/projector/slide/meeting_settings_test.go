@@ -0,0 +1,63 @@
+package slide
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestMotionSettingsOverride(t *testing.T) {
+	settings := motionSettings{
+		LineNumbering: "outside",
+		ShowReason:    true,
+		ShowText:      true,
+		ShowSidebox:   true,
+	}
+
+	var options motionSlideOptions
+	if err := json.Unmarshal([]byte(`{"mode":"diff","show_reason":false,"line_numbering":"none"}`), &options); err != nil {
+		t.Fatalf("parse options: %v", err)
+	}
+
+	if options.Mode != motionTextDiff {
+		t.Errorf("expected mode diff, got %s", options.Mode)
+	}
+
+	settings.apply(options.motionSettingsOverride)
+
+	if settings.ShowReason {
+		t.Errorf("expected reason to be hidden by projection option")
+	}
+
+	if settings.LineNumbering != "none" {
+		t.Errorf("expected line numbering none, got %s", settings.LineNumbering)
+	}
+
+	if !settings.ShowText || !settings.ShowSidebox {
+		t.Errorf("expected meeting settings to be kept when not overridden")
+	}
+}
+
+func TestMotionSettingsLineNumbering(t *testing.T) {
+	inline := "inline"
+	unknown := "unknown"
+
+	for _, tt := range []struct {
+		meeting  string
+		override *string
+		expected string
+	}{
+		{"outside", nil, "outside"},
+		{"inline", nil, "inline"},
+		{"none", nil, "none"},
+		{"", nil, "outside"},
+		{"inside", nil, "outside"},
+		{"outside", &inline, "inline"},
+		{"none", &unknown, "outside"},
+	} {
+		settings := motionSettings{LineNumbering: tt.meeting}
+		settings.apply(motionSettingsOverride{LineNumbering: tt.override})
+		if settings.LineNumbering != tt.expected {
+			t.Errorf("meeting %q with override %v: expected %s, got %s", tt.meeting, tt.override, tt.expected, settings.LineNumbering)
+		}
+	}
+}
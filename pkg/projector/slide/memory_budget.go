@@ -0,0 +1,145 @@
+package slide
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// BudgetConsumer is a cache sharing a memory budget with other caches.
+//
+// Consumers must not hold their own lock while calling the budget, since the
+// budget calls back into its consumers to evict entries.
+type BudgetConsumer interface {
+	// OldestUse returns the time the least recently used entry was used.
+	// Returns false if the consumer has no entries.
+	OldestUse() (time.Time, bool)
+
+	// EvictOldest removes the least recently used entry and returns its
+	// size in bytes. It must not call the budget.
+	EvictOldest() int64
+}
+
+// MemoryBudget bounds the combined size of caches, e.g. rendered slides and
+// buffered events. If the budget is exceeded, the least recently used entry
+// of all consumers is evicted until it fits again.
+//
+// A nil budget does not limit anything.
+type MemoryBudget struct {
+	mu        sync.Mutex
+	max       int64
+	used      int64
+	evicted   int
+	consumers []BudgetConsumer
+}
+
+// MemoryBudgetStats are the counters of a memory budget.
+type MemoryBudgetStats struct {
+	MaxBytes  int64
+	UsedBytes int64
+	Evicted   int
+}
+
+// NewMemoryBudget returns a budget of max bytes. Returns nil if max is not
+// positive, which disables the limit.
+func NewMemoryBudget(max int64) *MemoryBudget {
+	if max <= 0 {
+		return nil
+	}
+
+	return &MemoryBudget{max: max}
+}
+
+// Register adds a consumer whose entries may be evicted to stay within the
+// budget.
+func (b *MemoryBudget) Register(consumer BudgetConsumer) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.consumers = append(b.consumers, consumer)
+}
+
+// Unregister removes a consumer, e.g. a buffer which is not used anymore.
+// The consumer has to release its memory itself.
+func (b *MemoryBudget) Unregister(consumer BudgetConsumer) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.consumers = slices.DeleteFunc(b.consumers, func(c BudgetConsumer) bool { return c == consumer })
+}
+
+// Grow adds size bytes to the used memory and evicts entries while the budget
+// is exceeded.
+func (b *MemoryBudget) Grow(size int64) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.used += size
+	for b.used > b.max {
+		consumer := b.oldestConsumer()
+		if consumer == nil {
+			return
+		}
+
+		b.used -= consumer.EvictOldest()
+		b.evicted++
+	}
+}
+
+// Release removes size bytes from the used memory, e.g. after a consumer
+// evicted an entry on its own.
+func (b *MemoryBudget) Release(size int64) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.used -= size
+}
+
+// Stats returns the limit, the memory in use and the number of entries
+// evicted because of the budget.
+func (b *MemoryBudget) Stats() MemoryBudgetStats {
+	if b == nil {
+		return MemoryBudgetStats{}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return MemoryBudgetStats{MaxBytes: b.max, UsedBytes: b.used, Evicted: b.evicted}
+}
+
+// oldestConsumer returns the consumer holding the least recently used entry
+// or nil if all consumers are empty.
+func (b *MemoryBudget) oldestConsumer() BudgetConsumer {
+	var oldest BudgetConsumer
+	var oldestUse time.Time
+	for _, consumer := range b.consumers {
+		used, ok := consumer.OldestUse()
+		if !ok {
+			continue
+		}
+
+		if oldest == nil || used.Before(oldestUse) {
+			oldest = consumer
+			oldestUse = used
+		}
+	}
+
+	return oldest
+}
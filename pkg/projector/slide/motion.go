@@ -1,6 +1,7 @@
 package slide
 
 import (
+	"cmp"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -23,10 +24,13 @@ const (
 	motionTextDiff          motionSlideMode = "diff"
 	motionTextFinal         motionSlideMode = "agreed"
 	motionTextModifiedFinal motionSlideMode = "modified_final_version"
+	motionTextAmendmentDiff motionSlideMode = "amendment_diff"
 )
 
 type motionSlideOptions struct {
 	Mode motionSlideMode `json:"mode"`
+	motionSettingsOverride
+	itemNumberOverride
 }
 
 type motionSlideCommonData struct {
@@ -35,38 +39,34 @@ type motionSlideCommonData struct {
 	Mode                  string
 	Motion                *dsmodels.Motion
 	AmendmentParagraphs   map[string]template.HTML
-	ShowSidebox           bool
-	ShowReason            bool
-	ShowRecommendation    bool
-	ShowText              bool
-	HideMetaBackground    bool
-	Recommender           string
 	Recommendation        string
 	ReferencedRecoMotions string
 	Submitters            string
-	LineNumbering         string
-	LineLength            int
-	Preamble              string
+	ShowNumber            bool
+	motionSettings
 }
 
 func (m *motionSlideCommonData) templateData(additional map[string]any) map[string]any {
 	motionTextI18n, err := json.Marshal(map[string]string{
-		"line": m.Locale.Get("Line"),
+		"line":             m.Locale.Get("Line"),
+		"missingParagraph": m.Locale.Get("This paragraph does not exist in the motion."),
 	})
 	if err != nil {
 		log.Err(err).Msg("could not marshal motion slide i18n")
 	}
 
 	data := map[string]any{
+		"FirstLine":                 firstLineNumber(m.Motion),
 		"IsParagraphBasedAmendment": !m.Motion.LeadMotionID.Null(),
 		"LineLength":                m.LineLength,
 		"LineNumbering":             m.LineNumbering,
 		"Mode":                      m.Mode,
 		"Motion":                    m.Motion,
-		"MotionText":                template.HTML(m.Motion.Text),
+		"MotionText":                m.ProjectionReq.sanitizeHTML(m.Motion.Text),
 		"MotionTextI18n":            string(motionTextI18n),
 		"Preamble":                  m.Preamble,
 		"ReferencedRecoMotions":     m.ReferencedRecoMotions,
+		"ShowNumber":                m.ShowNumber,
 		"ShowSidebox":               m.ShowSidebox,
 		"ShowText":                  m.ShowText,
 		"Submitters":                m.Submitters,
@@ -74,7 +74,7 @@ func (m *motionSlideCommonData) templateData(additional map[string]any) map[stri
 	}
 
 	if m.ShowReason {
-		data["Reason"] = template.HTML(m.Motion.Reason)
+		data["Reason"] = m.ProjectionReq.sanitizeHTML(m.Motion.Reason)
 	}
 
 	if m.ShowRecommendation && m.Recommendation != "" && m.Recommender != "" {
@@ -85,7 +85,8 @@ func (m *motionSlideCommonData) templateData(additional map[string]any) map[stri
 	if m.AmendmentParagraphs != nil {
 		data["AmendmentParagraphs"] = m.AmendmentParagraphs
 		if lMotion, ok := m.Motion.LeadMotion.Value(); ok {
-			data["LeadMotionText"] = template.HTML(lMotion.Text)
+			data["LeadMotionText"] = m.ProjectionReq.sanitizeHTML(lMotion.Text)
+			data["LeadMotionFirstLine"] = firstLineNumber(&lMotion)
 		}
 	}
 
@@ -93,6 +94,10 @@ func (m *motionSlideCommonData) templateData(additional map[string]any) map[stri
 	return data
 }
 
+func init() {
+	RegisterSlideRenderer("motion", MotionSlideHandler)
+}
+
 func MotionSlideHandler(ctx context.Context, req *projectionRequest) (map[string]any, error) {
 	if req.ContentObjectID == nil {
 		return nil, fmt.Errorf("no motion id provided for slide")
@@ -125,26 +130,21 @@ func MotionSlideHandler(ctx context.Context, req *projectionRequest) (map[string
 		Mode:          string(options.Mode),
 		Motion:        &motion,
 		Submitters:    strings.Join(motionSubmitterList(&motion), ", "),
+		ShowNumber:    true,
 	}
 
-	req.Fetch.Meeting_MotionsDefaultLineNumbering(motion.MeetingID).Lazy(&data.LineNumbering)
-	req.Fetch.Meeting_MotionsEnableReasonOnProjector(motion.MeetingID).Lazy(&data.ShowReason)
-	req.Fetch.Meeting_MotionsEnableRecommendationOnProjector(motion.MeetingID).Lazy(&data.ShowRecommendation)
-	req.Fetch.Meeting_MotionsRecommendationsBy(motion.MeetingID).Lazy(&data.Recommender)
-	req.Fetch.Meeting_MotionsEnableSideboxOnProjector(motion.MeetingID).Lazy(&data.ShowSidebox)
-	req.Fetch.Meeting_MotionsEnableTextOnProjector(motion.MeetingID).Lazy(&data.ShowText)
-	req.Fetch.Meeting_MotionsLineLength(motion.MeetingID).Lazy(&data.LineLength)
-	req.Fetch.Meeting_MotionsPreamble(motion.MeetingID).Lazy(&data.Preamble)
-	req.Fetch.Meeting_MotionsHideMetadataBackground(motion.MeetingID).Lazy(&data.HideMetaBackground)
+	lazyMotionSettings(req.Fetch, motion.MeetingID, &data.motionSettings)
 	if err := req.Fetch.Execute(ctx); err != nil {
 		return nil, fmt.Errorf("could not fetch motion slide data: %w", err)
 	}
+	data.apply(options.motionSettingsOverride)
+	options.itemNumberOverride.apply(&data.ShowNumber)
 
 	if data.ShowRecommendation {
 		if val, ok := motion.Recommendation.Value(); ok {
 			data.Recommendation = val.RecommendationLabel
 			if motion.RecommendationExtension != "" && val.ShowRecommendationExtensionField {
-				ext, err := viewmodels.Motion_RecommendationParsed(ctx, req.Fetch, &motion)
+				ext, err := viewmodels.Motion_RecommendationParsed(ctx, req.Fetch, req.Locale, &motion)
 				if err != nil {
 					return nil, fmt.Errorf("error parsing motion recommendation: %w", err)
 				}
@@ -154,17 +154,20 @@ func MotionSlideHandler(ctx context.Context, req *projectionRequest) (map[string
 	}
 
 	if !motion.LeadMotionID.Null() && len(motion.AmendmentParagraphs) > 0 {
-		amendmentParagrapphs := map[string]template.HTML{}
-		if err := json.Unmarshal(motion.AmendmentParagraphs, &amendmentParagrapphs); err != nil {
+		amendmentParagrapphs, err := sanitizedParagraphs(req, motion.AmendmentParagraphs)
+		if err != nil {
 			return nil, fmt.Errorf("error parsing amendment paragraphs: %w", err)
-		} else {
-			data.AmendmentParagraphs = amendmentParagrapphs
 		}
+		data.AmendmentParagraphs = amendmentParagrapphs
 	}
 
 	if len(data.Motion.ReferencedInMotionRecommendationExtensionList) > 0 {
+		refMotions := slices.SortedFunc(slices.Values(data.Motion.ReferencedInMotionRecommendationExtensionList), func(a, b dsmodels.Motion) int {
+			return cmp.Compare(a.ID, b.ID)
+		})
+
 		refMotionNames := []string{}
-		for _, refMotion := range data.Motion.ReferencedInMotionRecommendationExtensionList {
+		for _, refMotion := range refMotions {
 			title := refMotion.Number
 			if title == "" {
 				title = refMotion.Title
@@ -184,6 +187,10 @@ func MotionSlideHandler(ctx context.Context, req *projectionRequest) (map[string
 		return data.motionTextDiffSlide(ctx)
 	case motionTextModifiedFinal:
 		return data.motionTextModifiedFinalSlide(ctx)
+	case motionTextAmendmentDiff:
+		if data.AmendmentParagraphs != nil {
+			return data.amendmentDiffSlide(ctx)
+		}
 	}
 
 	return data.templateData(map[string]any{}), nil
@@ -212,6 +219,22 @@ func (m *motionSlideCommonData) motionTextDiffSlide(ctx context.Context) (map[st
 	return m.templateData(data), nil
 }
 
+// amendmentDiffSlide shows the paragraphs of an amendment diffed against its
+// lead motion in the browser with the motion-diff library used by the client.
+// The change recommendations of the amendment are applied like in the diff
+// mode. Paragraphs which do not exist in the lead motion are shown with a
+// notice instead of being left out.
+func (m *motionSlideCommonData) amendmentDiffSlide(ctx context.Context) (map[string]any, error) {
+	data, err := m.motionChangeRecos(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch motion change recos: %w", err)
+	}
+
+	data["Mode"] = string(motionTextDiff)
+	data["ShowMissingParagraphs"] = true
+	return m.templateData(data), nil
+}
+
 func (m *motionSlideCommonData) motionTextModifiedFinalSlide(ctx context.Context) (map[string]any, error) {
 	fetch := m.ProjectionReq.Fetch
 	crIDs := m.Motion.ChangeRecommendationIDs
@@ -226,7 +249,7 @@ func (m *motionSlideCommonData) motionTextModifiedFinalSlide(ctx context.Context
 			newCr := motionChangeReco{
 				ID:   cr.ID,
 				Type: cr.Type,
-				Text: template.HTML(cr.Text),
+				Text: m.ProjectionReq.sanitizeHTML(cr.Text),
 			}
 
 			if cr.LineFrom == 0 {
@@ -235,16 +258,40 @@ func (m *motionSlideCommonData) motionTextModifiedFinalSlide(ctx context.Context
 		}
 	}
 
+	sortChangeRecos(titleChanges)
+
 	return m.templateData(map[string]any{
 		"TitleChangeRecos": titleChanges,
-		"MotionText":       template.HTML(m.Motion.ModifiedFinalVersion),
+		"MotionText":       m.ProjectionReq.sanitizeHTML(m.Motion.ModifiedFinalVersion),
 	}), nil
 }
 
+// sanitizedParagraphs decodes the paragraphs of an amendment with only the
+// elements allowed in the meeting.
+func sanitizedParagraphs(req *projectionRequest, encoded []byte) (map[string]template.HTML, error) {
+	var paragraphs map[string]string
+	if err := json.Unmarshal(encoded, &paragraphs); err != nil {
+		return nil, err
+	}
+
+	sanitized := make(map[string]template.HTML, len(paragraphs))
+	for key, paragraph := range paragraphs {
+		sanitized[key] = req.sanitizeHTML(paragraph)
+	}
+
+	return sanitized, nil
+}
+
+// firstLineNumber returns the number of the first line of the motion text.
+// Amendments are numbered by the lines of their lead motion.
+func firstLineNumber(motion *dsmodels.Motion) int {
+	return max(motion.StartLineNumber, 1)
+}
+
 func motionSubmitterList(motion *dsmodels.Motion) []string {
 	submitters := []string{}
 	slices.SortFunc(motion.SubmitterList, func(a dsmodels.MotionSubmitter, b dsmodels.MotionSubmitter) int {
-		return a.Weight - b.Weight
+		return viewmodels.CompareWeight(a.Weight, a.ID, b.Weight, b.ID)
 	})
 	for _, submitter := range motion.SubmitterList {
 		if meetingUser, ok := submitter.MeetingUser.Value(); ok {
@@ -267,6 +314,14 @@ type motionChangeReco struct {
 	Text     template.HTML
 }
 
+// sortChangeRecos orders change recommendations by their first line and then
+// by id.
+func sortChangeRecos(crs []motionChangeReco) {
+	slices.SortFunc(crs, func(a, b motionChangeReco) int {
+		return cmp.Or(cmp.Compare(a.LineFrom, b.LineFrom), cmp.Compare(a.ID, b.ID))
+	})
+}
+
 func (m *motionSlideCommonData) motionChangeRecos(ctx context.Context) (map[string]any, error) {
 	fetch := m.ProjectionReq.Fetch
 	crIDs := m.Motion.ChangeRecommendationIDs
@@ -285,7 +340,7 @@ func (m *motionSlideCommonData) motionChangeRecos(ctx context.Context) (map[stri
 				LineFrom: cr.LineFrom,
 				LineTo:   cr.LineTo,
 				Rejected: cr.Rejected,
-				Text:     template.HTML(cr.Text),
+				Text:     m.ProjectionReq.sanitizeHTML(cr.Text),
 			}
 
 			if cr.LineFrom > 0 {
@@ -296,6 +351,9 @@ func (m *motionSlideCommonData) motionChangeRecos(ctx context.Context) (map[stri
 		}
 	}
 
+	sortChangeRecos(changeRecos)
+	sortChangeRecos(titleChanges)
+
 	return m.templateData(map[string]any{
 		"HasTitleChanges":   len(titleChanges) > 0,
 		"TitleChangeRecos":  titleChanges,
@@ -321,6 +379,10 @@ func (m *motionSlideCommonData) motionAmendments(ctx context.Context) (map[strin
 		return nil, fmt.Errorf("could not fetch change recommendations slide data: %w", err)
 	}
 
+	slices.SortFunc(amendments, func(a, b dsmodels.Motion) int {
+		return viewmodels.CompareWeight(a.SortWeight, a.ID, b.SortWeight, b.ID)
+	})
+
 	tmplAmendments := []motionAmendment{}
 	for _, amendment := range amendments {
 		if amendment.State.MergeAmendmentIntoFinal != "do_merge" {
@@ -335,7 +397,7 @@ func (m *motionSlideCommonData) motionAmendments(ctx context.Context) (map[strin
 					Type:     cr.Type,
 					LineFrom: cr.LineFrom,
 					LineTo:   cr.LineTo,
-					Text:     template.HTML(cr.Text),
+					Text:     m.ProjectionReq.sanitizeHTML(cr.Text),
 				}
 
 				if cr.LineFrom > 0 {
@@ -344,6 +406,8 @@ func (m *motionSlideCommonData) motionAmendments(ctx context.Context) (map[strin
 			}
 		}
 
+		sortChangeRecos(changeRecos)
+
 		changeTitle := m.ProjectionReq.Locale.Get("Amendment")
 		if amendment.Number != "" {
 			changeTitle = amendment.Number
@@ -357,7 +421,7 @@ func (m *motionSlideCommonData) motionAmendments(ctx context.Context) (map[strin
 			ChangeRecos: changeRecos,
 		}
 
-		if err := json.Unmarshal(amendment.AmendmentParagraphs, &data.Paragraphs); err != nil {
+		if data.Paragraphs, err = sanitizedParagraphs(m.ProjectionReq, amendment.AmendmentParagraphs); err != nil {
 			return nil, fmt.Errorf("could not parse amendment paragraphs: %w", err)
 		}
 
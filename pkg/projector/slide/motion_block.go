@@ -1,6 +1,7 @@
 package slide
 
 import (
+	"cmp"
 	"context"
 	"fmt"
 	"slices"
@@ -9,6 +10,10 @@ import (
 	"github.com/OpenSlides/openslides-projector-service/pkg/viewmodels"
 )
 
+func init() {
+	RegisterSlideRenderer("motion_block", MotionBlockSlideHandler)
+}
+
 func MotionBlockSlideHandler(ctx context.Context, req *projectionRequest) (map[string]any, error) {
 	if req.ContentObjectID == nil {
 		return nil, fmt.Errorf("no motion block id provided for slide")
@@ -29,6 +34,7 @@ func MotionBlockSlideHandler(ctx context.Context, req *projectionRequest) (map[s
 	numMotions := len(block.MotionIDs)
 
 	type motionListEntry struct {
+		ID                      int
 		Number                  string
 		Title                   string
 		Recommendation          string
@@ -44,11 +50,12 @@ func MotionBlockSlideHandler(ctx context.Context, req *projectionRequest) (map[s
 			recoColor = reco.CssClass
 		}
 
-		ext, err := viewmodels.Motion_RecommendationParsed(ctx, req.Fetch, &motion)
+		ext, err := viewmodels.Motion_RecommendationParsed(ctx, req.Fetch, req.Locale, &motion)
 		if err != nil {
 			return nil, fmt.Errorf("error reading motion extension for %d: %w", motion.ID, err)
 		}
 		motionList = append(motionList, motionListEntry{
+			ID:                      motion.ID,
 			Number:                  motion.Number,
 			Title:                   motion.Title,
 			Recommendation:          recoName,
@@ -57,12 +64,13 @@ func MotionBlockSlideHandler(ctx context.Context, req *projectionRequest) (map[s
 		})
 	}
 
+	// Motions with the same number or title keep the order of their ids
 	slices.SortFunc(motionList, func(a, b motionListEntry) int {
 		if a.Number != "" || b.Number != "" {
-			return strings.Compare(a.Number, b.Number)
+			return cmp.Or(strings.Compare(a.Number, b.Number), cmp.Compare(a.ID, b.ID))
 		}
 
-		return strings.Compare(a.Title, b.Title)
+		return cmp.Or(strings.Compare(a.Title, b.Title), cmp.Compare(a.ID, b.ID))
 	})
 
 	return map[string]any{
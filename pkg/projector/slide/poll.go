@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/OpenSlides/openslides-projector-service/pkg/viewmodels"
@@ -13,6 +14,10 @@ import (
 
 type pollSlideOptions struct {
 	SingleVotes bool `json:"single_votes"`
+
+	// Ranked shows the results of an election ranked by votes with the
+	// elected candidates marked.
+	Ranked bool `json:"ranked"`
 }
 
 type pollSlideTableOption struct {
@@ -23,6 +28,11 @@ type pollSlideTableOption struct {
 	PercYes      decimal.Decimal
 	PercNo       decimal.Decimal
 	PercAbstain  decimal.Decimal
+
+	// Rank, Elected and Tied are only set for ranked election results.
+	Rank    int
+	Elected bool
+	Tied    bool
 }
 
 type pollSlideTableSum struct {
@@ -37,6 +47,10 @@ type pollSlideTable struct {
 	Sums               []pollSlideTableSum
 }
 
+func init() {
+	RegisterSlideRenderer("poll", PollSlideHandler)
+}
+
 func PollSlideHandler(ctx context.Context, req *projectionRequest) (map[string]any, error) {
 	pollID := *req.ContentObjectID
 
@@ -76,9 +90,20 @@ func PollSlideHandler(ctx context.Context, req *projectionRequest) (map[string]a
 			}, nil
 		}
 
+		poll, err := req.Fetch.Poll(pollID).First(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("could not load poll %w", err)
+		}
+
+		turnout, err := pollTurnout(ctx, req, poll)
+		if err != nil {
+			return nil, err
+		}
+
 		return map[string]any{
-			"Title": pollTitle,
-			"State": state,
+			"Title":   pollTitle,
+			"State":   state,
+			"Turnout": turnout,
 		}, nil
 	}
 
@@ -109,10 +134,17 @@ func PollSlideHandler(ctx context.Context, req *projectionRequest) (map[string]a
 		return nil, fmt.Errorf("could not load poll %w", err)
 	}
 
+	viewmodels.Option_SortByWeight(poll.OptionList)
+
 	userMap, err := viewmodels.User_MeetingUserMap(ctx, req.Fetch, poll.MeetingID)
 	if err != nil {
 		return nil, fmt.Errorf("could not load user map %w", err)
 	}
+	names, err := req.userNames(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	data := pollSlideTable{
 		Options: []pollSlideTableOption{},
 		Sums:    []pollSlideTableSum{},
@@ -120,7 +152,7 @@ func PollSlideHandler(ctx context.Context, req *projectionRequest) (map[string]a
 
 	for _, option := range poll.OptionList {
 		onehundredPercentBase := viewmodels.Poll_OneHundredPercentBase(poll, &option)
-		name, err := viewmodels.Option_OptionLabel(ctx, req.Fetch, req.Locale, &option, userMap)
+		name, err := viewmodels.Option_OptionLabel(ctx, req.Fetch, req.Locale, &option, userMap, names.name)
 		if err != nil {
 			return nil, err
 		}
@@ -233,18 +265,68 @@ func PollSlideHandler(ctx context.Context, req *projectionRequest) (map[string]a
 		return nil, fmt.Errorf("could not fetch meeting poll sort option: %w", err)
 	}
 
-	if sortResult {
-		slices.SortFunc(data.Options, func(a, b pollSlideTableOption) int {
+	// Live voting shows results before the poll is published, nobody is
+	// elected until then.
+	ranked := options.Ranked && poll.State == "published" && strings.HasPrefix(poll.ContentObjectID, "assignment/")
+	if sortResult || ranked {
+		// Options with the same result keep their weighted order
+		slices.SortStableFunc(data.Options, func(a, b pollSlideTableOption) int {
 			return b.TotalYes.Cmp(a.TotalYes)
 		})
 	}
 
+	var openPosts int
+	if ranked {
+		assignmentID, err := strconv.Atoi(strings.TrimPrefix(poll.ContentObjectID, "assignment/"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid assignment of poll %d: %w", pollID, err)
+		}
+
+		openPosts, err = req.Fetch.Assignment_OpenPosts(assignmentID).Value(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("could not fetch open posts of assignment: %w", err)
+		}
+
+		rankElectionResult(data.Options, openPosts)
+	}
+
+	turnout, err := pollTurnout(ctx, req, poll)
+	if err != nil {
+		return nil, err
+	}
+
 	return map[string]any{
-		"_fullHeight": true,
-		"Title":       pollTitle,
-		"Data":        data,
-		"Base":        poll.OnehundredPercentBase,
-		"Method":      poll.Pollmethod,
-		"Methods":     pollMethod,
+		"_fullHeight":         true,
+		"Title":               pollTitle,
+		"Data":                data,
+		"Base":                poll.OnehundredPercentBase,
+		"Method":              poll.Pollmethod,
+		"Methods":             pollMethod,
+		"Turnout":             turnout,
+		"Ranked":              ranked,
+		"OpenPosts":           openPosts,
+		"NotEnoughCandidates": ranked && len(data.Options) < openPosts,
 	}, nil
 }
+
+// rankElectionResult ranks options sorted by their votes. Options with the
+// same votes share a rank. Options with votes ranked within the open posts are
+// elected, unless they tie with an option beyond them, then all options of
+// the tie are marked as tied instead. Without open posts nobody is elected.
+func rankElectionResult(options []pollSlideTableOption, openPosts int) {
+	for start := 0; start < len(options); {
+		end := start + 1
+		for end < len(options) && options[end].TotalYes.Equal(options[start].TotalYes) {
+			end++
+		}
+
+		hasVotes := options[start].TotalYes.IsPositive()
+		for i := start; i < end; i++ {
+			options[i].Rank = start + 1
+			options[i].Elected = hasVotes && end <= openPosts
+			options[i].Tied = hasVotes && start < openPosts && end > openPosts
+		}
+
+		start = end
+	}
+}
@@ -39,6 +39,8 @@ func pollChartSlideHandler(ctx context.Context, req *projectionRequest) (map[str
 		return nil, fmt.Errorf("could not load poll %w", err)
 	}
 
+	viewmodels.Option_SortByWeight(poll.OptionList)
+
 	data := pollSlideChartProjectionData{
 		Options: []pollSlideProjectionOptionData{},
 	}
@@ -46,7 +48,12 @@ func pollChartSlideHandler(ctx context.Context, req *projectionRequest) (map[str
 	if len(poll.OptionList) == 1 {
 		opt := poll.OptionList[0]
 
-		optTitle, err := viewmodels.Option_OptionLabel(ctx, req.Fetch, req.Locale, &opt, nil)
+		names, err := req.userNames(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		optTitle, err := viewmodels.Option_OptionLabel(ctx, req.Fetch, req.Locale, &opt, nil, names.name)
 		if err != nil {
 			return nil, fmt.Errorf("could not load poll option name: %w", err)
 		}
@@ -138,10 +145,16 @@ func pollChartSlideHandler(ctx context.Context, req *projectionRequest) (map[str
 		data.PercValidvotes = poll.Votesvalid.Div(onehundredPercentBase).Mul(decimal.NewFromInt(100)).Round(3).String()
 	}
 
+	turnout, err := pollTurnout(ctx, req, poll)
+	if err != nil {
+		return nil, err
+	}
+
 	return map[string]any{
 		"_template":   "poll_chart",
 		"_fullHeight": true,
 		"Poll":        poll,
 		"Data":        data,
+		"Turnout":     turnout,
 	}, nil
 }
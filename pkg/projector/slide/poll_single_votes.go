@@ -121,9 +121,7 @@ func pollSingleVotesSlideHandler(ctx context.Context, req *projectionRequest) (m
 		}
 	}
 
-	slices.SortFunc(poll.OptionList, func(a dsmodels.Option, b dsmodels.Option) int {
-		return a.Weight - b.Weight
-	})
+	viewmodels.Option_SortByWeight(poll.OptionList)
 
 	optionIndexMap := map[string]int{}
 	for idx, option := range poll.OptionList {
@@ -178,7 +176,7 @@ func pollSingleVotesSlideHandler(ctx context.Context, req *projectionRequest) (m
 					} else if b.Majority && !a.Majority {
 						return 1
 					}
-					return a.Weight - b.Weight
+					return viewmodels.CompareWeight(a.Weight, a.ID, b.Weight, b.ID)
 				})
 			}
 		}
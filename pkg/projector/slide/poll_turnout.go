@@ -0,0 +1,97 @@
+package slide
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/OpenSlides/openslides-go/datastore/dsmodels"
+	"github.com/OpenSlides/openslides-projector-service/pkg/viewmodels"
+	"github.com/shopspring/decimal"
+)
+
+// pollSlideTurnout is the participation in an electronic poll.
+type pollSlideTurnout struct {
+	Votescast     decimal.Decimal
+	Votesvalid    decimal.Decimal
+	Votesinvalid  decimal.Decimal
+	EntitledUsers int
+
+	// Turnout is the share of entitled users who voted, e.g. 0.75
+	Turnout float64
+
+	// Running is set while votes are cast. Only the number of users who
+	// voted so far is known then.
+	Running bool
+}
+
+// pollTurnout returns the turnout of the poll or nil if none is shown.
+// Analog polls have no entitled users and polls which were not started yet
+// no votes. While a poll is running, only named polls show how many users
+// voted so far. The votes cast and the valid and invalid votes are only
+// shown once the poll is published, not while the votes are counted.
+func pollTurnout(ctx context.Context, req *projectionRequest, poll dsmodels.Poll) (*pollSlideTurnout, error) {
+	if poll.Type == "analog" {
+		return nil, nil
+	}
+
+	turnout := pollSlideTurnout{
+		Votescast:    poll.Votescast,
+		Votesvalid:   poll.Votesvalid,
+		Votesinvalid: poll.Votesinvalid,
+	}
+
+	switch poll.State {
+	case "published":
+		if poll.EntitledUsersAtStop == nil {
+			return nil, nil
+		}
+
+		entitled, err := viewmodels.Poll_EntitledUsers(poll)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse entitled users of poll %d: %w", poll.ID, err)
+		}
+		turnout.EntitledUsers = len(entitled)
+
+	case "started":
+		if poll.Type != "named" || poll.IsPseudoanonymized {
+			return nil, nil
+		}
+
+		entitled, err := pollEntitledMeetingUsers(ctx, req, poll)
+		if err != nil {
+			return nil, err
+		}
+
+		turnout.EntitledUsers = entitled
+		turnout.Votescast = decimal.NewFromInt(int64(len(poll.VotedIDs)))
+		turnout.Running = true
+
+	default:
+		return nil, nil
+	}
+
+	if turnout.EntitledUsers == 0 {
+		return nil, nil
+	}
+
+	turnout.Turnout = turnout.Votescast.InexactFloat64() / float64(turnout.EntitledUsers)
+	return &turnout, nil
+}
+
+// pollEntitledMeetingUsers counts the members of the entitled groups of a
+// running poll. Members of multiple groups are counted once.
+func pollEntitledMeetingUsers(ctx context.Context, req *projectionRequest, poll dsmodels.Poll) (int, error) {
+	groups, err := req.Fetch.Group(poll.EntitledGroupIDs...).Get(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("could not load entitled groups of poll %d: %w", poll.ID, err)
+	}
+
+	meetingUsers := map[int]struct{}{}
+	for _, group := range groups {
+		for _, id := range group.MeetingUserIDs {
+			meetingUsers[id] = struct{}{}
+		}
+	}
+
+	return len(meetingUsers), nil
+}
@@ -11,6 +11,10 @@ type projectorCountdownOptions struct {
 	DisplayType string `json:"displayType"`
 }
 
+func init() {
+	RegisterSlideRenderer("projector_countdown", ProjectorCountdownSlideHandler)
+}
+
 func ProjectorCountdownSlideHandler(ctx context.Context, req *projectionRequest) (map[string]any, error) {
 	if req.ContentObjectID == nil {
 		return nil, fmt.Errorf("no topic id provided for slide")
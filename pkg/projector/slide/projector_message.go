@@ -3,9 +3,12 @@ package slide
 import (
 	"context"
 	"fmt"
-	"html/template"
 )
 
+func init() {
+	RegisterSlideRenderer("projector_message", ProjectorMessageSlideHandler)
+}
+
 func ProjectorMessageSlideHandler(ctx context.Context, req *projectionRequest) (map[string]any, error) {
 	if req.ContentObjectID == nil {
 		return nil, fmt.Errorf("no topic id provided for slide")
@@ -17,6 +20,6 @@ func ProjectorMessageSlideHandler(ctx context.Context, req *projectionRequest) (
 	}
 
 	return map[string]any{
-		"Message": template.HTML(message.Message),
+		"Message": req.sanitizeHTML(message.Message),
 	}, nil
 }
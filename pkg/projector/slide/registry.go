@@ -0,0 +1,49 @@
+package slide
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"sync"
+)
+
+// SlideRenderer provides the data passed to the template of a slide. A nil
+// map leaves the slide empty.
+type SlideRenderer func(context.Context, *projectionRequest) (map[string]any, error)
+
+var (
+	renderersMu sync.RWMutex
+	renderers   = make(map[string]SlideRenderer)
+)
+
+// RegisterSlideRenderer makes a renderer available for projections of the
+// given type. Slides register themselves in an init function of their file.
+// Registering a type twice panics.
+func RegisterSlideRenderer(projectionType string, renderer SlideRenderer) {
+	renderersMu.Lock()
+	defer renderersMu.Unlock()
+
+	if renderer == nil {
+		panic(fmt.Sprintf("slide renderer for %s is nil", projectionType))
+	}
+
+	if _, ok := renderers[projectionType]; ok {
+		panic(fmt.Sprintf("slide renderer for %s registered twice", projectionType))
+	}
+
+	renderers[projectionType] = renderer
+}
+
+// registeredRenderers returns a copy of all registered renderers.
+func registeredRenderers() map[string]SlideRenderer {
+	renderersMu.RLock()
+	defer renderersMu.RUnlock()
+
+	return maps.Clone(renderers)
+}
+
+// GenericSlideRenderer is used for projection types without a registered
+// renderer. It leaves the slide empty.
+func GenericSlideRenderer(ctx context.Context, req *projectionRequest) (map[string]any, error) {
+	return nil, nil
+}
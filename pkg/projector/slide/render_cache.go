@@ -0,0 +1,322 @@
+package slide
+
+import (
+	"cmp"
+	"container/list"
+	"crypto/sha256"
+	"encoding/binary"
+	"hash"
+	"math"
+	"reflect"
+	"slices"
+	"sync"
+	"time"
+)
+
+// DefaultRenderCacheSize is the default number of rendered slides kept by a
+// render cache.
+const DefaultRenderCacheSize = 512
+
+// RenderCache shares rendered slides between projectors and requests.
+//
+// Entries are keyed by a sha256 hash of the slide data, the template, the
+// language with the custom translations and the media url. A change of the
+// data results in a different key, so outdated renders are never served and
+// are evicted as least recently used. Theme colors are referenced by the slides
+// through css variables and do not change the rendered html.
+//
+// A nil cache stores nothing.
+type RenderCache struct {
+	mu      sync.Mutex
+	size    int
+	entries map[renderCacheKey]*list.Element
+	order   *list.List
+	hits    int
+	misses  int
+	bytes   int64
+	budget  *MemoryBudget
+}
+
+// renderCacheKey is the sha256 hash of everything a render depends on. Unlike
+// a short hash, a collision serving the render of other data is not a
+// practical concern.
+type renderCacheKey [sha256.Size]byte
+
+type renderCacheEntry struct {
+	key     renderCacheKey
+	content string
+	used    time.Time
+}
+
+// RenderCacheStats are the counters of a render cache.
+type RenderCacheStats struct {
+	Entries int
+	Hits    int
+	Misses  int
+	Bytes   int64
+}
+
+// NewRenderCache returns a cache keeping up to size renders. Returns nil if
+// size is not positive, which disables caching.
+func NewRenderCache(size int) *RenderCache {
+	if size <= 0 {
+		return nil
+	}
+
+	return &RenderCache{
+		size:    size,
+		entries: make(map[renderCacheKey]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// UseBudget limits the memory of the cache together with the other
+// consumers of the budget. Has to be called before the cache is used.
+func (c *RenderCache) UseBudget(budget *MemoryBudget) {
+	if c == nil || budget == nil {
+		return
+	}
+
+	c.budget = budget
+	budget.Register(c)
+}
+
+func (c *RenderCache) get(key renderCacheKey) (string, bool) {
+	if c == nil {
+		return "", false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		c.misses++
+		return "", false
+	}
+
+	c.hits++
+	c.order.MoveToFront(element)
+	entry := element.Value.(*renderCacheEntry)
+	entry.used = time.Now()
+	return entry.content, true
+}
+
+func (c *RenderCache) add(key renderCacheKey, content string) {
+	if c == nil {
+		return
+	}
+
+	// The budget evicts entries of this cache, so it is only called after
+	// the lock is released.
+	grown := c.store(key, content)
+	if grown > 0 {
+		c.budget.Grow(grown)
+	} else if grown < 0 {
+		c.budget.Release(-grown)
+	}
+}
+
+// store adds the content and evicts the least recently used entries beyond
+// the size of the cache. Returns the change of the cached bytes.
+func (c *RenderCache) store(key renderCacheKey, content string) int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	before := c.bytes
+	if element, ok := c.entries[key]; ok {
+		entry := element.Value.(*renderCacheEntry)
+		c.bytes += int64(len(content) - len(entry.content))
+		entry.content = content
+		entry.used = time.Now()
+		c.order.MoveToFront(element)
+		return c.bytes - before
+	}
+
+	c.entries[key] = c.order.PushFront(&renderCacheEntry{key: key, content: content, used: time.Now()})
+	c.bytes += int64(len(content))
+	for c.order.Len() > c.size {
+		c.removeOldest()
+	}
+
+	return c.bytes - before
+}
+
+// removeOldest removes the least recently used entry and returns its size.
+// The lock has to be held.
+func (c *RenderCache) removeOldest() int64 {
+	oldest := c.order.Back()
+	if oldest == nil {
+		return 0
+	}
+
+	entry := oldest.Value.(*renderCacheEntry)
+	c.order.Remove(oldest)
+	delete(c.entries, entry.key)
+	c.bytes -= int64(len(entry.content))
+	return int64(len(entry.content))
+}
+
+// OldestUse returns the time the least recently used render was used. It is
+// called by the memory budget of the cache.
+func (c *RenderCache) OldestUse() (time.Time, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	oldest := c.order.Back()
+	if oldest == nil {
+		return time.Time{}, false
+	}
+
+	return oldest.Value.(*renderCacheEntry).used, true
+}
+
+// EvictOldest removes the least recently used render and returns its size.
+// It is called by the memory budget of the cache.
+func (c *RenderCache) EvictOldest() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.removeOldest()
+}
+
+// Stats returns the number of cached renders and the hits and misses so far.
+func (c *RenderCache) Stats() RenderCacheStats {
+	if c == nil {
+		return RenderCacheStats{}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return RenderCacheStats{Entries: c.order.Len(), Hits: c.hits, Misses: c.misses, Bytes: c.bytes}
+}
+
+// renderKey hashes everything the rendered html of a slide depends on.
+// Returns false if the data contains values which cannot be hashed.
+func renderKey(templateName string, fingerprint [sha256.Size]byte, mediaURL string, data map[string]any) (renderCacheKey, bool) {
+	h := sha256.New()
+	h.Write([]byte(templateName))
+	h.Write([]byte{0})
+	h.Write([]byte(mediaURL))
+	h.Write([]byte{0})
+	h.Write(fingerprint[:])
+
+	if !hashValue(h, reflect.ValueOf(data), map[uintptr]bool{}) {
+		return renderCacheKey{}, false
+	}
+
+	return renderCacheKey(h.Sum(nil)), true
+}
+
+func writeUint64(h hash.Hash, v uint64) {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], v)
+	h.Write(b[:])
+}
+
+// hashValue writes v with all nested values to h. Unexported fields are
+// included, since templates can reach them through methods. Pointers are
+// followed, path holds the pointers of the current path to detect cycles.
+func hashValue(h hash.Hash, v reflect.Value, path map[uintptr]bool) bool {
+	if !v.IsValid() {
+		h.Write([]byte{0})
+		return true
+	}
+
+	// The kind separates values with the same bytes, e.g. "" and [].
+	h.Write([]byte{byte(v.Kind())})
+
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			h.Write([]byte{1})
+		} else {
+			h.Write([]byte{0})
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		writeUint64(h, uint64(v.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		writeUint64(h, v.Uint())
+	case reflect.Float32, reflect.Float64:
+		writeUint64(h, math.Float64bits(v.Float()))
+	case reflect.String:
+		writeUint64(h, uint64(v.Len()))
+		h.Write([]byte(v.String()))
+	case reflect.Pointer:
+		if v.IsNil() {
+			h.Write([]byte{0})
+			return true
+		}
+
+		if path[v.Pointer()] {
+			return false
+		}
+		path[v.Pointer()] = true
+		defer delete(path, v.Pointer())
+
+		h.Write([]byte{1})
+		return hashValue(h, v.Elem(), path)
+	case reflect.Interface:
+		if !v.IsNil() {
+			// The dynamic type changes how a value is printed.
+			h.Write([]byte(v.Elem().Type().String()))
+		}
+		return hashValue(h, v.Elem(), path)
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			h.Write([]byte{0})
+			return true
+		}
+
+		writeUint64(h, uint64(v.Len()))
+		for i := range v.Len() {
+			if !hashValue(h, v.Index(i), path) {
+				return false
+			}
+		}
+	case reflect.Map:
+		compare, ok := mapKeyCompare(v.Type().Key().Kind())
+		if !ok {
+			return false
+		}
+
+		keys := v.MapKeys()
+		slices.SortFunc(keys, compare)
+
+		writeUint64(h, uint64(len(keys)))
+		for _, key := range keys {
+			hashValue(h, key, path)
+			if !hashValue(h, v.MapIndex(key), path) {
+				return false
+			}
+		}
+	case reflect.Struct:
+		h.Write([]byte(v.Type().String()))
+		for i := range v.NumField() {
+			if !hashValue(h, v.Field(i), path) {
+				return false
+			}
+		}
+	default:
+		// Functions, channels and the like have no comparable content.
+		return false
+	}
+
+	return true
+}
+
+// mapKeyCompare returns a function ordering map keys of the kind. Returns
+// false if maps with this kind of key cannot be hashed in a stable order.
+func mapKeyCompare(kind reflect.Kind) (func(a, b reflect.Value) int, bool) {
+	switch kind {
+	case reflect.String:
+		return func(a, b reflect.Value) int { return cmp.Compare(a.String(), b.String()) }, true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return func(a, b reflect.Value) int { return cmp.Compare(a.Int(), b.Int()) }, true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return func(a, b reflect.Value) int { return cmp.Compare(a.Uint(), b.Uint()) }, true
+	}
+
+	return nil, false
+}
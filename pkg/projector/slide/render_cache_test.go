@@ -0,0 +1,118 @@
+package slide_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/OpenSlides/openslides-go/datastore/dsfetch"
+	"github.com/OpenSlides/openslides-go/datastore/dsmodels"
+	"github.com/OpenSlides/openslides-projector-service/pkg/projector/slide"
+)
+
+func TestRenderCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	cache := slide.NewRenderCache(2)
+	cache.Add(slide.RenderCacheKey{1}, "one")
+	cache.Add(slide.RenderCacheKey{2}, "two")
+
+	// Reading 1 makes 2 the least recently used entry
+	if content, ok := cache.Get(slide.RenderCacheKey{1}); !ok || content != "one" {
+		t.Fatalf("expected cached render, got %q, %v", content, ok)
+	}
+
+	cache.Add(slide.RenderCacheKey{3}, "three")
+	if _, ok := cache.Get(slide.RenderCacheKey{2}); ok {
+		t.Errorf("expected least recently used render to be evicted")
+	}
+
+	for _, key := range []slide.RenderCacheKey{{1}, {3}} {
+		if _, ok := cache.Get(key); !ok {
+			t.Errorf("expected render %d to be cached", key[0])
+		}
+	}
+
+	if slide.NewRenderCache(0) != nil {
+		t.Errorf("expected a size of 0 to disable the cache")
+	}
+}
+
+func TestRenderCacheMemoryBudget(t *testing.T) {
+	budget := slide.NewMemoryBudget(10)
+	first := slide.NewRenderCache(10)
+	first.UseBudget(budget)
+	second := slide.NewRenderCache(10)
+	second.UseBudget(budget)
+
+	// The times of use decide which entry is evicted
+	step := func() { time.Sleep(time.Millisecond) }
+
+	first.Add(slide.RenderCacheKey{1}, "aaaa")
+	step()
+	second.Add(slide.RenderCacheKey{2}, "bbbb")
+	step()
+	first.Get(slide.RenderCacheKey{1})
+	step()
+
+	// Exceeding the budget evicts the least recently used entry of all caches
+	first.Add(slide.RenderCacheKey{3}, "cccc")
+	if _, ok := second.Get(slide.RenderCacheKey{2}); ok {
+		t.Errorf("expected least recently used render of the other cache to be evicted")
+	}
+
+	for _, key := range []slide.RenderCacheKey{{1}, {3}} {
+		if _, ok := first.Get(key); !ok {
+			t.Errorf("expected render %d to be cached", key[0])
+		}
+	}
+
+	stats := budget.Stats()
+	if stats.UsedBytes != 8 || stats.MaxBytes != 10 || stats.Evicted != 1 {
+		t.Errorf("expected 8 of 10 bytes used after one eviction, got %+v", stats)
+	}
+
+	if bytes := first.Stats().Bytes; bytes != 8 {
+		t.Errorf("expected 8 cached bytes, got %d", bytes)
+	}
+
+	// A render larger than the budget is not kept
+	second.Add(slide.RenderCacheKey{4}, "larger than ten bytes")
+	if stats := budget.Stats(); stats.UsedBytes > stats.MaxBytes {
+		t.Errorf("expected budget to be kept, got %+v", stats)
+	}
+
+	if slide.NewMemoryBudget(0) != nil {
+		t.Errorf("expected a budget of 0 to disable the limit")
+	}
+}
+
+func TestRenderKey(t *testing.T) {
+	var blockID dsfetch.Maybe[int]
+	blockID.Set(3)
+	motion := dsmodels.Motion{ID: 5, Title: "Lunch", BlockID: blockID}
+	key := func(data map[string]any) slide.RenderCacheKey {
+		t.Helper()
+
+		key, ok := slide.RenderKey("motion", [32]byte{1}, slide.DefaultMediaURL, data)
+		if !ok {
+			t.Fatalf("expected data to be hashable")
+		}
+		return key
+	}
+
+	base := key(map[string]any{"Motion": &motion, "Number": 1})
+	if other := key(map[string]any{"Number": 1, "Motion": &dsmodels.Motion{ID: 5, Title: "Lunch", BlockID: blockID}}); other != base {
+		t.Errorf("expected equal data to have the same key")
+	}
+
+	blockID.Set(4)
+	if other := key(map[string]any{"Motion": &dsmodels.Motion{ID: 5, Title: "Lunch", BlockID: blockID}, "Number": 1}); other == base {
+		t.Errorf("expected a changed value within a maybe to change the key")
+	}
+
+	if other, _ := slide.RenderKey("motion", [32]byte{2}, slide.DefaultMediaURL, map[string]any{"Motion": &motion, "Number": 1}); other == base {
+		t.Errorf("expected other translations to change the key")
+	}
+
+	if _, ok := slide.RenderKey("motion", [32]byte{1}, slide.DefaultMediaURL, map[string]any{"Callback": func() {}}); ok {
+		t.Errorf("expected functions not to be hashable")
+	}
+}
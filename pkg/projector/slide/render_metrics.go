@@ -0,0 +1,72 @@
+package slide
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// RenderDurationBuckets are the upper bounds in seconds of the buckets of the
+// render duration histograms.
+var RenderDurationBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5}
+
+// DurationHistogram counts the durations of renders.
+type DurationHistogram struct {
+	// Buckets holds the number of durations up to the bound of the same
+	// index in RenderDurationBuckets, including smaller ones.
+	Buckets []uint64
+	Count   uint64
+	Sum     float64
+}
+
+// durationRecorder collects a duration histogram per collection.
+type durationRecorder struct {
+	mu         sync.Mutex
+	histograms map[string]*DurationHistogram
+}
+
+func newDurationRecorder() *durationRecorder {
+	return &durationRecorder{histograms: make(map[string]*DurationHistogram)}
+}
+
+func (r *durationRecorder) observe(collection string, d time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	histogram, ok := r.histograms[collection]
+	if !ok {
+		histogram = &DurationHistogram{Buckets: make([]uint64, len(RenderDurationBuckets))}
+		r.histograms[collection] = histogram
+	}
+
+	seconds := d.Seconds()
+	for i, bound := range RenderDurationBuckets {
+		if seconds <= bound {
+			histogram.Buckets[i]++
+		}
+	}
+	histogram.Count++
+	histogram.Sum += seconds
+}
+
+func (r *durationRecorder) get() map[string]DurationHistogram {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	histograms := make(map[string]DurationHistogram, len(r.histograms))
+	for collection, histogram := range r.histograms {
+		copied := *histogram
+		copied.Buckets = slices.Clone(histogram.Buckets)
+		histograms[collection] = copied
+	}
+
+	return histograms
+}
+
+var renderDurations = newDurationRecorder()
+
+// RenderDurations returns the histograms of the time needed to render slides
+// per collection, from reading the projection to the rendered content.
+func RenderDurations() map[string]DurationHistogram {
+	return renderDurations.get()
+}
@@ -0,0 +1,330 @@
+package slide
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/OpenSlides/openslides-go/datastore/dskey"
+	"github.com/OpenSlides/openslides-go/datastore/dsmodels"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/net/html"
+)
+
+// defaultElements are the elements kept in rich text like motion texts if
+// nothing else is configured.
+var defaultElements = []string{
+	"a", "b", "blockquote", "br", "caption", "code", "col", "colgroup", "del", "div", "em", "figcaption",
+	"figure", "h1", "h2", "h3", "h4", "h5", "h6", "hr", "i", "img", "ins", "li", "mark", "ol", "p", "pre",
+	"s", "small", "span", "strike", "strong", "sub", "sup", "table", "tbody", "td", "tfoot", "th", "thead",
+	"tr", "u", "ul",
+}
+
+// optionalElements embed external content and are only kept if a sanitizer
+// config allows them.
+var optionalElements = []string{"audio", "iframe", "source", "video"}
+
+// droppedWithContent are removed together with their content. Besides
+// scripts they can run code or contain content not meant to be shown.
+var droppedWithContent = []string{
+	"audio", "embed", "iframe", "math", "noembed", "noframes", "noscript", "object", "plaintext", "script",
+	"style", "svg", "template", "textarea", "title", "video", "xmp",
+}
+
+// voidElements have no content and no end tag.
+var voidElements = []string{
+	"area", "base", "br", "col", "embed", "hr", "img", "input", "link", "meta", "source", "track", "wbr",
+}
+
+// neverAllowed cannot be allowed by a sanitizer config.
+var neverAllowed = []string{"base", "embed", "form", "link", "meta", "object", "script", "style", "svg"}
+
+// globalAttributes are kept on all elements, besides data-* attributes.
+var globalAttributes = []string{"class", "dir", "lang", "style", "title"}
+
+// elementAttributes are kept on the given elements.
+var elementAttributes = map[string][]string{
+	"a":      {"href", "rel", "target"},
+	"audio":  {"controls", "src"},
+	"col":    {"span"},
+	"iframe": {"allowfullscreen", "frameborder", "height", "src", "width"},
+	"img":    {"alt", "height", "src", "width"},
+	"li":     {"value"},
+	"ol":     {"start", "type"},
+	"source": {"src", "type"},
+	"td":     {"colspan", "rowspan"},
+	"th":     {"colspan", "rowspan"},
+	"video":  {"controls", "height", "poster", "src", "width"},
+}
+
+// SanitizerRules change the elements kept in rich text. Allow adds optional
+// elements like iframe, deny removes elements like img.
+type SanitizerRules struct {
+	Allow []string `json:"allow"`
+	Deny  []string `json:"deny"`
+}
+
+// SanitizerConfig configures the elements kept in rich text like motion
+// texts. The rules of the organization are applied to the default elements,
+// the rules of a meeting on top of them. Scripts are never kept.
+type SanitizerConfig struct {
+	Organization SanitizerRules         `json:"organization"`
+	Meetings     map[int]SanitizerRules `json:"meetings"`
+
+	// OrganizationField and MeetingField name json fields of the
+	// organization and the meetings holding their rules in the datastore.
+	// Rules set there replace the rules of the config for the organization
+	// or the meeting. Empty fields are not read.
+	OrganizationField string `json:"-"`
+	MeetingField      string `json:"-"`
+}
+
+// LoadSanitizerConfig reads a sanitizer config from a json file.
+func LoadSanitizerConfig(path string) (*SanitizerConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading sanitizer config: %w", err)
+	}
+
+	var config SanitizerConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("parsing sanitizer config: %w", err)
+	}
+
+	if err := config.Organization.validate(); err != nil {
+		return nil, fmt.Errorf("organization: %w", err)
+	}
+
+	for meetingID, rules := range config.Meetings {
+		if err := rules.validate(); err != nil {
+			return nil, fmt.Errorf("meeting %d: %w", meetingID, err)
+		}
+	}
+
+	return &config, nil
+}
+
+func (r SanitizerRules) validate() error {
+	for _, element := range slices.Concat(r.Allow, r.Deny) {
+		if slices.Contains(neverAllowed, element) {
+			return fmt.Errorf("element %s can never be allowed", element)
+		}
+
+		if !slices.Contains(defaultElements, element) && !slices.Contains(optionalElements, element) {
+			return fmt.Errorf("unknown element %s", element)
+		}
+	}
+
+	return nil
+}
+
+// apply adds and removes the elements of the rules.
+func (r SanitizerRules) apply(elements map[string]bool) {
+	for _, element := range r.Allow {
+		if !slices.Contains(neverAllowed, element) {
+			elements[element] = true
+		}
+	}
+
+	for _, element := range r.Deny {
+		delete(elements, element)
+	}
+}
+
+// elements returns the elements kept in rich text of the meeting by the
+// rules of the config. A nil config keeps the default elements.
+func (c *SanitizerConfig) elements(meetingID int) map[string]bool {
+	if c == nil {
+		return allowedElements(SanitizerRules{}, SanitizerRules{})
+	}
+
+	return allowedElements(c.Organization, c.Meetings[meetingID])
+}
+
+// meetingElements returns the elements kept in rich text of the meeting. The
+// rules of the organization and the meeting are read from the datastore
+// fields of the config and default to the rules of the config if a field is
+// empty or invalid.
+func (c *SanitizerConfig) meetingElements(ctx context.Context, fetch *dsmodels.Fetch, meetingID int) (map[string]bool, error) {
+	if c == nil || (c.OrganizationField == "" && c.MeetingField == "") {
+		return c.elements(meetingID), nil
+	}
+
+	organization, err := datastoreRules(ctx, fetch, "organization", 1, c.OrganizationField)
+	if err != nil {
+		return nil, fmt.Errorf("reading sanitizer rules of the organization: %w", err)
+	}
+
+	meeting, err := datastoreRules(ctx, fetch, "meeting", meetingID, c.MeetingField)
+	if err != nil {
+		return nil, fmt.Errorf("reading sanitizer rules of meeting %d: %w", meetingID, err)
+	}
+
+	if organization == nil {
+		organization = &c.Organization
+	}
+
+	if meeting == nil {
+		rules := c.Meetings[meetingID]
+		meeting = &rules
+	}
+
+	return allowedElements(*organization, *meeting), nil
+}
+
+// datastoreRules reads sanitizer rules from a json field of an object. It
+// returns nil if the field is not set, empty or holds invalid rules.
+func datastoreRules(ctx context.Context, fetch *dsmodels.Fetch, collection string, id int, field string) (*SanitizerRules, error) {
+	if field == "" {
+		return nil, nil
+	}
+
+	key, err := dskey.FromParts(collection, id, field)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sanitizer field: %w", err)
+	}
+
+	data, err := fetch.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if data[key] == nil {
+		return nil, nil
+	}
+
+	var rules SanitizerRules
+	if err := json.Unmarshal(data[key], &rules); err != nil {
+		log.Warn().Err(err).Str("key", key.String()).Msg("invalid sanitizer rules")
+		return nil, nil
+	}
+
+	if err := rules.validate(); err != nil {
+		log.Warn().Err(err).Str("key", key.String()).Msg("invalid sanitizer rules")
+		return nil, nil
+	}
+
+	return &rules, nil
+}
+
+// allowedElements returns the default elements changed by the rules of the
+// organization and the meeting.
+func allowedElements(organization SanitizerRules, meeting SanitizerRules) map[string]bool {
+	elements := make(map[string]bool, len(defaultElements))
+	for _, element := range defaultElements {
+		elements[element] = true
+	}
+
+	organization.apply(elements)
+	meeting.apply(elements)
+	return elements
+}
+
+// sanitizeHTML returns rich text with only the given elements and allowed
+// attributes. The content of removed elements is kept, except for scripts
+// and embedded content.
+func sanitizeHTML(elements map[string]bool, text string) template.HTML {
+	var b strings.Builder
+	tokenizer := html.NewTokenizer(strings.NewReader(text))
+	dropped := 0
+	for {
+		tokenType := tokenizer.Next()
+		switch tokenType {
+		case html.ErrorToken:
+			return template.HTML(b.String())
+
+		case html.TextToken:
+			if dropped == 0 {
+				b.WriteString(html.EscapeString(string(tokenizer.Text())))
+			}
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			if !elements[token.Data] && slices.Contains(droppedWithContent, token.Data) {
+				// Void elements like embed have no end tag closing them
+				if tokenType == html.StartTagToken && !slices.Contains(voidElements, token.Data) {
+					dropped++
+				}
+				continue
+			}
+
+			if dropped > 0 || !elements[token.Data] {
+				continue
+			}
+
+			b.WriteString("<" + token.Data)
+			for _, attr := range token.Attr {
+				if value, ok := sanitizeAttribute(token.Data, attr); ok {
+					b.WriteString(" " + attr.Key + `="` + html.EscapeString(value) + `"`)
+				}
+			}
+			if tokenType == html.SelfClosingTagToken {
+				b.WriteString(" /")
+			}
+			b.WriteString(">")
+
+		case html.EndTagToken:
+			token := tokenizer.Token()
+			if !elements[token.Data] && slices.Contains(droppedWithContent, token.Data) {
+				if !slices.Contains(voidElements, token.Data) {
+					dropped = max(dropped-1, 0)
+				}
+				continue
+			}
+
+			if dropped == 0 && elements[token.Data] {
+				b.WriteString("</" + token.Data + ">")
+			}
+		}
+	}
+}
+
+// sanitizeAttribute returns the value of an attribute allowed on the element.
+// Urls are only kept with safe schemes and styles without external
+// resources.
+func sanitizeAttribute(element string, attr html.Attribute) (string, bool) {
+	if attr.Namespace != "" {
+		return "", false
+	}
+
+	if !slices.Contains(globalAttributes, attr.Key) && !slices.Contains(elementAttributes[element], attr.Key) && !strings.HasPrefix(attr.Key, "data-") {
+		return "", false
+	}
+
+	switch attr.Key {
+	case "href", "src", "poster":
+		return attr.Val, safeURL(attr.Val, element == "img" && attr.Key == "src")
+	case "style":
+		style := strings.ToLower(attr.Val)
+		for _, unsafe := range []string{"url(", "expression(", "javascript:", "@import", "behavior", "\\", "/*"} {
+			if strings.Contains(style, unsafe) {
+				return "", false
+			}
+		}
+	}
+
+	return attr.Val, true
+}
+
+// safeURL returns true for relative urls and urls with the schemes http,
+// https and mailto. Data urls are only safe for images.
+func safeURL(value string, image bool) bool {
+	parsed, err := url.Parse(strings.TrimSpace(value))
+	if err != nil {
+		return false
+	}
+
+	switch strings.ToLower(parsed.Scheme) {
+	case "", "http", "https", "mailto":
+		return true
+	case "data":
+		return image && strings.HasPrefix(strings.ToLower(parsed.Opaque), "image/") && !strings.HasPrefix(strings.ToLower(parsed.Opaque), "image/svg")
+	}
+
+	return false
+}
@@ -0,0 +1,83 @@
+package slide
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSanitizeHTML(t *testing.T) {
+	config := &SanitizerConfig{
+		Organization: SanitizerRules{Allow: []string{"iframe"}},
+		Meetings: map[int]SanitizerRules{
+			2: {Deny: []string{"img", "iframe"}},
+			3: {Allow: []string{"script", "video"}},
+		},
+	}
+
+	for _, tt := range []struct {
+		name      string
+		config    *SanitizerConfig
+		meetingID int
+		text      string
+		expected  string
+	}{
+		{"formatting is kept", nil, 1, `<p class="x" style="text-align: center">A <strong>b</strong><br/>c</p>`, `<p class="x" style="text-align: center">A <strong>b</strong><br />c</p>`},
+		{"scripts are removed", nil, 1, `<p>A<script>alert(1)</script>B</p>`, `<p>AB</p>`},
+		{"event handlers are removed", nil, 1, `<img src="a.png" onerror="alert(1)">`, `<img src="a.png">`},
+		{"script urls are removed", nil, 1, `<a href=" javascript:alert(1)">x</a><a href="https://openslides.com">y</a>`, `<a>x</a><a href="https://openslides.com">y</a>`},
+		{"styles with urls are removed", nil, 1, `<span style="background: URL(https://x)">a</span>`, `<span>a</span>`},
+		{"image data urls are kept", nil, 1, `<img src="data:image/png;base64,AAAA">`, `<img src="data:image/png;base64,AAAA">`},
+		{"other data urls are removed", nil, 1, `<img src="data:text/html;base64,AAAA"><img src="data:image/svg+xml;base64,AAAA">`, `<img><img>`},
+		{"unknown elements keep their content", nil, 1, `<blink>a <b>b</b></blink>`, `a <b>b</b>`},
+		{"comments are removed", nil, 1, `a<!-- b -->c`, `ac`},
+		{"text is escaped", nil, 1, `a &lt;b&gt; &amp; c`, `a &lt;b&gt; &amp; c`},
+		{"content after a void embed is kept", nil, 1, `<p>a<embed src="https://x">b</p><p>c</p>`, `<p>ab</p><p>c</p>`},
+		{"iframes are removed by default", nil, 1, `<p>a<iframe src="https://x">fallback</iframe>b</p>`, `<p>ab</p>`},
+		{"iframes allowed by the organization", config, 1, `<iframe src="https://x" onload="alert(1)"></iframe>`, `<iframe src="https://x"></iframe>`},
+		{"images denied by the meeting", config, 2, `<p>a<img src="a.png">b<iframe src="https://x"></iframe></p>`, `<p>ab</p>`},
+		{"scripts are never allowed", config, 3, `<script>alert(1)</script><video src="https://x"></video>`, `<video src="https://x"></video>`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(sanitizeHTML(tt.config.elements(tt.meetingID), tt.text)); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestLoadSanitizerConfig(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		json  string
+		error string
+	}{
+		{"valid", `{"organization":{"allow":["iframe"]},"meetings":{"4":{"deny":["img"]}}}`, ""},
+		{"script", `{"meetings":{"4":{"allow":["script"]}}}`, "meeting 4: element script can never be allowed"},
+		{"unknown element", `{"organization":{"deny":["marquee"]}}`, "organization: unknown element marquee"},
+		{"invalid json", `{"meetings":[]}`, "parsing sanitizer config"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "sanitizer.json")
+			if err := os.WriteFile(path, []byte(tt.json), 0o600); err != nil {
+				t.Fatalf("write config: %v", err)
+			}
+
+			config, err := LoadSanitizerConfig(path)
+			if tt.error == "" {
+				if err != nil || config == nil {
+					t.Fatalf("expected config to be loaded, got %v", err)
+				}
+				if elements := config.elements(4); !elements["iframe"] || elements["img"] {
+					t.Errorf("expected iframes and no images in meeting 4, got %v", elements)
+				}
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tt.error) {
+				t.Errorf("expected error containing %q, got %v", tt.error, err)
+			}
+		})
+	}
+}
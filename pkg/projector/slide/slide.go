@@ -3,19 +3,26 @@ package slide
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"html/template"
 	"runtime/debug"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/attribute"
 
+	"github.com/OpenSlides/openslides-go/datastore/dsfetch"
 	"github.com/OpenSlides/openslides-go/datastore/dsmodels"
 	"github.com/OpenSlides/openslides-go/datastore/flow"
 	"github.com/OpenSlides/openslides-projector-service/pkg/database"
 	"github.com/OpenSlides/openslides-projector-service/pkg/i18n"
+	"github.com/OpenSlides/openslides-projector-service/pkg/tracing"
 )
 
 type projectionRequest struct {
@@ -23,70 +30,214 @@ type projectionRequest struct {
 	Projection      *dsmodels.Projection
 	Fetch           *dsmodels.Fetch
 	Locale          *i18n.ProjectorLocale
+
+	// Elements are the elements kept in rich text of the meeting
+	Elements map[string]bool
+}
+
+// sanitizeHTML returns rich text like a motion text with only the elements
+// allowed in the meeting of the projection.
+func (req *projectionRequest) sanitizeHTML(text string) template.HTML {
+	return sanitizeHTML(req.Elements, text)
 }
 
 type projectionUpdate struct {
-	ID      int
-	Content string
+	ID              int
+	Content         string
+	Weight          int
+	Stable          bool
+	Collection      string
+	ContentObjectID string
+	View            ProjectionView
+
+	// Unavailable is set if the projection could not be rendered because
+	// reading from the datastore failed. Content is empty in this case.
+	Unavailable bool
+
+	// Error is a message for users if the slide could not be rendered.
+	// Content holds a placeholder or is empty in this case.
+	Error string
+}
+
+// ProjectionView holds the scroll and scale options of a projection. Unset
+// values fall back to the settings of the projector.
+type ProjectionView struct {
+	Scroll *int `json:"scroll"`
+	Scale  *int `json:"scale"`
+}
+
+func (v ProjectionView) Equal(o ProjectionView) bool {
+	intPtrEqual := func(a, b *int) bool {
+		return a == b || (a != nil && b != nil && *a == *b)
+	}
+
+	return intPtrEqual(v.Scroll, o.Scroll) && intPtrEqual(v.Scale, o.Scale)
+}
+
+// collectionCounter counts events per collection.
+type collectionCounter struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+func newCollectionCounter() *collectionCounter {
+	return &collectionCounter{counts: make(map[string]int)}
+}
+
+func (c *collectionCounter) inc(collection string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.counts[collection]++
+}
+
+func (c *collectionCounter) get() map[string]int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	counts := make(map[string]int, len(c.counts))
+	for collection, count := range c.counts {
+		counts[collection] = count
+	}
+
+	return counts
+}
+
+var (
+	renderPanics    = newCollectionCounter()
+	oversizedSlides = newCollectionCounter()
+)
+
+// RenderPanics returns the number of recovered panics in slide handlers per
+// collection.
+func RenderPanics() map[string]int {
+	return renderPanics.get()
+}
+
+// OversizedSlides returns the number of slides per collection which were not
+// shown because their content exceeded the maximum size.
+func OversizedSlides() map[string]int {
+	return oversizedSlides.get()
 }
 
-type slideHandler func(context.Context, *projectionRequest) (map[string]any, error)
+// DefaultMediaURL is the prefix of urls pointing to mediafiles served by the
+// media service.
+const DefaultMediaURL = "/system/media/get/"
 
 type SlideRouter struct {
-	ctx    context.Context
-	db     *database.Datastore
-	ds     flow.Flow
-	locale *i18n.ProjectorLocale
-	Routes map[string]slideHandler
+	ctx      context.Context
+	db       *database.Datastore
+	ds       flow.Flow
+	locale   *i18n.ProjectorLocale
+	rerender chan struct{}
+	Routes   map[string]SlideRenderer
+
+	// Fallback renders projections whose type has no entry in Routes
+	Fallback SlideRenderer
+
+	// MediaURL is the prefix of mediafile urls used by the templates
+	MediaURL string
+
+	// MaxContentSize is the maximum size of a rendered slide in bytes. Larger
+	// slides are replaced by a placeholder. Zero disables the limit.
+	MaxContentSize int
+
+	// Funcs are added to the functions of TemplateFuncs available in the
+	// templates. Functions with the same name replace the built-in ones.
+	Funcs template.FuncMap
+
+	// Cache shares rendered slides with other routers. Nil disables caching.
+	Cache *RenderCache
+
+	// Sanitizer configures the elements kept in rich text per meeting. Nil
+	// keeps the default elements.
+	Sanitizer *SanitizerConfig
+
+	// RetryDelay is the delay before a projection is read again after the
+	// datastore was unavailable. It doubles with every further failure up
+	// to RetryMaxDelay.
+	RetryDelay    time.Duration
+	RetryMaxDelay time.Duration
 }
 
-func New(ctx context.Context, db *database.Datastore, ds flow.Flow, locale *i18n.ProjectorLocale) *SlideRouter {
-	routes := make(map[string]slideHandler)
-	routes["agenda_item_list"] = AgendaItemListSlideHandler
-	routes["assignment"] = AssignmentSlideHandler
-	routes["current_los"] = ListOfSpeakersSlideHandler
-	routes["current_speaker_chyron"] = CurrentSpeakerChyronSlideHandler
-	routes["current_speaking_structure_level"] = CurrentSpeakingStructureLevelSlideHandler
-	routes["current_structure_level_list"] = CurrentStructureLevelListSlideHandler
-	routes["home"] = HomeSlideHandler
-	routes["list_of_speakers"] = ListOfSpeakersSlideHandler
-	routes["meeting_mediafile"] = MeetingMediafileSlideHandler
-	routes["motion"] = MotionSlideHandler
-	routes["motion_block"] = MotionBlockSlideHandler
-	routes["poll"] = PollSlideHandler
-	routes["projector_countdown"] = ProjectorCountdownSlideHandler
-	routes["projector_message"] = ProjectorMessageSlideHandler
-	routes["topic"] = TopicSlideHandler
-	routes["wifi_access_data"] = WifiAccessDataSlideHandler
+// Delays before projections are read again after the datastore was
+// unavailable.
+const (
+	DefaultRetryDelay    = time.Second
+	DefaultRetryMaxDelay = 30 * time.Second
+)
 
+func New(ctx context.Context, db *database.Datastore, ds flow.Flow, locale *i18n.ProjectorLocale) *SlideRouter {
 	return &SlideRouter{
-		ctx:    ctx,
-		db:     db,
-		ds:     ds,
-		locale: locale,
-		Routes: routes,
+		ctx:      ctx,
+		db:       db,
+		ds:       ds,
+		locale:   locale,
+		rerender: make(chan struct{}, 1),
+		Routes:   registeredRenderers(),
+		Fallback: GenericSlideRenderer,
+		MediaURL: DefaultMediaURL,
+
+		RetryDelay:    DefaultRetryDelay,
+		RetryMaxDelay: DefaultRetryMaxDelay,
+	}
+}
+
+// Rerender renders all subscribed projections again, e.g. after the language
+// of the locale changed.
+func (r *SlideRouter) Rerender() {
+	select {
+	case r.rerender <- struct{}{}:
+	default:
+		// A rerender is already pending
 	}
 }
 
 func (r *SlideRouter) SubscribeContent(addProjection <-chan int, removeProjection <-chan int) <-chan *projectionUpdate {
 	updateChannel := make(chan *projectionUpdate)
 	contextCancel := make(map[int]context.CancelFunc)
+	stop := func() {
+		for _, cancel := range contextCancel {
+			cancel()
+		}
+	}
 
 	go func() {
 		for {
 			select {
 			case <-r.ctx.Done():
-				close(updateChannel)
+				// The channel is not closed, projections still rendering
+				// stop sending once their context is done.
 				return
-			case id := <-addProjection:
+			case id, ok := <-addProjection:
+				if !ok {
+					// The projector stopped subscribing
+					stop()
+					return
+				}
+
 				if _, ok := contextCancel[id]; !ok {
 					ctx, cancel := context.WithCancel(r.ctx)
 					contextCancel[id] = cancel
 					go r.subscribeProjection(ctx, id, updateChannel)
 				}
-			case id := <-removeProjection:
-				contextCancel[id]()
-				delete(contextCancel, id)
+			case id, ok := <-removeProjection:
+				if !ok {
+					stop()
+					return
+				}
+
+				if cancel, ok := contextCancel[id]; ok {
+					cancel()
+					delete(contextCancel, id)
+				}
+			case <-r.rerender:
+				for id, cancel := range contextCancel {
+					cancel()
+					ctx, cancel := context.WithCancel(r.ctx)
+					contextCancel[id] = cancel
+					go r.subscribeProjection(ctx, id, updateChannel)
+				}
 			}
 		}
 	}()
@@ -94,63 +245,226 @@ func (r *SlideRouter) SubscribeContent(addProjection <-chan int, removeProjectio
 	return updateChannel
 }
 
+// subscribeProjection renders the projection whenever its data changes. If
+// reading from the datastore fails, the projection is subscribed again with
+// a delay doubling up to RetryMaxDelay until it is rendered.
 func (r *SlideRouter) subscribeProjection(ctx context.Context, id int, updateChannel chan<- *projectionUpdate) {
+	for failures := 0; ; failures++ {
+		renderCtx, cancel := context.WithCancel(ctx)
+		failed := make(chan struct{}, 1)
+		var rendered atomic.Bool
+		r.renderProjection(renderCtx, id, updateChannel, func(ok bool) {
+			rendered.Store(ok)
+			if !ok {
+				select {
+				case failed <- struct{}{}:
+				default:
+				}
+			}
+		})
+
+		for retry := false; !retry; {
+			select {
+			case <-ctx.Done():
+				cancel()
+				return
+			case <-failed:
+			}
+
+			select {
+			case <-ctx.Done():
+				cancel()
+				return
+			case <-time.After(r.retryDelay(failures)):
+			}
+
+			// A change of the data might have rendered the projection
+			// in the meantime.
+			retry = !rendered.Load()
+			if !retry {
+				failures = 0
+			}
+		}
+		cancel()
+	}
+}
+
+// retryDelay returns the delay before the projection is read again after
+// the given number of failed attempts.
+func (r *SlideRouter) retryDelay(failures int) time.Duration {
+	delay := r.RetryDelay
+	for i := 0; i < failures && delay < r.RetryMaxDelay; i++ {
+		delay *= 2
+	}
+
+	return min(delay, r.RetryMaxDelay)
+}
+
+// renderProjection subscribes the projection to the datastore and sends its
+// content. report is called after every attempt with whether the datastore
+// could be read.
+func (r *SlideRouter) renderProjection(ctx context.Context, id int, updateChannel chan<- *projectionUpdate, report func(ok bool)) {
+	send := func(update *projectionUpdate) {
+		report(!update.Unavailable)
+		select {
+		case updateChannel <- update:
+		case <-ctx.Done():
+		}
+	}
+
 	onError := func(err error, msg string) {
-		log.Error().Err(err).Msg(msg)
+		// Objects deleted while being projected are not an error, the
+		// projection is rendered empty until it is removed.
+		var doesNotExist dsfetch.DoesNotExistError
+		if errors.As(err, &doesNotExist) {
+			log.Debug().Err(err).Msg(msg)
+		} else {
+			log.Error().Err(err).Msg(msg)
+		}
 
-		updateChannel <- &projectionUpdate{
+		send(&projectionUpdate{
 			ID:      id,
 			Content: "",
+		})
+	}
+
+	// onFetchError reports a projection as unavailable so the last rendered
+	// content can be kept while the datastore is not reachable.
+	onFetchError := func(err error, msg string) {
+		var doesNotExist dsfetch.DoesNotExistError
+		if errors.As(err, &doesNotExist) {
+			onError(err, msg)
+			return
 		}
+
+		log.Warn().Err(err).Msg(msg)
+		send(&projectionUpdate{
+			ID:          id,
+			Unavailable: true,
+		})
 	}
 
 	r.db.NewContext(ctx, func(fetch *dsmodels.Fetch) {
+		renderStart := time.Now()
+		ctx, span := tracing.Start(ctx, "render", attribute.Int("projection_id", id))
+		defer span.End()
+
 		projection, err := fetch.Projection(id).First(ctx)
 		if err != nil {
 			if !errors.Is(err, context.Canceled) {
-				onError(err, fmt.Sprintf("getting projection %d from db", id))
+				onFetchError(err, fmt.Sprintf("getting projection %d from db", id))
 			}
 
 			return
 		}
 
 		projectionType, contentObjectID := getProjectionType(&projection)
+		collection, _, _ := strings.Cut(projection.ContentObjectID, "/")
+		span.SetAttributes(attribute.String("collection", collection))
+
+		var view ProjectionView
+		if len(projection.Options) > 0 {
+			if err := json.Unmarshal(projection.Options, &view); err != nil {
+				log.Debug().Err(err).Msgf("could not parse view options of projection %d", id)
+			}
+		}
+
+		newUpdate := func(content string) *projectionUpdate {
+			return &projectionUpdate{
+				ID:              id,
+				Content:         content,
+				Weight:          projection.Weight,
+				Stable:          projection.Stable,
+				Collection:      collection,
+				ContentObjectID: projection.ContentObjectID,
+				View:            view,
+			}
+		}
+
+		sendContent := func(content string) {
+			renderDurations.observe(collection, time.Since(renderStart))
+			send(newUpdate(content))
+		}
+
+		// sendError sends the content shown instead of a slide which could
+		// not be rendered together with the message for users.
+		sendError := func(content string, msg string) {
+			renderDurations.observe(collection, time.Since(renderStart))
+			update := newUpdate(content)
+			update.Error = msg
+			send(update)
+		}
 
 		defer func() {
-			if r := recover(); r != nil {
-				var ok bool
-				err, ok := r.(error)
+			if rec := recover(); rec != nil {
+				err, ok := rec.(error)
 				if !ok {
-					err = fmt.Errorf("pkg: %v", r)
+					err = fmt.Errorf("pkg: %v", rec)
 				}
 
-				onError(err, fmt.Sprintf("panic in slide handler: %s (%d)\n%s", projectionType, id, string(debug.Stack())))
+				renderPanics.inc(collection)
+
+				projectorID, _ := projection.CurrentProjectorID.Value()
+
+				log.Error().
+					Err(err).
+					Int("projector", projectorID).
+					Int("projection", id).
+					Str("collection", collection).
+					Str("stack", string(debug.Stack())).
+					Msgf("panic in slide handler %s", projectionType)
+
+				sendError(r.errorPlaceholder(), r.errorMessage())
 			}
 		}()
 
-		if handler, ok := r.Routes[projectionType]; ok {
+		handler, ok := r.Routes[projectionType]
+		if !ok {
+			log.Warn().Msgf("unknown projection type %s", projectionType)
+			handler = r.Fallback
+		}
+
+		if handler != nil {
 			var cId *int
 			if contentObjectID != 0 {
 				cId = &contentObjectID
 			}
 
+			elements, err := r.Sanitizer.meetingElements(ctx, fetch, projection.MeetingID)
+			if err != nil {
+				onFetchError(err, fmt.Sprintf("reading sanitizer rules for projection %d", id))
+				return
+			}
+
 			projectionContent, err := handler(ctx, &projectionRequest{
 				ContentObjectID: cId,
 				Projection:      &projection,
 				Fetch:           fetch,
 				Locale:          r.locale,
+				Elements:        elements,
 			})
 
+			// Objects missing a required field are shown as slide error,
+			// reading them again would not help.
+			var missingField database.MissingFieldError
+			if errors.As(err, &missingField) {
+				log.Warn().
+					Err(err).
+					Str("collection", missingField.Key.Collection()).
+					Int("id", missingField.Key.ID()).
+					Str("field", missingField.Key.Field()).
+					Msgf("could not render projection %d", id)
+				sendError(r.errorPlaceholder(), r.errorMessage())
+				return
+			}
+
 			if err != nil {
-				onError(err, fmt.Sprintf("failed executing projection handler %s for %d", projectionType, id))
+				onFetchError(err, fmt.Sprintf("failed executing projection handler %s for %d", projectionType, id))
 				return
 			}
 
 			if projectionContent == nil {
-				updateChannel <- &projectionUpdate{
-					ID:      id,
-					Content: "",
-				}
+				sendContent("")
 				return
 			}
 
@@ -159,41 +473,78 @@ func (r *SlideRouter) subscribeProjection(ctx context.Context, id int, updateCha
 				templateName = val.(string)
 			}
 
+			cacheKey, cacheable := renderKey(templateName, r.locale.Fingerprint(), r.MediaURL, projectionContent)
+			if cacheable {
+				if content, ok := r.Cache.get(cacheKey); ok {
+					sendContent(content)
+					return
+				}
+			}
+
 			tmplName := fmt.Sprintf("%s.html", templateName)
-			tmpl, err := template.New(tmplName).Funcs(template.FuncMap{
-				"RenderIndex": func(i int) int {
-					return i + 1
-				},
-				"Loc": func() *i18n.ProjectorLocale {
-					return r.locale
-				},
-			}).ParseFiles(fmt.Sprintf("templates/slides/%s.html", templateName))
+			tmpl, err := template.New(tmplName).Funcs(TemplateFuncs(r.locale, r.MediaURL)).Funcs(r.Funcs).ParseFiles(fmt.Sprintf("templates/slides/%s.html", templateName))
 			if err != nil {
-				onError(err, fmt.Sprintf("could not load %s template", projectionType))
+				log.Error().Err(err).Msgf("could not load %s template", projectionType)
+				sendError("", r.errorMessage())
 				return
 			}
 
 			var content bytes.Buffer
 			err = tmpl.Lookup(tmplName).Execute(&content, projectionContent)
 			if err != nil {
-				onError(err, fmt.Sprintf("could not execute %s template for projection %d", projectionType, id))
+				log.Error().Err(err).Msgf("could not execute %s template for projection %d", projectionType, id)
+				sendError("", r.errorMessage())
 				return
 			}
 
-			updateChannel <- &projectionUpdate{
-				ID:      id,
-				Content: content.String(),
+			if r.MaxContentSize > 0 && content.Len() > r.MaxContentSize {
+				oversizedSlides.inc(collection)
+				log.Warn().
+					Int("projection", id).
+					Str("collection", collection).
+					Str("content_object", projection.ContentObjectID).
+					Int("size", content.Len()).
+					Int("max_size", r.MaxContentSize).
+					Msgf("rendered %s slide exceeds the maximum size", projectionType)
+
+				sendError(r.oversizedPlaceholder(), r.oversizedMessage())
+				return
 			}
-		} else {
-			log.Warn().Msgf("unknown projection type %s", projectionType)
-			updateChannel <- &projectionUpdate{
-				ID:      id,
-				Content: "",
+
+			if cacheable {
+				r.Cache.add(cacheKey, content.String())
 			}
+
+			sendContent(content.String())
+		} else {
+			sendContent("")
 		}
 	})
 }
 
+// errorMessage tells users that a slide could not be rendered.
+func (r *SlideRouter) errorMessage() string {
+	return r.locale.Get("This slide could not be rendered")
+}
+
+// errorPlaceholder is shown instead of a slide whose handler panicked.
+func (r *SlideRouter) errorPlaceholder() string {
+	msg := template.HTMLEscapeString(r.errorMessage())
+	return fmt.Sprintf(`<div class="content slide-error"><p>%s</p></div>`, msg)
+}
+
+// oversizedMessage tells users that a slide exceeds the maximum size.
+func (r *SlideRouter) oversizedMessage() string {
+	return r.locale.Get("This content is too large to be displayed")
+}
+
+// oversizedPlaceholder is shown instead of a slide whose content exceeds the
+// maximum size.
+func (r *SlideRouter) oversizedPlaceholder() string {
+	msg := template.HTMLEscapeString(r.oversizedMessage())
+	return fmt.Sprintf(`<div class="content slide-error slide-too-large"><p>%s</p></div>`, msg)
+}
+
 func getProjectionType(projection *dsmodels.Projection) (string, int) {
 	collection, id, found := strings.Cut(projection.ContentObjectID, "/")
 	if projection.Type != "" {
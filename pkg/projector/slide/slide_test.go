@@ -0,0 +1,1345 @@
+package slide_test
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/OpenSlides/openslides-go/datastore/dskey"
+	"github.com/OpenSlides/openslides-projector-service/pkg/database"
+	"github.com/OpenSlides/openslides-projector-service/pkg/dstest"
+	"github.com/OpenSlides/openslides-projector-service/pkg/i18n"
+	"github.com/OpenSlides/openslides-projector-service/pkg/projector/slide"
+	"golang.org/x/text/language"
+)
+
+func receiveUpdate[T any](t *testing.T, updates <-chan T) T {
+	t.Helper()
+
+	select {
+	case update := <-updates:
+		return update
+	case <-time.After(time.Second):
+		t.Fatalf("no projection update received")
+	}
+
+	var empty T
+	return empty
+}
+
+// waitForListeners waits until n datastore contexts are registered, so
+// changes sent afterwards are noticed.
+func waitForListeners(t *testing.T, db *database.Datastore, n int) {
+	t.Helper()
+
+	timeout := time.After(time.Second)
+	for db.NumDsListeners() < n {
+		select {
+		case <-timeout:
+			t.Fatalf("expected %d datastore listeners, got %d", n, db.NumDsListeners())
+		case <-time.After(time.Millisecond):
+		}
+	}
+}
+
+func TestProjectionOfDeletedObjectIsCleared(t *testing.T) {
+	t.Chdir("../../..")
+
+	flow := dstest.NewFlow(map[string]string{
+		"projection/1/id":                   "1",
+		"projection/1/meeting_id":           "1",
+		"projection/1/content_object_id":    `"topic/5"`,
+		"topic/5/id":                        "5",
+		"topic/5/meeting_id":                "1",
+		"topic/5/sequential_number":         "1",
+		"topic/5/list_of_speakers_id":       "1",
+		"topic/5/title":                     `"Break"`,
+		"topic/5/agenda_item_id":            "7",
+		"agenda_item/7/id":                  "7",
+		"agenda_item/7/meeting_id":          "1",
+		"agenda_item/7/content_object_id":   `"topic/5"`,
+		"meeting/1/id":                      "1",
+		"meeting/1/agenda_enable_numbering": "true",
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	db, err := database.New("", "", flow)
+	if err != nil {
+		t.Fatalf("create datastore: %v", err)
+	}
+
+	router := slide.New(ctx, db, flow, i18n.NewLocale(language.English))
+	addProjection := make(chan int)
+	removeProjection := make(chan int)
+	updates := router.SubscribeContent(addProjection, removeProjection)
+	addProjection <- 1
+
+	update := receiveUpdate(t, updates)
+	if !strings.Contains(update.Content, "Break") {
+		t.Fatalf("expected topic to be rendered, got %q", update.Content)
+	}
+	waitForListeners(t, db, 1)
+
+	flow.Changes <- map[dskey.Key][]byte{
+		dskey.MustKey("topic/5/id"):                  nil,
+		dskey.MustKey("topic/5/meeting_id"):          nil,
+		dskey.MustKey("topic/5/title"):               nil,
+		dskey.MustKey("topic/5/agenda_item_id"):      nil,
+		dskey.MustKey("topic/5/sequential_number"):   nil,
+		dskey.MustKey("topic/5/list_of_speakers_id"): nil,
+	}
+
+	update = receiveUpdate(t, updates)
+	if update.ID != 1 || update.Content != "" {
+		t.Errorf("expected projection 1 to be cleared, got %d: %q", update.ID, update.Content)
+	}
+}
+
+func TestMissingRequiredFieldIsShownAsSlideError(t *testing.T) {
+	t.Chdir("../../..")
+
+	// The topic has no title, which is required
+	flow := dstest.NewFlow(map[string]string{
+		"projection/1/id":                   "1",
+		"projection/1/meeting_id":           "1",
+		"projection/1/content_object_id":    `"topic/5"`,
+		"topic/5/id":                        "5",
+		"topic/5/meeting_id":                "1",
+		"topic/5/sequential_number":         "1",
+		"topic/5/list_of_speakers_id":       "1",
+		"topic/5/text":                      `"<p>Coffee and cake</p>"`,
+		"topic/5/agenda_item_id":            "7",
+		"agenda_item/7/id":                  "7",
+		"agenda_item/7/meeting_id":          "1",
+		"agenda_item/7/item_number":         `"TOP 3"`,
+		"agenda_item/7/content_object_id":   `"topic/5"`,
+		"meeting/1/id":                      "1",
+		"meeting/1/agenda_enable_numbering": "true",
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	db, err := database.New("", "", flow)
+	if err != nil {
+		t.Fatalf("create datastore: %v", err)
+	}
+
+	router := slide.New(ctx, db, flow, i18n.NewLocale(language.English))
+	addProjection := make(chan int)
+	removeProjection := make(chan int)
+	updates := router.SubscribeContent(addProjection, removeProjection)
+	addProjection <- 1
+
+	update := receiveUpdate(t, updates)
+	if update.Unavailable {
+		t.Fatalf("expected a slide error instead of an unavailable projection, got %+v", update)
+	}
+
+	if update.Error == "" || !strings.Contains(update.Content, "slide-error") {
+		t.Errorf("expected slide error, got %+v", update)
+	}
+
+	if strings.Contains(update.Content, "Coffee and cake") {
+		t.Errorf("expected no content with a zero value title, got %q", update.Content)
+	}
+}
+
+func TestPanickingSlideHandlerIsRecovered(t *testing.T) {
+	t.Chdir("../../..")
+
+	flow := dstest.NewFlow(map[string]string{
+		"projection/1/id":                "1",
+		"projection/1/meeting_id":        "1",
+		"projection/1/content_object_id": `"topic/5"`,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	db, err := database.New("", "", flow)
+	if err != nil {
+		t.Fatalf("create datastore: %v", err)
+	}
+
+	router := slide.New(ctx, db, flow, i18n.NewLocale(language.English))
+	router.Routes["topic"] = func(ctx context.Context, req *slide.ProjectionRequest) (map[string]any, error) {
+		panic("malformed data")
+	}
+
+	before := slide.RenderPanics()["topic"]
+
+	addProjection := make(chan int)
+	removeProjection := make(chan int)
+	updates := router.SubscribeContent(addProjection, removeProjection)
+	addProjection <- 1
+
+	update := receiveUpdate(t, updates)
+	if !strings.Contains(update.Content, "slide-error") {
+		t.Errorf("expected error placeholder, got %q", update.Content)
+	}
+
+	if update.Error != "This slide could not be rendered" {
+		t.Errorf("expected error message, got %q", update.Error)
+	}
+
+	if got := slide.RenderPanics()["topic"]; got != before+1 {
+		t.Errorf("expected %d render panics for topic, got %d", before+1, got)
+	}
+}
+
+func TestOversizedSlideIsReplaced(t *testing.T) {
+	t.Chdir("../../..")
+
+	flow := dstest.NewFlow(map[string]string{
+		"projection/1/id":                "1",
+		"projection/1/meeting_id":        "1",
+		"projection/1/content_object_id": `"topic/5"`,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	db, err := database.New("", "", flow)
+	if err != nil {
+		t.Fatalf("create datastore: %v", err)
+	}
+
+	router := slide.New(ctx, db, flow, i18n.NewLocale(language.English))
+	router.MaxContentSize = 1024
+	router.Routes["topic"] = func(ctx context.Context, req *slide.ProjectionRequest) (map[string]any, error) {
+		return map[string]any{
+			"AgendaItem": map[string]string{},
+			"Topic":      map[string]string{"Title": "Huge"},
+			"Text":       strings.Repeat("diff ", 1000),
+		}, nil
+	}
+
+	before := slide.OversizedSlides()["topic"]
+
+	addProjection := make(chan int)
+	removeProjection := make(chan int)
+	updates := router.SubscribeContent(addProjection, removeProjection)
+	addProjection <- 1
+
+	update := receiveUpdate(t, updates)
+	if !strings.Contains(update.Content, "slide-too-large") || strings.Contains(update.Content, "diff diff") {
+		t.Errorf("expected placeholder for oversized slide, got %q", update.Content)
+	}
+
+	if got := slide.OversizedSlides()["topic"]; got != before+1 {
+		t.Errorf("expected %d oversized topic slides, got %d", before+1, got)
+	}
+}
+
+func TestWifiAccessDataSlide(t *testing.T) {
+	t.Chdir("../../..")
+
+	flow := dstest.NewFlow(map[string]string{
+		"projection/1/id":                     "1",
+		"projection/1/meeting_id":             "1",
+		"projection/1/type":                   `"wifi_access_data"`,
+		"projection/1/content_object_id":      `"meeting/1"`,
+		"meeting/1/id":                        "1",
+		"meeting/1/users_pdf_wlan_ssid":       `"Venue;Guest"`,
+		"meeting/1/users_pdf_wlan_password":   `"secret"`,
+		"meeting/1/users_pdf_wlan_encryption": `"WPA"`,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	db, err := database.New("", "", flow)
+	if err != nil {
+		t.Fatalf("create datastore: %v", err)
+	}
+
+	router := slide.New(ctx, db, flow, i18n.NewLocale(language.English))
+	addProjection := make(chan int)
+	removeProjection := make(chan int)
+	updates := router.SubscribeContent(addProjection, removeProjection)
+	addProjection <- 1
+
+	update := receiveUpdate(t, updates)
+	if !strings.Contains(update.Content, `text="WIFI:S:Venue\;Guest;T:WPA;P:secret;;"`) {
+		t.Errorf("expected qr code payload, got %q", update.Content)
+	}
+
+	if !strings.Contains(update.Content, "Venue;Guest") || !strings.Contains(update.Content, "secret") {
+		t.Errorf("expected wifi name and password, got %q", update.Content)
+	}
+
+	// Without a password an encrypted network can not be joined by qr code
+	waitForListeners(t, db, 1)
+	flow.Changes <- map[dskey.Key][]byte{
+		dskey.MustKey("meeting/1/users_pdf_wlan_password"): nil,
+	}
+
+	update = receiveUpdate(t, updates)
+	if strings.Contains(update.Content, "projector-qr-code") {
+		t.Errorf("expected no qr code without password, got %q", update.Content)
+	}
+}
+
+func TestAssignmentCandidateNames(t *testing.T) {
+	t.Chdir("../../..")
+
+	flow := dstest.NewFlow(map[string]string{
+		"projection/1/id":                        "1",
+		"projection/1/meeting_id":                "1",
+		"projection/1/type":                      `"assignment"`,
+		"projection/1/content_object_id":         `"assignment/1"`,
+		"assignment/1/id":                        "1",
+		"assignment/1/title":                     `"Board"`,
+		"assignment/1/meeting_id":                "1",
+		"assignment/1/sequential_number":         "1",
+		"assignment/1/list_of_speakers_id":       "1",
+		"assignment/1/candidate_ids":             "[1,2,3]",
+		"assignment_candidate/1/id":              "1",
+		"assignment_candidate/1/assignment_id":   "1",
+		"assignment_candidate/1/meeting_id":      "1",
+		"assignment_candidate/1/weight":          "1",
+		"assignment_candidate/1/meeting_user_id": "1",
+		"assignment_candidate/2/id":              "2",
+		"assignment_candidate/2/assignment_id":   "1",
+		"assignment_candidate/2/meeting_id":      "1",
+		"assignment_candidate/2/weight":          "2",
+		"assignment_candidate/2/meeting_user_id": "2",
+		"assignment_candidate/3/id":              "3",
+		"assignment_candidate/3/assignment_id":   "1",
+		"assignment_candidate/3/meeting_id":      "1",
+		"assignment_candidate/3/weight":          "3",
+		"meeting_user/1/id":                      "1",
+		"meeting_user/1/meeting_id":              "1",
+		"meeting_user/1/group_ids":               "[1]",
+		"meeting_user/1/user_id":                 "1",
+		"meeting_user/1/structure_level_ids":     "[1]",
+		"meeting_user/1/vote_weight":             `"2.000000"`,
+		"meeting_user/2/id":                      "2",
+		"meeting_user/2/meeting_id":              "1",
+		"meeting_user/2/group_ids":               "[1]",
+		"meeting_user/2/user_id":                 "2",
+		"meeting_user/2/vote_weight":             `"1.000000"`,
+		"meeting/1/id":                           "1",
+		"meeting/1/users_enable_vote_weight":     "true",
+		"user/1/id":                              "1",
+		"user/1/organization_id":                 "1",
+		"user/1/username":                        `"ada"`,
+		"user/1/title":                           `"Dr."`,
+		"user/1/first_name":                      `"Ada"`,
+		"user/1/last_name":                       `"Lovelace"`,
+		"user/2/id":                              "2",
+		"user/2/organization_id":                 "1",
+		"user/2/username":                        `"anonymous"`,
+		"structure_level/1/id":                   "1",
+		"structure_level/1/meeting_id":           "1",
+		"structure_level/1/name":                 `"Berlin"`,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	db, err := database.New("", "", flow)
+	if err != nil {
+		t.Fatalf("create datastore: %v", err)
+	}
+
+	router := slide.New(ctx, db, flow, i18n.NewLocale(language.English))
+	addProjection := make(chan int)
+	removeProjection := make(chan int)
+	updates := router.SubscribeContent(addProjection, removeProjection)
+	addProjection <- 1
+
+	update := receiveUpdate(t, updates)
+	for _, name := range []string{"Dr. Ada Lovelace (Berlin · Vote weight: 2)", "User 2", "Unknown user"} {
+		if !strings.Contains(update.Content, name) {
+			t.Errorf("expected candidate %q, got %q", name, update.Content)
+		}
+	}
+
+	// A vote weight of one is not shown
+	if strings.Contains(update.Content, "User 2 (") {
+		t.Errorf("expected no vote weight for User 2, got %q", update.Content)
+	}
+}
+
+func TestChatMessageSlide(t *testing.T) {
+	t.Chdir("../../..")
+
+	flow := dstest.NewFlow(map[string]string{
+		"projection/1/id":                "1",
+		"projection/1/meeting_id":        "1",
+		"projection/1/stable":            "true",
+		"projection/1/content_object_id": `"chat_message/2"`,
+		"meeting/1/id":                   "1",
+		"chat_message/2/id":              "2",
+		"chat_message/2/meeting_id":      "1",
+		"chat_message/2/chat_group_id":   "3",
+		"chat_message/2/created":         "1700000000",
+		"chat_message/2/content":         `"Coffee break until <b>11:00</b>"`,
+		"chat_message/2/meeting_user_id": "4",
+		"chat_group/3/id":                "3",
+		"chat_group/3/meeting_id":        "1",
+		"chat_group/3/name":              `"Announcements"`,
+		"meeting_user/4/id":              "4",
+		"meeting_user/4/meeting_id":      "1",
+		"meeting_user/4/user_id":         "5",
+		"meeting_user/4/group_ids":       "[1]",
+		"user/5/id":                      "5",
+		"user/5/organization_id":         "1",
+		"user/5/username":                `"jdoe"`,
+		"user/5/first_name":              `"Jane"`,
+		"user/5/last_name":               `"Doe"`,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	db, err := database.New("", "", flow)
+	if err != nil {
+		t.Fatalf("create datastore: %v", err)
+	}
+
+	router := slide.New(ctx, db, flow, i18n.NewLocale(language.English))
+	addProjection := make(chan int)
+	removeProjection := make(chan int)
+	updates := router.SubscribeContent(addProjection, removeProjection)
+	addProjection <- 1
+
+	update := receiveUpdate(t, updates)
+	if !strings.Contains(update.Content, `data-is-overlay="true"`) {
+		t.Errorf("expected announcement to be rendered as overlay, got %q", update.Content)
+	}
+
+	for _, expected := range []string{"Jane Doe", "Announcements", "Coffee break until &lt;b&gt;11:00&lt;/b&gt;"} {
+		if !strings.Contains(update.Content, expected) {
+			t.Errorf("expected %q in announcement, got %q", expected, update.Content)
+		}
+	}
+
+	// Without content the announcement is not shown
+	waitForListeners(t, db, 1)
+	flow.Changes <- map[dskey.Key][]byte{
+		dskey.MustKey("chat_message/2/content"): []byte(`""`),
+	}
+
+	update = receiveUpdate(t, updates)
+	if update.Content != "" {
+		t.Errorf("expected empty announcement to render nothing, got %q", update.Content)
+	}
+}
+
+// renderProjection renders projection 1 once and returns its content.
+func renderProjection(t *testing.T, data map[string]string) string {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	flow := dstest.NewFlow(data)
+	db, err := database.New("", "", flow)
+	if err != nil {
+		t.Fatalf("create datastore: %v", err)
+	}
+
+	router := slide.New(ctx, db, flow, i18n.NewLocale(language.English))
+	addProjection := make(chan int)
+	removeProjection := make(chan int)
+	updates := router.SubscribeContent(addProjection, removeProjection)
+	addProjection <- 1
+
+	return receiveUpdate(t, updates).Content
+}
+
+// assertOrder checks that the names appear in content in the given order.
+func assertOrder(t *testing.T, content string, names ...string) {
+	t.Helper()
+
+	last := -1
+	for _, name := range names {
+		idx := strings.Index(content, name)
+		if idx == -1 {
+			t.Fatalf("expected %q in content, got %q", name, content)
+		}
+		if idx < last {
+			t.Errorf("expected %q after %q, got %q", name, names[slices.Index(names, name)-1], content)
+		}
+		last = idx
+	}
+}
+
+func TestCandidatesWithSameWeightAreOrderedByID(t *testing.T) {
+	t.Chdir("../../..")
+
+	var rendered []string
+	for _, candidateIDs := range []string{"[1,2,3]", "[3,1,2]", "[2,3,1]"} {
+		data := map[string]string{
+			"projection/1/id":                  "1",
+			"projection/1/meeting_id":          "1",
+			"projection/1/type":                `"assignment"`,
+			"meeting/1/id":                     "1",
+			"projection/1/content_object_id":   `"assignment/1"`,
+			"assignment/1/id":                  "1",
+			"assignment/1/title":               `"Board"`,
+			"assignment/1/meeting_id":          "1",
+			"assignment/1/sequential_number":   "1",
+			"assignment/1/list_of_speakers_id": "1",
+			"assignment/1/candidate_ids":       candidateIDs,
+		}
+		for i, name := range []string{"Ada", "Grace", "Edsger"} {
+			id := i + 1
+			data[fmt.Sprintf("assignment_candidate/%d/id", id)] = strconv.Itoa(id)
+			data[fmt.Sprintf("assignment_candidate/%d/assignment_id", id)] = "1"
+			data[fmt.Sprintf("assignment_candidate/%d/meeting_id", id)] = "1"
+			data[fmt.Sprintf("assignment_candidate/%d/weight", id)] = "1"
+			data[fmt.Sprintf("assignment_candidate/%d/meeting_user_id", id)] = strconv.Itoa(id)
+			data[fmt.Sprintf("meeting_user/%d/id", id)] = strconv.Itoa(id)
+			data[fmt.Sprintf("meeting_user/%d/meeting_id", id)] = "1"
+			data[fmt.Sprintf("meeting_user/%d/user_id", id)] = strconv.Itoa(id)
+			data[fmt.Sprintf("meeting_user/%d/group_ids", id)] = "[1]"
+			data[fmt.Sprintf("user/%d/id", id)] = strconv.Itoa(id)
+			data[fmt.Sprintf("user/%d/organization_id", id)] = "1"
+			data[fmt.Sprintf("user/%d/username", id)] = strconv.Quote(name)
+			data[fmt.Sprintf("user/%d/first_name", id)] = strconv.Quote(name)
+		}
+
+		content := renderProjection(t, data)
+		assertOrder(t, content, "Ada", "Grace", "Edsger")
+		rendered = append(rendered, content)
+	}
+
+	for i, content := range rendered[1:] {
+		if content != rendered[0] {
+			t.Errorf("expected render %d to equal the first render, got %q and %q", i+1, content, rendered[0])
+		}
+	}
+}
+
+func TestAgendaItemsWithSameWeightAreOrderedByID(t *testing.T) {
+	t.Chdir("../../..")
+
+	var rendered []string
+	for _, agendaItemIDs := range []string{"[1,2,3]", "[3,2,1]"} {
+		data := map[string]string{
+			"projection/1/id":                "1",
+			"projection/1/meeting_id":        "1",
+			"projection/1/type":              `"agenda_item_list"`,
+			"projection/1/content_object_id": `"meeting/1"`,
+			"meeting/1/id":                   "1",
+			"meeting/1/agenda_item_ids":      agendaItemIDs,
+		}
+		for i, title := range []string{"Welcome", "Budget", "Closing"} {
+			id := i + 1
+			weight := 1
+			if id == 3 {
+				weight = 2
+			}
+			data[fmt.Sprintf("agenda_item/%d/id", id)] = strconv.Itoa(id)
+			data[fmt.Sprintf("agenda_item/%d/meeting_id", id)] = "1"
+			data[fmt.Sprintf("agenda_item/%d/type", id)] = `"common"`
+			data[fmt.Sprintf("agenda_item/%d/weight", id)] = strconv.Itoa(weight)
+			data[fmt.Sprintf("agenda_item/%d/content_object_id", id)] = fmt.Sprintf(`"topic/%d"`, id)
+			data[fmt.Sprintf("topic/%d/id", id)] = strconv.Itoa(id)
+			data[fmt.Sprintf("topic/%d/meeting_id", id)] = "1"
+			data[fmt.Sprintf("topic/%d/title", id)] = strconv.Quote(title)
+			data[fmt.Sprintf("topic/%d/agenda_item_id", id)] = strconv.Itoa(id)
+		}
+
+		content := renderProjection(t, data)
+		assertOrder(t, content, "Welcome", "Budget", "Closing")
+		rendered = append(rendered, content)
+	}
+
+	if rendered[0] != rendered[1] {
+		t.Errorf("expected both renders to be equal, got %q and %q", rendered[0], rendered[1])
+	}
+}
+
+func TestAgendaItemTree(t *testing.T) {
+	t.Chdir("../../..")
+
+	data := map[string]string{
+		"projection/1/id":                "1",
+		"projection/1/meeting_id":        "1",
+		"projection/1/type":              `"agenda_item_list"`,
+		"projection/1/content_object_id": `"meeting/1"`,
+		"meeting/1/id":                   "1",
+		"meeting/1/agenda_item_ids":      "[1,2,3,4,5,6,7]",
+	}
+	for _, item := range []struct {
+		id     int
+		title  string
+		parent int
+		weight int
+		closed bool
+	}{
+		{1, "Welcome", 0, 1, false},
+		{2, "Budget", 0, 2, false},
+		{3, "Details", 2, 1, false},
+		{4, "Reports", 0, 3, true},
+		{5, "Annual report", 4, 1, false},
+		{6, "Cycle start", 7, 4, false},
+		{7, "Cycle end", 6, 5, false},
+	} {
+		prefix := fmt.Sprintf("agenda_item/%d/", item.id)
+		data[prefix+"id"] = strconv.Itoa(item.id)
+		data[prefix+"meeting_id"] = "1"
+		data[prefix+"type"] = `"common"`
+		data[prefix+"weight"] = strconv.Itoa(item.weight)
+		data[prefix+"closed"] = strconv.FormatBool(item.closed)
+		data[prefix+"content_object_id"] = fmt.Sprintf(`"topic/%d"`, item.id)
+		if item.parent != 0 {
+			data[prefix+"parent_id"] = strconv.Itoa(item.parent)
+		}
+		data[fmt.Sprintf("topic/%d/id", item.id)] = strconv.Itoa(item.id)
+		data[fmt.Sprintf("topic/%d/meeting_id", item.id)] = "1"
+		data[fmt.Sprintf("topic/%d/title", item.id)] = strconv.Quote(item.title)
+		data[fmt.Sprintf("topic/%d/agenda_item_id", item.id)] = strconv.Itoa(item.id)
+	}
+
+	flow := dstest.NewFlow(data)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	db, err := database.New("", "", flow)
+	if err != nil {
+		t.Fatalf("create datastore: %v", err)
+	}
+
+	router := slide.New(ctx, db, flow, i18n.NewLocale(language.English))
+	addProjection := make(chan int)
+	removeProjection := make(chan int)
+	updates := router.SubscribeContent(addProjection, removeProjection)
+	addProjection <- 1
+
+	// The html is compared without its indentation
+	render := func() string {
+		t.Helper()
+		return strings.Join(strings.Fields(receiveUpdate(t, updates).Content), " ")
+	}
+
+	content := render()
+	for _, expected := range []string{
+		"<li> Welcome </li> <li> Budget <ul> <li> Details </li> </ul> </li>",
+		`<li class="closed"> Reports <ul> <li> Annual report </li> </ul> </li>`,
+		"<li> Cycle start <ul> <li> Cycle end </li> </ul> </li>",
+	} {
+		if !strings.Contains(content, expected) {
+			t.Errorf("expected %q in content, got %q", expected, content)
+		}
+	}
+	waitForListeners(t, db, 1)
+
+	flow.Changes <- map[dskey.Key][]byte{
+		dskey.MustKey("agenda_item/3/parent_id"): []byte("1"),
+	}
+
+	content = render()
+	expected := "<li> Welcome <ul> <li> Details </li> </ul> </li> <li> Budget </li>"
+	if !strings.Contains(content, expected) {
+		t.Errorf("expected %q in content after the move, got %q", expected, content)
+	}
+
+	flow.Changes <- map[dskey.Key][]byte{
+		dskey.MustKey("projection/1/options"): []byte(`{"collapse_closed":true}`),
+	}
+
+	content = render()
+	if expected := `<li class="closed"> Reports </li>`; !strings.Contains(content, expected) || strings.Contains(content, "Annual report") {
+		t.Errorf("expected the sub items of a closed item to be collapsed with collapse_closed, got %q", content)
+	}
+}
+
+func TestListOfSpeakersShowFinished(t *testing.T) {
+	t.Chdir("../../..")
+
+	losData := func(options string, permissions string) map[string]string {
+		data := map[string]string{
+			"projection/1/id":                                     "1",
+			"projection/1/meeting_id":                             "1",
+			"projection/1/type":                                   `"list_of_speakers"`,
+			"projection/1/content_object_id":                      `"list_of_speakers/1"`,
+			"projection/1/options":                                options,
+			"meeting/1/id":                                        "1",
+			"meeting/1/default_group_id":                          "1",
+			"meeting/1/list_of_speakers_amount_last_on_projector": "1",
+			"group/1/id":                                          "1",
+			"group/1/meeting_id":                                  "1",
+			"group/1/permissions":                                 permissions,
+			"list_of_speakers/1/id":                               "1",
+			"list_of_speakers/1/meeting_id":                       "1",
+			"list_of_speakers/1/sequential_number":                "1",
+			"list_of_speakers/1/content_object_id":                `"topic/5"`,
+			"list_of_speakers/1/speaker_ids":                      "[1,2]",
+			"topic/5/id":                                          "5",
+			"topic/5/meeting_id":                                  "1",
+			"topic/5/sequential_number":                           "1",
+			"topic/5/list_of_speakers_id":                         "1",
+			"topic/5/title":                                       `"Budget"`,
+		}
+		for i, name := range []string{"Ada", "Grace"} {
+			id := i + 1
+			begin := 1000 + i*100
+			data[fmt.Sprintf("speaker/%d/id", id)] = strconv.Itoa(id)
+			data[fmt.Sprintf("speaker/%d/meeting_id", id)] = "1"
+			data[fmt.Sprintf("speaker/%d/list_of_speakers_id", id)] = "1"
+			data[fmt.Sprintf("speaker/%d/meeting_user_id", id)] = strconv.Itoa(id)
+			data[fmt.Sprintf("speaker/%d/begin_time", id)] = strconv.Itoa(begin)
+			data[fmt.Sprintf("speaker/%d/end_time", id)] = strconv.Itoa(begin + 75)
+			data[fmt.Sprintf("speaker/%d/total_pause", id)] = "10"
+			data[fmt.Sprintf("meeting_user/%d/id", id)] = strconv.Itoa(id)
+			data[fmt.Sprintf("meeting_user/%d/meeting_id", id)] = "1"
+			data[fmt.Sprintf("meeting_user/%d/user_id", id)] = strconv.Itoa(id)
+			data[fmt.Sprintf("meeting_user/%d/group_ids", id)] = "[1]"
+			data[fmt.Sprintf("user/%d/id", id)] = strconv.Itoa(id)
+			data[fmt.Sprintf("user/%d/organization_id", id)] = "1"
+			data[fmt.Sprintf("user/%d/username", id)] = strconv.Quote(name)
+			data[fmt.Sprintf("user/%d/first_name", id)] = strconv.Quote(name)
+		}
+		return data
+	}
+
+	content := renderProjection(t, losData("{}", `["list_of_speakers.can_see"]`))
+	if strings.Contains(content, "Ada") || !strings.Contains(content, "Grace") {
+		t.Errorf("expected only the last finished speaker by default, got %q", content)
+	}
+	if strings.Contains(content, "speaker-times") {
+		t.Errorf("expected no speaking times by default, got %q", content)
+	}
+
+	content = renderProjection(t, losData(`{"show_finished":true}`, `["list_of_speakers.can_manage"]`))
+	assertOrder(t, content, "Ada", "Grace")
+	if count := strings.Count(content, "(1:05)"); count != 2 {
+		t.Errorf("expected the speaking time without pauses of both speakers, got %q", content)
+	}
+
+	content = renderProjection(t, losData(`{"show_finished":true}`, `[]`))
+	assertOrder(t, content, "Ada", "Grace")
+	if strings.Contains(content, "speaker-times") {
+		t.Errorf("expected no speaking times without permission to see the list of speakers, got %q", content)
+	}
+}
+
+func TestListOfSpeakersPointOfOrder(t *testing.T) {
+	t.Chdir("../../..")
+
+	losData := func(permissions string) map[string]string {
+		return map[string]string{
+			"projection/1/id":                "1",
+			"projection/1/meeting_id":        "1",
+			"projection/1/type":              `"list_of_speakers"`,
+			"projection/1/content_object_id": `"list_of_speakers/1"`,
+			"meeting/1/id":                   "1",
+			"meeting/1/default_group_id":     "1",
+			"meeting/1/list_of_speakers_enable_point_of_order_speakers":   "true",
+			"meeting/1/list_of_speakers_enable_point_of_order_categories": "true",
+			"meeting/1/list_of_speakers_amount_next_on_projector":         "-1",
+			"group/1/id":                           "1",
+			"group/1/meeting_id":                   "1",
+			"group/1/permissions":                  permissions,
+			"list_of_speakers/1/id":                "1",
+			"list_of_speakers/1/meeting_id":        "1",
+			"list_of_speakers/1/sequential_number": "1",
+			"list_of_speakers/1/content_object_id": `"topic/5"`,
+			"list_of_speakers/1/speaker_ids":       "[1]",
+			"topic/5/id":                           "5",
+			"topic/5/meeting_id":                   "1",
+			"topic/5/sequential_number":            "1",
+			"topic/5/list_of_speakers_id":          "1",
+			"topic/5/title":                        `"Budget"`,
+			"speaker/1/id":                         "1",
+			"speaker/1/meeting_id":                 "1",
+			"speaker/1/list_of_speakers_id":        "1",
+			"speaker/1/point_of_order":             "true",
+			"speaker/1/point_of_order_category_id": "3",
+			"point_of_order_category/3/id":         "3",
+			"point_of_order_category/3/meeting_id": "1",
+			"point_of_order_category/3/rank":       "1",
+			"point_of_order_category/3/text":       `"Procedure"`,
+		}
+	}
+
+	content := renderProjection(t, losData(`["list_of_speakers.can_see"]`))
+	if !strings.Contains(content, "point-of-order") || !strings.Contains(content, "Procedure") {
+		t.Errorf("expected the point of order and its category, got %q", content)
+	}
+
+	content = renderProjection(t, losData(`[]`))
+	if strings.Contains(content, "point-of-order") || strings.Contains(content, "Procedure") {
+		t.Errorf("expected no point of order without permission to see the list of speakers, got %q", content)
+	}
+}
+
+func TestMotionLineNumbering(t *testing.T) {
+	t.Chdir("../../..")
+
+	motionData := func(options string) map[string]string {
+		return map[string]string{
+			"projection/1/id":                            "1",
+			"projection/1/meeting_id":                    "1",
+			"projection/1/type":                          `"motion"`,
+			"projection/1/content_object_id":             `"motion/1"`,
+			"projection/1/options":                       options,
+			"meeting/1/id":                               "1",
+			"meeting/1/motions_default_line_numbering":   `"outside"`,
+			"meeting/1/motions_line_length":              "80",
+			"meeting/1/motions_enable_text_on_projector": "true",
+			"motion/1/id":                                "1",
+			"motion/1/meeting_id":                        "1",
+			"motion/1/sequential_number":                 "1",
+			"motion/1/title":                             `"Budget"`,
+			"motion/1/text":                              `"<p>First paragraph</p><p>Second paragraph</p><p>Third paragraph</p>"`,
+			"motion/1/start_line_number":                 "12",
+			"motion/1/list_of_speakers_id":               "1",
+			"motion/1/state_id":                          "1",
+		}
+	}
+
+	for _, tt := range []struct {
+		options  string
+		expected string
+	}{
+		{"{}", "outside"},
+		{`{"line_numbering":"inline"}`, "inline"},
+		{`{"line_numbering":"none"}`, "none"},
+		{`{"line_numbering":"inside"}`, "outside"},
+	} {
+		content := renderProjection(t, motionData(tt.options))
+		for _, expected := range []string{
+			"line-numbers-" + tt.expected,
+			`line-numbering="` + tt.expected + `"`,
+			`first-line="12"`,
+			"<p>First paragraph</p><p>Second paragraph</p><p>Third paragraph</p>",
+		} {
+			if !strings.Contains(content, expected) {
+				t.Errorf("options %s: expected %q in content, got %q", tt.options, expected, content)
+			}
+		}
+	}
+}
+
+func TestMotionAmendmentDiffMode(t *testing.T) {
+	t.Chdir("../../..")
+
+	amendmentData := func(options string) map[string]string {
+		return map[string]string{
+			"projection/1/id":                            "1",
+			"projection/1/meeting_id":                    "1",
+			"projection/1/type":                          `"motion"`,
+			"projection/1/content_object_id":             `"motion/2"`,
+			"projection/1/options":                       options,
+			"meeting/1/id":                               "1",
+			"meeting/1/motions_line_length":              "80",
+			"meeting/1/motions_enable_text_on_projector": "true",
+			"motion/1/id":                                "1",
+			"motion/1/meeting_id":                        "1",
+			"motion/1/sequential_number":                 "1",
+			"motion/1/title":                             `"Budget"`,
+			"motion/1/text":                              `"<p>The quick brown fox</p><p>jumps over the lazy dog</p>"`,
+			"motion/1/start_line_number":                 "12",
+			"motion/1/list_of_speakers_id":               "1",
+			"motion/1/state_id":                          "1",
+			"motion/1/amendment_ids":                     "[2]",
+			"motion/2/id":                                "2",
+			"motion/2/meeting_id":                        "1",
+			"motion/2/sequential_number":                 "2",
+			"motion/2/title":                             `"Amendment to Budget"`,
+			"motion/2/lead_motion_id":                    "1",
+			"motion/2/amendment_paragraphs":              `{"1":"<p>jumps over the sleepy dog</p>","5":"<p>and runs away</p>"}`,
+			"motion/2/list_of_speakers_id":               "2",
+			"motion/2/state_id":                          "1",
+		}
+	}
+
+	// The diff is computed by the motion-diff library in the browser, all
+	// paragraphs are passed on, also those missing in the lead motion
+	paragraphs := []string{
+		"<projector-motion-amendment",
+		`first-line="12"`,
+		`<template id="lead-motion-text"><p>The quick brown fox</p><p>jumps over the lazy dog</p></template>`,
+		`<template class="paragraph" data-number="1"><p>jumps over the sleepy dog</p></template>`,
+		`<template class="paragraph" data-number="5"><p>and runs away</p></template>`,
+	}
+
+	content := renderProjection(t, amendmentData(`{"mode":"amendment_diff"}`))
+	for _, expected := range append(paragraphs, `mode="diff"`, "missing-paragraphs", "This paragraph does not exist in the motion.") {
+		if !strings.Contains(content, expected) {
+			t.Errorf("expected %q in the amendment diff, got %q", expected, content)
+		}
+	}
+
+	if strings.Contains(content, "<del>") || strings.Contains(content, "<ins>") {
+		t.Errorf("expected the diff not to be rendered on the server, got %q", content)
+	}
+
+	content = renderProjection(t, amendmentData(`{}`))
+	for _, expected := range append(paragraphs, `mode="original"`) {
+		if !strings.Contains(content, expected) {
+			t.Errorf("expected %q in the amendment, got %q", expected, content)
+		}
+	}
+
+	if strings.Contains(content, "missing-paragraphs") {
+		t.Errorf("expected missing paragraphs to be left out without the amendment diff mode, got %q", content)
+	}
+}
+
+func TestMotionRecommendationExtension(t *testing.T) {
+	t.Chdir("../../..")
+
+	data := map[string]string{
+		"projection/1/id":                "1",
+		"projection/1/meeting_id":        "1",
+		"projection/1/type":              `"motion"`,
+		"projection/1/content_object_id": `"motion/1"`,
+		"meeting/1/id":                   "1",
+		"meeting/1/motions_enable_recommendation_on_projector": "true",
+		"meeting/1/motions_recommendations_by":                 `"Committee"`,
+		"motion_state/2/id":                                    "2",
+		"motion_state/2/meeting_id":                            "1",
+		"motion_state/2/workflow_id":                           "1",
+		"motion_state/2/name":                                  `"accepted"`,
+		"motion_state/2/css_class":                             `"green"`,
+		"motion_state/2/weight":                                "2",
+		"motion_state/2/recommendation_label":                  `"Adopt as amended by"`,
+		"motion_state/2/show_recommendation_extension_field":   "true",
+		"motion/1/id":                                          "1",
+		"motion/1/meeting_id":                                  "1",
+		"motion/1/sequential_number":                           "1",
+		"motion/1/title":                                       `"Budget"`,
+		"motion/1/list_of_speakers_id":                         "1",
+		"motion/1/state_id":                                    "1",
+		"motion/1/recommendation_id":                           "2",
+		"motion/1/recommendation_extension":                    `"[motion/12], [motion/13] and in parts [motion/14]"`,
+		"motion/1/recommendation_extension_reference_ids":      `["motion/12","motion/13"]`,
+		"motion/12/id":                                         "12",
+		"motion/12/meeting_id":                                 "1",
+		"motion/12/sequential_number":                          "12",
+		"motion/12/number":                                     `"A 12"`,
+		"motion/12/title":                                      `"Amendment"`,
+		"motion/13/id":                                         "13",
+		"motion/13/meeting_id":                                 "1",
+		"motion/13/sequential_number":                          "13",
+		"motion/13/title":                                      `"Without number"`,
+	}
+
+	content := renderProjection(t, data)
+	expected := "Adopt as amended by A 12, Without number and in parts Unknown motion"
+	if !strings.Contains(content, expected) {
+		t.Errorf("expected %q in content, got %q", expected, content)
+	}
+}
+
+func TestShowItemNumber(t *testing.T) {
+	t.Chdir("../../..")
+
+	slideData := func(slideType string, contentObject string, numbering string, options string) map[string]string {
+		return map[string]string{
+			"projection/1/id":                   "1",
+			"projection/1/meeting_id":           "1",
+			"projection/1/type":                 strconv.Quote(slideType),
+			"projection/1/content_object_id":    strconv.Quote(contentObject),
+			"projection/1/options":              options,
+			"meeting/1/id":                      "1",
+			"meeting/1/agenda_enable_numbering": numbering,
+			"meeting/1/agenda_item_ids":         "[7]",
+			"agenda_item/7/id":                  "7",
+			"agenda_item/7/meeting_id":          "1",
+			"agenda_item/7/type":                `"common"`,
+			"agenda_item/7/item_number":         `"TOP 4"`,
+			"agenda_item/7/content_object_id":   `"topic/5"`,
+			"topic/5/id":                        "5",
+			"topic/5/meeting_id":                "1",
+			"topic/5/sequential_number":         "1",
+			"topic/5/list_of_speakers_id":       "1",
+			"topic/5/title":                     `"Budget"`,
+			"topic/5/agenda_item_id":            "7",
+			"motion/1/id":                       "1",
+			"motion/1/meeting_id":               "1",
+			"motion/1/sequential_number":        "1",
+			"motion/1/number":                   `"A 12"`,
+			"motion/1/title":                    `"Budget"`,
+			"motion/1/list_of_speakers_id":      "1",
+			"motion/1/state_id":                 "1",
+		}
+	}
+
+	for _, tt := range []struct {
+		name      string
+		slideType string
+		object    string
+		numbering string
+		options   string
+		number    string
+		shown     bool
+	}{
+		{"topic with numbering", "topic", "topic/5", "true", "{}", "TOP 4", true},
+		{"topic without numbering", "topic", "topic/5", "false", "{}", "TOP 4", false},
+		{"topic hidden by option", "topic", "topic/5", "true", `{"show_number":false}`, "TOP 4", false},
+		{"topic shown by option", "topic", "topic/5", "false", `{"show_number":true}`, "TOP 4", true},
+		{"agenda with numbering", "agenda_item_list", "meeting/1", "true", "{}", "TOP 4", true},
+		{"agenda without numbering", "agenda_item_list", "meeting/1", "false", "{}", "TOP 4", false},
+		{"agenda hidden by option", "agenda_item_list", "meeting/1", "true", `{"show_number":false}`, "TOP 4", false},
+		{"motion", "motion", "motion/1", "false", "{}", "A 12", true},
+		{"motion hidden by option", "motion", "motion/1", "true", `{"show_number":false}`, "A 12", false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			content := renderProjection(t, slideData(tt.slideType, tt.object, tt.numbering, tt.options))
+			if !strings.Contains(content, "Budget") {
+				t.Fatalf("expected the slide to be rendered, got %q", content)
+			}
+
+			if got := strings.Contains(content, tt.number); got != tt.shown {
+				t.Errorf("expected number shown to be %t, got %q", tt.shown, content)
+			}
+		})
+	}
+}
+
+func TestMeetingMediafileSlide(t *testing.T) {
+	t.Chdir("../../..")
+
+	mediafileData := func() map[string]string {
+		return map[string]string{
+			"projection/1/id":                   "1",
+			"projection/1/meeting_id":           "1",
+			"projection/1/type":                 `"meeting_mediafile"`,
+			"projection/1/content_object_id":    `"meeting_mediafile/4"`,
+			"meeting_mediafile/4/id":            "4",
+			"meeting_mediafile/4/meeting_id":    "1",
+			"meeting_mediafile/4/mediafile_id":  "9",
+			"meeting_mediafile/4/is_public":     "true",
+			"mediafile/9/id":                    "9",
+			"mediafile/9/owner_id":              `"meeting/1"`,
+			"mediafile/9/title":                 `"Floor plan"`,
+			"mediafile/9/mimetype":              `"image/png"`,
+			"mediafile/9/meeting_mediafile_ids": "[4]",
+		}
+	}
+
+	content := renderProjection(t, mediafileData())
+	if !strings.Contains(content, `<img src="/system/media/get/9" />`) {
+		t.Errorf("expected the image to be loaded from the media service by mediafile id, got %q", content)
+	}
+
+	for name, change := range map[string]func(map[string]string){
+		"missing mediafile": func(data map[string]string) {
+			for key := range data {
+				if strings.HasPrefix(key, "mediafile/9/") {
+					delete(data, key)
+				}
+			}
+		},
+		"missing meeting mediafile": func(data map[string]string) {
+			data["projection/1/content_object_id"] = `"meeting_mediafile/5"`
+		},
+		"other meeting": func(data map[string]string) {
+			data["meeting_mediafile/4/meeting_id"] = "2"
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			data := mediafileData()
+			change(data)
+
+			content := renderProjection(t, data)
+			if !strings.Contains(content, "This file is not available") {
+				t.Errorf("expected placeholder, got %q", content)
+			}
+
+			if strings.Contains(content, "/system/media/get/") {
+				t.Errorf("expected no mediafile url, got %q", content)
+			}
+		})
+	}
+}
+
+var (
+	registerTestRenderer sync.Once
+	testRendererCalls    atomic.Int32
+)
+
+func TestRegisteredSlideRenderer(t *testing.T) {
+	t.Chdir("../../..")
+
+	// Renderers can only be registered once per type, but tests may run
+	// several times in one process.
+	registerTestRenderer.Do(func() {
+		slide.RegisterSlideRenderer("test_renderer", func(ctx context.Context, req *slide.ProjectionRequest) (map[string]any, error) {
+			testRendererCalls.Add(1)
+			return map[string]any{
+				"_template": "projector_message",
+				"Message":   template.HTML(fmt.Sprintf("rendered by test renderer for %d", *req.ContentObjectID)),
+			}, nil
+		})
+	})
+	before := testRendererCalls.Load()
+
+	flow := dstest.NewFlow(map[string]string{
+		"projection/1/id":                "1",
+		"projection/1/meeting_id":        "1",
+		"projection/1/content_object_id": `"test_renderer/3"`,
+		"projection/2/id":                "2",
+		"projection/2/meeting_id":        "1",
+		"projection/2/content_object_id": `"unknown_collection/3"`,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	db, err := database.New("", "", flow)
+	if err != nil {
+		t.Fatalf("create datastore: %v", err)
+	}
+
+	router := slide.New(ctx, db, flow, i18n.NewLocale(language.English))
+	addProjection := make(chan int)
+	removeProjection := make(chan int)
+	updates := router.SubscribeContent(addProjection, removeProjection)
+
+	addProjection <- 1
+	update := receiveUpdate(t, updates)
+	if !strings.Contains(update.Content, "rendered by test renderer for 3") {
+		t.Errorf("expected content of the registered renderer, got %q", update.Content)
+	}
+
+	if got := testRendererCalls.Load() - before; got != 1 {
+		t.Errorf("expected registered renderer to be invoked once, got %d", got)
+	}
+
+	addProjection <- 2
+	update = receiveUpdate(t, updates)
+	if update.ID != 2 || update.Content != "" {
+		t.Errorf("expected empty content of the generic renderer for projection 2, got %d: %q", update.ID, update.Content)
+	}
+}
+
+func TestPollTurnout(t *testing.T) {
+	t.Chdir("../../..")
+
+	pollData := func(state string, pollType string) map[string]string {
+		return map[string]string{
+			"projection/1/id":                "1",
+			"projection/1/meeting_id":        "1",
+			"projection/1/content_object_id": `"poll/2"`,
+			"poll/2/id":                      "2",
+			"poll/2/meeting_id":              "1",
+			"poll/2/sequential_number":       "2",
+			"poll/2/content_object_id":       `"topic/3"`,
+			"poll/2/title":                   `"Budget"`,
+			"poll/2/type":                    `"` + pollType + `"`,
+			"poll/2/backend":                 `"fast"`,
+			"poll/2/pollmethod":              `"YNA"`,
+			"poll/2/state":                   `"` + state + `"`,
+			"poll/2/onehundred_percent_base": `"YNA"`,
+			"poll/2/option_ids":              "[4]",
+			"poll/2/entitled_group_ids":      "[5]",
+			"poll/2/voted_ids":               "[10, 11]",
+			"poll/2/votescast":               `"3.000000"`,
+			"poll/2/votesvalid":              `"2.000000"`,
+			"poll/2/votesinvalid":            `"1.000000"`,
+			"poll/2/entitled_users_at_stop":  `[{"user_id":10},{"user_id":11},{"user_id":12},{"user_id":13}]`,
+			"option/4/id":                    "4",
+			"option/4/meeting_id":            "1",
+			"option/4/poll_id":               "2",
+			"option/4/text":                  `"Budget"`,
+			"option/4/yes":                   `"2.000000"`,
+			"group/5/id":                     "5",
+			"group/5/meeting_id":             "1",
+			"group/5/name":                   `"Delegates"`,
+			"group/5/meeting_user_ids":       "[20, 21, 22, 23, 24]",
+			"meeting/1/id":                   "1",
+			"meeting/1/name":                 `"Meeting"`,
+		}
+	}
+
+	for _, tt := range []struct {
+		name     string
+		state    string
+		pollType string
+		expected []string
+		hidden   []string
+	}{
+		{"published", "published", "named", []string{"Total votes cast: 3", "Valid votes: 2", "Invalid votes: 1", "Entitled users: 4", "Turnout: 75%"}, nil},
+		{"counting", "finished", "pseudoanonymous", []string{"Counting of votes is in progress"}, []string{"poll-turnout", "Total votes cast", "Valid votes", "Invalid votes", "Turnout"}},
+		{"running named", "started", "named", []string{"Voting in progress", "Total votes cast: 2", "Entitled users: 5", "Turnout: 40%"}, []string{"Valid votes", "Invalid votes"}},
+		{"running pseudoanonymous", "started", "pseudoanonymous", nil, []string{"poll-turnout"}},
+		{"not started", "created", "named", nil, []string{"poll-turnout"}},
+		{"analog", "published", "analog", nil, []string{"poll-turnout"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			content := renderProjection(t, pollData(tt.state, tt.pollType))
+
+			for _, expected := range tt.expected {
+				if !strings.Contains(content, expected) {
+					t.Errorf("expected %q in poll slide, got %q", expected, content)
+				}
+			}
+
+			for _, hidden := range tt.hidden {
+				if strings.Contains(content, hidden) {
+					t.Errorf("expected no %q in poll slide, got %q", hidden, content)
+				}
+			}
+		})
+	}
+}
+
+func TestRankedElectionResult(t *testing.T) {
+	t.Chdir("../../..")
+
+	electionData := func(openPosts int, state string) map[string]string {
+		data := map[string]string{
+			"projection/1/id":                "1",
+			"projection/1/meeting_id":        "1",
+			"projection/1/content_object_id": `"poll/2"`,
+			"projection/1/options":           `{"ranked":true}`,
+			"poll/2/id":                      "2",
+			"poll/2/meeting_id":              "1",
+			"poll/2/sequential_number":       "2",
+			"poll/2/content_object_id":       `"assignment/3"`,
+			"poll/2/title":                   `"Board"`,
+			"poll/2/type":                    `"analog"`,
+			"poll/2/backend":                 `"fast"`,
+			"poll/2/pollmethod":              `"Y"`,
+			"poll/2/state":                   `"` + state + `"`,
+			"poll/2/live_voting_enabled":     "true",
+			"poll/2/onehundred_percent_base": `"disabled"`,
+			"poll/2/option_ids":              "[4, 5, 6, 7]",
+			"poll/2/votesvalid":              `"11.000000"`,
+			"assignment/3/id":                "3",
+			"assignment/3/meeting_id":        "1",
+			"assignment/3/title":             `"Board"`,
+			"assignment/3/open_posts":        strconv.Itoa(openPosts),
+			"meeting/1/id":                   "1",
+			"meeting/1/name":                 `"Meeting"`,
+		}
+
+		for i, option := range []struct{ name, yes string }{{"Dana", "0"}, {"Bob", "3"}, {"Alice", "5"}, {"Carol", "3"}} {
+			id := strconv.Itoa(4 + i)
+			data["option/"+id+"/id"] = id
+			data["option/"+id+"/meeting_id"] = "1"
+			data["option/"+id+"/poll_id"] = "2"
+			data["option/"+id+"/weight"] = id
+			data["option/"+id+"/text"] = `"` + option.name + `"`
+			data["option/"+id+"/yes"] = `"` + option.yes + `.000000"`
+		}
+		return data
+	}
+
+	// candidateStatus returns the rank and the election status shown for
+	// each candidate in the order of the slide.
+	candidateStatus := regexp.MustCompile(`(?s)<span>(\d+)\.</span>\s*</td>\s*<td class="voting-option">\s*<div>\s*<span class="candidate-name">(\w+)</span>\s*(?:<span class="election-status \w+">(\w+)</span>)?`)
+
+	for _, tt := range []struct {
+		name      string
+		openPosts int
+		state     string
+		expected  string
+		notEnough bool
+	}{
+		{"tie for the last post", 2, "published", "1 Alice Elected, 2 Bob Tie, 2 Carol Tie, 4 Dana", false},
+		{"tie within the posts", 3, "published", "1 Alice Elected, 2 Bob Elected, 2 Carol Elected, 4 Dana", false},
+		{"no votes are not elected", 4, "published", "1 Alice Elected, 2 Bob Elected, 2 Carol Elected, 4 Dana", false},
+		{"not enough candidates", 5, "published", "1 Alice Elected, 2 Bob Elected, 2 Carol Elected, 4 Dana", true},
+		{"no open posts", 0, "published", "1 Alice, 2 Bob, 2 Carol, 4 Dana", false},
+		{"live voting is not ranked", 2, "started", "1 Dana, 2 Bob, 3 Alice, 4 Carol", false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			content := renderProjection(t, electionData(tt.openPosts, tt.state))
+
+			got := []string{}
+			for _, match := range candidateStatus.FindAllStringSubmatch(content, -1) {
+				got = append(got, strings.TrimSpace(match[1]+" "+match[2]+" "+match[3]))
+			}
+
+			if strings.Join(got, ", ") != tt.expected {
+				t.Errorf("expected candidates %q, got %q", tt.expected, strings.Join(got, ", "))
+			}
+
+			if strings.Contains(content, "Not enough candidates") != tt.notEnough {
+				t.Errorf("expected not enough candidates note to be shown: %v, got %q", tt.notEnough, content)
+			}
+		})
+	}
+}
+
+func TestSanitizerRulesFromDatastore(t *testing.T) {
+	t.Chdir("../../..")
+
+	flow := dstest.NewFlow(map[string]string{
+		"projection/1/id":                 "1",
+		"projection/1/meeting_id":         "1",
+		"projection/1/type":               `"topic"`,
+		"projection/1/content_object_id":  `"topic/5"`,
+		"meeting/1/id":                    "1",
+		"meeting/1/description":           `{"deny":["iframe"]}`,
+		"agenda_item/7/id":                "7",
+		"agenda_item/7/meeting_id":        "1",
+		"agenda_item/7/content_object_id": `"topic/5"`,
+		"organization/1/description":      `{"deny":["img"]}`,
+		"topic/5/id":                      "5",
+		"topic/5/meeting_id":              "1",
+		"topic/5/sequential_number":       "1",
+		"topic/5/list_of_speakers_id":     "1",
+		"topic/5/title":                   `"Budget"`,
+		"topic/5/agenda_item_id":          "7",
+		"topic/5/text":                    `"<iframe src=\"https://x\"></iframe><img src=\"a.png\"><video src=\"https://y\"></video>"`,
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	db, err := database.New("", "", flow)
+	if err != nil {
+		t.Fatalf("create datastore: %v", err)
+	}
+
+	router := slide.New(ctx, db, flow, i18n.NewLocale(language.English))
+	router.Sanitizer = &slide.SanitizerConfig{
+		Organization:      slide.SanitizerRules{Allow: []string{"iframe"}},
+		Meetings:          map[int]slide.SanitizerRules{1: {Allow: []string{"video"}}},
+		OrganizationField: "description",
+		MeetingField:      "description",
+	}
+	addProjection := make(chan int)
+	removeProjection := make(chan int)
+	updates := router.SubscribeContent(addProjection, removeProjection)
+	addProjection <- 1
+
+	assertElements := func(content string, expected map[string]bool) {
+		t.Helper()
+
+		if !strings.Contains(content, "Budget") {
+			t.Fatalf("expected the slide to be rendered, got %q", content)
+		}
+
+		for element, kept := range expected {
+			if strings.Contains(content, "<"+element) != kept {
+				t.Errorf("expected %s to be kept: %t, got %q", element, kept, content)
+			}
+		}
+	}
+
+	// The rules in the datastore replace the rules of the file
+	content := receiveUpdate(t, updates).Content
+	assertElements(content, map[string]bool{"iframe": false, "img": false, "video": false})
+	waitForListeners(t, db, 1)
+
+	// Without rules in the datastore the rules of the file are used
+	flow.Changes <- map[dskey.Key][]byte{
+		dskey.MustKey("meeting/1/description"):      nil,
+		dskey.MustKey("organization/1/description"): nil,
+	}
+
+	content = receiveUpdate(t, updates).Content
+	assertElements(content, map[string]bool{"iframe": true, "img": true, "video": true})
+}
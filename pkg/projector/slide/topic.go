@@ -2,10 +2,14 @@ package slide
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
-	"html/template"
 )
 
+func init() {
+	RegisterSlideRenderer("topic", TopicSlideHandler)
+}
+
 func TopicSlideHandler(ctx context.Context, req *projectionRequest) (map[string]any, error) {
 	if req.ContentObjectID == nil {
 		return nil, fmt.Errorf("no topic id provided for slide")
@@ -17,9 +21,24 @@ func TopicSlideHandler(ctx context.Context, req *projectionRequest) (map[string]
 		return nil, fmt.Errorf("could not load topic %w", err)
 	}
 
+	var options itemNumberOverride
+	if len(req.Projection.Options) > 0 {
+		if err := json.Unmarshal(req.Projection.Options, &options); err != nil {
+			return nil, fmt.Errorf("could not parse topic slide options: %w", err)
+		}
+	}
+
+	var showNumber bool
+	lazyShowItemNumber(req.Fetch, topic.MeetingID, &showNumber)
+	if err := req.Fetch.Execute(ctx); err != nil {
+		return nil, fmt.Errorf("could not fetch agenda numbering setting: %w", err)
+	}
+	options.apply(&showNumber)
+
 	return map[string]any{
 		"AgendaItem": topic.AgendaItem,
+		"ShowNumber": showNumber,
 		"Topic":      topic,
-		"Text":       template.HTML(topic.Text),
+		"Text":       req.sanitizeHTML(topic.Text),
 	}, nil
 }
@@ -0,0 +1,62 @@
+package slide
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/OpenSlides/openslides-go/datastore/dsmodels"
+	"github.com/OpenSlides/openslides-projector-service/pkg/i18n"
+	"github.com/OpenSlides/openslides-projector-service/pkg/viewmodels"
+	"github.com/shopspring/decimal"
+)
+
+// userNames formats the names of meeting users following the users_*
+// settings of the meeting of the projection.
+type userNames struct {
+	locale *i18n.ProjectorLocale
+
+	// showVoteWeight is users_enable_vote_weight of the meeting
+	showVoteWeight bool
+}
+
+// userNames reads the settings of the meeting of the projection deciding how
+// user names are shown.
+func (req *projectionRequest) userNames(ctx context.Context) (userNames, error) {
+	names := userNames{locale: req.Locale}
+	req.Fetch.Meeting_UsersEnableVoteWeight(req.Projection.MeetingID).Lazy(&names.showVoteWeight)
+	if err := req.Fetch.Execute(ctx); err != nil {
+		return userNames{}, fmt.Errorf("could not load user name settings: %w", err)
+	}
+
+	return names, nil
+}
+
+// name returns the name of a meeting user as shown in lists on slides: the
+// short name of the user followed by the structure levels if
+// withStructureLevels is set and the vote weight if the meeting uses vote
+// weights and it is not one. Users without a name are shown as "User <id>".
+// If the meeting user or its user does not exist anymore, e.g. because the
+// user was deleted, the fallback is returned.
+func (n userNames) name(mu *dsmodels.MeetingUser, withStructureLevels bool, fallback string) string {
+	if mu == nil || mu.User == nil {
+		return fallback
+	}
+
+	additional := []string{}
+	if withStructureLevels && len(mu.StructureLevelList) > 0 {
+		structureLevels, _ := viewmodels.MeetingUser_StructureLevelNames(mu)
+		additional = append(additional, structureLevels)
+	}
+
+	if n.showVoteWeight && !mu.VoteWeight.IsZero() && !mu.VoteWeight.Equal(decimal.NewFromInt(1)) {
+		additional = append(additional, fmt.Sprintf("%s: %s", n.locale.Get("Vote weight"), mu.VoteWeight.String()))
+	}
+
+	name := viewmodels.User_ShortName(mu.User)
+	if len(additional) == 0 {
+		return name
+	}
+
+	return fmt.Sprintf("%s (%s)", name, strings.Join(additional, " · "))
+}
@@ -6,6 +6,10 @@ import (
 	"strings"
 )
 
+func init() {
+	RegisterSlideRenderer("wifi_access_data", WifiAccessDataSlideHandler)
+}
+
 func WifiAccessDataSlideHandler(ctx context.Context, req *projectionRequest) (map[string]any, error) {
 	if req.ContentObjectID == nil {
 		return nil, fmt.Errorf("no meeting id provided for slide")
@@ -21,7 +25,7 @@ func WifiAccessDataSlideHandler(ctx context.Context, req *projectionRequest) (ma
 	req.Fetch.Meeting_UsersPdfWlanPassword(*req.ContentObjectID).Lazy(&wlanData.Password)
 	req.Fetch.Meeting_UsersPdfWlanEncryption(*req.ContentObjectID).Lazy(&wlanData.Encryption)
 	if err := req.Fetch.Execute(ctx); err != nil {
-		return nil, fmt.Errorf("could not fetch wlan data")
+		return nil, fmt.Errorf("could not fetch wlan data: %w", err)
 	}
 
 	if wlanData.SSID != "" && (wlanData.Encryption == "" || wlanData.Encryption == "nopass" || wlanData.Password != "") {
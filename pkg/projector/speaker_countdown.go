@@ -0,0 +1,186 @@
+package projector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/OpenSlides/openslides-go/datastore/dsmodels"
+	"github.com/OpenSlides/openslides-projector-service/pkg/viewmodels"
+	"github.com/rs/zerolog/log"
+)
+
+// speakerSlideTypes are the projection types of meeting slides showing the
+// list of speakers of the reference projector.
+var speakerSlideTypes = []string{"current_los", "current_speaker_chyron", "current_speaking_structure_level"}
+
+// speakerCountdownState is the live time of the current speaker of the list
+// of speakers shown on the projector. It is only set if the speaker has a
+// time limit.
+type speakerCountdownState struct {
+	countdownState
+	SpeakerID        int `json:"speaker_id"`
+	ListOfSpeakersID int `json:"list_of_speakers_id"`
+}
+
+// getSpeakerCountdownSubscription returns a channel receiving the countdown
+// of the current speaker whenever it starts, pauses, resumes or ends. nil is
+// sent if no speaker with a time limit is shown anymore.
+func (p *projector) getSpeakerCountdownSubscription(ctx context.Context) <-chan *speakerCountdownState {
+	updateChannel := make(chan *speakerCountdownState)
+
+	go func() {
+		var last *speakerCountdownState
+		p.db.NewContext(ctx, func(f *dsmodels.Fetch) {
+			state, err := speakerCountdown(ctx, f, p.projector.ID)
+			if err != nil {
+				log.Error().Err(err).Msg("failed to load speaker countdown")
+				return
+			}
+
+			if last == state || (last != nil && state != nil && *last == *state) {
+				return
+			}
+			last = state
+
+			select {
+			case updateChannel <- state:
+			case <-ctx.Done():
+			}
+		})
+	}()
+
+	return updateChannel
+}
+
+// speakerCountdown returns the countdown of the current speaker of the first
+// list of speakers shown on the projector.
+func speakerCountdown(ctx context.Context, f *dsmodels.Fetch, projectorID int) (*speakerCountdownState, error) {
+	losID, err := shownListOfSpeakersID(ctx, f, projectorID)
+	if err != nil || losID == 0 {
+		return nil, err
+	}
+
+	losQ := f.ListOfSpeakers(losID)
+	los, err := losQ.Preload(losQ.SpeakerList().StructureLevelListOfSpeakers()).First(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("loading list of speakers %d: %w", losID, err)
+	}
+
+	speaker, err := viewmodels.ListOfSpeakers_CurrentSpeaker(ctx, &los)
+	if err != nil || speaker == nil {
+		return nil, err
+	}
+
+	var interventionTime, defaultTime int
+	var coupleCountdown bool
+	f.Meeting_ListOfSpeakersInterventionTime(los.MeetingID).Lazy(&interventionTime)
+	f.Meeting_ProjectorCountdownDefaultTime(los.MeetingID).Lazy(&defaultTime)
+	f.Meeting_ListOfSpeakersCoupleCountdown(los.MeetingID).Lazy(&coupleCountdown)
+	if err := f.Execute(ctx); err != nil {
+		return nil, fmt.Errorf("loading speaking times of meeting %d: %w", los.MeetingID, err)
+	}
+
+	state := speakerCountdownState{
+		SpeakerID:        speaker.ID,
+		ListOfSpeakersID: losID,
+	}
+
+	sllos, hasSLLOS := speaker.StructureLevelListOfSpeakers.Value()
+	switch {
+	case speaker.SpeechState == "intervention" && !speaker.Answer && interventionTime > 0:
+		state.DefaultTime = interventionTime
+		state.Running = speaker.PauseTime == 0
+		state.CountdownTime = viewmodels.Speaker_CalculateInterventionCountdownTime(speaker, interventionTime)
+	case hasSLLOS && sllos.StructureLevelID != 0:
+		// The time of a structure level continues over all of its speakers
+		state.DefaultTime = sllos.InitialTime
+		state.Running = sllos.CurrentStartTime != 0
+		state.CountdownTime = sllos.RemainingTime
+		if state.Running {
+			state.CountdownTime += float64(sllos.CurrentStartTime)
+		}
+	case coupleCountdown && defaultTime > 0:
+		state.DefaultTime = defaultTime
+		state.Running = speaker.PauseTime == 0
+		state.CountdownTime = viewmodels.Speaker_CalculateInterventionCountdownTime(speaker, defaultTime)
+	default:
+		return nil, nil
+	}
+
+	return &state, nil
+}
+
+// shownListOfSpeakersID returns the id of the first list of speakers shown on
+// the projector, either directly or by a current speaker slide. Returns 0 if
+// there is none.
+func shownListOfSpeakersID(ctx context.Context, f *dsmodels.Fetch, projectorID int) (int, error) {
+	projectionIDs, err := f.Projector_CurrentProjectionIDs(projectorID).Value(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("loading projections of projector %d: %w", projectorID, err)
+	}
+
+	projections, err := f.Projection(projectionIDs...).Get(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("loading projections of projector %d: %w", projectorID, err)
+	}
+
+	for _, projection := range projections {
+		collection, rawID, _ := strings.Cut(projection.ContentObjectID, "/")
+		id, err := strconv.Atoi(rawID)
+		if err != nil {
+			continue
+		}
+
+		projectionType := projection.Type
+		if projectionType == "" {
+			projectionType = collection
+		}
+
+		if collection == "list_of_speakers" && projectionType == "list_of_speakers" {
+			return id, nil
+		}
+
+		if collection != "meeting" || !slices.Contains(speakerSlideTypes, projectionType) {
+			continue
+		}
+
+		referenceProjectorID, err := f.Meeting_ReferenceProjectorID(id).Value(ctx)
+		if err != nil {
+			return 0, fmt.Errorf("loading reference projector of meeting %d: %w", id, err)
+		}
+
+		losID, err := viewmodels.Projector_ListOfSpeakersID(ctx, f, referenceProjectorID)
+		if err != nil {
+			return 0, fmt.Errorf("loading list of speakers of projector %d: %w", referenceProjectorID, err)
+		}
+
+		if losID != nil {
+			return *losID, nil
+		}
+	}
+
+	return 0, nil
+}
+
+// speakerCountdownEvent stamps the countdown of the current speaker with the
+// current server time. The data is null if no speaker countdown is shown.
+func speakerCountdownEvent(state *speakerCountdownState) *ProjectorUpdateEvent {
+	if state != nil {
+		stamped := *state
+		stamped.ServerTime = time.Now().Unix()
+		state = &stamped
+	}
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to encode speaker countdown event")
+		return nil
+	}
+
+	return &ProjectorUpdateEvent{Event: "speaker-countdown", Data: string(data)}
+}
@@ -0,0 +1,59 @@
+package projector
+
+import (
+	"maps"
+	"sync"
+)
+
+// PoolStats are the subscriber counts of the projectors of a pool.
+type PoolStats struct {
+	// ActiveProjectors is the number of projectors with at least one
+	// subscriber
+	ActiveProjectors int
+
+	// Subscribers is the number of subscribers of all projectors
+	Subscribers int
+
+	// ProjectorSubscribers is the number of subscribers per projector id.
+	// Projectors without subscribers are not listed.
+	ProjectorSubscribers map[int]int
+}
+
+// subscriberCounter counts the subscribers per projector when they come and
+// go, so the stats can be read without visiting every projector.
+type subscriberCounter struct {
+	mu     sync.Mutex
+	total  int
+	counts map[int]int
+}
+
+// add changes the number of subscribers of the projector by delta.
+func (c *subscriberCounter) add(projectorID int, delta int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.counts == nil {
+		c.counts = make(map[int]int)
+	}
+
+	c.total += delta
+	c.counts[projectorID] += delta
+	if c.counts[projectorID] <= 0 {
+		delete(c.counts, projectorID)
+	}
+}
+
+// Stats returns the current subscriber counts of the pool.
+func (pool *ProjectorPool) Stats() PoolStats {
+	c := &pool.subscribers
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	counts := make(map[int]int, len(c.counts))
+	maps.Copy(counts, c.counts)
+	return PoolStats{
+		ActiveProjectors:     len(counts),
+		Subscribers:          c.total,
+		ProjectorSubscribers: counts,
+	}
+}
@@ -0,0 +1,71 @@
+package projector
+
+import (
+	"regexp"
+
+	"github.com/OpenSlides/openslides-go/datastore/dsmodels"
+)
+
+var colorPattern = regexp.MustCompile(`^#(?:[0-9a-fA-F]{3,4}|[0-9a-fA-F]{6}|[0-9a-fA-F]{8})$`)
+
+// ThemePalette holds the colors of the organization theme used by the
+// projector. All values are validated hex colors.
+type ThemePalette struct {
+	Primary string `json:"primary"`
+	Accent  string `json:"accent"`
+	Warn    string `json:"warn"`
+	Headbar string `json:"headbar"`
+	Yes     string `json:"yes"`
+	No      string `json:"no"`
+	Abstain string `json:"abstain"`
+}
+
+var defaultThemePalette = ThemePalette{
+	Primary: "#317796",
+	Accent:  "#2196f3",
+	Warn:    "#f06400",
+	Headbar: "#317796",
+	Yes:     "#4caf50",
+	No:      "#cc6c5b",
+	Abstain: "#a6a6a6",
+}
+
+// validColor returns the color if it is a valid hex color and the fallback
+// otherwise.
+func validColor(color string, fallback string) string {
+	if colorPattern.MatchString(color) {
+		return color
+	}
+
+	return fallback
+}
+
+func newThemePalette(theme dsmodels.Theme) ThemePalette {
+	return ThemePalette{
+		Primary: validColor(theme.Primary500, defaultThemePalette.Primary),
+		Accent:  validColor(theme.Accent500, defaultThemePalette.Accent),
+		Warn:    validColor(theme.Warn500, defaultThemePalette.Warn),
+		Headbar: validColor(theme.Headbar, defaultThemePalette.Headbar),
+		Yes:     validColor(theme.Yes, defaultThemePalette.Yes),
+		No:      validColor(theme.No, defaultThemePalette.No),
+		Abstain: validColor(theme.Abstain, defaultThemePalette.Abstain),
+	}
+}
+
+// sanitizeColors removes invalid colors from the settings so the defaults of
+// the stylesheet are used instead.
+func (s *ProjectorSettings) sanitizeColors() {
+	for _, color := range []*string{
+		&s.Color,
+		&s.BackgroundColor,
+		&s.HeaderBackgroundColor,
+		&s.HeaderFontColor,
+		&s.HeaderH1Color,
+		&s.ChyronBackgroundColor,
+		&s.ChyronBackgroundColor2,
+		&s.ChyronFontColor,
+		&s.ChyronFontColor2,
+	} {
+		*color = validColor(*color, "")
+	}
+}
@@ -0,0 +1,52 @@
+package projector
+
+import (
+	"testing"
+
+	"github.com/OpenSlides/openslides-go/datastore/dsmodels"
+)
+
+func TestThemePaletteFallsBackForInvalidColors(t *testing.T) {
+	palette := newThemePalette(dsmodels.Theme{
+		Primary500: "#123456",
+		Accent500:  "red; background: url(evil)",
+		Headbar:    "#abc",
+		Yes:        "#12345",
+	})
+
+	if palette.Primary != "#123456" {
+		t.Errorf("expected valid primary color to be kept, got %q", palette.Primary)
+	}
+
+	if palette.Headbar != "#abc" {
+		t.Errorf("expected valid short headbar color to be kept, got %q", palette.Headbar)
+	}
+
+	if palette.Accent != defaultThemePalette.Accent {
+		t.Errorf("expected invalid accent color to fall back to default, got %q", palette.Accent)
+	}
+
+	if palette.Yes != defaultThemePalette.Yes {
+		t.Errorf("expected invalid yes color to fall back to default, got %q", palette.Yes)
+	}
+
+	if palette.Warn != defaultThemePalette.Warn {
+		t.Errorf("expected missing warn color to fall back to default, got %q", palette.Warn)
+	}
+}
+
+func TestSanitizeProjectorColors(t *testing.T) {
+	settings := ProjectorSettings{
+		Color:           "#000000",
+		BackgroundColor: "expression(alert(1))",
+	}
+	settings.sanitizeColors()
+
+	if settings.Color != "#000000" {
+		t.Errorf("expected valid color to be kept, got %q", settings.Color)
+	}
+
+	if settings.BackgroundColor != "" {
+		t.Errorf("expected invalid background color to be removed, got %q", settings.BackgroundColor)
+	}
+}
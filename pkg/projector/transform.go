@@ -0,0 +1,56 @@
+package projector
+
+import (
+	"fmt"
+	"runtime/debug"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ContentTransform post-processes the rendered html of a projection showing
+// an object of the given collection, e.g. to inject scripts or rewrite urls.
+type ContentTransform func(collection string, html string) string
+
+// applyContentTransforms runs the transforms in order. A panicking transform
+// is skipped, so a broken transform cannot stop the projector.
+func applyContentTransforms(transforms []ContentTransform, collection string, html string) string {
+	for i, transform := range transforms {
+		html = runContentTransform(i, transform, collection, html)
+	}
+
+	return html
+}
+
+func runContentTransform(i int, transform ContentTransform, collection string, html string) (result string) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			log.Error().
+				Err(fmt.Errorf("%v", rec)).
+				Int("transform", i).
+				Str("collection", collection).
+				Str("stack", string(debug.Stack())).
+				Msg("panic in content transform")
+
+			result = html
+		}
+	}()
+
+	return transform(collection, html)
+}
+
+// MediaCDNTransform rewrites mediafile urls in attributes of the rendered
+// html from mediaURL to the same path below cdnBase.
+func MediaCDNTransform(mediaURL string, cdnBase string) ContentTransform {
+	cdnBase = strings.TrimSuffix(cdnBase, "/") + "/"
+	mediaURL = strings.TrimSuffix(mediaURL, "/") + "/"
+	replacer := strings.NewReplacer(
+		`"`+mediaURL, `"`+cdnBase,
+		`'`+mediaURL, `'`+cdnBase,
+		`(`+mediaURL, `(`+cdnBase,
+	)
+
+	return func(collection string, html string) string {
+		return replacer.Replace(html)
+	}
+}
@@ -0,0 +1,66 @@
+package projector
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/OpenSlides/openslides-projector-service/pkg/dstest"
+	"golang.org/x/text/language"
+)
+
+func TestMediaCDNTransform(t *testing.T) {
+	transform := MediaCDNTransform("/system/media/get/", "https://cdn.example.com/media")
+
+	html := `<img src="/system/media/get/5"><a href='/system/media/get/6'>/system/media/get/7</a>`
+	expected := `<img src="https://cdn.example.com/media/5"><a href='https://cdn.example.com/media/6'>/system/media/get/7</a>`
+	if got := transform("meeting_mediafile", html); got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
+
+func TestContentTransformsAreApplied(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	data := testProjectorData()
+	data["projector/1/current_projection_ids"] = "[1]"
+	data["projection/1/id"] = "1"
+	data["projection/1/meeting_id"] = "1"
+	data["projection/1/content_object_id"] = `"topic/5"`
+	data["topic/5/id"] = "5"
+	data["topic/5/meeting_id"] = "1"
+	data["topic/5/sequential_number"] = "5"
+	data["topic/5/list_of_speakers_id"] = "1"
+	data["topic/5/title"] = `"Lunch"`
+	data["topic/5/agenda_item_id"] = "3"
+	data["agenda_item/3/id"] = "3"
+	data["agenda_item/3/meeting_id"] = "1"
+	data["agenda_item/3/content_object_id"] = `"topic/5"`
+	flow := dstest.NewFlow(data)
+	pool := newTestPool(t, ctx, flow)
+
+	collections := make(chan string, 10)
+	pool.ContentTransforms = []ContentTransform{
+		func(collection string, html string) string {
+			panic("broken transform")
+		},
+		func(collection string, html string) string {
+			collections <- collection
+			return strings.ReplaceAll(html, "Lunch", "Dinner")
+		},
+	}
+
+	content, err := pool.RenderProjector(1, language.English)
+	if err != nil {
+		t.Fatalf("render projector: %v", err)
+	}
+
+	if !strings.Contains(*content, "Dinner") || strings.Contains(*content, "Lunch") {
+		t.Errorf("expected transformed slide, got %q", *content)
+	}
+
+	if collection := <-collections; collection != "topic" {
+		t.Errorf("expected transform to be called for topic, got %q", collection)
+	}
+}
@@ -0,0 +1,66 @@
+package projector
+
+import (
+	"strconv"
+	"sync"
+)
+
+// maxProjectorMetricLabels is the number of projectors counted on their own
+// by the update metrics. Updates of further projectors are counted together
+// to keep the number of labels bounded.
+const maxProjectorMetricLabels = 100
+
+// OtherProjectorsLabel labels the updates of projectors beyond the ones
+// counted on their own.
+const OtherProjectorsLabel = "other"
+
+// projectorCounter counts events per projector.
+type projectorCounter struct {
+	mu     sync.Mutex
+	max    int
+	counts map[int]uint64
+	other  uint64
+}
+
+func newProjectorCounter(max int) *projectorCounter {
+	return &projectorCounter{max: max, counts: make(map[int]uint64)}
+}
+
+func (c *projectorCounter) inc(projectorID int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.counts[projectorID]; !ok && len(c.counts) >= c.max {
+		c.other++
+		return
+	}
+
+	c.counts[projectorID]++
+}
+
+// get returns the counts by the label of the projector, its id or
+// OtherProjectorsLabel.
+func (c *projectorCounter) get() map[string]uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	counts := make(map[string]uint64, len(c.counts)+1)
+	for id, count := range c.counts {
+		counts[strconv.Itoa(id)] = count
+	}
+
+	if c.other > 0 {
+		counts[OtherProjectorsLabel] = c.other
+	}
+
+	return counts
+}
+
+var projectorUpdates = newProjectorCounter(maxProjectorMetricLabels)
+
+// ProjectorUpdates returns the number of updates sent to the subscribers of
+// each projector. Projectors rendered in several languages are counted once
+// per language.
+func ProjectorUpdates() map[string]uint64 {
+	return projectorUpdates.get()
+}
@@ -0,0 +1,91 @@
+package projector
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/OpenSlides/openslides-go/datastore/dskey"
+	"github.com/OpenSlides/openslides-projector-service/pkg/dstest"
+	"github.com/OpenSlides/openslides-projector-service/pkg/projector/slide"
+	"golang.org/x/text/language"
+)
+
+func TestProjectorCounterIsBounded(t *testing.T) {
+	counter := newProjectorCounter(2)
+	for _, id := range []int{1, 2, 1, 3, 4, 2} {
+		counter.inc(id)
+	}
+
+	counts := counter.get()
+	expected := map[string]uint64{"1": 2, "2": 2, OtherProjectorsLabel: 2}
+	if len(counts) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, counts)
+	}
+
+	for label, count := range expected {
+		if counts[label] != count {
+			t.Errorf("expected %d updates for %s, got %d", count, label, counts[label])
+		}
+	}
+}
+
+func TestProjectorUpdateMetrics(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	data := testProjectorData()
+	data["projector/1/current_projection_ids"] = "[1]"
+	data["projection/1/id"] = "1"
+	data["projection/1/meeting_id"] = "1"
+	data["projection/1/content_object_id"] = `"topic/5"`
+	data["topic/5/id"] = "5"
+	data["topic/5/meeting_id"] = "1"
+	data["topic/5/sequential_number"] = "5"
+	data["topic/5/list_of_speakers_id"] = "1"
+	data["topic/5/title"] = `"First"`
+	data["topic/5/agenda_item_id"] = "3"
+	data["agenda_item/3/id"] = "3"
+	data["agenda_item/3/meeting_id"] = "1"
+	data["agenda_item/3/content_object_id"] = `"topic/5"`
+	flow := dstest.NewFlow(data)
+	pool := newTestPool(t, ctx, flow)
+
+	events := subscribe(t, ctx, pool, language.English)
+
+	// The counters are shared with the other tests of the package
+	updatesBefore := ProjectorUpdates()["1"]
+	rendersBefore := slide.RenderDurations()["topic"].Count
+
+	for _, title := range []string{`"Second"`, `"Third"`} {
+		flow.Changes <- map[dskey.Key][]byte{
+			dskey.MustKey("topic/5/title"): []byte(title),
+		}
+
+		timeout := time.After(time.Second)
+	wait:
+		for {
+			select {
+			case event := <-events:
+				if event.Event == "projection-updated" {
+					break wait
+				}
+			case <-timeout:
+				t.Fatalf("no projection update received")
+			}
+		}
+	}
+
+	if updates := ProjectorUpdates()["1"] - updatesBefore; updates != 2 {
+		t.Errorf("expected 2 updates of projector 1, got %d", updates)
+	}
+
+	histogram := slide.RenderDurations()["topic"]
+	if renders := histogram.Count - rendersBefore; renders != 2 {
+		t.Errorf("expected 2 renders of topics, got %d", renders)
+	}
+
+	if last := histogram.Buckets[len(histogram.Buckets)-1]; last > histogram.Count {
+		t.Errorf("expected cumulative buckets up to the count %d, got %d", histogram.Count, last)
+	}
+}
@@ -0,0 +1,68 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Timings collects the time spent in the stages of a request, e.g. the
+// restricter or rendering, for the Server-Timing header.
+type Timings struct {
+	mu        sync.Mutex
+	names     []string
+	durations map[string]time.Duration
+}
+
+type timingsKey struct{}
+
+// WithTimings returns a context collecting the timings of the stages measured
+// with Measure.
+func WithTimings(ctx context.Context) (context.Context, *Timings) {
+	timings := &Timings{durations: make(map[string]time.Duration)}
+	return context.WithValue(ctx, timingsKey{}, timings), timings
+}
+
+// Measure starts measuring a stage of the request and returns the function
+// stopping it. A stage measured more than once is reported with the sum of
+// all durations. Without timings in the context nothing is measured.
+func Measure(ctx context.Context, name string) func() {
+	timings, _ := ctx.Value(timingsKey{}).(*Timings)
+	if timings == nil {
+		return func() {}
+	}
+
+	start := time.Now()
+	return func() {
+		timings.add(name, time.Since(start))
+	}
+}
+
+func (t *Timings) add(name string, duration time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, ok := t.durations[name]; !ok {
+		t.names = append(t.names, name)
+	}
+	t.durations[name] += duration
+}
+
+// Header returns the value of the Server-Timing header with the durations in
+// milliseconds in the order the stages were first measured, e.g.
+// "restricter;dur=3.1, render;dur=1.4". Returns an empty string if
+// nothing was measured.
+func (t *Timings) Header() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	metrics := make([]string, 0, len(t.names))
+	for _, name := range t.names {
+		ms := float64(t.durations[name].Microseconds()) / 1000
+		metrics = append(metrics, fmt.Sprintf("%s;dur=%.1f", name, ms))
+	}
+
+	return strings.Join(metrics, ", ")
+}
@@ -0,0 +1,91 @@
+// Package tracing creates OpenTelemetry spans for requests, restricter calls,
+// datastore reads and slide rendering. It also measures the stages of a
+// request for the Server-Timing header, see Measure.
+//
+// Until Setup is called with an endpoint, all spans are non-recording and
+// nothing is exported.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	serviceName         = "projector"
+	instrumentationName = "github.com/OpenSlides/openslides-projector-service"
+)
+
+// propagator reads and writes the trace context of the W3C traceparent
+// header.
+var propagator = propagation.TraceContext{}
+
+// Setup exports spans via OTLP over HTTP to the given endpoint, e.g.
+// http://collector:4318. An empty endpoint disables tracing.
+//
+// The returned function flushes the spans not exported yet and has to be
+// called before the service exits.
+func Setup(ctx context.Context, endpoint string) (func(context.Context) error, error) {
+	if endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(endpoint))
+	if err != nil {
+		return nil, fmt.Errorf("creating otlp exporter: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(serviceName))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagator)
+
+	return provider.Shutdown, nil
+}
+
+// Start creates a span as child of the span in ctx.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// StartServer creates the span of an incoming request. A trace context sent
+// by the client is used as parent.
+func StartServer(r *http.Request) (context.Context, trace.Span) {
+	ctx := propagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+	return otel.Tracer(instrumentationName).Start(
+		ctx,
+		r.Method,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(r.Method),
+			semconv.URLPath(r.URL.Path),
+		),
+	)
+}
+
+// Inject adds the trace context of ctx to the headers of an outgoing request.
+func Inject(ctx context.Context, header http.Header) {
+	propagator.Inject(ctx, propagation.HeaderCarrier(header))
+}
+
+// End marks the span as failed if err is not nil and ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -1,37 +1,26 @@
 package viewmodels
 
 import (
-	"fmt"
-	"sort"
-	"strings"
-
-	"github.com/OpenSlides/openslides-go/datastore/dsmodels"
+	"cmp"
+	"slices"
 )
 
 type WeightedListEntry struct {
-	Name        string
-	MeetingUser *dsmodels.MeetingUser
-	Weight      int
+	ID     int
+	Name   string
+	Weight int
 }
 
-func CalcWeightedListNames(list []WeightedListEntry) {
-	for i, entry := range list {
-		if entry.MeetingUser != nil {
-			meetingUser := entry.MeetingUser
-			user := meetingUser.User
-			list[i].Name = User_ShortName(user)
-			if len(meetingUser.StructureLevelList) != 0 {
-				structureLevelNames := []string{}
-				for _, sl := range meetingUser.StructureLevelList {
-					structureLevelNames = append(structureLevelNames, sl.Name)
-				}
-
-				list[i].Name = fmt.Sprintf("%s (%s)", list[i].Name, strings.Join(structureLevelNames, ", "))
-			}
-		}
-	}
-
-	sort.Slice(list, func(i, j int) bool {
-		return list[i].Weight < list[j].Weight
+// SortWeightedList orders the entries by weight.
+func SortWeightedList(list []WeightedListEntry) {
+	slices.SortFunc(list, func(a, b WeightedListEntry) int {
+		return CompareWeight(a.Weight, a.ID, b.Weight, b.ID)
 	})
 }
+
+// CompareWeight orders list entries by weight and then by id, the canonical
+// order of OpenSlides lists. Entries with the same weight would otherwise be
+// shown in the order they were loaded, which can change between renders.
+func CompareWeight(aWeight, aID, bWeight, bID int) int {
+	return cmp.Or(cmp.Compare(aWeight, bWeight), cmp.Compare(aID, bID))
+}
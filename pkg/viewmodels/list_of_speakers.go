@@ -3,10 +3,11 @@ package viewmodels
 import (
 	"context"
 	"fmt"
-	"sort"
-	"strings"
+	"slices"
 
 	"github.com/OpenSlides/openslides-go/datastore/dsmodels"
+	"github.com/OpenSlides/openslides-go/perm"
+	"github.com/OpenSlides/openslides-projector-service/pkg/i18n"
 )
 
 func ListOfSpeakers_CurrentSpeaker(ctx context.Context, los *dsmodels.ListOfSpeakers) (*dsmodels.Speaker, error) {
@@ -28,15 +29,23 @@ func ListOfSpeakers_CurrentSpeaker(ctx context.Context, los *dsmodels.ListOfSpea
 }
 
 type SpeakerListItem struct {
+	ID                   int
 	Name                 string
 	Weight               int
 	IsSpeaking           bool
 	IsContribution       bool
 	IsPointOfOrder       bool
+	PointOfOrderCategory string
 	IsIntervention       bool
 	IsInterposedQuestion bool
 	IsForspeach          bool
 	IsCounterspeach      bool
+
+	// BeginTime, EndTime and Duration are unix timestamps and seconds of
+	// finished speakers, the duration without pauses.
+	BeginTime int
+	EndTime   int
+	Duration  int
 }
 
 type ListOfSpeakersLists struct {
@@ -47,11 +56,17 @@ type ListOfSpeakersLists struct {
 	WaitingInterposedQuestions []SpeakerListItem
 }
 
-func ListOfSpeakers_CategorizedLists(ctx context.Context, fetch *dsmodels.Fetch, losID int) (ListOfSpeakersLists, error) {
+// ListOfSpeakers_CategorizedLists returns the speakers of the list grouped
+// by their state. Speakers are named with userName.
+func ListOfSpeakers_CategorizedLists(ctx context.Context, fetch *dsmodels.Fetch, locale *i18n.ProjectorLocale, losID int, userName func(mu *dsmodels.MeetingUser, withStructureLevels bool, fallback string) string) (ListOfSpeakersLists, error) {
+	// Everything shown about the speakers is loaded at once, so a changed
+	// list is rendered again with a single request to the datastore
 	lQ := fetch.ListOfSpeakers(losID)
 	los, err := lQ.
 		Preload(lQ.SpeakerList().MeetingUser().StructureLevelList()).
-		Preload(lQ.SpeakerList().MeetingUser().User()).First(ctx)
+		Preload(lQ.SpeakerList().MeetingUser().User()).
+		Preload(lQ.SpeakerList().StructureLevelListOfSpeakers().StructureLevel()).
+		Preload(lQ.SpeakerList().PointOfOrderCategory()).First(ctx)
 	if err != nil {
 		return ListOfSpeakersLists{}, fmt.Errorf("could not load speakers: %w", err)
 	}
@@ -61,41 +76,45 @@ func ListOfSpeakers_CategorizedLists(ctx context.Context, fetch *dsmodels.Fetch,
 		return ListOfSpeakersLists{}, fmt.Errorf("could not fetch default_structure_level_time: %w", err)
 	}
 
+	var enablePointOfOrder bool
+	var enablePointOfOrderCategories bool
+	fetch.Meeting_ListOfSpeakersEnablePointOfOrderSpeakers(los.MeetingID).Lazy(&enablePointOfOrder)
+	fetch.Meeting_ListOfSpeakersEnablePointOfOrderCategories(los.MeetingID).Lazy(&enablePointOfOrderCategories)
+	if err := fetch.Execute(ctx); err != nil {
+		return ListOfSpeakersLists{}, fmt.Errorf("could not fetch point of order settings: %w", err)
+	}
+
+	// Points of order are only marked if everyone seeing the projector may
+	// see the details of the list of speakers
+	if enablePointOfOrder && slices.ContainsFunc(los.SpeakerList, func(s dsmodels.Speaker) bool { return s.PointOfOrder }) {
+		enablePointOfOrder, err = Meeting_AudienceHasPermission(ctx, fetch, los.MeetingID, perm.ListOfSpeakersCanSee, perm.ListOfSpeakersCanManage)
+		if err != nil {
+			return ListOfSpeakersLists{}, fmt.Errorf("could not check permission to see points of order: %w", err)
+		}
+	}
+
 	waitingSpeakers := []SpeakerListItem{}
 	interposedQuestions := []SpeakerListItem{}
 	finishedSpeakers := []SpeakerListItem{}
 	var currentSpeaker *SpeakerListItem
 	var currentInterposedQuestion *SpeakerListItem
 	for _, speaker := range los.SpeakerList {
-		name := ""
+		// Speakers of deleted users lose their meeting user
+		name := locale.Get("Unknown user")
 		if meetingUser, isSet := speaker.MeetingUser.Value(); isSet {
-			user := meetingUser.User
-			name = User_ShortName(user)
-
-			if defaultSlTime == 0 {
-				if len(meetingUser.StructureLevelList) != 0 {
-					structureLevelNames := []string{}
-					for _, sl := range meetingUser.StructureLevelList {
-						structureLevelNames = append(structureLevelNames, sl.Name)
-					}
-
-					name = fmt.Sprintf("%s (%s)", name, strings.Join(structureLevelNames, ", "))
-				}
-			} else if defaultSlTime > 0 {
-				slID, ok := speaker.StructureLevelListOfSpeakersID.Value()
-				if ok {
-					slData, err := fetch.StructureLevel(slID).First(ctx)
-					if err == nil {
-						name = fmt.Sprintf("%s (%s)", name, slData.Name)
-					}
+			name = userName(&meetingUser, defaultSlTime == 0, name)
+			if defaultSlTime > 0 && meetingUser.User != nil {
+				if slLos, ok := speaker.StructureLevelListOfSpeakers.Value(); ok && slLos.StructureLevel != nil {
+					name = fmt.Sprintf("%s (%s)", name, slLos.StructureLevel.Name)
 				}
 			}
 		}
 
 		item := SpeakerListItem{
+			ID:                   speaker.ID,
 			Name:                 name,
 			Weight:               speaker.Weight,
-			IsPointOfOrder:       speaker.PointOfOrder,
+			IsPointOfOrder:       enablePointOfOrder && speaker.PointOfOrder,
 			IsContribution:       speaker.SpeechState == "contribution",
 			IsIntervention:       speaker.SpeechState == "intervention",
 			IsInterposedQuestion: speaker.SpeechState == "interposed_question",
@@ -104,6 +123,12 @@ func ListOfSpeakers_CategorizedLists(ctx context.Context, fetch *dsmodels.Fetch,
 			IsSpeaking:           false,
 		}
 
+		if item.IsPointOfOrder && enablePointOfOrderCategories {
+			if category, ok := speaker.PointOfOrderCategory.Value(); ok {
+				item.PointOfOrderCategory = category.Text
+			}
+		}
+
 		if speaker.BeginTime == 0 && speaker.EndTime == 0 {
 			if speaker.SpeechState == "interposed_question" {
 				interposedQuestions = append(interposedQuestions, item)
@@ -122,21 +147,20 @@ func ListOfSpeakers_CategorizedLists(ctx context.Context, fetch *dsmodels.Fetch,
 			}
 		} else {
 			item.Weight = speaker.EndTime
+			item.BeginTime = speaker.BeginTime
+			item.EndTime = speaker.EndTime
+			item.Duration = max(speaker.EndTime-speaker.BeginTime-speaker.TotalPause, 0)
 			finishedSpeakers = append(finishedSpeakers, item)
 		}
 	}
 
-	sort.Slice(waitingSpeakers, func(i, j int) bool {
-		return waitingSpeakers[i].Weight < waitingSpeakers[j].Weight
-	})
-
-	sort.Slice(interposedQuestions, func(i, j int) bool {
-		return interposedQuestions[i].Weight < interposedQuestions[j].Weight
-	})
-
-	sort.Slice(finishedSpeakers, func(i, j int) bool {
-		return finishedSpeakers[i].Weight < finishedSpeakers[j].Weight
-	})
+	// Finished speakers are weighted by their end time
+	compareItems := func(a, b SpeakerListItem) int {
+		return CompareWeight(a.Weight, a.ID, b.Weight, b.ID)
+	}
+	slices.SortFunc(waitingSpeakers, compareItems)
+	slices.SortFunc(interposedQuestions, compareItems)
+	slices.SortFunc(finishedSpeakers, compareItems)
 
 	return ListOfSpeakersLists{
 		CurrentSpeaker:             currentSpeaker,
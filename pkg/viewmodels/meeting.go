@@ -0,0 +1,53 @@
+package viewmodels
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/OpenSlides/openslides-go/datastore/dsfetch"
+	"github.com/OpenSlides/openslides-go/datastore/dsmodels"
+	"github.com/OpenSlides/openslides-go/perm"
+)
+
+// Meeting_AudienceHasPermission reports whether everyone who may see the
+// projectors of the meeting has one of the permissions. These are the members
+// of the default group and, if anonymous access is enabled, the anonymous
+// user. Pass all permissions granting the requested one, e.g. can_see and
+// can_manage.
+func Meeting_AudienceHasPermission(ctx context.Context, fetch *dsmodels.Fetch, meetingID int, permissions ...perm.TPermission) (bool, error) {
+	var defaultGroupID int
+	var enableAnonymous bool
+	var anonymousGroupID dsfetch.Maybe[int]
+	fetch.Meeting_DefaultGroupID(meetingID).Lazy(&defaultGroupID)
+	fetch.Meeting_EnableAnonymous(meetingID).Lazy(&enableAnonymous)
+	fetch.Meeting_AnonymousGroupID(meetingID).Lazy(&anonymousGroupID)
+	if err := fetch.Execute(ctx); err != nil {
+		return false, fmt.Errorf("could not fetch meeting groups: %w", err)
+	}
+
+	groupIDs := []int{defaultGroupID}
+	if enableAnonymous {
+		// Without an anonymous group the anonymous user has no permissions
+		id, ok := anonymousGroupID.Value()
+		if !ok {
+			return false, nil
+		}
+		groupIDs = append(groupIDs, id)
+	}
+
+	for _, groupID := range groupIDs {
+		groupPermissions, err := fetch.Group_Permissions(groupID).Value(ctx)
+		if err != nil {
+			return false, fmt.Errorf("could not fetch permissions of group %d: %w", groupID, err)
+		}
+
+		if !slices.ContainsFunc(permissions, func(p perm.TPermission) bool {
+			return slices.Contains(groupPermissions, string(p))
+		}) {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
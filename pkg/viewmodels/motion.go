@@ -1,22 +1,50 @@
 package viewmodels
 
 import (
+	"cmp"
 	"context"
 	"fmt"
-	"strings"
+	"regexp"
 
 	"github.com/OpenSlides/openslides-go/datastore/dsmodels"
+	"github.com/OpenSlides/openslides-projector-service/pkg/i18n"
 )
 
-func Motion_RecommendationParsed(ctx context.Context, fetch *dsmodels.Fetch, motion *dsmodels.Motion) (string, error) {
-	ext := motion.RecommendationExtension
-	for _, refMotion := range motion.RecommendationExtensionReferenceIDs {
-		title, err := GetTitleInformationByContentObject(ctx, fetch, refMotion)
+// recommendationReference matches the placeholders of referenced objects in
+// a recommendation extension, e.g. [motion/12].
+var recommendationReference = regexp.MustCompile(`\[([a-z_]+/\d+)\]`)
+
+// Motion_RecommendationParsed returns the recommendation extension of the
+// motion with its references replaced by the number of the referenced
+// motion, or its title if it has no number. Free text around the references
+// is kept. References to deleted motions are replaced by a note.
+func Motion_RecommendationParsed(ctx context.Context, fetch *dsmodels.Fetch, locale *i18n.ProjectorLocale, motion *dsmodels.Motion) (string, error) {
+	var err error
+	ext := recommendationReference.ReplaceAllStringFunc(motion.RecommendationExtension, func(placeholder string) string {
 		if err != nil {
-			return "", fmt.Errorf("could not fetch recommendation motion: %w", err)
+			return placeholder
+		}
+
+		fqid := recommendationReference.FindStringSubmatch(placeholder)[1]
+		var id *int
+		if id, err = GetContentObjectField[int](ctx, fetch, "id", fqid); err != nil {
+			return placeholder
+		}
+
+		if id == nil {
+			return locale.Get("Unknown motion")
+		}
+
+		var title TitleInformation
+		if title, err = GetTitleInformationByContentObject(ctx, fetch, fqid); err != nil {
+			return placeholder
 		}
 
-		ext = strings.ReplaceAll(ext, fmt.Sprintf("[%s]", refMotion), title.Number)
+		return cmp.Or(title.Number, title.Title)
+	})
+	if err != nil {
+		return "", fmt.Errorf("could not fetch recommendation motion: %w", err)
 	}
+
 	return ext, nil
 }
@@ -3,6 +3,7 @@ package viewmodels
 import (
 	"context"
 	"fmt"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -10,7 +11,16 @@ import (
 	"github.com/OpenSlides/openslides-projector-service/pkg/i18n"
 )
 
-func Option_OptionLabel(ctx context.Context, fetch *dsmodels.Fetch, locale *i18n.ProjectorLocale, option *dsmodels.Option, userMap map[int]int) (string, error) {
+// Option_SortByWeight orders the options of a poll by weight and then by id.
+func Option_SortByWeight(options []dsmodels.Option) {
+	slices.SortFunc(options, func(a, b dsmodels.Option) int {
+		return CompareWeight(a.Weight, a.ID, b.Weight, b.ID)
+	})
+}
+
+// Option_OptionLabel returns the text, the user or the candidate list of the
+// option. Users are named with userName.
+func Option_OptionLabel(ctx context.Context, fetch *dsmodels.Fetch, locale *i18n.ProjectorLocale, option *dsmodels.Option, userMap map[int]int, userName func(mu *dsmodels.MeetingUser, withStructureLevels bool, fallback string) string) (string, error) {
 	if option.Text != "" {
 		return option.Text, nil
 	} else if !option.ContentObjectID.Null() {
@@ -30,21 +40,18 @@ func Option_OptionLabel(ctx context.Context, fetch *dsmodels.Fetch, locale *i18n
 				return "", fmt.Errorf("could not parse poll option fqid: %w", err)
 			}
 
-			muQ := fetch.MeetingUser(userMap[id])
+			muID, ok := userMap[id]
+			if !ok {
+				return locale.Get("Unknown user"), nil
+			}
+
+			muQ := fetch.MeetingUser(muID)
 			mu, err := muQ.Preload(muQ.User()).Preload(muQ.StructureLevelList()).First(ctx)
 			if err != nil {
 				return "", fmt.Errorf("could not fetch poll option meeting user: %w", err)
 			}
 
-			slName := ""
-			if len(mu.StructureLevelIDs) > 0 {
-				slNames := make([]string, 0, len(mu.StructureLevelList))
-				for _, sl := range mu.StructureLevelList {
-					slNames = append(slNames, sl.Name)
-				}
-				slName = fmt.Sprintf(" (%s)", strings.Join(slNames, ", "))
-			}
-			return fmt.Sprintf("%s %s%s", mu.User.FirstName, mu.User.LastName, slName), nil
+			return userName(&mu, true, locale.Get("Unknown user")), nil
 		} else if strings.HasPrefix(contentObjectID, "poll_candidate_list/") {
 			return locale.Get("Confirmation of the nomination list"), nil
 		}
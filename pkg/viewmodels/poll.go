@@ -1,6 +1,7 @@
 package viewmodels
 
 import (
+	"cmp"
 	"encoding/json"
 	"fmt"
 	"slices"
@@ -132,7 +133,7 @@ func Poll_EntitledUserIDsSorted(poll dsmodels.Poll, nameOrderSetting string) []i
 		muB, bExists := meetingUserMap[bID]
 		if !aExists || !bExists {
 			if !aExists && !bExists {
-				return 0
+				return cmp.Compare(aID, bID)
 			}
 			if !aExists {
 				return 1
@@ -162,14 +163,14 @@ func Poll_EntitledUserIDsSorted(poll dsmodels.Poll, nameOrderSetting string) []i
 			if firstNameA != firstNameB {
 				return strings.Compare(firstNameA, firstNameB)
 			}
-			return strings.Compare(userA.LastName, userB.LastName)
+			return cmp.Or(strings.Compare(userA.LastName, userB.LastName), cmp.Compare(aID, bID))
 		} else {
 			if userA.LastName != userB.LastName {
 				return strings.Compare(userA.LastName, userB.LastName)
 			}
 			firstNameA := strings.Trim(userA.Title+" "+userA.FirstName, " ")
 			firstNameB := strings.Trim(userB.Title+" "+userB.FirstName, " ")
-			return strings.Compare(firstNameA, firstNameB)
+			return cmp.Or(strings.Compare(firstNameA, firstNameB), cmp.Compare(aID, bID))
 		}
 	})
 
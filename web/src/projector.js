@@ -105,6 +105,86 @@ export function Projector(host, id, auth = () => ``, config = {}) {
     }
   });
 
+  eventSource.addEventListener(`transform`, e => {
+    const projectorContainer = container.querySelector(`#projector-container`);
+    const { scroll, scale } = JSON.parse(e.data);
+    projectorContainer.style.setProperty(`--projector-scroll`, scroll);
+    projectorContainer.style.setProperty(`--projector-scale`, scale);
+  });
+
+  // The countdown of the current speaker is kept to apply it to slides
+  // rendered after it was received.
+  let speakerCountdown = null;
+  const applySpeakerCountdown = () => {
+    for (let el of container.querySelectorAll(`projector-countdown.speaker-countdown`)) {
+      const active = !!speakerCountdown && +el.dataset.listOfSpeakers === speakerCountdown.list_of_speakers_id;
+      el.hidden = !active;
+      if (active) {
+        el.applyState(speakerCountdown);
+      }
+    }
+  };
+
+  eventSource.addEventListener(`speaker-countdown`, e => {
+    speakerCountdown = JSON.parse(e.data);
+    applySpeakerCountdown();
+  });
+
+  // The message of the organization is kept to show it again after the
+  // content was replaced.
+  let organizationMessage = null;
+  const applyOrganizationMessage = () => {
+    let el = container.querySelector(`.organization-message`);
+    if (!organizationMessage) {
+      el?.remove();
+      return;
+    }
+
+    if (!el) {
+      el = container.querySelector(`#projector-container`)?.appendChild(document.createElement(`div`));
+      el?.classList.add(`organization-message`);
+    }
+
+    if (el) {
+      el.textContent = organizationMessage.text;
+    }
+  };
+
+  eventSource.addEventListener(`organization-message`, e => {
+    organizationMessage = JSON.parse(e.data);
+    applyOrganizationMessage();
+  });
+
+  eventSource.addEventListener(`countdowns`, e => {
+    const countdowns = JSON.parse(e.data);
+    for (let id of Object.keys(countdowns)) {
+      for (let el of container.querySelectorAll(`projector-countdown#countdown-${id}`)) {
+        el.applyState(countdowns[id]);
+      }
+    }
+  });
+
+  eventSource.addEventListener(`tick`, e => {
+    const tick = JSON.parse(e.data);
+    if (tick.server_time) {
+      const timeOffset = tick.server_time - Math.floor(Date.now() / 1000);
+      window.serverTime = () => {
+        return new Date(Date.now() + timeOffset * 1000);
+      };
+    }
+
+    for (let id of Object.keys(tick.countdowns || {})) {
+      for (let el of container.querySelectorAll(`projector-countdown#countdown-${id}`)) {
+        el.applyTick(tick.countdowns[id]);
+      }
+    }
+  });
+
+  eventSource.addEventListener(`stale`, e => {
+    const { stale } = JSON.parse(e.data);
+    container.classList.toggle(`stale`, stale);
+  });
+
   eventSource.addEventListener(`deleted`, () => {
     console.debug(`deleted`);
   });
@@ -112,7 +192,7 @@ export function Projector(host, id, auth = () => ``, config = {}) {
   eventSource.addEventListener(`connected`, e => {
     const timeOffset = +e.data - Math.floor(Date.now() / 1000);
     window.serverTime = () => {
-      return new Date(Date.now() - timeOffset * 1000);
+      return new Date(Date.now() + timeOffset * 1000);
     };
     clock.update();
 
@@ -126,6 +206,8 @@ export function Projector(host, id, auth = () => ``, config = {}) {
     sizeListener.update();
     clock.update();
     overlayOrganizer.update();
+    applySpeakerCountdown();
+    applyOrganizationMessage();
   });
 
   eventSource.addEventListener(`projection-updated`, e => {
@@ -146,6 +228,66 @@ export function Projector(host, id, auth = () => ``, config = {}) {
     }
 
     overlayOrganizer.update();
+    applySpeakerCountdown();
+  });
+
+  eventSource.addEventListener(`projection-view`, e => {
+    const data = JSON.parse(e.data);
+
+    for (let id of Object.keys(data)) {
+      const el =
+        container.querySelector(`#slides > [data-id="${id}"]`) ||
+        container.querySelector(`.overlay-container > [data-id="${id}"]`);
+      if (!el) {
+        continue;
+      }
+
+      for (let [prop, value] of [
+        [`--projector-scroll`, data[id].scroll],
+        [`--projector-scale`, data[id].scale]
+      ]) {
+        if (value === null || value === undefined) {
+          el.style.removeProperty(prop);
+        } else {
+          el.style.setProperty(prop, value);
+        }
+      }
+    }
+  });
+
+  eventSource.addEventListener(`projection-order`, e => {
+    const order = JSON.parse(e.data);
+
+    for (let { id, z_index } of order) {
+      const el =
+        container.querySelector(`#slides > [data-id="${id}"]`) ||
+        container.querySelector(`.overlay-container > [data-id="${id}"]`);
+      if (!el) {
+        continue;
+      }
+
+      // Moves the element to the end of its container
+      el.parentNode.appendChild(el);
+      el.style.zIndex = z_index;
+    }
+
+    const overlayContainer = container.querySelector(`.overlay-container`);
+    overlayContainer?.parentNode.appendChild(overlayContainer);
+  });
+
+  eventSource.addEventListener(`slide_error`, e => {
+    const { projection_id, message } = JSON.parse(e.data);
+    const el =
+      container.querySelector(`#slides > [data-id="${projection_id}"]`) ||
+      container.querySelector(`.overlay-container > [data-id="${projection_id}"]`);
+    if (!el || el.querySelector(`.slide-error`)) {
+      return;
+    }
+
+    // Slides without a placeholder show a card instead of staying empty
+    const card = el.appendChild(document.createElement(`div`));
+    card.classList.add(`content`, `slide-error`);
+    card.appendChild(document.createElement(`p`)).textContent = message;
   });
 
   eventSource.addEventListener(`projection-deleted`, e => {
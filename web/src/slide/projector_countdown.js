@@ -2,7 +2,7 @@ export class ProjectorCountdown extends HTMLElement {
   get secondsRemaining() {
     const factor = this.defaultTime === 0 ? -1 : 1;
     if (this.running) {
-      return Math.floor(this.countdownTime - window.serverTime() / 1000) * factor;
+      return Math.floor(this.countdownTime - this.serverTime() / 1000) * factor;
     }
 
     return this.countdownTime * factor;
@@ -34,6 +34,56 @@ export class ProjectorCountdown extends HTMLElement {
     this.seconds = this.secondsRemaining;
   }
 
+  /**
+   * Returns the server time synced with the last state of this countdown or
+   * the global server time if no state was received yet.
+   */
+  serverTime() {
+    if (this.timeOffset === undefined) {
+      return window.serverTime();
+    }
+
+    return new Date(Date.now() + this.timeOffset);
+  }
+
+  /**
+   * Applies a state received with the countdowns event.
+   */
+  applyState(state) {
+    this.timeOffset = state.server_time * 1000 - Date.now();
+    this.countdownTime = state.countdown_time;
+    this.defaultTime = state.default_time;
+    this.running = state.running;
+
+    this.classList.remove('warning-time', 'negative-time');
+    this.updateComponent();
+    this.updateInterval();
+  }
+
+  /**
+   * Applies the remaining seconds received with the tick event. Running
+   * countdowns are synced to it, so all projectors show the same time.
+   */
+  applyTick(remaining) {
+    if (!this.running) {
+      return;
+    }
+
+    this.timeOffset = (this.countdownTime - remaining) * 1000 - Date.now();
+    this.updateComponent();
+  }
+
+  updateInterval() {
+    if (this.running && !this.updateCallback) {
+      this.updateCallback = setInterval(() => {
+        this.updateComponent();
+      }, 500);
+    } else if (!this.running && this.updateCallback) {
+      clearInterval(this.updateCallback);
+      this.updateCallback = null;
+    }
+  }
+
   constructor() {
     super();
   }
@@ -73,12 +123,7 @@ export class ProjectorCountdown extends HTMLElement {
     }
 
     this.updateComponent();
-
-    if (this.running) {
-      this.updateCallback = setInterval(() => {
-        this.updateComponent();
-      }, 500);
-    }
+    this.updateInterval();
   }
 
   updateComponent() {
@@ -101,6 +146,7 @@ export class ProjectorCountdown extends HTMLElement {
   disconnectedCallback() {
     if (this.updateCallback) {
       clearInterval(this.updateCallback);
+      this.updateCallback = null;
     }
   }
 }
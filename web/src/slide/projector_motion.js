@@ -64,7 +64,8 @@ export class ProjectorMotionText extends HTMLElement {
     this.i18n = this.getAttribute(`i18n`)
       ? JSON.parse(this.getAttribute(`i18n`))
       : {
-          line: `Line`
+          line: `Line`,
+          missingParagraph: `This paragraph does not exist in the motion.`
         };
   }
 
@@ -262,7 +263,7 @@ export class ProjectorMotionText extends HTMLElement {
       let style = `margin-left: 40px`;
       if (lineNumbering === `outside`) {
         style = `margin-right: 15px`;
-      } else if (lineNumbering === `inside`) {
+      } else if (lineNumbering === `inline`) {
         style = `margin-left: 45px`;
       }
 
@@ -326,20 +327,32 @@ export class ProjectorMotionAmendment extends ProjectorMotionText {
       .map(x => +x)
       .sort((a, b) => a - b);
 
-    const amendmentParagraphs = paragraphNumbers
-      .map(paraNo =>
-        HtmlDiff.getAmendmentParagraphsLines(
-          paraNo,
-          this.motionParagraphs[paraNo],
-          this.changeParagraphs[paraNo.toString()],
-          this.lineLength,
-          this.mode === `diff` ? this.changeRecos : undefined
-        )
-      )
-      .filter(para => para !== null);
-
     const text = [];
-    for (const p of amendmentParagraphs) {
+    for (const paraNo of paragraphNumbers) {
+      // The lead motion may have lost the paragraph since the amendment was
+      // written
+      if (this.motionParagraphs[paraNo] === undefined) {
+        if (this.hasAttribute(`missing-paragraphs`)) {
+          const notice = document.createElement(`div`);
+          notice.className = `alert alert-warning amendment-missing-paragraph`;
+          notice.textContent = this.i18n.missingParagraph;
+          text.push(notice.outerHTML);
+          text.push(this.changeParagraphs[paraNo.toString()]);
+        }
+        continue;
+      }
+
+      const p = HtmlDiff.getAmendmentParagraphsLines(
+        paraNo,
+        this.motionParagraphs[paraNo],
+        this.changeParagraphs[paraNo.toString()],
+        this.lineLength,
+        this.mode === `diff` ? this.changeRecos : undefined
+      );
+      if (p === null) {
+        continue;
+      }
+
       if (p.diffLineFrom === p.diffLineTo) {
         text.push(`<h3 class="amendment-line-header"><span>${this.i18n.line}</span> ${p.diffLineFrom}</h3>`);
       } else {